@@ -0,0 +1,380 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+
+	"github.com/ttarnok/instrument-swap-api/internal/data"
+	"github.com/ttarnok/instrument-swap-api/internal/data/mocks"
+)
+
+// newTestCategoryModelMock returns a category model mock with a small taxonomy:
+// the synthesizer subtype of the keyboard category and the electric-guitar subtype of the guitar category.
+func newTestCategoryModelMock() *mocks.CategoryModelMock {
+	keyboardID := int64(1)
+	guitarID := int64(3)
+
+	return mocks.NewCategoryModelMock([]*data.Category{
+		{ID: keyboardID, Name: "keyboard", Version: 1},
+		{ID: 2, ParentID: &keyboardID, Name: "synthesizer", Version: 1},
+		{ID: guitarID, Name: "guitar", Version: 1},
+		{ID: 4, ParentID: &guitarID, Name: "electric-guitar", Version: 1},
+	})
+}
+
+// TestCreateCategoryHandler implements unit tests for createCategoryHandler.
+func TestCreateCategoryHandler(t *testing.T) {
+
+	type inputBodyType struct {
+		Name     string `json:"name,omitempty"`
+		ParentID *int64 `json:"parent_id,omitempty"`
+	}
+
+	keyboardID := int64(1)
+	nonExistentID := int64(99)
+
+	type testCase struct {
+		name               string
+		inputBody          inputBodyType
+		expectedStatusCode int
+		expectedSubtree    []string
+	}
+
+	testCases := []testCase{
+		{
+			name:               "happy path",
+			inputBody:          inputBodyType{Name: "drums"},
+			expectedStatusCode: http.StatusCreated,
+			expectedSubtree:    []string{"drums"},
+		},
+		{
+			name:               "subtype",
+			inputBody:          inputBodyType{Name: "piano", ParentID: &keyboardID},
+			expectedStatusCode: http.StatusCreated,
+			expectedSubtree:    []string{"keyboard", "piano", "synthesizer"},
+		},
+		{
+			name:               "non valid name",
+			inputBody:          inputBodyType{Name: "Drum Kit"},
+			expectedStatusCode: http.StatusUnprocessableEntity,
+		},
+		{
+			name:               "duplicate name",
+			inputBody:          inputBodyType{Name: "synthesizer"},
+			expectedStatusCode: http.StatusUnprocessableEntity,
+		},
+		{
+			name:               "non existent parent",
+			inputBody:          inputBodyType{Name: "piano", ParentID: &nonExistentID},
+			expectedStatusCode: http.StatusUnprocessableEntity,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			app := &application{
+				logger: slog.New(slog.NewTextHandler(io.Discard, nil)),
+				models: data.Models{
+					Categories: newTestCategoryModelMock(),
+				},
+			}
+
+			mux := http.NewServeMux()
+			mux.HandleFunc("POST /", app.createCategoryHandler)
+
+			ts := httptest.NewServer(mux)
+			defer ts.Close()
+
+			bs, err := json.Marshal(tc.inputBody)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			resp, err := http.Post(ts.URL, "application/json", bytes.NewBuffer(bs))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer func() {
+				err := resp.Body.Close()
+				if err != nil {
+					t.Fatal(err)
+				}
+			}()
+
+			if tc.expectedStatusCode != resp.StatusCode {
+				t.Errorf(`expected status code %d, got %d`, tc.expectedStatusCode, resp.StatusCode)
+			}
+
+			if tc.expectedStatusCode != http.StatusCreated {
+				return
+			}
+
+			root := tc.inputBody.Name
+			if tc.inputBody.ParentID != nil {
+				parent, err := app.models.Categories.Get(*tc.inputBody.ParentID)
+				if err != nil {
+					t.Fatal(err)
+				}
+				root = parent.Name
+			}
+
+			subtree, err := app.models.Categories.GetSubtreeNames(root)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !slices.Equal(tc.expectedSubtree, subtree) {
+				t.Errorf(`expected subtree %v, got %v`, tc.expectedSubtree, subtree)
+			}
+		})
+	}
+}
+
+// TestUpdateCategoryHandler implements unit tests for updateCategoryHandler.
+func TestUpdateCategoryHandler(t *testing.T) {
+
+	type testCase struct {
+		name               string
+		pathParam          string
+		inputBody          string
+		expectedStatusCode int
+		expectedName       string
+		expectedLineage    []string
+	}
+
+	testCases := []testCase{
+		{
+			name:               "rename",
+			pathParam:          "2",
+			inputBody:          `{"name": "synth"}`,
+			expectedStatusCode: http.StatusOK,
+			expectedName:       "synth",
+			expectedLineage:    []string{"keyboard", "synth"},
+		},
+		{
+			name:               "move under another parent",
+			pathParam:          "4",
+			inputBody:          `{"parent_id": 1}`,
+			expectedStatusCode: http.StatusOK,
+			expectedName:       "electric-guitar",
+			expectedLineage:    []string{"electric-guitar", "keyboard"},
+		},
+		{
+			name:               "move to the top level",
+			pathParam:          "2",
+			inputBody:          `{"parent_id": 0}`,
+			expectedStatusCode: http.StatusOK,
+			expectedName:       "synthesizer",
+			expectedLineage:    []string{"synthesizer"},
+		},
+		{
+			name:               "move under a subcategory",
+			pathParam:          "1",
+			inputBody:          `{"parent_id": 2}`,
+			expectedStatusCode: http.StatusUnprocessableEntity,
+		},
+		{
+			name:               "move under itself",
+			pathParam:          "1",
+			inputBody:          `{"parent_id": 1}`,
+			expectedStatusCode: http.StatusUnprocessableEntity,
+		},
+		{
+			name:               "duplicate name",
+			pathParam:          "2",
+			inputBody:          `{"name": "guitar"}`,
+			expectedStatusCode: http.StatusUnprocessableEntity,
+		},
+		{
+			name:               "non existent category",
+			pathParam:          "99",
+			inputBody:          `{"name": "drums"}`,
+			expectedStatusCode: http.StatusNotFound,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			app := &application{
+				logger: slog.New(slog.NewTextHandler(io.Discard, nil)),
+				models: data.Models{
+					Categories: newTestCategoryModelMock(),
+				},
+			}
+
+			setUser := func(next http.HandlerFunc) http.HandlerFunc {
+				return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+					r = app.contextSetUser(r, &data.User{ID: 1})
+
+					next.ServeHTTP(w, r)
+				})
+			}
+
+			mux := http.NewServeMux()
+			mux.HandleFunc("PATCH /{id}", setUser(app.updateCategoryHandler))
+
+			ts := httptest.NewServer(mux)
+			defer ts.Close()
+
+			req, err := http.NewRequest(http.MethodPatch, fmt.Sprintf("%s/%s", ts.URL, tc.pathParam), bytes.NewBufferString(tc.inputBody))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			resp, err := ts.Client().Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer func() {
+				err := resp.Body.Close()
+				if err != nil {
+					t.Fatal(err)
+				}
+			}()
+
+			if tc.expectedStatusCode != resp.StatusCode {
+				t.Errorf(`expected status code %d, got %d`, tc.expectedStatusCode, resp.StatusCode)
+			}
+
+			if tc.expectedStatusCode != http.StatusOK {
+				return
+			}
+
+			var respBody struct {
+				Category data.Category `json:"category"`
+			}
+
+			err = json.NewDecoder(resp.Body).Decode(&respBody)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if respBody.Category.Name != tc.expectedName {
+				t.Errorf(`expected name %q, got %q`, tc.expectedName, respBody.Category.Name)
+			}
+			if respBody.Category.Version != 2 {
+				t.Errorf(`expected version %d, got %d`, 2, respBody.Category.Version)
+			}
+
+			lineage, err := app.models.Categories.GetLineageNames(tc.expectedName)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !slices.Equal(tc.expectedLineage, lineage) {
+				t.Errorf(`expected lineage %v, got %v`, tc.expectedLineage, lineage)
+			}
+		})
+	}
+}
+
+// TestDeleteCategoryHandler implements unit tests for deleteCategoryHandler.
+func TestDeleteCategoryHandler(t *testing.T) {
+
+	type testCase struct {
+		name                string
+		pathParam           string
+		expectedStatusCode  int
+		expectedCategoryIDs []int64
+	}
+
+	testCases := []testCase{
+		{
+			name:                "happy path",
+			pathParam:           "2",
+			expectedStatusCode:  http.StatusOK,
+			expectedCategoryIDs: []int64{1, 3, 4},
+		},
+		{
+			name:                "category with subcategories",
+			pathParam:           "1",
+			expectedStatusCode:  http.StatusConflict,
+			expectedCategoryIDs: []int64{1, 2, 3, 4},
+		},
+		{
+			name:                "non existent category",
+			pathParam:           "99",
+			expectedStatusCode:  http.StatusNotFound,
+			expectedCategoryIDs: []int64{1, 2, 3, 4},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			app := &application{
+				logger: slog.New(slog.NewTextHandler(io.Discard, nil)),
+				models: data.Models{
+					Categories: newTestCategoryModelMock(),
+				},
+			}
+
+			mux := http.NewServeMux()
+			mux.HandleFunc("GET /", app.listCategoriesHandler)
+			mux.HandleFunc("DELETE /{id}", app.deleteCategoryHandler)
+
+			ts := httptest.NewServer(mux)
+			defer ts.Close()
+
+			req, err := http.NewRequest(http.MethodDelete, fmt.Sprintf("%s/%s", ts.URL, tc.pathParam), nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			resp, err := ts.Client().Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer func() {
+				err := resp.Body.Close()
+				if err != nil {
+					t.Fatal(err)
+				}
+			}()
+
+			if tc.expectedStatusCode != resp.StatusCode {
+				t.Errorf(`expected status code %d, got %d`, tc.expectedStatusCode, resp.StatusCode)
+			}
+
+			listResp, err := http.Get(ts.URL)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer func() {
+				err := listResp.Body.Close()
+				if err != nil {
+					t.Fatal(err)
+				}
+			}()
+
+			var respBody struct {
+				Categories []*data.Category `json:"categories"`
+			}
+
+			err = json.NewDecoder(listResp.Body).Decode(&respBody)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			categoryIDs := []int64{}
+			for _, category := range respBody.Categories {
+				categoryIDs = append(categoryIDs, category.ID)
+			}
+
+			if !slices.Equal(tc.expectedCategoryIDs, categoryIDs) {
+				t.Errorf(`expected categories %v, got %v`, tc.expectedCategoryIDs, categoryIDs)
+			}
+		})
+	}
+}
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+
+	"github.com/ttarnok/instrument-swap-api/internal/data"
+	"github.com/ttarnok/instrument-swap-api/internal/validator"
+)
+
+// createConditionReportHandler handles reporting the condition of an instrument of the swap with the given id.
+// Both the requester and the recipient can report every instrument of the swap once at the handover and once at the return.
+func (app *application) createConditionReportHandler(w http.ResponseWriter, r *http.Request) {
+	authUser := app.contextGetUser(r)
+
+	swapID, err := app.extractIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	var input struct {
+		InstrumentID int64    `json:"instrument_id"`
+		Stage        string   `json:"stage"`
+		Grade        string   `json:"grade"`
+		Defects      []string `json:"defects"`
+		Notes        string   `json:"notes"`
+	}
+
+	err = app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	report := &data.ConditionReport{
+		SwapID:         swapID,
+		InstrumentID:   input.InstrumentID,
+		ReporterUserID: authUser.ID,
+		Stage:          input.Stage,
+		Grade:          input.Grade,
+		Defects:        input.Defects,
+		Notes:          input.Notes,
+	}
+
+	v := validator.New()
+
+	if data.ValidateConditionReport(v, report); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	swap, err := app.models.Swaps.Get(swapID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorLogResponse(w, r, err)
+		}
+		return
+	}
+
+	requesterUserID, recipientUserID, err := app.swapOwnerIDs(swap)
+	if err != nil {
+		app.serverErrorLogResponse(w, r, err)
+		return
+	}
+
+	if authUser.ID != requesterUserID && authUser.ID != recipientUserID {
+		app.forbiddenResponse(w, r)
+		return
+	}
+
+	if v.Check(slices.Contains(swap.InstrumentIDs(), report.InstrumentID), "instrument_id", "must be an instrument of the swap"); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	if !data.IsConditionReportable(swap, report.Stage) {
+		app.badRequestResponse(w, r, data.ErrSwapNotReportable)
+		return
+	}
+
+	err = app.models.ConditionReports.Insert(report)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrDuplicateConditionReport):
+			app.badRequestResponse(w, r, err)
+		default:
+			app.serverErrorLogResponse(w, r, err)
+		}
+		return
+	}
+
+	headers := make(http.Header)
+	headers.Set("Location", fmt.Sprintf("/v1/swaps/%d/condition-reports", swap.ID))
+
+	err = app.writeJSON(w, http.StatusCreated, envelope{"condition_report": report}, headers)
+	if err != nil {
+		app.serverErrorLogResponse(w, r, err)
+		return
+	}
+}
+
+// listConditionReportsHandler handles listing the condition reports of the swap with the given id,
+// the handover and return reports of every instrument are shown side-by-side.
+// The parties of the swap and the dispute moderators can see the condition reports.
+func (app *application) listConditionReportsHandler(w http.ResponseWriter, r *http.Request) {
+	authUser := app.contextGetUser(r)
+
+	swapID, err := app.extractIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	swap, err := app.models.Swaps.Get(swapID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorLogResponse(w, r, err)
+		}
+		return
+	}
+
+	if !authUser.Permissions.Include(data.PermissionDisputesModerate) {
+		requesterUserID, recipientUserID, err := app.swapOwnerIDs(swap)
+		if err != nil {
+			app.serverErrorLogResponse(w, r, err)
+			return
+		}
+
+		if authUser.ID != requesterUserID && authUser.ID != recipientUserID {
+			app.forbiddenResponse(w, r)
+			return
+		}
+	}
+
+	reports, err := app.models.ConditionReports.GetAllForSwap(swap.ID)
+	if err != nil {
+		app.serverErrorLogResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"condition_reports": data.CompareConditionReports(swap, reports)}, nil)
+	if err != nil {
+		app.serverErrorLogResponse(w, r, err)
+		return
+	}
+}
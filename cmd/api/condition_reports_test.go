@@ -0,0 +1,313 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/ttarnok/instrument-swap-api/internal/data"
+	"github.com/ttarnok/instrument-swap-api/internal/data/mocks"
+)
+
+// newConditionReportTestApp returns an application with an ongoing swap (id 1), an ended swap (id 2)
+// and a pending swap (id 3) between the users 10 and 20, and the given condition reports.
+func newConditionReportTestApp(reports *mocks.ConditionReportModelMock) *application {
+	now := time.Now()
+
+	return &application{
+		logger: slog.New(slog.NewTextHandler(io.Discard, nil)),
+		models: data.Models{
+			Swaps: mocks.NewSwapModelMock([]*data.Swap{
+				{ID: 1, RequesterInstrumentID: 1, RecipientInstrumentID: 2, IsAccepted: true, AcceptedAt: &now},
+				{ID: 2, RequesterInstrumentID: 3, RecipientInstrumentID: 4, IsAccepted: true, AcceptedAt: &now, IsEnded: true, EndedAt: &now},
+				{ID: 3, RequesterInstrumentID: 5, RecipientInstrumentID: 6},
+			}),
+			ConditionReports: reports,
+			Instruments: mocks.NewNonEmptyInstrumentModelMock([]*data.Instrument{
+				{ID: 1, OwnerUserID: 10}, {ID: 2, OwnerUserID: 20},
+				{ID: 3, OwnerUserID: 10}, {ID: 4, OwnerUserID: 20},
+				{ID: 5, OwnerUserID: 10}, {ID: 6, OwnerUserID: 20},
+			}),
+		},
+	}
+}
+
+// TestCreateConditionReportHandler implements unit tests for createConditionReportHandler.
+func TestCreateConditionReportHandler(t *testing.T) {
+
+	type inputBodyType struct {
+		InstrumentID int64    `json:"instrument_id"`
+		Stage        string   `json:"stage"`
+		Grade        string   `json:"grade"`
+		Defects      []string `json:"defects,omitempty"`
+		Notes        string   `json:"notes,omitempty"`
+	}
+
+	type testCase struct {
+		name               string
+		pathParam          string
+		inputBody          inputBodyType
+		reqUser            data.User
+		reports            []*data.ConditionReport
+		expectedStatusCode int
+	}
+
+	testCases := []testCase{
+		{
+			name:               "happy path - handover",
+			pathParam:          "1",
+			inputBody:          inputBodyType{InstrumentID: 2, Stage: data.ConditionStageHandover, Grade: data.ConditionGradeGood, Defects: []string{"scratches"}, Notes: "Small scratch on the back."},
+			reqUser:            data.User{ID: 10},
+			expectedStatusCode: http.StatusCreated,
+		},
+		{
+			name:               "happy path - return",
+			pathParam:          "2",
+			inputBody:          inputBodyType{InstrumentID: 3, Stage: data.ConditionStageReturn, Grade: data.ConditionGradeFair},
+			reqUser:            data.User{ID: 10},
+			expectedStatusCode: http.StatusCreated,
+		},
+		{
+			name:               "the other party reports the same instrument",
+			pathParam:          "1",
+			inputBody:          inputBodyType{InstrumentID: 2, Stage: data.ConditionStageHandover, Grade: data.ConditionGradeGood},
+			reqUser:            data.User{ID: 20},
+			reports:            []*data.ConditionReport{{ID: 1, SwapID: 1, InstrumentID: 2, ReporterUserID: 10, Stage: data.ConditionStageHandover, Grade: data.ConditionGradeGood}},
+			expectedStatusCode: http.StatusCreated,
+		},
+		{
+			name:               "duplicate report",
+			pathParam:          "1",
+			inputBody:          inputBodyType{InstrumentID: 2, Stage: data.ConditionStageHandover, Grade: data.ConditionGradeGood},
+			reqUser:            data.User{ID: 10},
+			reports:            []*data.ConditionReport{{ID: 1, SwapID: 1, InstrumentID: 2, ReporterUserID: 10, Stage: data.ConditionStageHandover, Grade: data.ConditionGradeMint}},
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:               "return of an ongoing swap",
+			pathParam:          "1",
+			inputBody:          inputBodyType{InstrumentID: 2, Stage: data.ConditionStageReturn, Grade: data.ConditionGradeGood},
+			reqUser:            data.User{ID: 10},
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:               "handover of an ended swap",
+			pathParam:          "2",
+			inputBody:          inputBodyType{InstrumentID: 3, Stage: data.ConditionStageHandover, Grade: data.ConditionGradeGood},
+			reqUser:            data.User{ID: 10},
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:               "handover of a pending swap",
+			pathParam:          "3",
+			inputBody:          inputBodyType{InstrumentID: 5, Stage: data.ConditionStageHandover, Grade: data.ConditionGradeGood},
+			reqUser:            data.User{ID: 10},
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:               "instrument of another swap",
+			pathParam:          "1",
+			inputBody:          inputBodyType{InstrumentID: 3, Stage: data.ConditionStageHandover, Grade: data.ConditionGradeGood},
+			reqUser:            data.User{ID: 10},
+			expectedStatusCode: http.StatusUnprocessableEntity,
+		},
+		{
+			name:               "unknown grade and defect",
+			pathParam:          "1",
+			inputBody:          inputBodyType{InstrumentID: 2, Stage: data.ConditionStageHandover, Grade: "awesome", Defects: []string{"haunted"}},
+			reqUser:            data.User{ID: 10},
+			expectedStatusCode: http.StatusUnprocessableEntity,
+		},
+		{
+			name:               "not a party of the swap",
+			pathParam:          "1",
+			inputBody:          inputBodyType{InstrumentID: 2, Stage: data.ConditionStageHandover, Grade: data.ConditionGradeGood},
+			reqUser:            data.User{ID: 30},
+			expectedStatusCode: http.StatusForbidden,
+		},
+		{
+			name:               "non existent swap",
+			pathParam:          "99",
+			inputBody:          inputBodyType{InstrumentID: 2, Stage: data.ConditionStageHandover, Grade: data.ConditionGradeGood},
+			reqUser:            data.User{ID: 10},
+			expectedStatusCode: http.StatusNotFound,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			app := newConditionReportTestApp(mocks.NewConditionReportModelMock(tc.reports))
+
+			setUser := func(next http.HandlerFunc) http.HandlerFunc {
+				return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+					r = app.contextSetUser(r, &tc.reqUser)
+
+					next.ServeHTTP(w, r)
+				})
+			}
+
+			mux := http.NewServeMux()
+			mux.HandleFunc("POST /{id}", setUser(app.createConditionReportHandler))
+
+			ts := httptest.NewServer(mux)
+			defer ts.Close()
+
+			bs, err := json.Marshal(tc.inputBody)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			resp, err := http.Post(fmt.Sprintf("%s/%s", ts.URL, tc.pathParam), "application/json", bytes.NewBuffer(bs))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer func() {
+				err := resp.Body.Close()
+				if err != nil {
+					t.Fatal(err)
+				}
+			}()
+
+			if tc.expectedStatusCode != resp.StatusCode {
+				t.Errorf(`expected status code %d, got %d`, tc.expectedStatusCode, resp.StatusCode)
+			}
+
+			if tc.expectedStatusCode != http.StatusCreated {
+				return
+			}
+
+			var respBody struct {
+				ConditionReport data.ConditionReport `json:"condition_report"`
+			}
+
+			err = json.NewDecoder(resp.Body).Decode(&respBody)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			report := respBody.ConditionReport
+			if report.ReporterUserID != tc.reqUser.ID || report.InstrumentID != tc.inputBody.InstrumentID ||
+				report.Stage != tc.inputBody.Stage || report.Grade != tc.inputBody.Grade {
+				t.Errorf(`unexpected condition report %#v`, report)
+			}
+			if !slices.Equal(report.Defects, tc.inputBody.Defects) {
+				t.Errorf(`expected defects %v, got %v`, tc.inputBody.Defects, report.Defects)
+			}
+		})
+	}
+}
+
+// TestListConditionReportsHandler implements unit tests for listConditionReportsHandler.
+func TestListConditionReportsHandler(t *testing.T) {
+
+	type testCase struct {
+		name               string
+		pathParam          string
+		reqUser            data.User
+		expectedStatusCode int
+	}
+
+	testCases := []testCase{
+		{
+			name:               "happy path - party",
+			pathParam:          "2",
+			reqUser:            data.User{ID: 20},
+			expectedStatusCode: http.StatusOK,
+		},
+		{
+			name:               "happy path - moderator",
+			pathParam:          "2",
+			reqUser:            data.User{ID: 30, Permissions: data.Permissions{data.PermissionDisputesModerate}},
+			expectedStatusCode: http.StatusOK,
+		},
+		{
+			name:               "not a party of the swap",
+			pathParam:          "2",
+			reqUser:            data.User{ID: 30},
+			expectedStatusCode: http.StatusForbidden,
+		},
+		{
+			name:               "non existent swap",
+			pathParam:          "99",
+			reqUser:            data.User{ID: 10},
+			expectedStatusCode: http.StatusNotFound,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			app := newConditionReportTestApp(mocks.NewConditionReportModelMock([]*data.ConditionReport{
+				{ID: 1, SwapID: 2, InstrumentID: 3, ReporterUserID: 20, Stage: data.ConditionStageHandover, Grade: data.ConditionGradeExcellent},
+				{ID: 2, SwapID: 2, InstrumentID: 3, ReporterUserID: 10, Stage: data.ConditionStageReturn, Grade: data.ConditionGradePoor, Defects: []string{"cracks"}},
+				{ID: 3, SwapID: 2, InstrumentID: 4, ReporterUserID: 10, Stage: data.ConditionStageHandover, Grade: data.ConditionGradeGood},
+				{ID: 4, SwapID: 1, InstrumentID: 1, ReporterUserID: 10, Stage: data.ConditionStageHandover, Grade: data.ConditionGradeGood},
+			}))
+
+			setUser := func(next http.HandlerFunc) http.HandlerFunc {
+				return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+					r = app.contextSetUser(r, &tc.reqUser)
+
+					next.ServeHTTP(w, r)
+				})
+			}
+
+			mux := http.NewServeMux()
+			mux.HandleFunc("GET /{id}", setUser(app.listConditionReportsHandler))
+
+			ts := httptest.NewServer(mux)
+			defer ts.Close()
+
+			resp, err := http.Get(fmt.Sprintf("%s/%s", ts.URL, tc.pathParam))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer func() {
+				err := resp.Body.Close()
+				if err != nil {
+					t.Fatal(err)
+				}
+			}()
+
+			if tc.expectedStatusCode != resp.StatusCode {
+				t.Errorf(`expected status code %d, got %d`, tc.expectedStatusCode, resp.StatusCode)
+			}
+
+			if tc.expectedStatusCode != http.StatusOK {
+				return
+			}
+
+			var respBody struct {
+				ConditionReports []*data.ConditionComparison `json:"condition_reports"`
+			}
+
+			err = json.NewDecoder(resp.Body).Decode(&respBody)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			comparisons := respBody.ConditionReports
+			if len(comparisons) != 2 {
+				t.Fatalf(`expected 2 compared instruments, got %d`, len(comparisons))
+			}
+			if comparisons[0].InstrumentID != 3 || len(comparisons[0].Handover) != 1 || len(comparisons[0].Return) != 1 {
+				t.Errorf(`unexpected comparison of the requester instrument %#v`, comparisons[0])
+			}
+			if comparisons[1].InstrumentID != 4 || len(comparisons[1].Handover) != 1 || len(comparisons[1].Return) != 0 {
+				t.Errorf(`unexpected comparison of the recipient instrument %#v`, comparisons[1])
+			}
+		})
+	}
+}
@@ -0,0 +1,339 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/ttarnok/instrument-swap-api/internal/data"
+	"github.com/ttarnok/instrument-swap-api/internal/validator"
+)
+
+// createDisputeHandler handles opening a dispute for an ended swap with the given id.
+// Only the owners of the instruments of the swap can open a dispute.
+func (app *application) createDisputeHandler(w http.ResponseWriter, r *http.Request) {
+	authUser := app.contextGetUser(r)
+
+	swapID, err := app.extractIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	var input struct {
+		Reason string `json:"reason"`
+	}
+
+	err = app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	dispute := &data.Dispute{
+		SwapID:         swapID,
+		OpenedByUserID: authUser.ID,
+		Status:         data.DisputeStatusOpen,
+		Reason:         input.Reason,
+	}
+
+	v := validator.New()
+
+	if data.ValidateDispute(v, dispute); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	swap, err := app.models.Swaps.Get(swapID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorLogResponse(w, r, err)
+		}
+		return
+	}
+
+	requesterUserID, recipientUserID, err := app.swapOwnerIDs(swap)
+	if err != nil {
+		app.serverErrorLogResponse(w, r, err)
+		return
+	}
+
+	if authUser.ID != requesterUserID && authUser.ID != recipientUserID {
+		app.forbiddenResponse(w, r)
+		return
+	}
+
+	if !data.IsSwapDisputable(swap) {
+		app.badRequestResponse(w, r, data.ErrSwapNotDisputable)
+		return
+	}
+
+	err = app.models.Disputes.Insert(dispute)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrDuplicateDispute):
+			app.badRequestResponse(w, r, err)
+		default:
+			app.serverErrorLogResponse(w, r, err)
+		}
+		return
+	}
+
+	headers := make(http.Header)
+	headers.Set("Location", fmt.Sprintf("/v1/disputes/%d", dispute.ID))
+
+	err = app.writeJSON(w, http.StatusCreated, envelope{"dispute": dispute}, headers)
+	if err != nil {
+		app.serverErrorLogResponse(w, r, err)
+		return
+	}
+}
+
+// canAccessDispute checks whether the given user can see and add evidence notes to the given dispute.
+// The owners of the instruments of the disputed swap and the dispute moderators can access a dispute.
+func (app *application) canAccessDispute(user *data.User, dispute *data.Dispute) (bool, error) {
+	if user.Permissions.Include(data.PermissionDisputesModerate) {
+		return true, nil
+	}
+
+	swap, err := app.models.Swaps.Get(dispute.SwapID)
+	if err != nil {
+		return false, err
+	}
+
+	requesterUserID, recipientUserID, err := app.swapOwnerIDs(swap)
+	if err != nil {
+		return false, err
+	}
+
+	return user.ID == requesterUserID || user.ID == recipientUserID, nil
+}
+
+// showDisputeHandler handles the retrieval of a dispute with the given id together with its evidence notes.
+func (app *application) showDisputeHandler(w http.ResponseWriter, r *http.Request) {
+	authUser := app.contextGetUser(r)
+
+	id, err := app.extractIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	dispute, err := app.models.Disputes.Get(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorLogResponse(w, r, err)
+		}
+		return
+	}
+
+	ok, err := app.canAccessDispute(authUser, dispute)
+	if err != nil {
+		app.serverErrorLogResponse(w, r, err)
+		return
+	}
+	if !ok {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"dispute": dispute}, nil)
+	if err != nil {
+		app.serverErrorLogResponse(w, r, err)
+		return
+	}
+}
+
+// createDisputeNoteHandler handles adding an evidence note to an unresolved dispute with the given id.
+func (app *application) createDisputeNoteHandler(w http.ResponseWriter, r *http.Request) {
+	authUser := app.contextGetUser(r)
+
+	id, err := app.extractIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	var input struct {
+		Body string `json:"body"`
+	}
+
+	err = app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	note := &data.DisputeNote{
+		DisputeID:    id,
+		AuthorUserID: authUser.ID,
+		Body:         input.Body,
+	}
+
+	v := validator.New()
+
+	if data.ValidateDisputeNote(v, note); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	dispute, err := app.models.Disputes.Get(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorLogResponse(w, r, err)
+		}
+		return
+	}
+
+	ok, err := app.canAccessDispute(authUser, dispute)
+	if err != nil {
+		app.serverErrorLogResponse(w, r, err)
+		return
+	}
+	if !ok {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	if dispute.Status == data.DisputeStatusResolved {
+		app.badRequestResponse(w, r, data.ErrDisputeResolved)
+		return
+	}
+
+	err = app.models.Disputes.InsertNote(note)
+	if err != nil {
+		app.serverErrorLogResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusCreated, envelope{"note": note}, nil)
+	if err != nil {
+		app.serverErrorLogResponse(w, r, err)
+		return
+	}
+}
+
+// listDisputesHandler handles listing the disputes for the dispute moderators.
+func (app *application) listDisputesHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Status string
+		data.Filters
+	}
+
+	v := validator.New()
+
+	qs := r.URL.Query()
+
+	input.Status = app.readQParamString(qs, "status", "")
+
+	input.Page = app.readQParamInt(qs, "page", 1, v)
+	input.PageSize = app.readQParamInt(qs, "page_size", 20, v)
+
+	input.Sort = app.readQParamString(qs, "sort", "id")
+	input.SortSafeList = []string{"id", "created_at", "-id", "-created_at"}
+
+	v.Check(
+		validator.PermittedValue(input.Status, "", data.DisputeStatusOpen, data.DisputeStatusUnderReview, data.DisputeStatusResolved),
+		"status",
+		"must be open, under_review or resolved",
+	)
+	data.ValidateFilters(v, input.Filters)
+
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	disputes, metadata, err := app.models.Disputes.GetAll(input.Status, input.Filters)
+	if err != nil {
+		app.serverErrorLogResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"disputes": disputes, "metadata": metadata}, nil)
+	if err != nil {
+		app.serverErrorLogResponse(w, r, err)
+		return
+	}
+}
+
+// updateDisputeStatusHandler handles the status changes of a dispute with the given id by the dispute moderators.
+func (app *application) updateDisputeStatusHandler(w http.ResponseWriter, r *http.Request) {
+	authUser := app.contextGetUser(r)
+
+	id, err := app.extractIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	var input struct {
+		Status     string `json:"status"`
+		Resolution string `json:"resolution"`
+	}
+
+	err = app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	dispute, err := app.models.Disputes.Get(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorLogResponse(w, r, err)
+		}
+		return
+	}
+
+	err = data.ValidateDisputeStatusTransition(dispute, input.Status)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	dispute.Status = input.Status
+	if dispute.Status == data.DisputeStatusResolved {
+		now := time.Now()
+		dispute.Resolution = input.Resolution
+		dispute.ResolvedAt = &now
+		dispute.ResolvedByUserID = &authUser.ID
+	}
+
+	v := validator.New()
+
+	if data.ValidateDispute(v, dispute); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = app.models.Disputes.Update(dispute)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrEditConflict):
+			app.editConflictResponse(w, r)
+		default:
+			app.serverErrorLogResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"dispute": dispute}, nil)
+	if err != nil {
+		app.serverErrorLogResponse(w, r, err)
+		return
+	}
+}
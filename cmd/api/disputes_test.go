@@ -0,0 +1,582 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/ttarnok/instrument-swap-api/internal/data"
+	"github.com/ttarnok/instrument-swap-api/internal/data/mocks"
+)
+
+// newDisputeTestApp returns an application with an ended swap (id 1) between the users 10 and 20,
+// an accepted swap (id 2) and the given disputes.
+func newDisputeTestApp(disputes *mocks.DisputeModelMock) *application {
+	now := time.Now()
+
+	return &application{
+		logger: slog.New(slog.NewTextHandler(io.Discard, nil)),
+		models: data.Models{
+			Swaps: mocks.NewSwapModelMock([]*data.Swap{
+				{ID: 1, RequesterInstrumentID: 1, RecipientInstrumentID: 2, IsAccepted: true, AcceptedAt: &now, IsEnded: true, EndedAt: &now},
+				{ID: 2, RequesterInstrumentID: 1, RecipientInstrumentID: 2, IsAccepted: true, AcceptedAt: &now},
+			}),
+			Disputes:    disputes,
+			Instruments: mocks.NewNonEmptyInstrumentModelMock([]*data.Instrument{{ID: 1, OwnerUserID: 10}, {ID: 2, OwnerUserID: 20}}),
+		},
+	}
+}
+
+// TestCreateDisputeHandler implements unit tests for createDisputeHandler.
+func TestCreateDisputeHandler(t *testing.T) {
+
+	type inputBodyType struct {
+		Reason string `json:"reason"`
+	}
+
+	type testCase struct {
+		name               string
+		pathParam          string
+		inputBody          inputBodyType
+		reqUser            data.User
+		disputes           []*data.Dispute
+		expectedStatusCode int
+	}
+
+	testCases := []testCase{
+		{
+			name:               "happy path",
+			pathParam:          "1",
+			inputBody:          inputBodyType{Reason: "The guitar came back with a broken neck."},
+			reqUser:            data.User{ID: 10},
+			expectedStatusCode: http.StatusCreated,
+		},
+		{
+			name:               "already resolved dispute",
+			pathParam:          "1",
+			inputBody:          inputBodyType{Reason: "The guitar came back with a broken neck."},
+			reqUser:            data.User{ID: 20},
+			disputes:           []*data.Dispute{{ID: 1, SwapID: 1, Status: data.DisputeStatusResolved, Version: 1}},
+			expectedStatusCode: http.StatusCreated,
+		},
+		{
+			name:               "already open dispute",
+			pathParam:          "1",
+			inputBody:          inputBodyType{Reason: "The guitar came back with a broken neck."},
+			reqUser:            data.User{ID: 20},
+			disputes:           []*data.Dispute{{ID: 1, SwapID: 1, Status: data.DisputeStatusUnderReview, Version: 1}},
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:               "not ended swap",
+			pathParam:          "2",
+			inputBody:          inputBodyType{Reason: "The guitar came back with a broken neck."},
+			reqUser:            data.User{ID: 10},
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:               "not a party of the swap",
+			pathParam:          "1",
+			inputBody:          inputBodyType{Reason: "The guitar came back with a broken neck."},
+			reqUser:            data.User{ID: 30},
+			expectedStatusCode: http.StatusForbidden,
+		},
+		{
+			name:               "missing reason",
+			pathParam:          "1",
+			reqUser:            data.User{ID: 10},
+			expectedStatusCode: http.StatusUnprocessableEntity,
+		},
+		{
+			name:               "non existent swap",
+			pathParam:          "99",
+			inputBody:          inputBodyType{Reason: "The guitar came back with a broken neck."},
+			reqUser:            data.User{ID: 10},
+			expectedStatusCode: http.StatusNotFound,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			app := newDisputeTestApp(mocks.NewDisputeModelMock(tc.disputes))
+
+			setUser := func(next http.HandlerFunc) http.HandlerFunc {
+				return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+					r = app.contextSetUser(r, &tc.reqUser)
+
+					next.ServeHTTP(w, r)
+				})
+			}
+
+			mux := http.NewServeMux()
+			mux.HandleFunc("POST /{id}", setUser(app.createDisputeHandler))
+
+			ts := httptest.NewServer(mux)
+			defer ts.Close()
+
+			bs, err := json.Marshal(tc.inputBody)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			resp, err := http.Post(fmt.Sprintf("%s/%s", ts.URL, tc.pathParam), "application/json", bytes.NewBuffer(bs))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer func() {
+				err := resp.Body.Close()
+				if err != nil {
+					t.Fatal(err)
+				}
+			}()
+
+			if tc.expectedStatusCode != resp.StatusCode {
+				t.Errorf(`expected status code %d, got %d`, tc.expectedStatusCode, resp.StatusCode)
+			}
+
+			if tc.expectedStatusCode != http.StatusCreated {
+				return
+			}
+
+			var respBody struct {
+				Dispute data.Dispute `json:"dispute"`
+			}
+
+			err = json.NewDecoder(resp.Body).Decode(&respBody)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if respBody.Dispute.Status != data.DisputeStatusOpen || respBody.Dispute.OpenedByUserID != tc.reqUser.ID || respBody.Dispute.Reason != tc.inputBody.Reason {
+				t.Errorf(`unexpected dispute %#v`, respBody.Dispute)
+			}
+		})
+	}
+}
+
+// TestShowDisputeHandler implements unit tests for showDisputeHandler.
+func TestShowDisputeHandler(t *testing.T) {
+
+	type testCase struct {
+		name               string
+		pathParam          string
+		reqUser            data.User
+		expectedStatusCode int
+	}
+
+	testCases := []testCase{
+		{
+			name:               "happy path - party",
+			pathParam:          "1",
+			reqUser:            data.User{ID: 20},
+			expectedStatusCode: http.StatusOK,
+		},
+		{
+			name:               "happy path - moderator",
+			pathParam:          "1",
+			reqUser:            data.User{ID: 30, Permissions: data.Permissions{data.PermissionDisputesModerate}},
+			expectedStatusCode: http.StatusOK,
+		},
+		{
+			name:               "not a party of the swap",
+			pathParam:          "1",
+			reqUser:            data.User{ID: 30},
+			expectedStatusCode: http.StatusNotFound,
+		},
+		{
+			name:               "non existent dispute",
+			pathParam:          "2",
+			reqUser:            data.User{ID: 10},
+			expectedStatusCode: http.StatusNotFound,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			disputes := mocks.NewDisputeModelMock([]*data.Dispute{{ID: 1, SwapID: 1, OpenedByUserID: 10, Status: data.DisputeStatusOpen, Reason: "Broken neck.", Version: 1}})
+			err := disputes.InsertNote(&data.DisputeNote{DisputeID: 1, AuthorUserID: 10, Body: "See the attached photos."})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			app := newDisputeTestApp(disputes)
+
+			setUser := func(next http.HandlerFunc) http.HandlerFunc {
+				return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+					r = app.contextSetUser(r, &tc.reqUser)
+
+					next.ServeHTTP(w, r)
+				})
+			}
+
+			mux := http.NewServeMux()
+			mux.HandleFunc("GET /{id}", setUser(app.showDisputeHandler))
+
+			ts := httptest.NewServer(mux)
+			defer ts.Close()
+
+			resp, err := http.Get(fmt.Sprintf("%s/%s", ts.URL, tc.pathParam))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer func() {
+				err := resp.Body.Close()
+				if err != nil {
+					t.Fatal(err)
+				}
+			}()
+
+			if tc.expectedStatusCode != resp.StatusCode {
+				t.Errorf(`expected status code %d, got %d`, tc.expectedStatusCode, resp.StatusCode)
+			}
+
+			if tc.expectedStatusCode != http.StatusOK {
+				return
+			}
+
+			var respBody struct {
+				Dispute data.Dispute `json:"dispute"`
+			}
+
+			err = json.NewDecoder(resp.Body).Decode(&respBody)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(respBody.Dispute.Notes) != 1 {
+				t.Errorf(`expected 1 evidence note, got %d`, len(respBody.Dispute.Notes))
+			}
+		})
+	}
+}
+
+// TestCreateDisputeNoteHandler implements unit tests for createDisputeNoteHandler.
+func TestCreateDisputeNoteHandler(t *testing.T) {
+
+	type inputBodyType struct {
+		Body string `json:"body"`
+	}
+
+	type testCase struct {
+		name               string
+		pathParam          string
+		inputBody          inputBodyType
+		reqUser            data.User
+		expectedStatusCode int
+	}
+
+	testCases := []testCase{
+		{
+			name:               "happy path - party",
+			pathParam:          "1",
+			inputBody:          inputBodyType{Body: "It was already scratched before the swap."},
+			reqUser:            data.User{ID: 20},
+			expectedStatusCode: http.StatusCreated,
+		},
+		{
+			name:               "happy path - moderator",
+			pathParam:          "1",
+			inputBody:          inputBodyType{Body: "Please send photos of the damage."},
+			reqUser:            data.User{ID: 30, Permissions: data.Permissions{data.PermissionDisputesModerate}},
+			expectedStatusCode: http.StatusCreated,
+		},
+		{
+			name:               "not a party of the swap",
+			pathParam:          "1",
+			inputBody:          inputBodyType{Body: "Hi!"},
+			reqUser:            data.User{ID: 30},
+			expectedStatusCode: http.StatusNotFound,
+		},
+		{
+			name:               "resolved dispute",
+			pathParam:          "2",
+			inputBody:          inputBodyType{Body: "One more thing..."},
+			reqUser:            data.User{ID: 10},
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:               "empty body",
+			pathParam:          "1",
+			reqUser:            data.User{ID: 10},
+			expectedStatusCode: http.StatusUnprocessableEntity,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			disputes := mocks.NewDisputeModelMock([]*data.Dispute{
+				{ID: 1, SwapID: 1, OpenedByUserID: 10, Status: data.DisputeStatusOpen, Reason: "Broken neck.", Version: 1},
+				{ID: 2, SwapID: 1, OpenedByUserID: 10, Status: data.DisputeStatusResolved, Reason: "Missing strap.", Resolution: "Strap returned.", Version: 2},
+			})
+
+			app := newDisputeTestApp(disputes)
+
+			setUser := func(next http.HandlerFunc) http.HandlerFunc {
+				return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+					r = app.contextSetUser(r, &tc.reqUser)
+
+					next.ServeHTTP(w, r)
+				})
+			}
+
+			mux := http.NewServeMux()
+			mux.HandleFunc("POST /{id}", setUser(app.createDisputeNoteHandler))
+
+			ts := httptest.NewServer(mux)
+			defer ts.Close()
+
+			bs, err := json.Marshal(tc.inputBody)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			resp, err := http.Post(fmt.Sprintf("%s/%s", ts.URL, tc.pathParam), "application/json", bytes.NewBuffer(bs))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer func() {
+				err := resp.Body.Close()
+				if err != nil {
+					t.Fatal(err)
+				}
+			}()
+
+			if tc.expectedStatusCode != resp.StatusCode {
+				t.Errorf(`expected status code %d, got %d`, tc.expectedStatusCode, resp.StatusCode)
+			}
+
+			dispute, err := disputes.Get(1)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			expectedNotes := 0
+			if tc.expectedStatusCode == http.StatusCreated {
+				expectedNotes = 1
+			}
+			if len(dispute.Notes) != expectedNotes {
+				t.Errorf(`expected %d evidence notes, got %d`, expectedNotes, len(dispute.Notes))
+			}
+		})
+	}
+}
+
+// TestListDisputesHandler implements unit tests for listDisputesHandler.
+func TestListDisputesHandler(t *testing.T) {
+
+	type testCase struct {
+		name               string
+		query              string
+		expectedStatusCode int
+		expectedDisputes   int
+	}
+
+	testCases := []testCase{
+		{
+			name:               "all disputes",
+			expectedStatusCode: http.StatusOK,
+			expectedDisputes:   2,
+		},
+		{
+			name:               "open disputes",
+			query:              "?status=open",
+			expectedStatusCode: http.StatusOK,
+			expectedDisputes:   1,
+		},
+		{
+			name:               "non valid status",
+			query:              "?status=closed",
+			expectedStatusCode: http.StatusUnprocessableEntity,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			app := newDisputeTestApp(mocks.NewDisputeModelMock([]*data.Dispute{
+				{ID: 1, SwapID: 1, Status: data.DisputeStatusOpen, Version: 1},
+				{ID: 2, SwapID: 1, Status: data.DisputeStatusResolved, Version: 2},
+			}))
+
+			mux := http.NewServeMux()
+			mux.HandleFunc("GET /", app.listDisputesHandler)
+
+			ts := httptest.NewServer(mux)
+			defer ts.Close()
+
+			resp, err := http.Get(fmt.Sprintf("%s/%s", ts.URL, tc.query))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer func() {
+				err := resp.Body.Close()
+				if err != nil {
+					t.Fatal(err)
+				}
+			}()
+
+			if tc.expectedStatusCode != resp.StatusCode {
+				t.Errorf(`expected status code %d, got %d`, tc.expectedStatusCode, resp.StatusCode)
+			}
+
+			if tc.expectedStatusCode != http.StatusOK {
+				return
+			}
+
+			var respBody struct {
+				Disputes []*data.Dispute `json:"disputes"`
+			}
+
+			err = json.NewDecoder(resp.Body).Decode(&respBody)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(respBody.Disputes) != tc.expectedDisputes {
+				t.Errorf(`expected %d disputes, got %d`, tc.expectedDisputes, len(respBody.Disputes))
+			}
+		})
+	}
+}
+
+// TestUpdateDisputeStatusHandler implements unit tests for updateDisputeStatusHandler.
+func TestUpdateDisputeStatusHandler(t *testing.T) {
+
+	type inputBodyType struct {
+		Status     string `json:"status"`
+		Resolution string `json:"resolution,omitempty"`
+	}
+
+	type testCase struct {
+		name               string
+		pathParam          string
+		inputBody          inputBodyType
+		expectedStatusCode int
+	}
+
+	testCases := []testCase{
+		{
+			name:               "take under review",
+			pathParam:          "1",
+			inputBody:          inputBodyType{Status: data.DisputeStatusUnderReview},
+			expectedStatusCode: http.StatusOK,
+		},
+		{
+			name:               "resolve",
+			pathParam:          "1",
+			inputBody:          inputBodyType{Status: data.DisputeStatusResolved, Resolution: "The requester pays for the repair."},
+			expectedStatusCode: http.StatusOK,
+		},
+		{
+			name:               "resolve without resolution",
+			pathParam:          "1",
+			inputBody:          inputBodyType{Status: data.DisputeStatusResolved},
+			expectedStatusCode: http.StatusUnprocessableEntity,
+		},
+		{
+			name:               "reopen a resolved dispute",
+			pathParam:          "2",
+			inputBody:          inputBodyType{Status: data.DisputeStatusOpen},
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:               "unknown status",
+			pathParam:          "1",
+			inputBody:          inputBodyType{Status: "closed"},
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:               "non existent dispute",
+			pathParam:          "99",
+			inputBody:          inputBodyType{Status: data.DisputeStatusUnderReview},
+			expectedStatusCode: http.StatusNotFound,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			reqUser := data.User{ID: 30, Permissions: data.Permissions{data.PermissionDisputesModerate}}
+
+			disputes := mocks.NewDisputeModelMock([]*data.Dispute{
+				{ID: 1, SwapID: 1, OpenedByUserID: 10, Status: data.DisputeStatusOpen, Reason: "Broken neck.", Version: 1},
+				{ID: 2, SwapID: 1, OpenedByUserID: 10, Status: data.DisputeStatusResolved, Reason: "Missing strap.", Resolution: "Strap returned.", Version: 2},
+			})
+
+			app := newDisputeTestApp(disputes)
+
+			setUser := func(next http.HandlerFunc) http.HandlerFunc {
+				return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+					r = app.contextSetUser(r, &reqUser)
+
+					next.ServeHTTP(w, r)
+				})
+			}
+
+			mux := http.NewServeMux()
+			mux.HandleFunc("PATCH /{id}", setUser(app.updateDisputeStatusHandler))
+
+			ts := httptest.NewServer(mux)
+			defer ts.Close()
+
+			bs, err := json.Marshal(tc.inputBody)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			req, err := http.NewRequest(http.MethodPatch, fmt.Sprintf("%s/%s", ts.URL, tc.pathParam), bytes.NewBuffer(bs))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer func() {
+				err := resp.Body.Close()
+				if err != nil {
+					t.Fatal(err)
+				}
+			}()
+
+			if tc.expectedStatusCode != resp.StatusCode {
+				t.Errorf(`expected status code %d, got %d`, tc.expectedStatusCode, resp.StatusCode)
+			}
+
+			if tc.expectedStatusCode != http.StatusOK {
+				return
+			}
+
+			dispute, err := disputes.Get(1)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if dispute.Status != tc.inputBody.Status || dispute.Resolution != tc.inputBody.Resolution {
+				t.Errorf(`unexpected stored dispute %#v`, dispute)
+			}
+			if tc.inputBody.Status == data.DisputeStatusResolved && (dispute.ResolvedByUserID == nil || *dispute.ResolvedByUserID != reqUser.ID) {
+				t.Errorf(`expected the dispute to be resolved by %d`, reqUser.ID)
+			}
+		})
+	}
+}
@@ -1,7 +1,10 @@
 package main
 
 import (
+	"errors"
 	"net/http"
+
+	"github.com/ttarnok/instrument-swap-api/internal/jsonpatch"
 )
 
 // logError logs and error message, besides request related informations.
@@ -58,6 +61,53 @@ func (app *application) editConflictResponse(w http.ResponseWriter, r *http.Requ
 	app.errorResponse(w, r, http.StatusConflict, message)
 }
 
+// unsupportedPatchTypeResponse sends UnsupportedMediaType response to the client.
+// The accepted patch media types are listed in the Accept-Patch header.
+func (app *application) unsupportedPatchTypeResponse(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Accept-Patch", mediaTypeMergePatch+", "+mediaTypeJSONPatch)
+
+	message := "the request body must be a JSON merge patch or a JSON patch"
+	app.errorResponse(w, r, http.StatusUnsupportedMediaType, message)
+}
+
+// patchErrorResponse sends the response matching the given error returned by readPatch.
+// Patches that can not be applied to the current state of the resource are reported with Conflict response.
+func (app *application) patchErrorResponse(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, errUnsupportedPatchType):
+		app.unsupportedPatchTypeResponse(w, r)
+	case errors.Is(err, jsonpatch.ErrPathNotFound) || errors.Is(err, jsonpatch.ErrTestFailed):
+		app.errorResponse(w, r, http.StatusConflict, err.Error())
+	default:
+		app.badRequestResponse(w, r, err)
+	}
+}
+
+// preconditionFailedResponse sends PreconditionFailed response to the client.
+// Indicates that the resource has been modified since the client retrieved the entity tag given in the If-Match header.
+func (app *application) preconditionFailedResponse(w http.ResponseWriter, r *http.Request) {
+	message := "the resource has been modified since it was retrieved, please retrieve it again"
+	app.errorResponse(w, r, http.StatusPreconditionFailed, message)
+}
+
+// preconditionRequiredResponse sends PreconditionRequired response to the client.
+// Indicates that the request has to be conditional, it has to contain an If-Match header.
+func (app *application) preconditionRequiredResponse(w http.ResponseWriter, r *http.Request) {
+	message := "the request must contain the If-Match header with the ETag of the resource"
+	app.errorResponse(w, r, http.StatusPreconditionRequired, message)
+}
+
+// versionConflictResponse sends the response of a failed optimistic lock of a versioned resource.
+// Conditional requests get PreconditionFailed response, as their If-Match header can not match the changed resource,
+// other requests get Conflict response.
+func (app *application) versionConflictResponse(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("If-Match") != "" {
+		app.preconditionFailedResponse(w, r)
+		return
+	}
+	app.editConflictResponse(w, r)
+}
+
 // rateLimitExcededResponse sends TooManyRequests to the client.
 func (app *application) rateLimitExcededResponse(w http.ResponseWriter, r *http.Request) {
 	message := "rate limit exceeded"
@@ -94,6 +144,13 @@ func (app *application) inactiveAccountResponse(w http.ResponseWriter, r *http.R
 	app.errorResponse(w, r, http.StatusForbidden, message)
 }
 
+// notPermittedResponse sends Forbidden response to the client.
+// Indicates that the user that is initiating the request does not have the necessary permissions.
+func (app *application) notPermittedResponse(w http.ResponseWriter, r *http.Request) {
+	message := "your user account doesn't have the necessary permissions to access this resource"
+	app.errorResponse(w, r, http.StatusForbidden, message)
+}
+
 func (app *application) forbiddenResponse(w http.ResponseWriter, r *http.Request) {
 	message := http.StatusText(http.StatusForbidden)
 	app.errorResponse(w, r, http.StatusForbidden, message)
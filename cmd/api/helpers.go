@@ -1,15 +1,19 @@
 package main
 
 import (
+	"bytes"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
+	"mime"
 	"net/http"
 	"net/url"
 	"strconv"
 	"strings"
+	"time"
 
+	"github.com/ttarnok/instrument-swap-api/internal/jsonpatch"
 	"github.com/ttarnok/instrument-swap-api/internal/validator"
 )
 
@@ -18,8 +22,13 @@ type envelope map[string]any
 
 // extractIDParam is used to extract id parameters from request paths.
 func (app *application) extractIDParam(r *http.Request) (int64, error) {
+	return app.extractNamedIDParam(r, "id")
+}
+
+// extractNamedIDParam is used to extract the id parameter with the given name from request paths.
+func (app *application) extractNamedIDParam(r *http.Request, name string) (int64, error) {
 	// Read and Validate params
-	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	id, err := strconv.ParseInt(r.PathValue(name), 10, 64)
 	if err != nil {
 		return 0, err
 	}
@@ -110,6 +119,166 @@ func (app *application) readJSON(w http.ResponseWriter, r *http.Request, dst any
 	return nil
 }
 
+// Media types of the accepted patch documents.
+const (
+	mediaTypeMergePatch = "application/merge-patch+json" // JSON merge patch (RFC 7396)
+	mediaTypeJSONPatch  = "application/json-patch+json"  // JSON patch (RFC 6902)
+)
+
+// errUnsupportedPatchType is returned by readPatch if the request body is not a supported patch document.
+var errUnsupportedPatchType = errors.New("unsupported patch media type")
+
+// readPatch applies the patch document of a client request to the JSON representation of current,
+// and decodes the patched document into dst.
+// The type of the patch is given by the Content-Type header, application/json bodies are handled as JSON merge patches.
+// Members removed by the patch are left at their zero values in dst, so null or removed members clear the fields.
+// Returns errUnsupportedPatchType for other media types,
+// errors wrapping jsonpatch.ErrPathNotFound or jsonpatch.ErrTestFailed if the patch can not be applied to current.
+func (app *application) readPatch(w http.ResponseWriter, r *http.Request, current any, dst any) error {
+
+	apply := jsonpatch.MergePatch
+
+	if contentType := r.Header.Get("Content-Type"); contentType != "" {
+		mediaType, _, err := mime.ParseMediaType(contentType)
+		if err != nil {
+			return errUnsupportedPatchType
+		}
+
+		switch mediaType {
+		case "application/json", mediaTypeMergePatch:
+			apply = jsonpatch.MergePatch
+		case mediaTypeJSONPatch:
+			apply = jsonpatch.Apply
+		default:
+			return errUnsupportedPatchType
+		}
+	}
+
+	// 1Mb
+	maxBytes := 1_048_576
+	r.Body = http.MaxBytesReader(w, r.Body, int64(maxBytes))
+
+	patch, err := io.ReadAll(r.Body)
+	if err != nil {
+		var maxBytesError *http.MaxBytesError
+		if errors.As(err, &maxBytesError) {
+			return fmt.Errorf("body must not be larger than %d bytes", maxBytesError.Limit)
+		}
+		return err
+	}
+	if len(patch) == 0 {
+		return errors.New("body must not be empty")
+	}
+
+	doc, err := json.Marshal(current)
+	if err != nil {
+		return err
+	}
+
+	patched, err := apply(doc, patch)
+	if err != nil {
+		return err
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(patched))
+	dec.DisallowUnknownFields()
+
+	err = dec.Decode(dst)
+	if err != nil {
+		var unmarshalTypeError *json.UnmarshalTypeError
+
+		switch {
+		case errors.As(err, &unmarshalTypeError):
+			if unmarshalTypeError.Field != "" {
+				return fmt.Errorf("patched document contains incorrect JSON type for field %q", unmarshalTypeError.Field)
+			}
+			return errors.New("patched document must be a JSON object")
+
+		case strings.HasPrefix(err.Error(), "json: unknown field "):
+			fieldName := strings.TrimPrefix(err.Error(), "json: unknown field ")
+			return fmt.Errorf("patched document contains unknown key %s", fieldName)
+
+		default:
+			return err
+		}
+	}
+
+	return nil
+}
+
+// etag returns the strong entity tag of a resource representation derived from the given version number.
+func etag(version int64) string {
+	return `"` + strconv.FormatInt(version, 10) + `"`
+}
+
+// notModified sets the ETag header of the response to the given entity tag,
+// and reports whether the If-None-Match header of the request matches it.
+// If it matches, 304 Not Modified is sent and the handler must not write a body.
+func (app *application) notModified(w http.ResponseWriter, r *http.Request, etag string) bool {
+	w.Header().Set("ETag", etag)
+
+	ifNoneMatch := strings.Join(r.Header.Values("If-None-Match"), ",")
+	if ifNoneMatch == "" || !etagsMatch(ifNoneMatch, etag, false) {
+		return false
+	}
+
+	w.WriteHeader(http.StatusNotModified)
+	return true
+}
+
+// checkIfMatch reports whether the If-Match header of the request matches the given current entity tag of the resource.
+// Requests without an If-Match header are accepted, unless the header is required by the configuration.
+// Sends 412 Precondition Failed or 428 Precondition Required and returns false, if the request can not be processed.
+func (app *application) checkIfMatch(w http.ResponseWriter, r *http.Request, etag string) bool {
+	ifMatch := strings.Join(r.Header.Values("If-Match"), ",")
+
+	if ifMatch == "" {
+		if app.config.preconditions.requireIfMatch {
+			app.preconditionRequiredResponse(w, r)
+			return false
+		}
+		return true
+	}
+
+	if !etagsMatch(ifMatch, etag, true) {
+		app.preconditionFailedResponse(w, r)
+		return false
+	}
+
+	return true
+}
+
+// etagsMatch reports whether the given If-Match or If-None-Match header value lists the given entity tag, or it is *.
+// The weak comparison ignores the W/ prefixes, the strong comparison never matches weak entity tags.
+func etagsMatch(header string, etag string, strong bool) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+
+		if candidate == "*" {
+			return true
+		}
+		if strings.HasPrefix(candidate, "W/") {
+			if strong {
+				continue
+			}
+			candidate = strings.TrimPrefix(candidate, "W/")
+		}
+		if candidate == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// valueOrZero returns the value the given pointer points to, or the zero value if it is nil.
+func valueOrZero[T any](p *T) T {
+	if p == nil {
+		var zero T
+		return zero
+	}
+	return *p
+}
+
 // readQParamString is used to extract string typed query string from requests.
 func (app *application) readQParamString(qs url.Values, key string, defaultValue string) string {
 
@@ -150,3 +319,38 @@ func (app *application) readQParamInt(qs url.Values, key string, defaultValue in
 	return iv
 
 }
+
+// readQParamTime is used to extract RFC 3339 formatted time typed query string from requests.
+func (app *application) readQParamTime(qs url.Values, key string, defaultValue time.Time, v *validator.Validator) time.Time {
+
+	sv := qs.Get(key)
+	if sv == "" {
+		return defaultValue
+	}
+
+	tv, err := time.Parse(time.RFC3339, sv)
+	if err != nil {
+		v.AddError(key, "must be an RFC 3339 formatted time value")
+		return defaultValue
+	}
+
+	return tv
+}
+
+// background runs the given function in a background goroutine.
+// Recovers from panics within the function, and makes the graceful shutdown wait for its completion.
+func (app *application) background(fn func()) {
+	app.wg.Add(1)
+
+	go func() {
+		defer app.wg.Done()
+
+		defer func() {
+			if err := recover(); err != nil {
+				app.logger.Error(fmt.Sprintf("%v", err))
+			}
+		}()
+
+		fn()
+	}()
+}
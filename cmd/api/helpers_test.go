@@ -11,6 +11,7 @@ import (
 	"net/url"
 	"slices"
 	"testing"
+	"time"
 
 	"github.com/ttarnok/instrument-swap-api/internal/validator"
 )
@@ -385,3 +386,106 @@ func TestReadQParamInt(t *testing.T) {
 
 	}
 }
+
+// TestReadQParamTime unit tests the functionality of readQParamTime.
+func TestReadQParamTime(t *testing.T) {
+
+	tests := []struct {
+		name           string
+		setKey         string
+		retrieveKey    string
+		inputValue     string
+		expectedValue  time.Time
+		defaultValue   time.Time
+		shouldValidate bool
+	}{
+		{
+			name:           "happy path",
+			setKey:         "created_from",
+			retrieveKey:    "created_from",
+			inputValue:     "2024-05-01T10:00:00Z",
+			expectedValue:  time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC),
+			defaultValue:   time.Time{},
+			shouldValidate: false,
+		},
+		{
+			name:           "default value",
+			setKey:         "created_from",
+			retrieveKey:    "created_to",
+			inputValue:     "2024-05-01T10:00:00Z",
+			expectedValue:  time.Time{},
+			defaultValue:   time.Time{},
+			shouldValidate: false,
+		},
+		{
+			name:           "should contain error",
+			setKey:         "created_from",
+			retrieveKey:    "created_from",
+			inputValue:     "2024-05-01",
+			expectedValue:  time.Time{},
+			defaultValue:   time.Time{},
+			shouldValidate: true,
+		},
+	}
+
+	for _, tt := range tests {
+
+		t.Run(tt.name, func(t *testing.T) {
+
+			values := url.Values{}
+			values.Set(tt.setKey, tt.inputValue)
+
+			app := &application{}
+
+			validator := validator.New()
+
+			paramValue := app.readQParamTime(values, tt.retrieveKey, tt.defaultValue, validator)
+
+			if !paramValue.Equal(tt.expectedValue) {
+				t.Errorf(`expected value %v, got %v`, tt.expectedValue, paramValue)
+			}
+
+			if tt.shouldValidate && validator.Valid() {
+				t.Error(`should comtain validation errors`)
+			}
+
+			if !tt.shouldValidate && !validator.Valid() {
+				t.Errorf(`should not contain validation errors, got %#v`, validator.Errors)
+			}
+
+		})
+
+	}
+}
+
+// TestEtagsMatch implements unit tests to test etagsMatch.
+func TestEtagsMatch(t *testing.T) {
+
+	tests := []struct {
+		name     string
+		header   string
+		etag     string
+		strong   bool
+		expected bool
+	}{
+		{name: "matching tag", header: `"3"`, etag: `"3"`, strong: true, expected: true},
+		{name: "different tag", header: `"2"`, etag: `"3"`, strong: true, expected: false},
+		{name: "tag in a list", header: `"1", "2" ,"3"`, etag: `"3"`, strong: true, expected: true},
+		{name: "any tag", header: `*`, etag: `"3"`, strong: true, expected: true},
+		{name: "weak tag with weak comparison", header: `W/"3"`, etag: `"3"`, strong: false, expected: true},
+		{name: "weak tag with strong comparison", header: `W/"3"`, etag: `"3"`, strong: true, expected: false},
+		{name: "unquoted tag", header: `3`, etag: `"3"`, strong: true, expected: false},
+		{name: "composite tag", header: `"3-1"`, etag: `"3-1"`, strong: true, expected: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got := etagsMatch(tt.header, tt.etag, tt.strong)
+			if got != tt.expected {
+				t.Errorf(`expected %t for header %q and entity tag %q, got %t`, tt.expected, tt.header, tt.etag, got)
+			}
+		})
+	}
+}
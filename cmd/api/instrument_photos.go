@@ -0,0 +1,378 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/ttarnok/instrument-swap-api/internal/data"
+	"github.com/ttarnok/instrument-swap-api/internal/imaging"
+	"github.com/ttarnok/instrument-swap-api/internal/storage"
+	"github.com/ttarnok/instrument-swap-api/internal/validator"
+)
+
+// multipartOverhead is the size permitted for the multipart framing of an uploaded photo, in addition to the photo itself.
+const multipartOverhead = 64 * 1024
+
+// listInstrumentPhotosHandler lists the photos of an instrument in their order.
+func (app *application) listInstrumentPhotosHandler(w http.ResponseWriter, r *http.Request) {
+	instrument, ok := app.instrumentForPhotos(w, r, false)
+	if !ok {
+		return
+	}
+
+	photos, err := app.models.InstrumentPhotos.GetAllForInstrument(instrument.ID)
+	if err != nil {
+		app.serverErrorLogResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"photos": photos}, nil)
+	if err != nil {
+		app.serverErrorLogResponse(w, r, err)
+		return
+	}
+}
+
+// uploadInstrumentPhotoHandler adds a photo to an instrument of the authenticated user.
+// The photo is expected in the photo field of a multipart/form-data request body.
+// The photo is re-encoded without its metadata and a thumbnail is generated for it, both are kept in the blob storage.
+func (app *application) uploadInstrumentPhotoHandler(w http.ResponseWriter, r *http.Request) {
+	instrument, ok := app.instrumentForPhotos(w, r, true)
+	if !ok {
+		return
+	}
+
+	maxSize := app.config.photos.maxSize
+	r.Body = http.MaxBytesReader(w, r.Body, maxSize+multipartOverhead)
+
+	content, err := readMultipartFile(r, "photo", maxSize)
+	if err != nil {
+		var maxBytesError *http.MaxBytesError
+		switch {
+		case errors.Is(err, errFileTooLarge) || errors.As(err, &maxBytesError):
+			app.errorResponse(w, r, http.StatusRequestEntityTooLarge, fmt.Sprintf("the photo must not be larger than %d bytes", maxSize))
+		case errors.Is(err, http.ErrMissingFile):
+			app.failedValidationResponse(w, r, map[string]string{"photo": "must be provided"})
+		default:
+			app.badRequestResponse(w, r, err)
+		}
+		return
+	}
+
+	photo, thumbnail, err := imaging.Process(content)
+	if err != nil {
+		switch {
+		case errors.Is(err, imaging.ErrUnsupportedFormat):
+			app.errorResponse(w, r, http.StatusUnsupportedMediaType, "the photo must be a JPEG or a PNG image")
+		case errors.Is(err, imaging.ErrInvalidImage):
+			app.failedValidationResponse(w, r, map[string]string{"photo": "must be a valid image"})
+		case errors.Is(err, imaging.ErrTooLarge):
+			app.failedValidationResponse(w, r, map[string]string{"photo": fmt.Sprintf("must not be larger than %dx%d pixels", imaging.MaxDimension, imaging.MaxDimension)})
+		default:
+			app.serverErrorLogResponse(w, r, err)
+		}
+		return
+	}
+
+	key, err := newPhotoKey(instrument.ID)
+	if err != nil {
+		app.serverErrorLogResponse(w, r, err)
+		return
+	}
+
+	extension := ".png"
+	if photo.ContentType == imaging.ContentTypeJPEG {
+		extension = ".jpg"
+	}
+
+	instrumentPhoto := &data.InstrumentPhoto{
+		InstrumentID:    instrument.ID,
+		ContentType:     photo.ContentType,
+		Size:            int64(len(photo.Data)),
+		Width:           int32(photo.Width),      //nolint:gosec // The dimensions are limited by imaging.MaxDimension.
+		Height:          int32(photo.Height),     //nolint:gosec // The dimensions are limited by imaging.MaxDimension.
+		ThumbnailWidth:  int32(thumbnail.Width),  //nolint:gosec // The dimensions are limited by imaging.ThumbnailSize.
+		ThumbnailHeight: int32(thumbnail.Height), //nolint:gosec // The dimensions are limited by imaging.ThumbnailSize.
+		ImageKey:        key + extension,
+		ThumbnailKey:    key + "_thumbnail" + extension,
+	}
+
+	err = app.storage.Put(r.Context(), instrumentPhoto.ImageKey, photo.Data, photo.ContentType)
+	if err == nil {
+		err = app.storage.Put(r.Context(), instrumentPhoto.ThumbnailKey, thumbnail.Data, thumbnail.ContentType)
+	}
+	if err == nil {
+		err = app.models.InstrumentPhotos.Insert(instrumentPhoto)
+	}
+	if err != nil {
+		app.deleteInstrumentPhotoBlobs(instrumentPhoto)
+
+		switch {
+		case errors.Is(err, data.ErrTooManyInstrumentPhotos):
+			app.failedValidationResponse(w, r, map[string]string{"photo": fmt.Sprintf("an instrument can not have more than %d photos", data.MaxInstrumentPhotos)})
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorLogResponse(w, r, err)
+		}
+		return
+	}
+
+	headers := make(http.Header)
+	headers.Set("Location", fmt.Sprintf("/v1/instruments/%d/photos/%d/image", instrument.ID, instrumentPhoto.ID))
+
+	err = app.writeJSON(w, http.StatusCreated, envelope{"photo": instrumentPhoto}, headers)
+	if err != nil {
+		app.serverErrorLogResponse(w, r, err)
+		return
+	}
+}
+
+// showInstrumentPhotoHandler serves the image of a photo of an instrument.
+func (app *application) showInstrumentPhotoHandler(w http.ResponseWriter, r *http.Request) {
+	app.serveInstrumentPhoto(w, r, false)
+}
+
+// showInstrumentPhotoThumbnailHandler serves the thumbnail of a photo of an instrument.
+func (app *application) showInstrumentPhotoThumbnailHandler(w http.ResponseWriter, r *http.Request) {
+	app.serveInstrumentPhoto(w, r, true)
+}
+
+// serveInstrumentPhoto serves the image or the thumbnail of the photo given in the request path from the blob storage.
+// The blobs of a photo never change, so they can be cached by the clients.
+func (app *application) serveInstrumentPhoto(w http.ResponseWriter, r *http.Request, thumbnail bool) {
+	instrument, ok := app.instrumentForPhotos(w, r, false)
+	if !ok {
+		return
+	}
+
+	photoID, err := app.extractNamedIDParam(r, "photo_id")
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	photo, err := app.models.InstrumentPhotos.Get(photoID, instrument.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorLogResponse(w, r, err)
+		}
+		return
+	}
+
+	key := photo.ImageKey
+	if thumbnail {
+		key = photo.ThumbnailKey
+	}
+
+	blob, err := app.storage.Get(r.Context(), key)
+	if err != nil {
+		switch {
+		case errors.Is(err, storage.ErrNotFound):
+			app.logError(r, err)
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorLogResponse(w, r, err)
+		}
+		return
+	}
+	defer func() {
+		err := blob.Close()
+		if err != nil {
+			app.logError(r, err)
+		}
+	}()
+
+	w.Header().Set("Content-Type", photo.ContentType)
+	w.Header().Set("Cache-Control", "private, max-age=31536000, immutable")
+	if !thumbnail {
+		w.Header().Set("Content-Length", strconv.FormatInt(photo.Size, 10))
+	}
+	w.WriteHeader(http.StatusOK)
+
+	_, err = io.Copy(w, blob)
+	if err != nil {
+		app.logError(r, err)
+	}
+}
+
+// reorderInstrumentPhotosHandler sets the order of the photos of an instrument of the authenticated user.
+// Every photo of the instrument has to be listed in the new order.
+func (app *application) reorderInstrumentPhotosHandler(w http.ResponseWriter, r *http.Request) {
+	instrument, ok := app.instrumentForPhotos(w, r, true)
+	if !ok {
+		return
+	}
+
+	var input struct {
+		PhotoIDs []int64 `json:"photo_ids"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	photos, err := app.models.InstrumentPhotos.GetAllForInstrument(instrument.ID)
+	if err != nil {
+		app.serverErrorLogResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+
+	if data.ValidateInstrumentPhotoOrder(v, input.PhotoIDs, photos); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	photos, err = app.models.InstrumentPhotos.Reorder(instrument.ID, input.PhotoIDs)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrInvalidInstrumentPhotoOrder):
+			app.editConflictResponse(w, r)
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorLogResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"photos": photos}, nil)
+	if err != nil {
+		app.serverErrorLogResponse(w, r, err)
+		return
+	}
+}
+
+// deleteInstrumentPhotoHandler removes a photo of an instrument of the authenticated user, together with its blobs.
+func (app *application) deleteInstrumentPhotoHandler(w http.ResponseWriter, r *http.Request) {
+	instrument, ok := app.instrumentForPhotos(w, r, true)
+	if !ok {
+		return
+	}
+
+	photoID, err := app.extractNamedIDParam(r, "photo_id")
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	photo, err := app.models.InstrumentPhotos.Delete(photoID, instrument.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorLogResponse(w, r, err)
+		}
+		return
+	}
+
+	app.deleteInstrumentPhotoBlobs(photo)
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"message": "photo successfully deleted"}, nil)
+	if err != nil {
+		app.serverErrorLogResponse(w, r, err)
+		return
+	}
+}
+
+// instrumentForPhotos retrieves the instrument given in the request path.
+// If ownerOnly is set, the authenticated user has to be the owner of the instrument.
+// Sends the error response and returns false if the instrument can not be used.
+func (app *application) instrumentForPhotos(w http.ResponseWriter, r *http.Request, ownerOnly bool) (*data.Instrument, bool) {
+	id, err := app.extractIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return nil, false
+	}
+
+	instrument, err := app.models.Instruments.Get(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorLogResponse(w, r, err)
+		}
+		return nil, false
+	}
+
+	if ownerOnly && app.contextGetUser(r).ID != instrument.OwnerUserID {
+		app.forbiddenResponse(w, r)
+		return nil, false
+	}
+
+	return instrument, true
+}
+
+// deleteInstrumentPhotoBlobs removes the image and the thumbnail of the given photo from the blob storage.
+// The errors are only logged, a leftover blob is not referenced anymore.
+func (app *application) deleteInstrumentPhotoBlobs(photo *data.InstrumentPhoto) {
+	for _, key := range []string{photo.ImageKey, photo.ThumbnailKey} {
+		err := app.storage.Delete(context.Background(), key)
+		if err != nil {
+			app.logger.Error(err.Error(), "key", key)
+		}
+	}
+}
+
+// newPhotoKey returns a new random blob storage key prefix for a photo of the given instrument.
+func newPhotoKey(instrumentID int64) (string, error) {
+	randomBytes := make([]byte, 16)
+
+	_, err := rand.Read(randomBytes)
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("instruments/%d/photos/%s", instrumentID, hex.EncodeToString(randomBytes)), nil
+}
+
+// errFileTooLarge is returned by readMultipartFile if the file exceeds the size limit.
+var errFileTooLarge = errors.New("file too large")
+
+// readMultipartFile reads the content of the file field with the given name from a multipart/form-data request body.
+// Returns http.ErrMissingFile if the field is missing, errFileTooLarge if the file is larger than maxSize bytes.
+func readMultipartFile(r *http.Request, field string, maxSize int64) ([]byte, error) {
+	mr, err := r.MultipartReader()
+	if err != nil {
+		return nil, errors.New("body must be a multipart/form-data request")
+	}
+
+	for {
+		part, err := mr.NextPart()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil, http.ErrMissingFile
+			}
+			return nil, err
+		}
+
+		if part.FormName() != field {
+			continue
+		}
+
+		content, err := io.ReadAll(io.LimitReader(part, maxSize+1))
+		if err != nil {
+			return nil, err
+		}
+		if int64(len(content)) > maxSize {
+			return nil, errFileTooLarge
+		}
+
+		return content, nil
+	}
+}
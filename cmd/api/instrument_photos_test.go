@@ -0,0 +1,594 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"log/slog"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+
+	"github.com/ttarnok/instrument-swap-api/internal/data"
+	"github.com/ttarnok/instrument-swap-api/internal/data/mocks"
+	"github.com/ttarnok/instrument-swap-api/internal/imaging"
+)
+
+// newTestPNG returns an encoded PNG image with the given dimensions.
+func newTestPNG(t *testing.T, width, height int) []byte {
+	t.Helper()
+
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := range height {
+		for x := range width {
+			img.Set(x, y, color.RGBA{R: uint8(x), G: uint8(y), B: 128, A: 255}) //nolint:gosec // Test data.
+		}
+	}
+
+	var buf bytes.Buffer
+	err := png.Encode(&buf, img)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// newTestMultipartBody returns a multipart/form-data body with the given content in the given file field,
+// and its content type.
+func newTestMultipartBody(t *testing.T, field string, content []byte) (*bytes.Buffer, string) {
+	t.Helper()
+
+	body := &bytes.Buffer{}
+	mw := multipart.NewWriter(body)
+
+	fw, err := mw.CreateFormFile(field, "photo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = fw.Write(content)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = mw.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return body, mw.FormDataContentType()
+}
+
+// newTestInstrumentPhotos returns n stored photos of the instrument with the given id, together with their blobs.
+func newTestInstrumentPhotos(t *testing.T, instrumentID int64, n int) ([]*data.InstrumentPhoto, *mocks.StorageMock) {
+	t.Helper()
+
+	storage := mocks.NewStorageMock()
+	photos := make([]*data.InstrumentPhoto, 0, n)
+
+	for i := range n {
+		photo := &data.InstrumentPhoto{
+			ID:           int64(i + 1),
+			InstrumentID: instrumentID,
+			Position:     int32(i + 1), //nolint:gosec // Test data.
+			ContentType:  imaging.ContentTypePNG,
+			ImageKey:     fmt.Sprintf("instruments/%d/photos/%d.png", instrumentID, i+1),
+			ThumbnailKey: fmt.Sprintf("instruments/%d/photos/%d_thumbnail.png", instrumentID, i+1),
+		}
+
+		for _, key := range []string{photo.ImageKey, photo.ThumbnailKey} {
+			err := storage.Put(context.Background(), key, []byte(key), photo.ContentType)
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		photos = append(photos, photo)
+	}
+
+	return photos, storage
+}
+
+// TestUploadInstrumentPhotoHandler implements unit tests for uploadInstrumentPhotoHandler.
+func TestUploadInstrumentPhotoHandler(t *testing.T) {
+
+	testPNG := newTestPNG(t, 640, 480)
+
+	type testCase struct {
+		name               string
+		pathParam          string
+		field              string
+		content            []byte
+		existingPhotos     int
+		expectedStatusCode int
+	}
+
+	testCases := []testCase{
+		{
+			name:               "happy path",
+			pathParam:          "1",
+			field:              "photo",
+			content:            testPNG,
+			expectedStatusCode: http.StatusCreated,
+		},
+		{
+			name:               "not the owner",
+			pathParam:          "2",
+			field:              "photo",
+			content:            testPNG,
+			expectedStatusCode: http.StatusForbidden,
+		},
+		{
+			name:               "non existent instrument",
+			pathParam:          "99",
+			field:              "photo",
+			content:            testPNG,
+			expectedStatusCode: http.StatusNotFound,
+		},
+		{
+			name:               "missing photo field",
+			pathParam:          "1",
+			field:              "image",
+			content:            testPNG,
+			expectedStatusCode: http.StatusUnprocessableEntity,
+		},
+		{
+			name:               "unsupported format",
+			pathParam:          "1",
+			field:              "photo",
+			content:            []byte("definitely not an image"),
+			expectedStatusCode: http.StatusUnsupportedMediaType,
+		},
+		{
+			name:               "corrupt image",
+			pathParam:          "1",
+			field:              "photo",
+			content:            testPNG[:100],
+			expectedStatusCode: http.StatusUnprocessableEntity,
+		},
+		{
+			name:               "too large",
+			pathParam:          "1",
+			field:              "photo",
+			content:            bytes.Repeat(testPNG, 10),
+			expectedStatusCode: http.StatusRequestEntityTooLarge,
+		},
+		{
+			name:               "too many photos",
+			pathParam:          "1",
+			field:              "photo",
+			content:            testPNG,
+			existingPhotos:     data.MaxInstrumentPhotos,
+			expectedStatusCode: http.StatusUnprocessableEntity,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			photos, storage := newTestInstrumentPhotos(t, 1, tc.existingPhotos)
+			storedKeys := storage.Keys()
+
+			app := &application{
+				logger: slog.New(slog.NewTextHandler(io.Discard, nil)),
+				models: data.Models{
+					Instruments:      mocks.NewNonEmptyInstrumentModelMock([]*data.Instrument{{ID: 1, OwnerUserID: 10}, {ID: 2, OwnerUserID: 20}}),
+					InstrumentPhotos: mocks.NewInstrumentPhotoModelMock(photos),
+				},
+				storage: storage,
+			}
+			app.config.photos.maxSize = int64(len(testPNG) * 2)
+
+			setUser := func(next http.HandlerFunc) http.HandlerFunc {
+				return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+					r = app.contextSetUser(r, &data.User{ID: 10})
+
+					next.ServeHTTP(w, r)
+				})
+			}
+
+			mux := http.NewServeMux()
+			mux.HandleFunc("POST /v1/instruments/{id}/photos", setUser(app.uploadInstrumentPhotoHandler))
+
+			ts := httptest.NewServer(mux)
+			defer ts.Close()
+
+			body, contentType := newTestMultipartBody(t, tc.field, tc.content)
+
+			resp, err := http.Post(ts.URL+"/v1/instruments/"+tc.pathParam+"/photos", contentType, body)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer func() {
+				err := resp.Body.Close()
+				if err != nil {
+					t.Fatal(err)
+				}
+			}()
+
+			if tc.expectedStatusCode != resp.StatusCode {
+				t.Errorf(`expected status code %d, got %d`, tc.expectedStatusCode, resp.StatusCode)
+			}
+
+			if tc.expectedStatusCode != http.StatusCreated {
+				if keys := storage.Keys(); !slices.Equal(storedKeys, keys) {
+					t.Errorf(`expected stored blobs %v, got %v`, storedKeys, keys)
+				}
+				return
+			}
+
+			var respBody struct {
+				Photo data.InstrumentPhoto `json:"photo"`
+			}
+			err = json.NewDecoder(resp.Body).Decode(&respBody)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			expectedLocation := fmt.Sprintf("/v1/instruments/1/photos/%d/image", respBody.Photo.ID)
+			if location := resp.Header.Get("Location"); location != expectedLocation {
+				t.Errorf(`expected location %q, got %q`, expectedLocation, location)
+			}
+			if respBody.Photo.Width != 640 || respBody.Photo.Height != 480 {
+				t.Errorf(`expected dimensions 640x480, got %dx%d`, respBody.Photo.Width, respBody.Photo.Height)
+			}
+			if respBody.Photo.ThumbnailWidth != imaging.ThumbnailSize || respBody.Photo.ThumbnailHeight != 240 {
+				t.Errorf(`expected thumbnail dimensions %dx240, got %dx%d`, imaging.ThumbnailSize, respBody.Photo.ThumbnailWidth, respBody.Photo.ThumbnailHeight)
+			}
+
+			stored, err := app.models.InstrumentPhotos.GetAllForInstrument(1)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(stored) != 1 {
+				t.Fatalf(`expected 1 stored photo, got %d`, len(stored))
+			}
+
+			expectedKeys := []string{stored[0].ImageKey, stored[0].ThumbnailKey}
+			if keys := storage.Keys(); !slices.Equal(expectedKeys, keys) {
+				t.Errorf(`expected stored blobs %v, got %v`, expectedKeys, keys)
+			}
+		})
+	}
+}
+
+// TestShowInstrumentPhotoThumbnailHandler implements unit tests for showInstrumentPhotoThumbnailHandler.
+func TestShowInstrumentPhotoThumbnailHandler(t *testing.T) {
+
+	type testCase struct {
+		name               string
+		path               string
+		missingBlob        bool
+		expectedStatusCode int
+		expectedBody       string
+	}
+
+	testCases := []testCase{
+		{
+			name:               "happy path",
+			path:               "/v1/instruments/2/photos/1/thumbnail",
+			expectedStatusCode: http.StatusOK,
+			expectedBody:       "instruments/2/photos/1_thumbnail.png",
+		},
+		{
+			name:               "photo of another instrument",
+			path:               "/v1/instruments/1/photos/1/thumbnail",
+			expectedStatusCode: http.StatusNotFound,
+		},
+		{
+			name:               "non existent photo",
+			path:               "/v1/instruments/2/photos/99/thumbnail",
+			expectedStatusCode: http.StatusNotFound,
+		},
+		{
+			name:               "missing blob",
+			path:               "/v1/instruments/2/photos/1/thumbnail",
+			missingBlob:        true,
+			expectedStatusCode: http.StatusNotFound,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			photos, storage := newTestInstrumentPhotos(t, 2, 1)
+			if tc.missingBlob {
+				err := storage.Delete(context.Background(), photos[0].ThumbnailKey)
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			app := &application{
+				logger: slog.New(slog.NewTextHandler(io.Discard, nil)),
+				models: data.Models{
+					Instruments:      mocks.NewNonEmptyInstrumentModelMock([]*data.Instrument{{ID: 1, OwnerUserID: 10}, {ID: 2, OwnerUserID: 20}}),
+					InstrumentPhotos: mocks.NewInstrumentPhotoModelMock(photos),
+				},
+				storage: storage,
+			}
+
+			mux := http.NewServeMux()
+			mux.HandleFunc("GET /v1/instruments/{id}/photos/{photo_id}/thumbnail", app.showInstrumentPhotoThumbnailHandler)
+
+			ts := httptest.NewServer(mux)
+			defer ts.Close()
+
+			resp, err := http.Get(ts.URL + tc.path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer func() {
+				err := resp.Body.Close()
+				if err != nil {
+					t.Fatal(err)
+				}
+			}()
+
+			if tc.expectedStatusCode != resp.StatusCode {
+				t.Errorf(`expected status code %d, got %d`, tc.expectedStatusCode, resp.StatusCode)
+			}
+
+			if tc.expectedStatusCode != http.StatusOK {
+				return
+			}
+
+			if contentType := resp.Header.Get("Content-Type"); contentType != imaging.ContentTypePNG {
+				t.Errorf(`expected content type %q, got %q`, imaging.ContentTypePNG, contentType)
+			}
+
+			body, err := io.ReadAll(resp.Body)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(body) != tc.expectedBody {
+				t.Errorf(`expected body %q, got %q`, tc.expectedBody, body)
+			}
+		})
+	}
+}
+
+// TestReorderInstrumentPhotosHandler implements unit tests for reorderInstrumentPhotosHandler.
+func TestReorderInstrumentPhotosHandler(t *testing.T) {
+
+	type testCase struct {
+		name               string
+		pathParam          string
+		photoIDs           []int64
+		expectedStatusCode int
+		expectedPhotoIDs   []int64
+	}
+
+	testCases := []testCase{
+		{
+			name:               "happy path",
+			pathParam:          "1",
+			photoIDs:           []int64{3, 1, 2},
+			expectedStatusCode: http.StatusOK,
+			expectedPhotoIDs:   []int64{3, 1, 2},
+		},
+		{
+			name:               "not the owner",
+			pathParam:          "2",
+			photoIDs:           []int64{3, 1, 2},
+			expectedStatusCode: http.StatusForbidden,
+			expectedPhotoIDs:   []int64{1, 2, 3},
+		},
+		{
+			name:               "missing photo",
+			pathParam:          "1",
+			photoIDs:           []int64{3, 1},
+			expectedStatusCode: http.StatusUnprocessableEntity,
+			expectedPhotoIDs:   []int64{1, 2, 3},
+		},
+		{
+			name:               "duplicated photo",
+			pathParam:          "1",
+			photoIDs:           []int64{3, 1, 1},
+			expectedStatusCode: http.StatusUnprocessableEntity,
+			expectedPhotoIDs:   []int64{1, 2, 3},
+		},
+		{
+			name:               "unknown photo",
+			pathParam:          "1",
+			photoIDs:           []int64{3, 1, 99},
+			expectedStatusCode: http.StatusUnprocessableEntity,
+			expectedPhotoIDs:   []int64{1, 2, 3},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			photos, storage := newTestInstrumentPhotos(t, 1, 3)
+
+			app := &application{
+				logger: slog.New(slog.NewTextHandler(io.Discard, nil)),
+				models: data.Models{
+					Instruments:      mocks.NewNonEmptyInstrumentModelMock([]*data.Instrument{{ID: 1, OwnerUserID: 10}, {ID: 2, OwnerUserID: 20}}),
+					InstrumentPhotos: mocks.NewInstrumentPhotoModelMock(photos),
+				},
+				storage: storage,
+			}
+
+			setUser := func(next http.HandlerFunc) http.HandlerFunc {
+				return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+					r = app.contextSetUser(r, &data.User{ID: 10})
+
+					next.ServeHTTP(w, r)
+				})
+			}
+
+			mux := http.NewServeMux()
+			mux.HandleFunc("PUT /v1/instruments/{id}/photos/order", setUser(app.reorderInstrumentPhotosHandler))
+
+			ts := httptest.NewServer(mux)
+			defer ts.Close()
+
+			bs, err := json.Marshal(map[string][]int64{"photo_ids": tc.photoIDs})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			req, err := http.NewRequest(http.MethodPut, ts.URL+"/v1/instruments/"+tc.pathParam+"/photos/order", bytes.NewBuffer(bs))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			resp, err := ts.Client().Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer func() {
+				err := resp.Body.Close()
+				if err != nil {
+					t.Fatal(err)
+				}
+			}()
+
+			if tc.expectedStatusCode != resp.StatusCode {
+				t.Errorf(`expected status code %d, got %d`, tc.expectedStatusCode, resp.StatusCode)
+			}
+
+			stored, err := app.models.InstrumentPhotos.GetAllForInstrument(1)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			photoIDs := make([]int64, 0, len(stored))
+			for i, photo := range stored {
+				if photo.Position != int32(i+1) { //nolint:gosec // Test data.
+					t.Errorf(`expected position %d of photo %d, got %d`, i+1, photo.ID, photo.Position)
+				}
+				photoIDs = append(photoIDs, photo.ID)
+			}
+			if !slices.Equal(tc.expectedPhotoIDs, photoIDs) {
+				t.Errorf(`expected photo order %v, got %v`, tc.expectedPhotoIDs, photoIDs)
+			}
+		})
+	}
+}
+
+// TestDeleteInstrumentPhotoHandler implements unit tests for deleteInstrumentPhotoHandler.
+func TestDeleteInstrumentPhotoHandler(t *testing.T) {
+
+	type testCase struct {
+		name               string
+		path               string
+		expectedStatusCode int
+		expectedPhotoIDs   []int64
+	}
+
+	testCases := []testCase{
+		{
+			name:               "happy path",
+			path:               "/v1/instruments/1/photos/2",
+			expectedStatusCode: http.StatusOK,
+			expectedPhotoIDs:   []int64{1, 3},
+		},
+		{
+			name:               "not the owner",
+			path:               "/v1/instruments/2/photos/2",
+			expectedStatusCode: http.StatusForbidden,
+			expectedPhotoIDs:   []int64{1, 2, 3},
+		},
+		{
+			name:               "non existent photo",
+			path:               "/v1/instruments/1/photos/99",
+			expectedStatusCode: http.StatusNotFound,
+			expectedPhotoIDs:   []int64{1, 2, 3},
+		},
+		{
+			name:               "invalid photo id",
+			path:               "/v1/instruments/1/photos/abc",
+			expectedStatusCode: http.StatusNotFound,
+			expectedPhotoIDs:   []int64{1, 2, 3},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			photos, storage := newTestInstrumentPhotos(t, 1, 3)
+
+			app := &application{
+				logger: slog.New(slog.NewTextHandler(io.Discard, nil)),
+				models: data.Models{
+					Instruments:      mocks.NewNonEmptyInstrumentModelMock([]*data.Instrument{{ID: 1, OwnerUserID: 10}, {ID: 2, OwnerUserID: 20}}),
+					InstrumentPhotos: mocks.NewInstrumentPhotoModelMock(photos),
+				},
+				storage: storage,
+			}
+
+			setUser := func(next http.HandlerFunc) http.HandlerFunc {
+				return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+					r = app.contextSetUser(r, &data.User{ID: 10})
+
+					next.ServeHTTP(w, r)
+				})
+			}
+
+			mux := http.NewServeMux()
+			mux.HandleFunc("DELETE /v1/instruments/{id}/photos/{photo_id}", setUser(app.deleteInstrumentPhotoHandler))
+
+			ts := httptest.NewServer(mux)
+			defer ts.Close()
+
+			req, err := http.NewRequest(http.MethodDelete, ts.URL+tc.path, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			resp, err := ts.Client().Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer func() {
+				err := resp.Body.Close()
+				if err != nil {
+					t.Fatal(err)
+				}
+			}()
+
+			if tc.expectedStatusCode != resp.StatusCode {
+				t.Errorf(`expected status code %d, got %d`, tc.expectedStatusCode, resp.StatusCode)
+			}
+
+			stored, err := app.models.InstrumentPhotos.GetAllForInstrument(1)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			photoIDs := make([]int64, 0, len(stored))
+			expectedKeys := make([]string, 0, 2*len(stored))
+			for i, photo := range stored {
+				if photo.Position != int32(i+1) { //nolint:gosec // Test data.
+					t.Errorf(`expected position %d of photo %d, got %d`, i+1, photo.ID, photo.Position)
+				}
+				photoIDs = append(photoIDs, photo.ID)
+				expectedKeys = append(expectedKeys, photo.ImageKey, photo.ThumbnailKey)
+			}
+			if !slices.Equal(tc.expectedPhotoIDs, photoIDs) {
+				t.Errorf(`expected photos %v, got %v`, tc.expectedPhotoIDs, photoIDs)
+			}
+
+			slices.Sort(expectedKeys)
+			if keys := storage.Keys(); !slices.Equal(expectedKeys, keys) {
+				t.Errorf(`expected stored blobs %v, got %v`, expectedKeys, keys)
+			}
+		})
+	}
+}
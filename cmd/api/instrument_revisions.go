@@ -0,0 +1,158 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/ttarnok/instrument-swap-api/internal/data"
+	"github.com/ttarnok/instrument-swap-api/internal/validator"
+)
+
+// listInstrumentRevisionsHandler lists the previous states of an instrument, together with the times and the authors of their changes.
+func (app *application) listInstrumentRevisionsHandler(w http.ResponseWriter, r *http.Request) {
+	instrumentID, err := app.extractIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	var input struct {
+		data.Filters
+	}
+
+	v := validator.New()
+
+	qs := r.URL.Query()
+
+	input.Page = app.readQParamInt(qs, "page", 1, v)
+	input.PageSize = app.readQParamInt(qs, "page_size", 20, v)
+
+	input.Sort = app.readQParamString(qs, "sort", "-version")
+	input.SortSafeList = []string{"version", "changed_at", "-version", "-changed_at"}
+
+	data.ValidateFilters(v, input.Filters)
+
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	instrument, err := app.models.Instruments.Get(instrumentID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorLogResponse(w, r, err)
+		}
+		return
+	}
+
+	revisions, metadata, err := app.models.InstrumentRevisions.GetAllForInstrument(instrument.ID, input.Filters)
+	if err != nil {
+		app.serverErrorLogResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"revisions": revisions, "metadata": metadata}, nil)
+	if err != nil {
+		app.serverErrorLogResponse(w, r, err)
+		return
+	}
+}
+
+// showInstrumentDiffHandler shows the changes of an instrument between the versions given by the from and to parameters.
+// The to parameter defaults to the current version of the instrument.
+func (app *application) showInstrumentDiffHandler(w http.ResponseWriter, r *http.Request) {
+	instrumentID, err := app.extractIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	v := validator.New()
+
+	qs := r.URL.Query()
+
+	from := app.readQParamInt(qs, "from", 0, v)
+	to := app.readQParamInt(qs, "to", 0, v)
+
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	instrument, err := app.models.Instruments.Get(instrumentID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorLogResponse(w, r, err)
+		}
+		return
+	}
+
+	if !qs.Has("to") {
+		to = int(instrument.Version)
+	}
+
+	if !qs.Has("from") {
+		v.AddError("from", "must be provided")
+	}
+	v.Check(from > 0, "from", "must be greater than zero")
+	v.Check(from <= int(instrument.Version), "from", "must not be greater than the current version")
+	v.Check(to > 0, "to", "must be greater than zero")
+	v.Check(to <= int(instrument.Version), "to", "must not be greater than the current version")
+
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	//nolint:gosec // The versions are limited by the current version of the instrument.
+	fromInstrument, err := app.instrumentAtVersion(instrument, int32(from))
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorLogResponse(w, r, err)
+		}
+		return
+	}
+
+	//nolint:gosec // The versions are limited by the current version of the instrument.
+	toInstrument, err := app.instrumentAtVersion(instrument, int32(to))
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorLogResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"diff": data.DiffInstruments(fromInstrument, toInstrument)}, nil)
+	if err != nil {
+		app.serverErrorLogResponse(w, r, err)
+		return
+	}
+}
+
+// instrumentAtVersion returns the state of the given current instrument at the given version.
+// The previous versions are retrieved from the revisions of the instrument.
+// Returns data.ErrRecordNotFound if no revision was recorded at the given version.
+func (app *application) instrumentAtVersion(instrument *data.Instrument, version int32) (*data.Instrument, error) {
+	if version == instrument.Version {
+		return instrument, nil
+	}
+
+	revision, err := app.models.InstrumentRevisions.Get(instrument.ID, version)
+	if err != nil {
+		return nil, err
+	}
+
+	return revision.Instrument, nil
+}
@@ -0,0 +1,246 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+
+	"github.com/ttarnok/instrument-swap-api/internal/data"
+	"github.com/ttarnok/instrument-swap-api/internal/data/mocks"
+)
+
+// newTestInstrumentRevisionApp returns an application with an instrument at version 3 and its revisions at versions 1 and 2.
+// The name of the instrument changed at version 2, its estimated value and famous owners at version 3.
+func newTestInstrumentRevisionApp() *application {
+	changedBy := int64(10)
+
+	revisions := []*data.InstrumentRevision{
+		{
+			ID:              1,
+			ChangedByUserID: &changedBy,
+			Instrument:      &data.Instrument{ID: 1, Name: "Juno", EstimatedValue: 1000, FamousOwners: []string{}, OwnerUserID: 10, Version: 1},
+		},
+		{
+			ID:              2,
+			ChangedByUserID: &changedBy,
+			Instrument:      &data.Instrument{ID: 1, Name: "Juno 60", EstimatedValue: 1000, FamousOwners: []string{}, OwnerUserID: 10, Version: 2},
+		},
+	}
+
+	return &application{
+		logger: slog.New(slog.NewTextHandler(io.Discard, nil)),
+		models: data.Models{
+			Instruments: mocks.NewNonEmptyInstrumentModelMock([]*data.Instrument{
+				{ID: 1, Name: "Juno 60", EstimatedValue: 1500, FamousOwners: []string{"Band"}, OwnerUserID: 10, Version: 3},
+			}),
+			InstrumentRevisions: mocks.NewInstrumentRevisionModelMock(revisions),
+		},
+	}
+}
+
+// TestListInstrumentRevisionsHandler implements unit tests for listInstrumentRevisionsHandler.
+func TestListInstrumentRevisionsHandler(t *testing.T) {
+
+	type testCase struct {
+		name               string
+		pathParam          string
+		query              string
+		expectedStatusCode int
+		expectedVersions   []int32
+	}
+
+	testCases := []testCase{
+		{
+			name:               "happy path",
+			pathParam:          "1",
+			expectedStatusCode: http.StatusOK,
+			expectedVersions:   []int32{1, 2},
+		},
+		{
+			name:               "non existent instrument",
+			pathParam:          "99",
+			expectedStatusCode: http.StatusNotFound,
+		},
+		{
+			name:               "invalid sort",
+			pathParam:          "1",
+			query:              "?sort=name",
+			expectedStatusCode: http.StatusUnprocessableEntity,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			app := newTestInstrumentRevisionApp()
+
+			mux := http.NewServeMux()
+			mux.HandleFunc("GET /v1/instruments/{id}/revisions", app.listInstrumentRevisionsHandler)
+
+			ts := httptest.NewServer(mux)
+			defer ts.Close()
+
+			resp, err := http.Get(fmt.Sprintf("%s/v1/instruments/%s/revisions%s", ts.URL, tc.pathParam, tc.query))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer resp.Body.Close()
+
+			if tc.expectedStatusCode != resp.StatusCode {
+				t.Fatalf(`expected status code %d, got %d`, tc.expectedStatusCode, resp.StatusCode)
+			}
+			if tc.expectedStatusCode != http.StatusOK {
+				return
+			}
+
+			var body struct {
+				Revisions []*data.InstrumentRevision `json:"revisions"`
+			}
+			err = json.NewDecoder(resp.Body).Decode(&body)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			versions := []int32{}
+			for _, revision := range body.Revisions {
+				versions = append(versions, revision.Instrument.Version)
+			}
+			if !slices.Equal(tc.expectedVersions, versions) {
+				t.Errorf(`expected revision versions %v, got %v`, tc.expectedVersions, versions)
+			}
+		})
+	}
+}
+
+// TestShowInstrumentDiffHandler implements unit tests for showInstrumentDiffHandler.
+func TestShowInstrumentDiffHandler(t *testing.T) {
+
+	type testCase struct {
+		name               string
+		pathParam          string
+		query              string
+		expectedStatusCode int
+		expectedFields     []string
+	}
+
+	testCases := []testCase{
+		{
+			name:               "happy path - to the current version",
+			pathParam:          "1",
+			query:              "?from=1",
+			expectedStatusCode: http.StatusOK,
+			expectedFields:     []string{"name", "estimated_value", "famous_owners"},
+		},
+		{
+			name:               "happy path - between revisions",
+			pathParam:          "1",
+			query:              "?from=1&to=2",
+			expectedStatusCode: http.StatusOK,
+			expectedFields:     []string{"name"},
+		},
+		{
+			name:               "happy path - backwards",
+			pathParam:          "1",
+			query:              "?from=3&to=2",
+			expectedStatusCode: http.StatusOK,
+			expectedFields:     []string{"estimated_value", "famous_owners"},
+		},
+		{
+			name:               "same version",
+			pathParam:          "1",
+			query:              "?from=2&to=2",
+			expectedStatusCode: http.StatusOK,
+			expectedFields:     []string{},
+		},
+		{
+			name:               "missing from",
+			pathParam:          "1",
+			expectedStatusCode: http.StatusUnprocessableEntity,
+		},
+		{
+			name:               "invalid from",
+			pathParam:          "1",
+			query:              "?from=first",
+			expectedStatusCode: http.StatusUnprocessableEntity,
+		},
+		{
+			name:               "zero from",
+			pathParam:          "1",
+			query:              "?from=0",
+			expectedStatusCode: http.StatusUnprocessableEntity,
+		},
+		{
+			name:               "negative from",
+			pathParam:          "1",
+			query:              "?from=-1",
+			expectedStatusCode: http.StatusUnprocessableEntity,
+		},
+		{
+			name:               "zero to",
+			pathParam:          "1",
+			query:              "?from=1&to=0",
+			expectedStatusCode: http.StatusUnprocessableEntity,
+		},
+		{
+			name:               "future version",
+			pathParam:          "1",
+			query:              "?from=1&to=4",
+			expectedStatusCode: http.StatusUnprocessableEntity,
+		},
+		{
+			name:               "non existent instrument",
+			pathParam:          "99",
+			query:              "?from=1",
+			expectedStatusCode: http.StatusNotFound,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			app := newTestInstrumentRevisionApp()
+
+			mux := http.NewServeMux()
+			mux.HandleFunc("GET /v1/instruments/{id}/revisions/diff", app.showInstrumentDiffHandler)
+
+			ts := httptest.NewServer(mux)
+			defer ts.Close()
+
+			resp, err := http.Get(fmt.Sprintf("%s/v1/instruments/%s/revisions/diff%s", ts.URL, tc.pathParam, tc.query))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer resp.Body.Close()
+
+			if tc.expectedStatusCode != resp.StatusCode {
+				t.Fatalf(`expected status code %d, got %d`, tc.expectedStatusCode, resp.StatusCode)
+			}
+			if tc.expectedStatusCode != http.StatusOK {
+				return
+			}
+
+			var body struct {
+				Diff data.InstrumentDiff `json:"diff"`
+			}
+			err = json.NewDecoder(resp.Body).Decode(&body)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			fields := []string{}
+			for _, change := range body.Diff.Changes {
+				fields = append(fields, change.Field)
+			}
+			if !slices.Equal(tc.expectedFields, fields) {
+				t.Errorf(`expected changed fields %v, got %v`, tc.expectedFields, fields)
+			}
+		})
+	}
+}
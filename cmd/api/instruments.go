@@ -4,6 +4,8 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
+	"slices"
+	"strings"
 
 	"github.com/ttarnok/instrument-swap-api/internal/data"
 	"github.com/ttarnok/instrument-swap-api/internal/validator"
@@ -59,6 +61,7 @@ func (app *application) listInstrumentsHandler(w http.ResponseWriter, r *http.Re
 }
 
 // showInstrumentHandler shows a specific instrument.
+// The ETag of the response is derived from the version of the instrument, If-None-Match is honored.
 func (app *application) showInstrumentHandler(w http.ResponseWriter, r *http.Request) {
 
 	id, err := app.extractIDParam(r)
@@ -79,6 +82,10 @@ func (app *application) showInstrumentHandler(w http.ResponseWriter, r *http.Req
 		}
 	}
 
+	if app.notModified(w, r, etag(int64(instrument.Version))) {
+		return
+	}
+
 	err = app.writeJSON(w, http.StatusOK, envelope{"instrument": instrument}, nil)
 	if err != nil {
 		app.serverErrorLogResponse(w, r, err)
@@ -129,12 +136,24 @@ func (app *application) createInstrumentHandler(w http.ResponseWriter, r *http.R
 		return
 	}
 
+	err = app.validateInstrumentType(v, "type", instrument.Type)
+	if err != nil {
+		app.serverErrorLogResponse(w, r, err)
+		return
+	}
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
 	err = app.models.Instruments.Insert(instrument)
 	if err != nil {
 		app.serverErrorLogResponse(w, r, err)
 		return
 	}
 
+	app.notifySavedSearches(instrument, nil)
+
 	// create a location header for the client, with the location of the newly created resource
 	headers := make(http.Header)
 	headers.Set("Location", fmt.Sprintf("/v1/instrumets/%d", instrument.ID))
@@ -147,8 +166,43 @@ func (app *application) createInstrumentHandler(w http.ResponseWriter, r *http.R
 
 }
 
+// instrumentPatchDocument is the representation of an instrument that PATCH requests are applied to.
+// Members set to null or removed by a patch clear the corresponding fields.
+type instrumentPatchDocument struct {
+	Name            *string  `json:"name"`
+	Manufacturer    *string  `json:"manufacturer"`
+	ManufactureYear *int32   `json:"manufacture_year"`
+	Type            *string  `json:"type"`
+	EstimatedValue  *int64   `json:"estimated_value"`
+	Condition       *string  `json:"condition"`
+	Description     *string  `json:"description"`
+	FamousOwners    []string `json:"famous_owners"`
+}
+
+// newInstrumentPatchDocument returns the patch document of the given instrument.
+// The missing famous owners are represented by an empty list, so JSON patches can add items to it.
+func newInstrumentPatchDocument(instrument *data.Instrument) instrumentPatchDocument {
+	famousOwners := instrument.FamousOwners
+	if famousOwners == nil {
+		famousOwners = []string{}
+	}
+
+	return instrumentPatchDocument{
+		Name:            &instrument.Name,
+		Manufacturer:    &instrument.Manufacturer,
+		ManufactureYear: &instrument.ManufactureYear,
+		Type:            &instrument.Type,
+		EstimatedValue:  &instrument.EstimatedValue,
+		Condition:       &instrument.Condition,
+		Description:     &instrument.Description,
+		FamousOwners:    famousOwners,
+	}
+}
+
 // updateInstrumentHandler updates an instrument.
-// JSON items with null values will be ignored and will remain unchanged.
+// The request body is a JSON merge patch or a JSON patch applied to the patch document of the instrument,
+// members missing from a merge patch remain unchanged, null members clear the fields.
+// The If-Match header is checked against the ETag of the instrument.
 func (app *application) updateInstrumentHandler(w http.ResponseWriter, r *http.Request) {
 
 	ownerUser := app.contextGetUser(r)
@@ -175,47 +229,33 @@ func (app *application) updateInstrumentHandler(w http.ResponseWriter, r *http.R
 		return
 	}
 
-	var input struct {
-		Name            string   `json:"name"`
-		Manufacturer    string   `json:"manufacturer"`
-		ManufactureYear int32    `json:"manufacture_year"`
-		Type            string   `json:"type"`
-		EstimatedValue  int64    `json:"estimated_value"`
-		Condition       string   `json:"condition"`
-		Description     string   `json:"description"`
-		FamousOwners    []string `json:"famous_owners"`
+	if !app.checkIfMatch(w, r, etag(int64(instrument.Version))) {
+		return
 	}
 
-	err = app.readJSON(w, r, &input)
+	previous := *instrument
+	previous.FamousOwners = slices.Clone(instrument.FamousOwners)
+
+	current := newInstrumentPatchDocument(instrument)
+
+	var input instrumentPatchDocument
+
+	err = app.readPatch(w, r, current, &input)
 	if err != nil {
-		app.badRequestResponse(w, r, err)
+		app.patchErrorResponse(w, r, err)
 		return
 	}
 
-	if input.Name != "" {
-		instrument.Name = input.Name
-	}
-	if input.Manufacturer != "" {
-		instrument.Manufacturer = input.Manufacturer
-	}
-
-	if input.ManufactureYear != 0 {
-		instrument.ManufactureYear = input.ManufactureYear
-	}
-	if input.Type != "" {
-		instrument.Type = input.Type
-	}
-	if input.EstimatedValue != 0 {
-		instrument.EstimatedValue = input.EstimatedValue
-	}
-	if input.Condition != "" {
-		instrument.Condition = input.Condition
-	}
-	if input.Description != "" {
-		instrument.Description = input.Description
-	}
-	if len(input.FamousOwners) != 0 {
-		instrument.FamousOwners = input.FamousOwners
+	instrument.Name = valueOrZero(input.Name)
+	instrument.Manufacturer = valueOrZero(input.Manufacturer)
+	instrument.ManufactureYear = valueOrZero(input.ManufactureYear)
+	instrument.Type = valueOrZero(input.Type)
+	instrument.EstimatedValue = valueOrZero(input.EstimatedValue)
+	instrument.Condition = valueOrZero(input.Condition)
+	instrument.Description = valueOrZero(input.Description)
+	instrument.FamousOwners = input.FamousOwners
+	if instrument.FamousOwners == nil {
+		instrument.FamousOwners = []string{}
 	}
 
 	v := validator.New()
@@ -225,18 +265,35 @@ func (app *application) updateInstrumentHandler(w http.ResponseWriter, r *http.R
 		return
 	}
 
-	err = app.models.Instruments.Update(instrument)
+	err = app.validateInstrumentType(v, "type", instrument.Type)
+	if err != nil {
+		app.serverErrorLogResponse(w, r, err)
+		return
+	}
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = app.models.Instruments.Update(instrument, ownerUser.ID)
 	if err != nil {
 		switch {
 		case errors.Is(err, data.ErrEditConflict):
-			app.editConflictResponse(w, r)
+			app.versionConflictResponse(w, r)
 		default:
 			app.serverErrorLogResponse(w, r, err)
 		}
 		return
 	}
 
-	err = app.writeJSON(w, http.StatusOK, envelope{"instrument": instrument}, nil)
+	if searchableFieldsChanged(&previous, instrument) {
+		app.notifySavedSearches(instrument, &previous)
+	}
+
+	headers := make(http.Header)
+	headers.Set("ETag", etag(int64(instrument.Version)))
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"instrument": instrument}, headers)
 	if err != nil {
 		app.serverErrorLogResponse(w, r, err)
 		return
@@ -245,6 +302,7 @@ func (app *application) updateInstrumentHandler(w http.ResponseWriter, r *http.R
 }
 
 // deleteInstrumentHandler deletes an instrument.
+// The If-Match header is checked against the ETag of the instrument.
 func (app *application) deleteInstrumentHandler(w http.ResponseWriter, r *http.Request) {
 
 	ownerUser := app.contextGetUser(r)
@@ -271,11 +329,24 @@ func (app *application) deleteInstrumentHandler(w http.ResponseWriter, r *http.R
 		return
 	}
 
-	err = app.models.Instruments.Delete(id)
+	if !app.checkIfMatch(w, r, etag(int64(instrument.Version))) {
+		return
+	}
+
+	// The photo records are removed together with the instrument, their blobs are removed afterwards.
+	photos, err := app.models.InstrumentPhotos.GetAllForInstrument(id)
+	if err != nil {
+		app.serverErrorLogResponse(w, r, err)
+		return
+	}
+
+	err = app.models.Instruments.Delete(id, instrument.Version)
 	if err != nil {
 		switch {
 		case errors.Is(err, data.ErrConflict):
 			app.badRequestResponse(w, r, errors.New("can not perform the operation on a swapped instrument"))
+		case errors.Is(err, data.ErrEditConflict):
+			app.versionConflictResponse(w, r)
 		case errors.Is(err, data.ErrRecordNotFound):
 			app.notFoundResponse(w, r)
 		default:
@@ -283,6 +354,11 @@ func (app *application) deleteInstrumentHandler(w http.ResponseWriter, r *http.R
 		}
 		return
 	}
+
+	for _, photo := range photos {
+		app.deleteInstrumentPhotoBlobs(photo)
+	}
+
 	err = app.writeJSON(w, http.StatusOK, envelope{"message": "instrument successfully deleted"}, nil)
 	if err != nil {
 		app.serverErrorLogResponse(w, r, err)
@@ -290,3 +366,94 @@ func (app *application) deleteInstrumentHandler(w http.ResponseWriter, r *http.R
 	}
 
 }
+
+// listInstrumentMatchesHandler suggests the available instruments of other users as swap partners for the given instrument.
+// The matches are ranked by estimated value proximity, by the preferred types and by the reputation of their owners.
+// The preferred types can be given by the types parameter, they default to the type of the instrument.
+// A preferred category covers all of its subtypes.
+func (app *application) listInstrumentMatchesHandler(w http.ResponseWriter, r *http.Request) {
+	authUser := app.contextGetUser(r)
+
+	instrumentID, err := app.extractIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	var input struct {
+		Types []string
+		data.Filters
+	}
+
+	v := validator.New()
+
+	qs := r.URL.Query()
+
+	input.Types = app.readQParamCSV(qs, "types", []string{})
+
+	input.Page = app.readQParamInt(qs, "page", 1, v)
+	input.PageSize = app.readQParamInt(qs, "page_size", 20, v)
+
+	input.Sort = "-score"
+	input.SortSafeList = []string{"-score"}
+
+	data.ValidateFilters(v, input.Filters)
+
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	for index, iType := range input.Types {
+		input.Types[index] = strings.ToLower(iType)
+
+		err = app.validateInstrumentType(v, "types", input.Types[index])
+		if err != nil {
+			app.serverErrorLogResponse(w, r, err)
+			return
+		}
+	}
+
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	instrument, err := app.models.Instruments.Get(instrumentID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorLogResponse(w, r, err)
+		}
+		return
+	}
+
+	if authUser.ID != instrument.OwnerUserID {
+		app.forbiddenResponse(w, r)
+		return
+	}
+
+	if len(input.Types) == 0 {
+		input.Types = []string{strings.ToLower(instrument.Type)}
+	}
+
+	input.Types, err = app.models.Categories.GetSubtreeNames(input.Types...)
+	if err != nil {
+		app.serverErrorLogResponse(w, r, err)
+		return
+	}
+
+	matches, metadata, err := app.models.Instruments.GetMatches(instrument, input.Types, input.Filters)
+	if err != nil {
+		app.serverErrorLogResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"matches": matches, "metadata": metadata}, nil)
+	if err != nil {
+		app.serverErrorLogResponse(w, r, err)
+		return
+	}
+}
@@ -3,6 +3,7 @@ package main
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log"
@@ -10,6 +11,7 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"reflect"
+	"strings"
 	"testing"
 	"time"
 
@@ -315,15 +317,36 @@ func TestCreateInstrumentHandler(t *testing.T) {
 			expectedResult:         nil,
 			expectedErrResult:      map[string]string{"name": "must be provided"},
 		},
+		{
+			name: "unknown instrument type",
+			input: inputInstrument{
+				Name:            "M1",
+				Manufacturer:    "Korg",
+				ManufactureYear: 1990,
+				Type:            "drum",
+				EstimatedValue:  100000,
+				Condition:       "used",
+			},
+			ownerUser:              data.User{ID: 1, Name: "Test User", Email: "testuser@example.com"},
+			expectedStatusCode:     http.StatusUnprocessableEntity,
+			expectedLocationHeader: "",
+			checkResult:            true,
+			expectedResult:         nil,
+			expectedErrResult:      map[string]string{"type": "must be an existing category"},
+		},
 	}
 
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
 
 			app := &application{
+				logger: slog.New(slog.NewTextHandler(io.Discard, nil)),
 				models: data.Models{
-					Instruments: mocks.NewNonEmptyInstrumentModelMock(testDataEmpty),
+					Instruments:   mocks.NewNonEmptyInstrumentModelMock(testDataEmpty),
+					SavedSearches: mocks.NewSavedSearchModelMock(nil),
+					Categories:    newTestCategoryModelMock(),
 				},
+				mailer: mocks.NewMailerMock(),
 			}
 
 			setUser := func(next http.HandlerFunc) http.HandlerFunc {
@@ -416,14 +439,14 @@ func TestCreateInstrumentHandler(t *testing.T) {
 func TestUpdateInstrumentHandler(t *testing.T) {
 
 	type inputInstrument struct {
-		Name            string   `json:"name"`
-		Manufacturer    string   `json:"manufacturer"`
-		ManufactureYear int32    `json:"manufacture_year"`
-		Type            string   `json:"type"`
-		EstimatedValue  int64    `json:"estimated_value"`
-		Condition       string   `json:"condition"`
-		Description     string   `json:"description"`
-		FamousOwners    []string `json:"famous_owners"`
+		Name            string   `json:"name,omitempty"`
+		Manufacturer    string   `json:"manufacturer,omitempty"`
+		ManufactureYear int32    `json:"manufacture_year,omitempty"`
+		Type            string   `json:"type,omitempty"`
+		EstimatedValue  int64    `json:"estimated_value,omitempty"`
+		Condition       string   `json:"condition,omitempty"`
+		Description     string   `json:"description,omitempty"`
+		FamousOwners    []string `json:"famous_owners,omitempty"`
 	}
 
 	type testCase struct {
@@ -536,7 +559,12 @@ func TestUpdateInstrumentHandler(t *testing.T) {
 
 			app := &application{
 				logger: slog.New(slog.NewTextHandler(io.Discard, nil)),
-				models: data.Models{Instruments: mocks.NewNonEmptyInstrumentModelMock(testData)},
+				models: data.Models{
+					Instruments:   mocks.NewNonEmptyInstrumentModelMock(testData),
+					SavedSearches: mocks.NewSavedSearchModelMock(nil),
+					Categories:    newTestCategoryModelMock(),
+				},
+				mailer: mocks.NewMailerMock(),
 			}
 
 			setUser := func(next http.HandlerFunc) http.HandlerFunc {
@@ -630,6 +658,373 @@ func TestUpdateInstrumentHandler(t *testing.T) {
 	}
 }
 
+// TestUpdateInstrumentHandlerPatchTypes implements unit tests for the merge patch and JSON patch bodies of updateInstrumentHandler.
+func TestUpdateInstrumentHandlerPatchTypes(t *testing.T) {
+
+	type testCase struct {
+		name                 string
+		contentType          string
+		body                 string
+		expectedStatusCode   int
+		expectedName         string
+		expectedDescription  string
+		expectedFamousOwners []string
+	}
+
+	testCases := []testCase{
+		{
+			name:                 "merge patch clears fields",
+			contentType:          "application/merge-patch+json",
+			body:                 `{"description": null, "famous_owners": null}`,
+			expectedStatusCode:   http.StatusOK,
+			expectedName:         "M1",
+			expectedDescription:  "",
+			expectedFamousOwners: []string{},
+		},
+		{
+			name:                 "merge patch sets empty values",
+			contentType:          "application/merge-patch+json",
+			body:                 `{"description": "", "famous_owners": []}`,
+			expectedStatusCode:   http.StatusOK,
+			expectedName:         "M1",
+			expectedDescription:  "",
+			expectedFamousOwners: []string{},
+		},
+		{
+			name:                 "plain json is a merge patch",
+			contentType:          "application/json",
+			body:                 `{"name": "M1R", "description": null}`,
+			expectedStatusCode:   http.StatusOK,
+			expectedName:         "M1R",
+			expectedDescription:  "",
+			expectedFamousOwners: []string{"The Orb"},
+		},
+		{
+			name:                 "merge patch clears required field",
+			contentType:          "application/merge-patch+json",
+			body:                 `{"name": null}`,
+			expectedStatusCode:   http.StatusUnprocessableEntity,
+			expectedName:         "M1",
+			expectedDescription:  "A music workstation manufactured by Korg.",
+			expectedFamousOwners: []string{"The Orb"},
+		},
+		{
+			name:        "json patch",
+			contentType: "application/json-patch+json",
+			body: `[
+				{"op": "test", "path": "/name", "value": "M1"},
+				{"op": "replace", "path": "/name", "value": "M1R"},
+				{"op": "remove", "path": "/description"},
+				{"op": "add", "path": "/famous_owners/-", "value": "Depeche Mode"}
+			]`,
+			expectedStatusCode:   http.StatusOK,
+			expectedName:         "M1R",
+			expectedDescription:  "",
+			expectedFamousOwners: []string{"The Orb", "Depeche Mode"},
+		},
+		{
+			name:                 "json patch with failed test",
+			contentType:          "application/json-patch+json",
+			body:                 `[{"op": "test", "path": "/name", "value": "TB303"}, {"op": "replace", "path": "/name", "value": "M1R"}]`,
+			expectedStatusCode:   http.StatusConflict,
+			expectedName:         "M1",
+			expectedDescription:  "A music workstation manufactured by Korg.",
+			expectedFamousOwners: []string{"The Orb"},
+		},
+		{
+			name:                 "json patch with unknown member",
+			contentType:          "application/json-patch+json",
+			body:                 `[{"op": "add", "path": "/owner_user_id", "value": 2}]`,
+			expectedStatusCode:   http.StatusBadRequest,
+			expectedName:         "M1",
+			expectedDescription:  "A music workstation manufactured by Korg.",
+			expectedFamousOwners: []string{"The Orb"},
+		},
+		{
+			name:                 "malformed json patch",
+			contentType:          "application/json-patch+json",
+			body:                 `{"name": "M1R"}`,
+			expectedStatusCode:   http.StatusBadRequest,
+			expectedName:         "M1",
+			expectedDescription:  "A music workstation manufactured by Korg.",
+			expectedFamousOwners: []string{"The Orb"},
+		},
+		{
+			name:                 "unsupported media type",
+			contentType:          "text/plain",
+			body:                 `name=M1R`,
+			expectedStatusCode:   http.StatusUnsupportedMediaType,
+			expectedName:         "M1",
+			expectedDescription:  "A music workstation manufactured by Korg.",
+			expectedFamousOwners: []string{"The Orb"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			app := &application{
+				logger: slog.New(slog.NewTextHandler(io.Discard, nil)),
+				models: data.Models{
+					Instruments: mocks.NewNonEmptyInstrumentModelMock([]*data.Instrument{{
+						ID:              1,
+						Name:            "M1",
+						Manufacturer:    "Korg",
+						ManufactureYear: 1990,
+						Type:            "synthesizer",
+						EstimatedValue:  100000,
+						Condition:       "used",
+						Description:     "A music workstation manufactured by Korg.",
+						FamousOwners:    []string{"The Orb"},
+						OwnerUserID:     1,
+						Version:         1,
+					}}),
+					SavedSearches: mocks.NewSavedSearchModelMock(nil),
+					Categories:    newTestCategoryModelMock(),
+				},
+				mailer: mocks.NewMailerMock(),
+			}
+
+			setUser := func(next http.HandlerFunc) http.HandlerFunc {
+				return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+					r = app.contextSetUser(r, &data.User{ID: 1})
+
+					next.ServeHTTP(w, r)
+				})
+			}
+
+			mux := http.NewServeMux()
+			mux.HandleFunc("PATCH /{id}", setUser(app.updateInstrumentHandler))
+
+			ts := httptest.NewServer(mux)
+			defer ts.Close()
+
+			request, err := http.NewRequest(http.MethodPatch, ts.URL+"/1", strings.NewReader(tc.body))
+			if err != nil {
+				t.Fatal(err)
+			}
+			request.Header.Set("Content-Type", tc.contentType)
+
+			res, err := ts.Client().Do(request)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer func() {
+				err := res.Body.Close()
+				if err != nil {
+					t.Fatal(err)
+				}
+			}()
+
+			if tc.expectedStatusCode != res.StatusCode {
+				t.Errorf(`expected status code %d, got %d`, tc.expectedStatusCode, res.StatusCode)
+			}
+
+			if res.StatusCode == http.StatusUnsupportedMediaType && res.Header.Get("Accept-Patch") == "" {
+				t.Error(`expected Accept-Patch header`)
+			}
+
+			app.wg.Wait()
+
+			instrument, err := app.models.Instruments.Get(1)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if instrument.Name != tc.expectedName {
+				t.Errorf(`expected name %q, got %q`, tc.expectedName, instrument.Name)
+			}
+			if instrument.Description != tc.expectedDescription {
+				t.Errorf(`expected description %q, got %q`, tc.expectedDescription, instrument.Description)
+			}
+			if !reflect.DeepEqual(instrument.FamousOwners, tc.expectedFamousOwners) {
+				t.Errorf(`expected famous owners %v, got %v`, tc.expectedFamousOwners, instrument.FamousOwners)
+			}
+		})
+	}
+}
+
+// TestInstrumentConditionalRequests implements unit tests for the ETag based conditional requests of the instrument handlers.
+func TestInstrumentConditionalRequests(t *testing.T) {
+
+	type testCase struct {
+		name               string
+		method             string
+		body               string
+		headers            map[string]string
+		requireIfMatch     bool
+		expectedStatusCode int
+		expectedETag       string
+		expectedName       string
+		expectedDeleted    bool
+	}
+
+	testCases := []testCase{
+		{
+			name:               "get with etag",
+			method:             http.MethodGet,
+			expectedStatusCode: http.StatusOK,
+			expectedETag:       `"3"`,
+			expectedName:       "M1",
+		},
+		{
+			name:               "get with matching if-none-match",
+			method:             http.MethodGet,
+			headers:            map[string]string{"If-None-Match": `"3"`},
+			expectedStatusCode: http.StatusNotModified,
+			expectedETag:       `"3"`,
+			expectedName:       "M1",
+		},
+		{
+			name:               "get with stale if-none-match",
+			method:             http.MethodGet,
+			headers:            map[string]string{"If-None-Match": `"2"`},
+			expectedStatusCode: http.StatusOK,
+			expectedETag:       `"3"`,
+			expectedName:       "M1",
+		},
+		{
+			name:               "patch with matching if-match",
+			method:             http.MethodPatch,
+			body:               `{"name": "M1R"}`,
+			headers:            map[string]string{"If-Match": `"3"`},
+			expectedStatusCode: http.StatusOK,
+			expectedETag:       `"3"`,
+			expectedName:       "M1R",
+		},
+		{
+			name:               "patch with stale if-match",
+			method:             http.MethodPatch,
+			body:               `{"name": "M1R"}`,
+			headers:            map[string]string{"If-Match": `"2"`},
+			expectedStatusCode: http.StatusPreconditionFailed,
+			expectedName:       "M1",
+		},
+		{
+			name:               "patch without required if-match",
+			method:             http.MethodPatch,
+			body:               `{"name": "M1R"}`,
+			requireIfMatch:     true,
+			expectedStatusCode: http.StatusPreconditionRequired,
+			expectedName:       "M1",
+		},
+		{
+			name:               "patch without optional if-match",
+			method:             http.MethodPatch,
+			body:               `{"name": "M1R"}`,
+			expectedStatusCode: http.StatusOK,
+			expectedETag:       `"3"`,
+			expectedName:       "M1R",
+		},
+		{
+			name:               "delete with stale if-match",
+			method:             http.MethodDelete,
+			headers:            map[string]string{"If-Match": `"2"`},
+			expectedStatusCode: http.StatusPreconditionFailed,
+			expectedName:       "M1",
+		},
+		{
+			name:               "delete with matching if-match",
+			method:             http.MethodDelete,
+			headers:            map[string]string{"If-Match": `"3"`},
+			expectedStatusCode: http.StatusOK,
+			expectedDeleted:    true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			app := &application{
+				logger: slog.New(slog.NewTextHandler(io.Discard, nil)),
+				models: data.Models{
+					Instruments: mocks.NewNonEmptyInstrumentModelMock([]*data.Instrument{{
+						ID:              1,
+						Name:            "M1",
+						Manufacturer:    "Korg",
+						ManufactureYear: 1990,
+						Type:            "synthesizer",
+						EstimatedValue:  100000,
+						Condition:       "used",
+						OwnerUserID:     1,
+						Version:         3,
+					}}),
+					InstrumentPhotos: mocks.NewInstrumentPhotoModelMock(nil),
+					SavedSearches:    mocks.NewSavedSearchModelMock(nil),
+					Categories:       newTestCategoryModelMock(),
+				},
+				mailer:  mocks.NewMailerMock(),
+				storage: mocks.NewStorageMock(),
+			}
+			app.config.preconditions.requireIfMatch = tc.requireIfMatch
+
+			setUser := func(next http.HandlerFunc) http.HandlerFunc {
+				return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+					r = app.contextSetUser(r, &data.User{ID: 1})
+
+					next.ServeHTTP(w, r)
+				})
+			}
+
+			mux := http.NewServeMux()
+			mux.HandleFunc("GET /{id}", setUser(app.showInstrumentHandler))
+			mux.HandleFunc("PATCH /{id}", setUser(app.updateInstrumentHandler))
+			mux.HandleFunc("DELETE /{id}", setUser(app.deleteInstrumentHandler))
+
+			ts := httptest.NewServer(mux)
+			defer ts.Close()
+
+			request, err := http.NewRequest(tc.method, ts.URL+"/1", strings.NewReader(tc.body))
+			if err != nil {
+				t.Fatal(err)
+			}
+			for key, value := range tc.headers {
+				request.Header.Set(key, value)
+			}
+
+			res, err := ts.Client().Do(request)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer func() {
+				err := res.Body.Close()
+				if err != nil {
+					t.Fatal(err)
+				}
+			}()
+
+			if tc.expectedStatusCode != res.StatusCode {
+				t.Errorf(`expected status code %d, got %d`, tc.expectedStatusCode, res.StatusCode)
+			}
+
+			if etag := res.Header.Get("ETag"); etag != tc.expectedETag {
+				t.Errorf(`expected ETag %q, got %q`, tc.expectedETag, etag)
+			}
+
+			app.wg.Wait()
+
+			instrument, err := app.models.Instruments.Get(1)
+			if tc.expectedDeleted {
+				if !errors.Is(err, data.ErrRecordNotFound) {
+					t.Errorf(`expected deleted instrument, got error %v`, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if instrument.Name != tc.expectedName {
+				t.Errorf(`expected name %q, got %q`, tc.expectedName, instrument.Name)
+			}
+		})
+	}
+}
+
 func TestDeleteInstrumentHandler(t *testing.T) {
 
 	type testCase struct {
@@ -733,7 +1128,11 @@ func TestDeleteInstrumentHandler(t *testing.T) {
 
 			app := &application{
 				logger: slog.New(slog.NewTextHandler(io.Discard, nil)),
-				models: data.Models{Instruments: mocks.NewNonEmptyInstrumentModelMock(tc.dabatase)},
+				models: data.Models{
+					Instruments:      mocks.NewNonEmptyInstrumentModelMock(tc.dabatase),
+					InstrumentPhotos: mocks.NewInstrumentPhotoModelMock(nil),
+				},
+				storage: mocks.NewStorageMock(),
 			}
 
 			setUser := func(next http.HandlerFunc) http.HandlerFunc {
@@ -793,3 +1192,153 @@ func TestDeleteInstrumentHandler(t *testing.T) {
 	}
 
 }
+
+func TestListInstrumentMatchesHandler(t *testing.T) {
+
+	testInstruments := []*data.Instrument{
+		{ID: 1, Type: "Synthesizer", EstimatedValue: 1000, OwnerUserID: 10},
+		{ID: 2, Type: "guitar", EstimatedValue: 1000, OwnerUserID: 20},
+		{ID: 3, Type: "synthesizer", EstimatedValue: 900, OwnerUserID: 20},
+	}
+
+	testMatches := []*data.InstrumentMatch{
+		{Instrument: testInstruments[2], Score: 0.87},
+		{Instrument: testInstruments[1], Score: 0.62},
+	}
+
+	type testCase struct {
+		name                  string
+		pathParam             string
+		query                 string
+		reqUser               data.User
+		expectedStatusCode    int
+		expectedTypes         []string
+		expectedInstrumentIDs []int64
+	}
+
+	testCases := []testCase{
+		{
+			name:                  "happy path",
+			pathParam:             "1",
+			reqUser:               data.User{ID: 10},
+			expectedStatusCode:    http.StatusOK,
+			expectedTypes:         []string{"synthesizer"},
+			expectedInstrumentIDs: []int64{3, 2},
+		},
+		{
+			name:                  "preferred types",
+			pathParam:             "1",
+			query:                 "?types=Guitar",
+			reqUser:               data.User{ID: 10},
+			expectedStatusCode:    http.StatusOK,
+			expectedTypes:         []string{"electric-guitar", "guitar"},
+			expectedInstrumentIDs: []int64{3, 2},
+		},
+		{
+			name:                  "preferred parent category",
+			pathParam:             "1",
+			query:                 "?types=keyboard",
+			reqUser:               data.User{ID: 10},
+			expectedStatusCode:    http.StatusOK,
+			expectedTypes:         []string{"keyboard", "synthesizer"},
+			expectedInstrumentIDs: []int64{3, 2},
+		},
+		{
+			name:               "non valid type",
+			pathParam:          "1",
+			query:              "?types=guitar,drum",
+			reqUser:            data.User{ID: 10},
+			expectedStatusCode: http.StatusUnprocessableEntity,
+		},
+		{
+			name:               "non valid page size",
+			pathParam:          "1",
+			query:              "?page_size=0",
+			reqUser:            data.User{ID: 10},
+			expectedStatusCode: http.StatusUnprocessableEntity,
+		},
+		{
+			name:               "not the owner of the instrument",
+			pathParam:          "1",
+			reqUser:            data.User{ID: 20},
+			expectedStatusCode: http.StatusForbidden,
+		},
+		{
+			name:               "non existent instrument",
+			pathParam:          "99",
+			reqUser:            data.User{ID: 10},
+			expectedStatusCode: http.StatusNotFound,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+
+			instruments := mocks.NewNonEmptyInstrumentModelMock(testInstruments).SetMatches(testMatches...)
+
+			app := &application{
+				logger: slog.New(slog.NewTextHandler(io.Discard, nil)),
+				models: data.Models{
+					Instruments: instruments,
+					Categories:  newTestCategoryModelMock(),
+				},
+			}
+
+			setUser := func(next http.HandlerFunc) http.HandlerFunc {
+				return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+					r = app.contextSetUser(r, &tc.reqUser)
+
+					next.ServeHTTP(w, r)
+				})
+			}
+
+			mux := http.NewServeMux()
+			mux.HandleFunc("GET /{id}", setUser(app.listInstrumentMatchesHandler))
+
+			ts := httptest.NewServer(mux)
+			defer ts.Close()
+
+			resp, err := http.Get(fmt.Sprintf("%s/%s%s", ts.URL, tc.pathParam, tc.query))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer func() {
+				err := resp.Body.Close()
+				if err != nil {
+					t.Fatal(err)
+				}
+			}()
+
+			if tc.expectedStatusCode != resp.StatusCode {
+				t.Errorf(`expected status code %d, got %d`, tc.expectedStatusCode, resp.StatusCode)
+			}
+
+			if tc.expectedStatusCode != http.StatusOK {
+				return
+			}
+
+			var respBody struct {
+				Matches []*data.InstrumentMatch `json:"matches"`
+			}
+
+			err = json.NewDecoder(resp.Body).Decode(&respBody)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			instrumentIDs := []int64{}
+			for _, match := range respBody.Matches {
+				instrumentIDs = append(instrumentIDs, match.Instrument.ID)
+			}
+
+			if !reflect.DeepEqual(tc.expectedInstrumentIDs, instrumentIDs) {
+				t.Errorf(`expected instruments %v, got %v`, tc.expectedInstrumentIDs, instrumentIDs)
+			}
+
+			if !reflect.DeepEqual(tc.expectedTypes, instruments.PreferredTypes()) {
+				t.Errorf(`expected preferred types %v, got %v`, tc.expectedTypes, instruments.PreferredTypes())
+			}
+		})
+	}
+}
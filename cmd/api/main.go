@@ -10,12 +10,15 @@ import (
 	"net/http"
 	"os"
 	"os/signal"
+	"sync"
 	"syscall"
 	"time"
 
 	_ "github.com/lib/pq"
 	"github.com/ttarnok/instrument-swap-api/internal/auth"
 	"github.com/ttarnok/instrument-swap-api/internal/data"
+	"github.com/ttarnok/instrument-swap-api/internal/mailer"
+	"github.com/ttarnok/instrument-swap-api/internal/storage"
 	"github.com/ttarnok/instrument-swap-api/internal/vcs"
 )
 
@@ -45,13 +48,46 @@ type config struct {
 	jwt struct {
 		secret string
 	}
+	smtp struct {
+		host     string
+		port     int
+		username string
+		password string
+		sender   string
+	}
+	swap struct {
+		pendingTTL     time.Duration
+		cycleCooldown  time.Duration
+		topUpTolerance float64
+		currency       string
+	}
+	storage struct {
+		backend  string
+		localDir string
+		s3       struct {
+			endpoint  string
+			bucket    string
+			region    string
+			accessKey string
+			secretKey string
+		}
+	}
+	photos struct {
+		maxSize int64
+	}
+	preconditions struct {
+		requireIfMatch bool
+	}
 }
 
 type application struct {
-	config config
-	logger *slog.Logger
-	models data.Models
-	auth   *auth.Auth
+	config  config
+	logger  *slog.Logger
+	models  data.Models
+	auth    *auth.Auth
+	mailer  mailer.Mailer
+	storage storage.Storage
+	wg      sync.WaitGroup
 }
 
 func main() {
@@ -80,6 +116,29 @@ func main() {
 	flag.StringVar(&cfg.redis.password, "redis-password", "", "Redis password")
 	flag.IntVar(&cfg.redis.db, "redis-db", 0, "Redis database")
 
+	flag.StringVar(&cfg.smtp.host, "smtp-host", "localhost", "SMTP host")
+	flag.IntVar(&cfg.smtp.port, "smtp-port", 25, "SMTP port")
+	flag.StringVar(&cfg.smtp.username, "smtp-username", "", "SMTP username")
+	flag.StringVar(&cfg.smtp.password, "smtp-password", "", "SMTP password")
+	flag.StringVar(&cfg.smtp.sender, "smtp-sender", "Instrument Swap <no-reply@instrument-swap.example.example>", "SMTP sender")
+
+	flag.DurationVar(&cfg.swap.pendingTTL, "swap-pending-ttl", 14*24*time.Hour, "Pending swaps and proposed swap cycles expire after this duration, 0 disables the expiry")
+	flag.DurationVar(&cfg.swap.cycleCooldown, "swap-cycle-cooldown", 7*24*time.Hour, "A rejected or expired swap cycle is not proposed again to the same participants within this duration")
+	flag.Float64Var(&cfg.swap.topUpTolerance, "swap-top-up-tolerance", 0.1, "Permitted imbalance of swaps with a top-up, as a fraction of the more valuable side")
+	flag.StringVar(&cfg.swap.currency, "swap-currency", "EUR", "Currency of the estimated values of the instruments and of the swap top-ups (3 letter ISO 4217 code)")
+
+	flag.StringVar(&cfg.storage.backend, "storage-backend", "local", "Blob storage of the uploaded photos (local|s3)")
+	flag.StringVar(&cfg.storage.localDir, "storage-local-dir", "./uploads", "Directory of the local blob storage")
+	flag.StringVar(&cfg.storage.s3.endpoint, "storage-s3-endpoint", "", "Endpoint URL of the S3 compatible blob storage")
+	flag.StringVar(&cfg.storage.s3.bucket, "storage-s3-bucket", "", "Bucket of the S3 compatible blob storage")
+	flag.StringVar(&cfg.storage.s3.region, "storage-s3-region", "us-east-1", "Region of the S3 compatible blob storage")
+	flag.StringVar(&cfg.storage.s3.accessKey, "storage-s3-access-key", "", "Access key of the S3 compatible blob storage")
+	flag.StringVar(&cfg.storage.s3.secretKey, "storage-s3-secret-key", "", "Secret key of the S3 compatible blob storage")
+
+	flag.Int64Var(&cfg.photos.maxSize, "photos-max-size", 10*1024*1024, "Maximum size of the uploaded photos in bytes")
+
+	flag.BoolVar(&cfg.preconditions.requireIfMatch, "require-if-match", false, "Require the If-Match header on the updates and deletions of versioned resources")
+
 	displayVersion := flag.Bool("version", false, "Display version and exit")
 
 	flag.Parse()
@@ -94,6 +153,11 @@ func main() {
 
 	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{}))
 
+	if !data.CurrencyRX.MatchString(cfg.swap.currency) {
+		logger.Error("swap-currency must be a 3 letter ISO 4217 currency code", "currency", cfg.swap.currency)
+		os.Exit(1)
+	}
+
 	// ----------------------------–----------------------------------------------
 	// Init database
 
@@ -123,14 +187,27 @@ func main() {
 
 	logger.Info("Redis connection established")
 
+	// ----------------------------–----------------------------------------------
+	// Init blob storage
+
+	blobStorage, err := openStorage(cfg)
+	if err != nil {
+		logger.Error(err.Error())
+		os.Exit(1)
+	}
+
+	logger.Info("blob storage initialized", "backend", cfg.storage.backend)
+
 	// ----------------------------–----------------------------------------------
 	// Init and Stratup Server
 
-	app := application{
-		config: cfg,
-		logger: logger,
-		models: data.NewModel(db),
-		auth:   auth.NewAuth(cfg.jwt.secret, auth.NewBlacklistService(redisClient)),
+	app := &application{
+		config:  cfg,
+		logger:  logger,
+		models:  data.NewModel(db),
+		auth:    auth.NewAuth(cfg.jwt.secret, auth.NewBlacklistService(redisClient)),
+		mailer:  mailer.NewSMTPMailer(cfg.smtp.host, cfg.smtp.port, cfg.smtp.username, cfg.smtp.password, cfg.smtp.sender),
+		storage: blobStorage,
 	}
 
 	err = app.serve()
@@ -152,6 +229,15 @@ func (app *application) serve() error {
 
 	shutdownError := make(chan error)
 
+	workersCtx, stopWorkers := context.WithCancel(context.Background())
+	defer stopWorkers()
+
+	if app.config.swap.pendingTTL > 0 {
+		app.startSwapExpiryWorker(workersCtx, swapExpiryInterval)
+	}
+
+	app.startSwapOverdueWorker(workersCtx, swapOverdueInterval)
+
 	go func() {
 		quit := make(chan os.Signal, 1)
 
@@ -164,7 +250,17 @@ func (app *application) serve() error {
 		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 		defer cancel()
 
-		shutdownError <- srv.Shutdown(ctx)
+		err := srv.Shutdown(ctx)
+		if err != nil {
+			shutdownError <- err
+			return
+		}
+
+		app.logger.Info("completing background tasks", "addr", srv.Addr)
+
+		stopWorkers()
+		app.wg.Wait()
+		shutdownError <- nil
 
 	}()
 
@@ -217,3 +313,15 @@ func openDB(cfg config) (*sql.DB, error) {
 
 	return db, nil
 }
+
+func openStorage(cfg config) (storage.Storage, error) {
+	switch cfg.storage.backend {
+	case "local":
+		return storage.NewLocalStorage(cfg.storage.localDir)
+	case "s3":
+		s3 := cfg.storage.s3
+		return storage.NewS3Storage(s3.endpoint, s3.bucket, s3.region, s3.accessKey, s3.secretKey)
+	default:
+		return nil, fmt.Errorf("unknown storage backend %q", cfg.storage.backend)
+	}
+}
@@ -16,9 +16,11 @@ import (
 
 	"github.com/go-redis/redis"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"github.com/stretchr/testify/suite"
 	"github.com/ttarnok/instrument-swap-api/internal/auth"
 	"github.com/ttarnok/instrument-swap-api/internal/data"
+	"github.com/ttarnok/instrument-swap-api/internal/data/mocks"
 	"github.com/ttarnok/instrument-swap-api/internal/testhelpers"
 )
 
@@ -29,6 +31,7 @@ type MainTestSuite struct {
 	redisContainer *testhelpers.RedisContainer
 	ts             *httptest.Server
 	app            *application
+	mailer         *mocks.MailerMock
 	ctx            context.Context
 }
 
@@ -78,11 +81,14 @@ func (suite *MainTestSuite) SetupSuite() {
 		log.Fatal(err)
 	}
 
+	suite.mailer = mocks.NewMailerMock()
+
 	suite.app = &application{
 		config: cfg,
 		logger: slog.New(slog.NewTextHandler(io.Discard, nil)),
 		models: data.NewModel(db),
 		auth:   auth.NewAuth(cfg.jwt.secret, auth.NewBlacklistService(redisClient)),
+		mailer: suite.mailer,
 	}
 
 	suite.ts = httptest.NewServer(suite.app.routes())
@@ -136,10 +142,13 @@ func CreateRequestBody[T any](t *testing.T, input T) io.Reader {
 // This test does the following test calls in the following order.
 // 1. User registration, on happy path.
 // 2. User login, on happy path.
-// 3. Create an instrument on happy path.
-// 4. Create another instrument, on happy path.
-// 5. Get the first instrument for the user, on the happy path.
-// 6. Get all instruments for the user, on the happy path.
+// 3. Create an instrument before activation, should be forbidden.
+// 4. User activation with the emailed activation token, on happy path.
+// 5. Create an instrument on happy path.
+// 6. Create another instrument, on happy path.
+// 7. Get the first instrument for the user, on the happy path.
+// 8. Get all instruments for the user, on the happy path.
+// 9. List all users without admin permission, should be forbidden.
 func (suite *MainTestSuite) TestBasicUserStory() {
 	t := suite.T()
 
@@ -187,7 +196,35 @@ func (suite *MainTestSuite) TestBasicUserStory() {
 	assert.NotEmpty(t, refreshToken, "refresh token should not be empty")
 
 	// ***************************************************************************
-	// 3. Create an instrument on happy path.
+	// 3. Create an instrument before activation, should be forbidden.
+	expectedStatusCode = http.StatusForbidden
+	path = fmt.Sprintf("%s/v1/instruments", suite.ts.URL)
+	headers := make(map[string]string)
+	headers["Authorization"] = strings.Join([]string{"Bearer", accessToken}, " ")
+	resp = testhelpers.DoTestAPICall(t, "POST", path, CreateRequestBody(t, struct{}{}), headers)
+	assert.Equal(t, expectedStatusCode, resp.StatusCode, "status code mismatch")
+
+	// ***************************************************************************
+	// 4. User activation with the emailed activation token, on happy path.
+	expectedStatusCode = http.StatusOK
+	suite.app.wg.Wait()
+	sentMails := suite.mailer.Sent()
+	require.Len(t, sentMails, 1, "one welcome email should be sent")
+	mailData, ok := sentMails[0].Data.(map[string]any)
+	require.True(t, ok, "welcome email data should be a map")
+	inputActivate := struct {
+		Token string `json:"token"`
+	}{
+		Token: fmt.Sprint(mailData["activationToken"]),
+	}
+	path = fmt.Sprintf("%s/v1/users/activated", suite.ts.URL)
+	resp = testhelpers.DoTestAPICall(t, "PUT", path, CreateRequestBody(t, inputActivate), nil)
+	assert.Equal(t, expectedStatusCode, resp.StatusCode, "status code mismatch")
+	respActivateBody := testhelpers.GetResponseBody[map[string]*data.User](t, resp)
+	assert.True(t, respActivateBody["user"].Activated, "user should be activated")
+
+	// ***************************************************************************
+	// 5. Create an instrument on happy path.
 	expectedStatusCode = http.StatusCreated
 	inputInstrument1 := struct {
 		Name            string   `json:"name"`
@@ -209,7 +246,7 @@ func (suite *MainTestSuite) TestBasicUserStory() {
 		FamousOwners:    []string{"The Orb", "Orbital"},
 	}
 	path = fmt.Sprintf("%s/v1/instruments", suite.ts.URL)
-	headers := make(map[string]string)
+	headers = make(map[string]string)
 	headers["Authorization"] = strings.Join([]string{"Bearer", accessToken}, " ")
 	resp = testhelpers.DoTestAPICall(t, "POST", path, CreateRequestBody(t, inputInstrument1), headers)
 	assert.Equal(t, expectedStatusCode, resp.StatusCode, "status code mismatch")
@@ -231,7 +268,7 @@ func (suite *MainTestSuite) TestBasicUserStory() {
 	assert.NotEmpty(t, createdInstrument1.Version, "version should not be empty")
 
 	// ***************************************************************************
-	// 4. Create another instrument, on happy path.
+	// 6. Create another instrument, on happy path.
 	expectedStatusCode = http.StatusCreated
 	inputInstrument2 := struct {
 		Name            string   `json:"name"`
@@ -275,7 +312,7 @@ func (suite *MainTestSuite) TestBasicUserStory() {
 	assert.NotEmpty(t, createdInstrument2.Version, "version should not be empty")
 
 	// ***************************************************************************
-	// 5. Get the first instrument for the user, on the happy path.
+	// 7. Get the first instrument for the user, on the happy path.
 	expectedStatusCode = http.StatusOK
 	path = fmt.Sprintf("%s/v1/instruments/%d", suite.ts.URL, createdInstrument1.ID)
 	headers = make(map[string]string)
@@ -288,7 +325,7 @@ func (suite *MainTestSuite) TestBasicUserStory() {
 	assert.Equal(t, retrievedInstrument, createdInstrument1, "instrument mismatch")
 
 	// ***************************************************************************
-	// 6. Get all instruments for the user, on the happy path.
+	// 8. Get all instruments for the user, on the happy path.
 	type ResponseType struct {
 		Instruments []*data.Instrument `json:"instruments"`
 		Metadata    data.MetaData      `json:"metadata"`
@@ -313,6 +350,15 @@ func (suite *MainTestSuite) TestBasicUserStory() {
 	assert.Equal(t, []*data.Instrument{createdInstrument1, createdInstrument2}, respInstrumentsBody.Instruments, "instruments mismatch")
 	assert.Equal(t, expectedMetadata, respInstrumentsBody.Metadata)
 
+	// ***************************************************************************
+	// 9. List all users without admin permission, should be forbidden.
+	expectedStatusCode = http.StatusForbidden
+	path = fmt.Sprintf("%s/v1/users", suite.ts.URL)
+	headers = make(map[string]string)
+	headers["Authorization"] = strings.Join([]string{"Bearer", accessToken}, " ")
+	resp = testhelpers.DoTestAPICall(t, "GET", path, nil, headers)
+	assert.Equal(t, expectedStatusCode, resp.StatusCode, "status code mismatch")
+
 }
 
 // TestMainTestSuite runs the MainTestSuite related tests.
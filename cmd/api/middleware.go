@@ -153,6 +153,12 @@ func (app *application) authenticate(next http.Handler) http.Handler {
 			return
 		}
 
+		user.Permissions, err = app.models.Permissions.GetAllForUser(user.ID)
+		if err != nil {
+			app.serverErrorLogResponse(w, r, err)
+			return
+		}
+
 		r = app.contextSetUser(r, user)
 
 		next.ServeHTTP(w, r)
@@ -190,6 +196,22 @@ func (app *application) requireActivatedUser(next http.HandlerFunc) http.Handler
 	return app.requireAuthenticatedUser(fn)
 }
 
+// requirePermission middleware is responsible requiring requests to come from activated users with the given permission code.
+func (app *application) requirePermission(code string, next http.HandlerFunc) http.HandlerFunc {
+	fn := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user := app.contextGetUser(r)
+
+		if !user.Permissions.Include(code) {
+			app.notPermittedResponse(w, r)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+
+	return app.requireActivatedUser(fn)
+}
+
 // requireMatchingUserIDs middleware checks whether the authenticated user's is matches to the id specified in the url path.
 func (app *application) requireMatchingUserIDs(next http.HandlerFunc) http.HandlerFunc {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -216,11 +238,12 @@ func (app *application) enableCORS(next http.Handler) http.Handler {
 
 		// TODO: Implement an allowed origin list based dinamic response, dont forget the Vary response header.
 		w.Header().Set("Access-Control-Allow-Origin", "*")
+		w.Header().Set("Access-Control-Expose-Headers", "ETag")
 
 		if r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != "" {
 
 			w.Header().Set("Access-Control-Allow-Methods", "OPTIONS, PUT, PATCH, DELETE")
-			w.Header().Set("Access-Control-Allow-Headers", "Authorization, Content-Type")
+			w.Header().Set("Access-Control-Allow-Headers", "Authorization, Content-Type, If-Match, If-None-Match")
 
 			w.WriteHeader(http.StatusOK)
 			return
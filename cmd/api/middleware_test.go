@@ -8,6 +8,7 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"reflect"
+	"slices"
 	"strconv"
 	"testing"
 	"time"
@@ -252,11 +253,13 @@ func TestAuthenticate(t *testing.T) {
 	}
 
 	type testCase struct {
-		name               string
-		token              string
-		expectedStatusCode int
-		expectedUser       *data.User
-		blacklist          []string
+		name                string
+		token               string
+		expectedStatusCode  int
+		expectedUser        *data.User
+		blacklist           []string
+		roles               map[int64][]string
+		expectedPermissions data.Permissions
 	}
 
 	testCases := []testCase{
@@ -267,6 +270,15 @@ func TestAuthenticate(t *testing.T) {
 			expectedUser:       &data.User{ID: 1, Name: "Test User"},
 			blacklist:          nil,
 		},
+		{
+			name:                "valid token of an admin user",
+			token:               "Bearer " + string(validJWTBytes),
+			expectedStatusCode:  http.StatusOK,
+			expectedUser:        &data.User{ID: 1, Name: "Test User"},
+			blacklist:           nil,
+			roles:               map[int64][]string{1: {data.RoleAdmin}},
+			expectedPermissions: data.Permissions{data.PermissionCategoriesWrite, data.PermissionDisputesModerate, data.PermissionUsersModerate, data.PermissionUsersRead},
+		},
 		{
 			name:               "without token",
 			token:              "",
@@ -340,7 +352,8 @@ func TestAuthenticate(t *testing.T) {
 			} else {
 				app = &application{
 					models: data.Models{
-						Users: mocks.NewUserModelMock([]*data.User{tc.expectedUser}),
+						Users:       mocks.NewUserModelMock([]*data.User{tc.expectedUser}),
+						Permissions: mocks.NewPermissionModelMock(tc.roles),
 					},
 					auth:   auth.NewAuth(testSecret, mocks.NewBlacklistServiceMockWithData(tc.blacklist)),
 					logger: slog.New(slog.NewTextHandler(io.Discard, nil)),
@@ -362,6 +375,13 @@ func TestAuthenticate(t *testing.T) {
 					t.Errorf(`expected user %#v, got %#v`, tc.expectedUser, app.contextGetUser(r))
 				}
 
+				if tc.expectedUser != data.AnonymousUser {
+					permissions := app.contextGetUser(r).Permissions
+					if !slices.Equal(tc.expectedPermissions, permissions) {
+						t.Errorf(`expected permissions %v, got %v`, tc.expectedPermissions, permissions)
+					}
+				}
+
 				_, err := w.Write([]byte("OK"))
 				if err != nil {
 					t.Fatal(err)
@@ -498,6 +518,81 @@ func TestRequireActivatedUser(t *testing.T) {
 	}
 }
 
+// TestRequirePermission implements unit tests for requirePermission middleware.
+func TestRequirePermission(t *testing.T) {
+	type testCase struct {
+		name               string
+		inputUser          *data.User
+		permission         string
+		expectedStatusCode int
+	}
+
+	testCases := []testCase{
+		{
+			name:               "happy path",
+			inputUser:          &data.User{ID: 1, Activated: true, Permissions: data.Permissions{data.PermissionUsersRead}},
+			permission:         data.PermissionUsersRead,
+			expectedStatusCode: http.StatusOK,
+		},
+		{
+			name:               "missing permission",
+			inputUser:          &data.User{ID: 1, Activated: true, Permissions: data.Permissions{data.PermissionUsersRead}},
+			permission:         data.PermissionUsersModerate,
+			expectedStatusCode: http.StatusForbidden,
+		},
+		{
+			name:               "no permissions",
+			inputUser:          &data.User{ID: 1, Activated: true},
+			permission:         data.PermissionUsersRead,
+			expectedStatusCode: http.StatusForbidden,
+		},
+		{
+			name:               "not activated user",
+			inputUser:          &data.User{ID: 1, Activated: false, Permissions: data.Permissions{data.PermissionUsersRead}},
+			permission:         data.PermissionUsersRead,
+			expectedStatusCode: http.StatusForbidden,
+		},
+		{
+			name:               "AnonymousUser input",
+			inputUser:          data.AnonymousUser,
+			permission:         data.PermissionUsersRead,
+			expectedStatusCode: http.StatusUnauthorized,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+
+			app := &application{
+				logger: slog.New(slog.NewTextHandler(io.Discard, nil)),
+			}
+
+			req, err := http.NewRequest("GET", "/", nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			req = app.contextSetUser(req, tc.inputUser)
+
+			next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				_, err := w.Write([]byte("OK"))
+				if err != nil {
+					t.Fatal(err)
+				}
+			})
+
+			rr := httptest.NewRecorder()
+
+			app.requirePermission(tc.permission, next).ServeHTTP(rr, req)
+			recRes := rr.Result()
+
+			if tc.expectedStatusCode != recRes.StatusCode {
+				t.Errorf(`Expected status code %d, got %d`, tc.expectedStatusCode, recRes.StatusCode)
+			}
+		})
+	}
+}
+
 // TestRequireMatchingUserIDs unti tests requireMatchingUserIDs middleware.
 func TestRequireMatchingUserIDs(t *testing.T) {
 	type testCase struct {
@@ -656,8 +751,8 @@ func TestEnableCORS(t *testing.T) {
 				if recRes.Header.Get("Access-Control-Allow-Methods") != "OPTIONS, PUT, PATCH, DELETE" {
 					t.Errorf(`response shoud contain "Access-Control-Allow-Methods" header with the value of "OPTIONS, PUT, PATCH, DELETE" got value: "%s"`, recRes.Header.Get("Access-Control-Allow-Methods"))
 				}
-				if recRes.Header.Get("Access-Control-Allow-Headers") != "Authorization, Content-Type" {
-					t.Errorf(`response shoud contain "Access-Control-Allow-Headers" header with the value of "Authorization, Content-Type" got value: "%s"`, recRes.Header.Get("Access-Control-Allow-Headers"))
+				if recRes.Header.Get("Access-Control-Allow-Headers") != "Authorization, Content-Type, If-Match, If-None-Match" {
+					t.Errorf(`response shoud contain "Access-Control-Allow-Headers" header with the value of "Authorization, Content-Type, If-Match, If-None-Match" got value: "%s"`, recRes.Header.Get("Access-Control-Allow-Headers"))
 				}
 			}
 
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/ttarnok/instrument-swap-api/internal/data"
+	"github.com/ttarnok/instrument-swap-api/internal/validator"
+)
+
+// createSwapReviewHandler handles the review of the other party of an ended swap with the given id.
+// Both the requester and the recipient can review the other party exactly once.
+func (app *application) createSwapReviewHandler(w http.ResponseWriter, r *http.Request) {
+	authUser := app.contextGetUser(r)
+
+	swapID, err := app.extractIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	var input struct {
+		Rating  int    `json:"rating"`
+		Comment string `json:"comment"`
+	}
+
+	err = app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	review := &data.Review{
+		SwapID:         swapID,
+		ReviewerUserID: authUser.ID,
+		Rating:         input.Rating,
+		Comment:        input.Comment,
+	}
+
+	v := validator.New()
+
+	if data.ValidateReview(v, review); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	swap, err := app.models.Swaps.Get(swapID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorLogResponse(w, r, err)
+		}
+		return
+	}
+
+	requesterUserID, recipientUserID, err := app.swapOwnerIDs(swap)
+	if err != nil {
+		app.serverErrorLogResponse(w, r, err)
+		return
+	}
+
+	switch authUser.ID {
+	case requesterUserID:
+		review.RevieweeUserID = recipientUserID
+	case recipientUserID:
+		review.RevieweeUserID = requesterUserID
+	default:
+		app.forbiddenResponse(w, r)
+		return
+	}
+
+	if !data.IsSwapReviewable(swap) {
+		app.badRequestResponse(w, r, data.ErrSwapNotReviewable)
+		return
+	}
+
+	err = app.models.Reviews.Insert(review)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrDuplicateReview):
+			app.badRequestResponse(w, r, err)
+		default:
+			app.serverErrorLogResponse(w, r, err)
+		}
+		return
+	}
+
+	headers := make(http.Header)
+	headers.Set("Location", fmt.Sprintf("/v1/users/%d/reviews", review.RevieweeUserID))
+
+	err = app.writeJSON(w, http.StatusCreated, envelope{"review": review}, headers)
+	if err != nil {
+		app.serverErrorLogResponse(w, r, err)
+		return
+	}
+}
+
+// listUserReviewsHandler handles listing the reviews the user with the given id received, together with the reputation of the user.
+func (app *application) listUserReviewsHandler(w http.ResponseWriter, r *http.Request) {
+
+	userID, err := app.extractIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	var input struct {
+		data.Filters
+	}
+
+	v := validator.New()
+
+	qs := r.URL.Query()
+
+	input.Page = app.readQParamInt(qs, "page", 1, v)
+	input.PageSize = app.readQParamInt(qs, "page_size", 20, v)
+
+	input.Sort = app.readQParamString(qs, "sort", "-created_at")
+	input.SortSafeList = []string{"id", "created_at", "rating", "-id", "-created_at", "-rating"}
+
+	data.ValidateFilters(v, input.Filters)
+
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	user, err := app.models.Users.GetByID(userID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorLogResponse(w, r, err)
+		}
+		return
+	}
+
+	reputation, err := app.models.Reviews.GetReputation(user.ID)
+	if err != nil {
+		app.serverErrorLogResponse(w, r, err)
+		return
+	}
+
+	reviews, metadata, err := app.models.Reviews.GetAllForUser(user.ID, input.Filters)
+	if err != nil {
+		app.serverErrorLogResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"reviews": reviews, "reputation": reputation, "metadata": metadata}, nil)
+	if err != nil {
+		app.serverErrorLogResponse(w, r, err)
+		return
+	}
+}
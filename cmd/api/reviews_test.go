@@ -0,0 +1,287 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/ttarnok/instrument-swap-api/internal/data"
+	"github.com/ttarnok/instrument-swap-api/internal/data/mocks"
+)
+
+// TestCreateSwapReviewHandler implements unit tests for createSwapReviewHandler.
+func TestCreateSwapReviewHandler(t *testing.T) {
+
+	type inputBodyType struct {
+		Rating  int    `json:"rating"`
+		Comment string `json:"comment"`
+	}
+
+	type testCase struct {
+		name                   string
+		pathParam              string
+		inputBody              inputBodyType
+		reqUser                data.User
+		reviews                []*data.Review
+		expectedStatusCode     int
+		expectedRevieweeUserID int64
+	}
+
+	testCases := []testCase{
+		{
+			name:                   "happy path - requester",
+			pathParam:              "1",
+			inputBody:              inputBodyType{Rating: 5, Comment: "Great synth, smooth swap."},
+			reqUser:                data.User{ID: 10},
+			expectedStatusCode:     http.StatusCreated,
+			expectedRevieweeUserID: 20,
+		},
+		{
+			name:                   "happy path - recipient",
+			pathParam:              "1",
+			inputBody:              inputBodyType{Rating: 4},
+			reqUser:                data.User{ID: 20},
+			reviews:                []*data.Review{{ID: 1, SwapID: 1, ReviewerUserID: 10, RevieweeUserID: 20, Rating: 5}},
+			expectedStatusCode:     http.StatusCreated,
+			expectedRevieweeUserID: 10,
+		},
+		{
+			name:               "already reviewed",
+			pathParam:          "1",
+			inputBody:          inputBodyType{Rating: 1},
+			reqUser:            data.User{ID: 10},
+			reviews:            []*data.Review{{ID: 1, SwapID: 1, ReviewerUserID: 10, RevieweeUserID: 20, Rating: 5}},
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:               "not ended swap",
+			pathParam:          "2",
+			inputBody:          inputBodyType{Rating: 5},
+			reqUser:            data.User{ID: 10},
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:               "rejected swap",
+			pathParam:          "3",
+			inputBody:          inputBodyType{Rating: 5},
+			reqUser:            data.User{ID: 10},
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:               "not a party of the swap",
+			pathParam:          "1",
+			inputBody:          inputBodyType{Rating: 5},
+			reqUser:            data.User{ID: 30},
+			expectedStatusCode: http.StatusForbidden,
+		},
+		{
+			name:               "invalid rating",
+			pathParam:          "1",
+			inputBody:          inputBodyType{Rating: 6},
+			reqUser:            data.User{ID: 10},
+			expectedStatusCode: http.StatusUnprocessableEntity,
+		},
+		{
+			name:               "missing rating",
+			pathParam:          "1",
+			inputBody:          inputBodyType{Comment: "No rating."},
+			reqUser:            data.User{ID: 10},
+			expectedStatusCode: http.StatusUnprocessableEntity,
+		},
+		{
+			name:               "non existent swap",
+			pathParam:          "99",
+			inputBody:          inputBodyType{Rating: 5},
+			reqUser:            data.User{ID: 10},
+			expectedStatusCode: http.StatusNotFound,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			now := time.Now()
+
+			reviews := mocks.NewReviewModelMock(tc.reviews)
+
+			app := &application{
+				logger: slog.New(slog.NewTextHandler(io.Discard, nil)),
+				models: data.Models{
+					Swaps: mocks.NewSwapModelMock([]*data.Swap{
+						{ID: 1, RequesterInstrumentID: 1, RecipientInstrumentID: 2, IsAccepted: true, AcceptedAt: &now, IsEnded: true, EndedAt: &now},
+						{ID: 2, RequesterInstrumentID: 1, RecipientInstrumentID: 2, IsAccepted: true, AcceptedAt: &now},
+						{ID: 3, RequesterInstrumentID: 1, RecipientInstrumentID: 2, IsRejected: true, RejectedAt: &now, IsEnded: true, EndedAt: &now},
+					}),
+					Reviews:     reviews,
+					Instruments: mocks.NewNonEmptyInstrumentModelMock([]*data.Instrument{{ID: 1, OwnerUserID: 10}, {ID: 2, OwnerUserID: 20}}),
+				},
+			}
+
+			setUser := func(next http.HandlerFunc) http.HandlerFunc {
+				return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+					r = app.contextSetUser(r, &tc.reqUser)
+
+					next.ServeHTTP(w, r)
+				})
+			}
+
+			mux := http.NewServeMux()
+			mux.HandleFunc("POST /{id}", setUser(app.createSwapReviewHandler))
+
+			ts := httptest.NewServer(mux)
+			defer ts.Close()
+
+			bs, err := json.Marshal(tc.inputBody)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			resp, err := http.Post(fmt.Sprintf("%s/%s", ts.URL, tc.pathParam), "application/json", bytes.NewBuffer(bs))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer func() {
+				err := resp.Body.Close()
+				if err != nil {
+					t.Fatal(err)
+				}
+			}()
+
+			if tc.expectedStatusCode != resp.StatusCode {
+				t.Errorf(`expected status code %d, got %d`, tc.expectedStatusCode, resp.StatusCode)
+			}
+
+			if tc.expectedStatusCode != http.StatusCreated {
+				return
+			}
+
+			stored, _, err := reviews.GetAllForUser(tc.expectedRevieweeUserID, data.Filters{})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(stored) != 1 {
+				t.Fatalf(`expected 1 stored review, got %d`, len(stored))
+			}
+			if stored[0].ReviewerUserID != tc.reqUser.ID || stored[0].Rating != tc.inputBody.Rating || stored[0].Comment != tc.inputBody.Comment {
+				t.Errorf(`unexpected stored review %#v`, stored[0])
+			}
+		})
+	}
+}
+
+// TestListUserReviewsHandler implements unit tests for listUserReviewsHandler.
+func TestListUserReviewsHandler(t *testing.T) {
+
+	type testCase struct {
+		name               string
+		pathParam          string
+		query              string
+		expectedStatusCode int
+		expectedReviews    int
+		expectedReputation data.Reputation
+	}
+
+	testCases := []testCase{
+		{
+			name:               "happy path",
+			pathParam:          "20",
+			expectedStatusCode: http.StatusOK,
+			expectedReviews:    2,
+			expectedReputation: data.Reputation{Average: 4.5, Count: 2},
+		},
+		{
+			name:               "user without reviews",
+			pathParam:          "10",
+			expectedStatusCode: http.StatusOK,
+			expectedReviews:    0,
+		},
+		{
+			name:               "non existent user",
+			pathParam:          "99",
+			expectedStatusCode: http.StatusNotFound,
+		},
+		{
+			name:               "non valid path param",
+			pathParam:          "nonvalid",
+			expectedStatusCode: http.StatusNotFound,
+		},
+		{
+			name:               "non valid sort",
+			pathParam:          "20",
+			query:              "?sort=comment",
+			expectedStatusCode: http.StatusUnprocessableEntity,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			app := &application{
+				logger: slog.New(slog.NewTextHandler(io.Discard, nil)),
+				models: data.Models{
+					Users: mocks.NewUserModelMock([]*data.User{
+						{ID: 10, Name: "Requester"},
+						{ID: 20, Name: "Recipient", Reputation: data.Reputation{Average: 4.5, Count: 2}},
+					}),
+					Reviews: mocks.NewReviewModelMock([]*data.Review{
+						{ID: 1, SwapID: 1, ReviewerUserID: 10, RevieweeUserID: 20, Rating: 5},
+						{ID: 2, SwapID: 2, ReviewerUserID: 10, RevieweeUserID: 20, Rating: 4},
+					}),
+				},
+			}
+
+			mux := http.NewServeMux()
+			mux.HandleFunc("GET /{id}", app.listUserReviewsHandler)
+
+			ts := httptest.NewServer(mux)
+			defer ts.Close()
+
+			resp, err := http.Get(fmt.Sprintf("%s/%s%s", ts.URL, tc.pathParam, tc.query))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer func() {
+				err := resp.Body.Close()
+				if err != nil {
+					t.Fatal(err)
+				}
+			}()
+
+			if tc.expectedStatusCode != resp.StatusCode {
+				t.Errorf(`expected status code %d, got %d`, tc.expectedStatusCode, resp.StatusCode)
+			}
+
+			if tc.expectedStatusCode != http.StatusOK {
+				return
+			}
+
+			var respBody struct {
+				Reviews    []*data.Review  `json:"reviews"`
+				Reputation data.Reputation `json:"reputation"`
+			}
+
+			err = json.NewDecoder(resp.Body).Decode(&respBody)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(respBody.Reviews) != tc.expectedReviews {
+				t.Errorf(`expected %d reviews, got %d`, tc.expectedReviews, len(respBody.Reviews))
+			}
+
+			if respBody.Reputation != tc.expectedReputation {
+				t.Errorf(`expected reputation %v, got %v`, tc.expectedReputation, respBody.Reputation)
+			}
+		})
+	}
+}
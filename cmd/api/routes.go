@@ -3,6 +3,8 @@ package main
 import (
 	"expvar"
 	"net/http"
+
+	"github.com/ttarnok/instrument-swap-api/internal/data"
 )
 
 func (app *application) routes() http.Handler {
@@ -10,27 +12,79 @@ func (app *application) routes() http.Handler {
 	mux := http.NewServeMux()
 	mux.HandleFunc("GET /v1/liveliness", app.livelinessHandler)
 
-	mux.HandleFunc("GET /v1/users", app.listUsersHandler)
+	mux.HandleFunc("GET /v1/users", app.requirePermission(data.PermissionUsersRead, app.listUsersHandler))
 	mux.HandleFunc("POST /v1/users", app.registerUserHandler)
+	mux.HandleFunc("PUT /v1/users/activated", app.activateUserHandler)
+	mux.HandleFunc("PUT /v1/users/password", app.resetPasswordHandler)
 	mux.HandleFunc("PUT /v1/users/{id}/password", app.requireActivatedUser(app.requireMatchingUserIDs(app.updatePasswordHandler)))
+	mux.HandleFunc("GET /v1/users/{id}", app.requireActivatedUser(app.requireMatchingUserIDs(app.showUserHandler)))
 	mux.HandleFunc("PATCH /v1/users/{id}", app.requireActivatedUser(app.requireMatchingUserIDs(app.updateUserHandler)))
 	mux.HandleFunc("DELETE /v1/users/{id}", app.requireActivatedUser(app.requireMatchingUserIDs(app.deleteUserHandler)))
+	mux.HandleFunc("PUT /v1/users/{id}/activation", app.requirePermission(data.PermissionUsersModerate, app.updateUserActivationHandler))
+	mux.HandleFunc("GET /v1/users/{id}/roles", app.requirePermission(data.PermissionUsersRead, app.listUserRolesHandler))
+	mux.HandleFunc("PUT /v1/users/{id}/roles", app.requirePermission(data.PermissionUsersModerate, app.updateUserRolesHandler))
+	mux.HandleFunc("GET /v1/users/{id}/reviews", app.requireActivatedUser(app.listUserReviewsHandler))
 
 	mux.HandleFunc("POST /v1/token", app.loginHandler)
 	mux.HandleFunc("POST /v1/token/refresh", app.refreshHandler)
 	mux.HandleFunc("POST /v1/token/blacklist", app.blacklistHandler)
 	mux.HandleFunc("POST /v1/token/logout", app.logoutHandler)
 
+	mux.HandleFunc("POST /v1/tokens/password-reset", app.createPasswordResetTokenHandler)
+
 	mux.HandleFunc("GET /v1/instruments", app.requireActivatedUser(app.listInstrumentsHandler))
 	mux.HandleFunc("GET /v1/instruments/{id}", app.requireActivatedUser(app.showInstrumentHandler))
 	mux.HandleFunc("POST /v1/instruments", app.requireActivatedUser(app.createInstrumentHandler))
 	mux.HandleFunc("PATCH /v1/instruments/{id}", app.requireActivatedUser(app.updateInstrumentHandler))
 	mux.HandleFunc("DELETE /v1/instruments/{id}", app.requireActivatedUser(app.deleteInstrumentHandler))
+	mux.HandleFunc("GET /v1/instruments/{id}/swaps", app.requireActivatedUser(app.listInstrumentSwapsHandler))
+	mux.HandleFunc("GET /v1/instruments/{id}/matches", app.requireActivatedUser(app.listInstrumentMatchesHandler))
+	mux.HandleFunc("GET /v1/instruments/{id}/revisions", app.requireActivatedUser(app.listInstrumentRevisionsHandler))
+	mux.HandleFunc("GET /v1/instruments/{id}/revisions/diff", app.requireActivatedUser(app.showInstrumentDiffHandler))
+	mux.HandleFunc("GET /v1/instruments/{id}/photos", app.requireActivatedUser(app.listInstrumentPhotosHandler))
+	mux.HandleFunc("POST /v1/instruments/{id}/photos", app.requireActivatedUser(app.uploadInstrumentPhotoHandler))
+	mux.HandleFunc("PUT /v1/instruments/{id}/photos/order", app.requireActivatedUser(app.reorderInstrumentPhotosHandler))
+	mux.HandleFunc("GET /v1/instruments/{id}/photos/{photo_id}/image", app.requireActivatedUser(app.showInstrumentPhotoHandler))
+	mux.HandleFunc("GET /v1/instruments/{id}/photos/{photo_id}/thumbnail", app.requireActivatedUser(app.showInstrumentPhotoThumbnailHandler))
+	mux.HandleFunc("DELETE /v1/instruments/{id}/photos/{photo_id}", app.requireActivatedUser(app.deleteInstrumentPhotoHandler))
+
+	mux.HandleFunc("GET /v1/categories", app.requireActivatedUser(app.listCategoriesHandler))
+	mux.HandleFunc("GET /v1/categories/{id}", app.requireActivatedUser(app.showCategoryHandler))
+	mux.HandleFunc("POST /v1/categories", app.requirePermission(data.PermissionCategoriesWrite, app.createCategoryHandler))
+	mux.HandleFunc("PATCH /v1/categories/{id}", app.requirePermission(data.PermissionCategoriesWrite, app.updateCategoryHandler))
+	mux.HandleFunc("DELETE /v1/categories/{id}", app.requirePermission(data.PermissionCategoriesWrite, app.deleteCategoryHandler))
 
 	mux.HandleFunc("GET /v1/swaps", app.requireActivatedUser(app.listSwapsHandler))
 	mux.HandleFunc("POST /v1/swaps", app.requireActivatedUser(app.createSwapHandler))
 	mux.HandleFunc("GET /v1/swaps/{id}", app.requireActivatedUser(app.showSwapHandler))
-	mux.HandleFunc("PATCH /v1/swaps/{id}", app.updateSwapStatusHandler)
+	mux.HandleFunc("PATCH /v1/swaps/{id}", app.requireActivatedUser(app.updateSwapStatusHandler))
+	mux.HandleFunc("GET /v1/swaps/{id}/events", app.requireActivatedUser(app.listSwapEventsHandler))
+	mux.HandleFunc("POST /v1/swaps/{id}/counter-offers", app.requireActivatedUser(app.createCounterOfferHandler))
+	mux.HandleFunc("GET /v1/swaps/{id}/messages", app.requireActivatedUser(app.listSwapMessagesHandler))
+	mux.HandleFunc("POST /v1/swaps/{id}/messages", app.requireActivatedUser(app.createSwapMessageHandler))
+	mux.HandleFunc("GET /v1/swaps/{id}/messages/unread", app.requireActivatedUser(app.showSwapMessagesUnreadCountHandler))
+	mux.HandleFunc("POST /v1/swaps/{id}/reviews", app.requireActivatedUser(app.createSwapReviewHandler))
+	mux.HandleFunc("GET /v1/swaps/{id}/condition-reports", app.requireActivatedUser(app.listConditionReportsHandler))
+	mux.HandleFunc("POST /v1/swaps/{id}/condition-reports", app.requireActivatedUser(app.createConditionReportHandler))
+	mux.HandleFunc("POST /v1/swaps/{id}/disputes", app.requireActivatedUser(app.createDisputeHandler))
+
+	mux.HandleFunc("GET /v1/wants", app.requireActivatedUser(app.listWantsHandler))
+	mux.HandleFunc("POST /v1/wants", app.requireActivatedUser(app.createWantHandler))
+	mux.HandleFunc("DELETE /v1/wants/{id}", app.requireActivatedUser(app.deleteWantHandler))
+
+	mux.HandleFunc("GET /v1/saved-searches", app.requireActivatedUser(app.listSavedSearchesHandler))
+	mux.HandleFunc("POST /v1/saved-searches", app.requireActivatedUser(app.createSavedSearchHandler))
+	mux.HandleFunc("DELETE /v1/saved-searches/{id}", app.requireActivatedUser(app.deleteSavedSearchHandler))
+
+	mux.HandleFunc("GET /v1/swap-cycles", app.requireActivatedUser(app.listSwapCyclesHandler))
+	mux.HandleFunc("POST /v1/swap-cycles", app.requireActivatedUser(app.createSwapCycleHandler))
+	mux.HandleFunc("GET /v1/swap-cycles/{id}", app.requireActivatedUser(app.showSwapCycleHandler))
+	mux.HandleFunc("PATCH /v1/swap-cycles/{id}", app.requireActivatedUser(app.updateSwapCycleStatusHandler))
+
+	mux.HandleFunc("GET /v1/disputes", app.requirePermission(data.PermissionDisputesModerate, app.listDisputesHandler))
+	mux.HandleFunc("GET /v1/disputes/{id}", app.requireActivatedUser(app.showDisputeHandler))
+	mux.HandleFunc("PATCH /v1/disputes/{id}", app.requirePermission(data.PermissionDisputesModerate, app.updateDisputeStatusHandler))
+	mux.HandleFunc("POST /v1/disputes/{id}/notes", app.requireActivatedUser(app.createDisputeNoteHandler))
 
 	mux.Handle("GET /debug/vars", expvar.Handler())
 
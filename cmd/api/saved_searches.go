@@ -0,0 +1,190 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"slices"
+
+	"github.com/ttarnok/instrument-swap-api/internal/data"
+	"github.com/ttarnok/instrument-swap-api/internal/validator"
+)
+
+// listSavedSearchesHandler lists the saved searches of the authenticated user.
+func (app *application) listSavedSearchesHandler(w http.ResponseWriter, r *http.Request) {
+	authUser := app.contextGetUser(r)
+
+	searches, err := app.models.SavedSearches.GetAllForUser(authUser.ID)
+	if err != nil {
+		app.serverErrorLogResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"saved_searches": searches}, nil)
+	if err != nil {
+		app.serverErrorLogResponse(w, r, err)
+		return
+	}
+}
+
+// createSavedSearchHandler saves a search of the authenticated user with the filters of the instrument list.
+func (app *application) createSavedSearchHandler(w http.ResponseWriter, r *http.Request) {
+	authUser := app.contextGetUser(r)
+
+	var input struct {
+		Name         string   `json:"name"`
+		Manufacturer string   `json:"manufacturer"`
+		Type         string   `json:"type"`
+		FamousOwners []string `json:"famous_owners"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	search := &data.SavedSearch{
+		UserID:       authUser.ID,
+		Name:         input.Name,
+		Manufacturer: input.Manufacturer,
+		Type:         input.Type,
+		FamousOwners: input.FamousOwners,
+	}
+
+	v := validator.New()
+
+	if data.ValidateSavedSearch(v, search); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	if search.Type != "" {
+		err = app.validateInstrumentType(v, "type", search.Type)
+		if err != nil {
+			app.serverErrorLogResponse(w, r, err)
+			return
+		}
+		if !v.Valid() {
+			app.failedValidationResponse(w, r, v.Errors)
+			return
+		}
+	}
+
+	err = app.models.SavedSearches.Insert(search)
+	if err != nil {
+		app.serverErrorLogResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusCreated, envelope{"saved_search": search}, nil)
+	if err != nil {
+		app.serverErrorLogResponse(w, r, err)
+		return
+	}
+}
+
+// deleteSavedSearchHandler removes a saved search of the authenticated user.
+func (app *application) deleteSavedSearchHandler(w http.ResponseWriter, r *http.Request) {
+	authUser := app.contextGetUser(r)
+
+	id, err := app.extractIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	err = app.models.SavedSearches.Delete(id, authUser.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorLogResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"message": "saved search successfully deleted"}, nil)
+	if err != nil {
+		app.serverErrorLogResponse(w, r, err)
+		return
+	}
+}
+
+// notifySavedSearches notifies the users in the background, whose saved searches match the given instrument.
+// Every user gets a single email, regardless of the number of their matching saved searches.
+// A saved search for a category matches the instruments of its subtypes too.
+// If the previous state of an updated instrument is given, the saved searches already matching it are not notified again.
+func (app *application) notifySavedSearches(instrument *data.Instrument, previous *data.Instrument) {
+	app.background(func() {
+		searches, err := app.matchingSavedSearches(instrument)
+		if err != nil {
+			app.logger.Error(err.Error())
+			return
+		}
+
+		if previous != nil {
+			previousSearches, err := app.matchingSavedSearches(previous)
+			if err != nil {
+				app.logger.Error(err.Error())
+				return
+			}
+
+			searches = slices.DeleteFunc(searches, func(search *data.SavedSearch) bool {
+				return slices.ContainsFunc(previousSearches, func(previousSearch *data.SavedSearch) bool {
+					return previousSearch.ID == search.ID
+				})
+			})
+		}
+
+		searchIDs := make(map[int64][]int64)
+		userIDs := []int64{}
+		for _, search := range searches {
+			if _, ok := searchIDs[search.UserID]; !ok {
+				userIDs = append(userIDs, search.UserID)
+			}
+			searchIDs[search.UserID] = append(searchIDs[search.UserID], search.ID)
+		}
+
+		for _, userID := range userIDs {
+			user, err := app.models.Users.GetByID(userID)
+			if err != nil {
+				app.logger.Error(err.Error())
+				continue
+			}
+
+			mailData := map[string]any{
+				"instrumentID":           instrument.ID,
+				"instrumentName":         instrument.Name,
+				"instrumentManufacturer": instrument.Manufacturer,
+				"instrumentType":         instrument.Type,
+				"savedSearchIDs":         searchIDs[userID],
+			}
+
+			err = app.mailer.Send(user.Email, "saved_search_match.tmpl", mailData)
+			if err != nil {
+				app.logger.Error(err.Error())
+			}
+		}
+	})
+}
+
+// matchingSavedSearches retrieves the saved searches of the other users matching the given instrument,
+// including the saved searches for the ancestor categories of its type.
+func (app *application) matchingSavedSearches(instrument *data.Instrument) ([]*data.SavedSearch, error) {
+	instrumentTypes, err := app.models.Categories.GetLineageNames(instrument.Type)
+	if err != nil {
+		return nil, err
+	}
+
+	return app.models.SavedSearches.GetAllMatching(instrument, instrumentTypes)
+}
+
+// searchableFieldsChanged checks whether any of the instrument fields filtered by the saved searches differs
+// between the given previous and current state of an instrument.
+func searchableFieldsChanged(previous *data.Instrument, current *data.Instrument) bool {
+	return previous.Name != current.Name ||
+		previous.Manufacturer != current.Manufacturer ||
+		previous.Type != current.Type ||
+		!slices.Equal(previous.FamousOwners, current.FamousOwners)
+}
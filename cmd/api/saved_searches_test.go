@@ -0,0 +1,436 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+
+	"github.com/ttarnok/instrument-swap-api/internal/data"
+	"github.com/ttarnok/instrument-swap-api/internal/data/mocks"
+)
+
+// TestCreateSavedSearchHandler implements unit tests for createSavedSearchHandler.
+func TestCreateSavedSearchHandler(t *testing.T) {
+
+	type inputBodyType struct {
+		Name         string   `json:"name,omitempty"`
+		Manufacturer string   `json:"manufacturer,omitempty"`
+		Type         string   `json:"type,omitempty"`
+		FamousOwners []string `json:"famous_owners,omitempty"`
+	}
+
+	type testCase struct {
+		name               string
+		inputBody          inputBodyType
+		expectedStatusCode int
+	}
+
+	testCases := []testCase{
+		{
+			name:               "happy path",
+			inputBody:          inputBodyType{Name: "M1", Manufacturer: "Korg", Type: "synthesizer", FamousOwners: []string{"The Orb"}},
+			expectedStatusCode: http.StatusCreated,
+		},
+		{
+			name:               "famous owners only",
+			inputBody:          inputBodyType{FamousOwners: []string{"Jimi Hendrix"}},
+			expectedStatusCode: http.StatusCreated,
+		},
+		{
+			name:               "empty search",
+			expectedStatusCode: http.StatusUnprocessableEntity,
+		},
+		{
+			name:               "non valid type",
+			inputBody:          inputBodyType{Type: "drum"},
+			expectedStatusCode: http.StatusUnprocessableEntity,
+		},
+		{
+			name:               "duplicate famous owners",
+			inputBody:          inputBodyType{FamousOwners: []string{"The Orb", "The Orb"}},
+			expectedStatusCode: http.StatusUnprocessableEntity,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			app := &application{
+				logger: slog.New(slog.NewTextHandler(io.Discard, nil)),
+				models: data.Models{
+					SavedSearches: mocks.NewSavedSearchModelMock(nil),
+					Categories:    newTestCategoryModelMock(),
+				},
+			}
+
+			setUser := func(next http.HandlerFunc) http.HandlerFunc {
+				return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+					r = app.contextSetUser(r, &data.User{ID: 10})
+
+					next.ServeHTTP(w, r)
+				})
+			}
+
+			mux := http.NewServeMux()
+			mux.HandleFunc("POST /", setUser(app.createSavedSearchHandler))
+
+			ts := httptest.NewServer(mux)
+			defer ts.Close()
+
+			bs, err := json.Marshal(tc.inputBody)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			resp, err := http.Post(ts.URL, "application/json", bytes.NewBuffer(bs))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer func() {
+				err := resp.Body.Close()
+				if err != nil {
+					t.Fatal(err)
+				}
+			}()
+
+			if tc.expectedStatusCode != resp.StatusCode {
+				t.Errorf(`expected status code %d, got %d`, tc.expectedStatusCode, resp.StatusCode)
+			}
+		})
+	}
+}
+
+// TestDeleteSavedSearchHandler implements unit tests for deleteSavedSearchHandler.
+func TestDeleteSavedSearchHandler(t *testing.T) {
+
+	type testCase struct {
+		name               string
+		pathParam          string
+		expectedStatusCode int
+		expectedSearchIDs  []int64
+	}
+
+	testCases := []testCase{
+		{
+			name:               "happy path",
+			pathParam:          "1",
+			expectedStatusCode: http.StatusOK,
+			expectedSearchIDs:  []int64{2},
+		},
+		{
+			name:               "saved search of another user",
+			pathParam:          "3",
+			expectedStatusCode: http.StatusNotFound,
+			expectedSearchIDs:  []int64{1, 2},
+		},
+		{
+			name:               "non existent saved search",
+			pathParam:          "99",
+			expectedStatusCode: http.StatusNotFound,
+			expectedSearchIDs:  []int64{1, 2},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			app := &application{
+				logger: slog.New(slog.NewTextHandler(io.Discard, nil)),
+				models: data.Models{
+					SavedSearches: mocks.NewSavedSearchModelMock([]*data.SavedSearch{
+						{ID: 1, UserID: 10, Type: "guitar"},
+						{ID: 2, UserID: 10, Manufacturer: "Moog"},
+						{ID: 3, UserID: 20, Type: "synthesizer"},
+					}),
+				},
+			}
+
+			setUser := func(next http.HandlerFunc) http.HandlerFunc {
+				return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+					r = app.contextSetUser(r, &data.User{ID: 10})
+
+					next.ServeHTTP(w, r)
+				})
+			}
+
+			mux := http.NewServeMux()
+			mux.HandleFunc("DELETE /{id}", setUser(app.deleteSavedSearchHandler))
+			mux.HandleFunc("GET /", setUser(app.listSavedSearchesHandler))
+
+			ts := httptest.NewServer(mux)
+			defer ts.Close()
+
+			req, err := http.NewRequest(http.MethodDelete, fmt.Sprintf("%s/%s", ts.URL, tc.pathParam), nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			resp, err := ts.Client().Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer func() {
+				err := resp.Body.Close()
+				if err != nil {
+					t.Fatal(err)
+				}
+			}()
+
+			if tc.expectedStatusCode != resp.StatusCode {
+				t.Errorf(`expected status code %d, got %d`, tc.expectedStatusCode, resp.StatusCode)
+			}
+
+			listResp, err := http.Get(ts.URL)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer func() {
+				err := listResp.Body.Close()
+				if err != nil {
+					t.Fatal(err)
+				}
+			}()
+
+			var respBody struct {
+				SavedSearches []*data.SavedSearch `json:"saved_searches"`
+			}
+
+			err = json.NewDecoder(listResp.Body).Decode(&respBody)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			searchIDs := []int64{}
+			for _, search := range respBody.SavedSearches {
+				searchIDs = append(searchIDs, search.ID)
+			}
+
+			if !slices.Equal(tc.expectedSearchIDs, searchIDs) {
+				t.Errorf(`expected saved searches %v, got %v`, tc.expectedSearchIDs, searchIDs)
+			}
+		})
+	}
+}
+
+// TestSavedSearchAlerts checks that the users are notified about the created instruments matching their saved searches.
+func TestSavedSearchAlerts(t *testing.T) {
+
+	type testCase struct {
+		name               string
+		matching           []*data.SavedSearch
+		expectedRecipients []string
+	}
+
+	testCases := []testCase{
+		{
+			name: "matching saved searches",
+			matching: []*data.SavedSearch{
+				{ID: 1, UserID: 20, Name: "m1", Manufacturer: "korg"},
+				{ID: 2, UserID: 20, Type: "synthesizer"},
+				{ID: 3, UserID: 30, FamousOwners: []string{"The Orb"}},
+			},
+			expectedRecipients: []string{"user20@example.com", "user30@example.com"},
+		},
+		{
+			name:               "no matching saved searches",
+			expectedRecipients: []string{},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			mailer := mocks.NewMailerMock()
+
+			app := &application{
+				logger: slog.New(slog.NewTextHandler(io.Discard, nil)),
+				models: data.Models{
+					Instruments:   mocks.NewEmptyInstrumentModelMock(),
+					SavedSearches: mocks.NewSavedSearchModelMock(nil).SetMatching("M1", tc.matching...),
+					Categories:    newTestCategoryModelMock(),
+					Users: mocks.NewUserModelMock([]*data.User{
+						{ID: 10, Email: "user10@example.com"},
+						{ID: 20, Email: "user20@example.com"},
+						{ID: 30, Email: "user30@example.com"},
+					}),
+				},
+				mailer: mailer,
+			}
+
+			setUser := func(next http.HandlerFunc) http.HandlerFunc {
+				return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+					r = app.contextSetUser(r, &data.User{ID: 10})
+
+					next.ServeHTTP(w, r)
+				})
+			}
+
+			mux := http.NewServeMux()
+			mux.HandleFunc("POST /", setUser(app.createInstrumentHandler))
+
+			ts := httptest.NewServer(mux)
+			defer ts.Close()
+
+			body := `{"name": "M1", "manufacturer": "Korg", "manufacture_year": 1990, "type": "synthesizer",
+				"estimated_value": 100000, "condition": "used", "famous_owners": ["The Orb"]}`
+
+			resp, err := http.Post(ts.URL, "application/json", bytes.NewBufferString(body))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer func() {
+				err := resp.Body.Close()
+				if err != nil {
+					t.Fatal(err)
+				}
+			}()
+
+			if resp.StatusCode != http.StatusCreated {
+				t.Fatalf(`expected status code %d, got %d`, http.StatusCreated, resp.StatusCode)
+			}
+
+			app.wg.Wait()
+
+			recipients := []string{}
+			for _, mail := range mailer.Sent() {
+				recipients = append(recipients, mail.Recipient)
+				if mail.TemplateFile != "saved_search_match.tmpl" {
+					t.Errorf(`expected template %q, got %q`, "saved_search_match.tmpl", mail.TemplateFile)
+				}
+			}
+
+			if !slices.Equal(tc.expectedRecipients, recipients) {
+				t.Errorf(`expected recipients %v, got %v`, tc.expectedRecipients, recipients)
+			}
+		})
+	}
+}
+
+// TestSavedSearchUpdateAlerts checks that the users are notified about the updated instruments,
+// that newly match their saved searches.
+func TestSavedSearchUpdateAlerts(t *testing.T) {
+
+	type testCase struct {
+		name               string
+		body               string
+		matching           map[string][]*data.SavedSearch
+		expectedRecipients []string
+	}
+
+	testCases := []testCase{
+		{
+			name: "newly matching saved searches",
+			body: `{"name": "M1 Ex"}`,
+			matching: map[string][]*data.SavedSearch{
+				"M1":    {{ID: 1, UserID: 20, Manufacturer: "korg"}},
+				"M1 Ex": {{ID: 1, UserID: 20, Manufacturer: "korg"}, {ID: 2, UserID: 30, Name: "ex"}},
+			},
+			expectedRecipients: []string{"user30@example.com"},
+		},
+		{
+			name: "previously not matching instrument",
+			body: `{"name": "M1 Ex"}`,
+			matching: map[string][]*data.SavedSearch{
+				"M1 Ex": {{ID: 1, UserID: 20, Manufacturer: "korg"}},
+			},
+			expectedRecipients: []string{"user20@example.com"},
+		},
+		{
+			name: "unchanged searchable fields",
+			body: `{"estimated_value": 120000, "condition": "poor"}`,
+			matching: map[string][]*data.SavedSearch{
+				"M1": {{ID: 1, UserID: 20, Manufacturer: "korg"}},
+			},
+			expectedRecipients: []string{},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			mailer := mocks.NewMailerMock()
+
+			searches := mocks.NewSavedSearchModelMock(nil)
+			for name, matching := range tc.matching {
+				searches.SetMatching(name, matching...)
+			}
+
+			app := &application{
+				logger: slog.New(slog.NewTextHandler(io.Discard, nil)),
+				models: data.Models{
+					Instruments: mocks.NewNonEmptyInstrumentModelMock([]*data.Instrument{
+						{ID: 1, Name: "M1", Manufacturer: "Korg", ManufactureYear: 1990, Type: "synthesizer",
+							EstimatedValue: 100000, Condition: "used", FamousOwners: []string{"The Orb"}, OwnerUserID: 10, Version: 1},
+					}),
+					SavedSearches: searches,
+					Categories:    newTestCategoryModelMock(),
+					Users: mocks.NewUserModelMock([]*data.User{
+						{ID: 10, Email: "user10@example.com"},
+						{ID: 20, Email: "user20@example.com"},
+						{ID: 30, Email: "user30@example.com"},
+					}),
+				},
+				mailer: mailer,
+			}
+
+			setUser := func(next http.HandlerFunc) http.HandlerFunc {
+				return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+					r = app.contextSetUser(r, &data.User{ID: 10})
+
+					next.ServeHTTP(w, r)
+				})
+			}
+
+			mux := http.NewServeMux()
+			mux.HandleFunc("PATCH /{id}", setUser(app.updateInstrumentHandler))
+
+			ts := httptest.NewServer(mux)
+			defer ts.Close()
+
+			request, err := http.NewRequest(http.MethodPatch, ts.URL+"/1", bytes.NewBufferString(tc.body))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			resp, err := http.DefaultClient.Do(request)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer func() {
+				err := resp.Body.Close()
+				if err != nil {
+					t.Fatal(err)
+				}
+			}()
+
+			if resp.StatusCode != http.StatusOK {
+				t.Fatalf(`expected status code %d, got %d`, http.StatusOK, resp.StatusCode)
+			}
+
+			app.wg.Wait()
+
+			recipients := []string{}
+			for _, mail := range mailer.Sent() {
+				recipients = append(recipients, mail.Recipient)
+			}
+
+			if !slices.Equal(tc.expectedRecipients, recipients) {
+				t.Errorf(`expected recipients %v, got %v`, tc.expectedRecipients, recipients)
+			}
+		})
+	}
+}
@@ -0,0 +1,208 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/ttarnok/instrument-swap-api/internal/data"
+	"github.com/ttarnok/instrument-swap-api/internal/validator"
+)
+
+// listSwapCyclesHandler lists the swap cycles the authenticated user participates in.
+func (app *application) listSwapCyclesHandler(w http.ResponseWriter, r *http.Request) {
+	authUser := app.contextGetUser(r)
+
+	var input struct {
+		Status string
+		data.Filters
+	}
+
+	v := validator.New()
+
+	qs := r.URL.Query()
+
+	input.Status = app.readQParamString(qs, "status", "")
+
+	input.Page = app.readQParamInt(qs, "page", 1, v)
+	input.PageSize = app.readQParamInt(qs, "page_size", 20, v)
+
+	input.Sort = app.readQParamString(qs, "sort", "id")
+	input.SortSafeList = []string{"id", "created_at", "-id", "-created_at"}
+
+	v.Check(input.Status == "" || validator.PermittedValue(input.Status, data.SwapCycleStatuses...), "status", "invalid status value")
+	data.ValidateFilters(v, input.Filters)
+
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	cycles, metadata, err := app.models.SwapCycles.GetAllForUser(authUser.ID, input.Status, input.Filters)
+	if err != nil {
+		app.serverErrorLogResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"swap_cycles": cycles, "metadata": metadata}, nil)
+	if err != nil {
+		app.serverErrorLogResponse(w, r, err)
+		return
+	}
+}
+
+// createSwapCycleHandler looks for a swap cycle among the wants and the available instruments of the users,
+// in which the authenticated user receives an instrument they want, and proposes it to its participants.
+// The leg of the authenticated user is accepted right away.
+// The participants of a recently rejected or expired swap cycle are not proposed the same cycle again within the configured cooldown.
+func (app *application) createSwapCycleHandler(w http.ResponseWriter, r *http.Request) {
+	authUser := app.contextGetUser(r)
+
+	candidates, err := app.models.SwapCycles.GetCandidateLegs(authUser.ID)
+	if err != nil {
+		app.serverErrorLogResponse(w, r, err)
+		return
+	}
+
+	excludedParticipants, err := app.models.SwapCycles.GetRecentParticipantSets(authUser.ID, time.Now().Add(-app.config.swap.cycleCooldown))
+	if err != nil {
+		app.serverErrorLogResponse(w, r, err)
+		return
+	}
+
+	legs := data.FindSwapCycle(authUser.ID, candidates, excludedParticipants)
+	if legs == nil {
+		app.errorResponse(w, r, http.StatusUnprocessableEntity, data.ErrNoSwapCycle.Error())
+		return
+	}
+
+	cycle := &data.SwapCycle{
+		Status: data.SwapCycleStatusProposed,
+		Legs:   legs,
+	}
+
+	err = data.TransitionSwapCycle(cycle, data.SwapCycleStatusAccepted, authUser.ID, time.Now())
+	if err != nil {
+		app.serverErrorLogResponse(w, r, err)
+		return
+	}
+
+	err = app.models.SwapCycles.Create(cycle)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrInstrumentAlreadySwapped), errors.Is(err, data.ErrInstrumentDisputed):
+			app.badRequestResponse(w, r, err)
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.badRequestResponse(w, r, errors.New("instrument not found"))
+		default:
+			app.serverErrorLogResponse(w, r, err)
+		}
+		return
+	}
+
+	headers := make(http.Header)
+	headers.Set("Location", fmt.Sprintf("/v1/swap-cycles/%d", cycle.ID))
+
+	err = app.writeJSON(w, http.StatusCreated, envelope{"swap_cycle": cycle}, headers)
+	if err != nil {
+		app.serverErrorLogResponse(w, r, err)
+		return
+	}
+}
+
+// showSwapCycleHandler shows a swap cycle with the given id.
+// Only the participants of the swap cycle can see it.
+func (app *application) showSwapCycleHandler(w http.ResponseWriter, r *http.Request) {
+	authUser := app.contextGetUser(r)
+
+	id, err := app.extractIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	cycle, err := app.models.SwapCycles.Get(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorLogResponse(w, r, err)
+		}
+		return
+	}
+
+	if !cycle.HasParticipant(authUser.ID) {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"swap_cycle": cycle}, nil)
+	if err != nil {
+		app.serverErrorLogResponse(w, r, err)
+		return
+	}
+}
+
+// updateSwapCycleStatusHandler handles the acceptance, the rejection and the ending of a swap cycle by its participants.
+// The swap cycle becomes active when every participant accepted it.
+func (app *application) updateSwapCycleStatusHandler(w http.ResponseWriter, r *http.Request) {
+	authUser := app.contextGetUser(r)
+
+	var input struct {
+		Status string `json:"status"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if !validator.PermittedValue(input.Status, data.SwapCycleStatusAccepted, data.SwapCycleStatusRejected, data.SwapCycleStatusEnded) {
+		app.errorResponse(w, r, http.StatusBadRequest, fmt.Sprintf("not valid status value: %q", input.Status))
+		return
+	}
+
+	id, err := app.extractIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	cycle, err := app.models.SwapCycles.Get(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorLogResponse(w, r, err)
+		}
+		return
+	}
+
+	if !cycle.HasParticipant(authUser.ID) {
+		app.forbiddenResponse(w, r)
+		return
+	}
+
+	cycle, err = app.models.SwapCycles.Transition(cycle.ID, input.Status, authUser.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		case errors.Is(err, data.ErrInvalidSwapCycleStatusTransition):
+			app.badRequestResponse(w, r, err)
+		default:
+			app.serverErrorLogResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"swap_cycle": cycle}, nil)
+	if err != nil {
+		app.serverErrorLogResponse(w, r, err)
+		return
+	}
+}
@@ -0,0 +1,615 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/ttarnok/instrument-swap-api/internal/data"
+	"github.com/ttarnok/instrument-swap-api/internal/data/mocks"
+)
+
+// swapCycleCandidate returns a candidate leg, in which the giver gives the given instrument to the receiver.
+func swapCycleCandidate(receiverUserID int64, giverUserID int64, instrumentID int64) *data.SwapCycleLeg {
+	return &data.SwapCycleLeg{ReceiverUserID: receiverUserID, GiverUserID: giverUserID, InstrumentID: instrumentID}
+}
+
+// newTestSwapCycle returns a proposed swap cycle (id 1) among the users 10, 20 and 30.
+func newTestSwapCycle() *data.SwapCycle {
+	return &data.SwapCycle{
+		ID:     1,
+		Status: data.SwapCycleStatusProposed,
+		Legs: []*data.SwapCycleLeg{
+			{Position: 1, GiverUserID: 20, ReceiverUserID: 10, InstrumentID: 2},
+			{Position: 2, GiverUserID: 30, ReceiverUserID: 20, InstrumentID: 3},
+			{Position: 3, GiverUserID: 10, ReceiverUserID: 30, InstrumentID: 1},
+		},
+		Version: 1,
+	}
+}
+
+// TestCreateSwapCycleHandler implements unit tests for createSwapCycleHandler.
+// The instruments 1, 2, 3 and 4 are owned by the users 10, 20, 30 and 40.
+func TestCreateSwapCycleHandler(t *testing.T) {
+
+	now := time.Now()
+	longAgo := now.Add(-30 * 24 * time.Hour)
+
+	// a recently rejected swap cycle among the users 10, 20 and 30
+	rejectedCycle := newTestSwapCycle()
+	rejectedCycle.Status = data.SwapCycleStatusRejected
+	rejectedCycle.RejectedAt = &now
+
+	// a swap cycle among the users 10, 20 and 30 rejected before the cooldown
+	oldRejectedCycle := newTestSwapCycle()
+	oldRejectedCycle.Status = data.SwapCycleStatusRejected
+	oldRejectedCycle.RejectedAt = &longAgo
+
+	// a proposed swap cycle among the users 10, 50 and 60 with the instrument 1
+	proposedCycle := &data.SwapCycle{
+		ID:     1,
+		Status: data.SwapCycleStatusProposed,
+		Legs: []*data.SwapCycleLeg{
+			{Position: 1, GiverUserID: 50, ReceiverUserID: 10, InstrumentID: 5},
+			{Position: 2, GiverUserID: 60, ReceiverUserID: 50, InstrumentID: 6},
+			{Position: 3, GiverUserID: 10, ReceiverUserID: 60, InstrumentID: 1},
+		},
+		Version: 1,
+	}
+
+	threeWayCandidates := []*data.SwapCycleLeg{
+		swapCycleCandidate(10, 20, 2),
+		swapCycleCandidate(20, 30, 3),
+		swapCycleCandidate(30, 10, 1),
+		swapCycleCandidate(30, 40, 4),
+		swapCycleCandidate(40, 10, 1),
+	}
+
+	type testCase struct {
+		name                  string
+		candidates            []*data.SwapCycleLeg
+		cycles                []*data.SwapCycle
+		expectedStatusCode    int
+		expectedGiverUserIDs  []int64
+		expectedInstrumentIDs []int64
+	}
+
+	testCases := []testCase{
+		{
+			name:                  "three-way cycle",
+			candidates:            threeWayCandidates,
+			expectedStatusCode:    http.StatusCreated,
+			expectedGiverUserIDs:  []int64{20, 30, 10},
+			expectedInstrumentIDs: []int64{2, 3, 1},
+		},
+		{
+			name: "four-way cycle",
+			candidates: []*data.SwapCycleLeg{
+				swapCycleCandidate(10, 20, 2),
+				swapCycleCandidate(20, 30, 3),
+				swapCycleCandidate(30, 40, 4),
+				swapCycleCandidate(40, 10, 1),
+			},
+			expectedStatusCode:    http.StatusCreated,
+			expectedGiverUserIDs:  []int64{20, 30, 40, 10},
+			expectedInstrumentIDs: []int64{2, 3, 4, 1},
+		},
+		{
+			name: "shortest cycle",
+			candidates: []*data.SwapCycleLeg{
+				swapCycleCandidate(10, 20, 2),
+				swapCycleCandidate(10, 30, 3),
+				swapCycleCandidate(20, 30, 3),
+				swapCycleCandidate(30, 40, 4),
+				swapCycleCandidate(40, 10, 1),
+			},
+			expectedStatusCode:    http.StatusCreated,
+			expectedGiverUserIDs:  []int64{30, 40, 10},
+			expectedInstrumentIDs: []int64{3, 4, 1},
+		},
+		{
+			// the user 40 is reached through the user 20 first, where the only continuation is blocked by the path
+			name: "cycle behind a dead end",
+			candidates: []*data.SwapCycleLeg{
+				swapCycleCandidate(10, 20, 2),
+				swapCycleCandidate(10, 30, 3),
+				swapCycleCandidate(20, 40, 4),
+				swapCycleCandidate(30, 40, 4),
+				swapCycleCandidate(40, 20, 2),
+				swapCycleCandidate(20, 10, 1),
+			},
+			expectedStatusCode:    http.StatusCreated,
+			expectedGiverUserIDs:  []int64{30, 40, 20, 10},
+			expectedInstrumentIDs: []int64{3, 4, 2, 1},
+		},
+		{
+			name:                  "participants of a recently rejected cycle",
+			candidates:            threeWayCandidates,
+			cycles:                []*data.SwapCycle{rejectedCycle},
+			expectedStatusCode:    http.StatusCreated,
+			expectedGiverUserIDs:  []int64{20, 30, 40, 10},
+			expectedInstrumentIDs: []int64{2, 3, 4, 1},
+		},
+		{
+			name:                  "participants of a cycle rejected before the cooldown",
+			candidates:            threeWayCandidates,
+			cycles:                []*data.SwapCycle{oldRejectedCycle},
+			expectedStatusCode:    http.StatusCreated,
+			expectedGiverUserIDs:  []int64{20, 30, 10},
+			expectedInstrumentIDs: []int64{2, 3, 1},
+		},
+		{
+			name:               "only the cycle of a recently rejected participant set",
+			candidates:         threeWayCandidates[:3],
+			cycles:             []*data.SwapCycle{rejectedCycle},
+			expectedStatusCode: http.StatusUnprocessableEntity,
+		},
+		{
+			name: "only a two-way swap",
+			candidates: []*data.SwapCycleLeg{
+				swapCycleCandidate(10, 20, 2),
+				swapCycleCandidate(20, 10, 1),
+			},
+			expectedStatusCode: http.StatusUnprocessableEntity,
+		},
+		{
+			name:               "no candidates",
+			expectedStatusCode: http.StatusUnprocessableEntity,
+		},
+		{
+			name:               "instrument in a proposed swap cycle",
+			candidates:         threeWayCandidates,
+			cycles:             []*data.SwapCycle{proposedCycle},
+			expectedStatusCode: http.StatusBadRequest,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			var cfg config
+			cfg.swap.cycleCooldown = 7 * 24 * time.Hour
+
+			app := &application{
+				config: cfg,
+				logger: slog.New(slog.NewTextHandler(io.Discard, nil)),
+				models: data.Models{
+					SwapCycles: mocks.NewSwapCycleModelMock(slices.Clone(tc.cycles)).SetCandidateLegs(tc.candidates...),
+				},
+			}
+
+			setUser := func(next http.HandlerFunc) http.HandlerFunc {
+				return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+					r = app.contextSetUser(r, &data.User{ID: 10})
+
+					next.ServeHTTP(w, r)
+				})
+			}
+
+			mux := http.NewServeMux()
+			mux.HandleFunc("POST /", setUser(app.createSwapCycleHandler))
+
+			ts := httptest.NewServer(mux)
+			defer ts.Close()
+
+			resp, err := http.Post(ts.URL, "application/json", nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer func() {
+				err := resp.Body.Close()
+				if err != nil {
+					t.Fatal(err)
+				}
+			}()
+
+			if tc.expectedStatusCode != resp.StatusCode {
+				t.Errorf(`expected status code %d, got %d`, tc.expectedStatusCode, resp.StatusCode)
+			}
+
+			if tc.expectedStatusCode != http.StatusCreated {
+				return
+			}
+
+			var respBody struct {
+				SwapCycle data.SwapCycle `json:"swap_cycle"`
+			}
+
+			err = json.NewDecoder(resp.Body).Decode(&respBody)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if respBody.SwapCycle.Status != data.SwapCycleStatusProposed {
+				t.Errorf(`expected status %q, got %q`, data.SwapCycleStatusProposed, respBody.SwapCycle.Status)
+			}
+
+			giverUserIDs := []int64{}
+			for _, leg := range respBody.SwapCycle.Legs {
+				giverUserIDs = append(giverUserIDs, leg.GiverUserID)
+			}
+
+			if !slices.Equal(tc.expectedGiverUserIDs, giverUserIDs) {
+				t.Errorf(`expected givers %v, got %v`, tc.expectedGiverUserIDs, giverUserIDs)
+			}
+
+			if !slices.Equal(tc.expectedInstrumentIDs, respBody.SwapCycle.InstrumentIDs()) {
+				t.Errorf(`expected instruments %v, got %v`, tc.expectedInstrumentIDs, respBody.SwapCycle.InstrumentIDs())
+			}
+
+			for _, leg := range respBody.SwapCycle.Legs {
+				if leg.IsAccepted != (leg.GiverUserID == 10) {
+					t.Errorf(`expected only the leg of the proposing user to be accepted, got leg %d of user %d accepted: %t`, leg.Position, leg.GiverUserID, leg.IsAccepted)
+				}
+			}
+		})
+	}
+}
+
+// TestShowSwapCycleHandler implements unit tests for showSwapCycleHandler.
+func TestShowSwapCycleHandler(t *testing.T) {
+
+	type testCase struct {
+		name               string
+		pathParam          string
+		reqUser            data.User
+		expectedStatusCode int
+	}
+
+	testCases := []testCase{
+		{
+			name:               "happy path",
+			pathParam:          "1",
+			reqUser:            data.User{ID: 30},
+			expectedStatusCode: http.StatusOK,
+		},
+		{
+			name:               "not a participant",
+			pathParam:          "1",
+			reqUser:            data.User{ID: 40},
+			expectedStatusCode: http.StatusNotFound,
+		},
+		{
+			name:               "non existent swap cycle",
+			pathParam:          "99",
+			reqUser:            data.User{ID: 10},
+			expectedStatusCode: http.StatusNotFound,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			app := &application{
+				logger: slog.New(slog.NewTextHandler(io.Discard, nil)),
+				models: data.Models{
+					SwapCycles: mocks.NewSwapCycleModelMock([]*data.SwapCycle{newTestSwapCycle()}),
+				},
+			}
+
+			setUser := func(next http.HandlerFunc) http.HandlerFunc {
+				return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+					r = app.contextSetUser(r, &tc.reqUser)
+
+					next.ServeHTTP(w, r)
+				})
+			}
+
+			mux := http.NewServeMux()
+			mux.HandleFunc("GET /{id}", setUser(app.showSwapCycleHandler))
+
+			ts := httptest.NewServer(mux)
+			defer ts.Close()
+
+			resp, err := http.Get(fmt.Sprintf("%s/%s", ts.URL, tc.pathParam))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer func() {
+				err := resp.Body.Close()
+				if err != nil {
+					t.Fatal(err)
+				}
+			}()
+
+			if tc.expectedStatusCode != resp.StatusCode {
+				t.Errorf(`expected status code %d, got %d`, tc.expectedStatusCode, resp.StatusCode)
+			}
+		})
+	}
+}
+
+// TestListSwapCyclesHandler implements unit tests for listSwapCyclesHandler.
+func TestListSwapCyclesHandler(t *testing.T) {
+
+	type testCase struct {
+		name               string
+		query              string
+		reqUser            data.User
+		expectedStatusCode int
+		expectedCycleIDs   []int64
+	}
+
+	testCases := []testCase{
+		{
+			name:               "happy path",
+			reqUser:            data.User{ID: 10},
+			expectedStatusCode: http.StatusOK,
+			expectedCycleIDs:   []int64{1},
+		},
+		{
+			name:               "status filter",
+			query:              "?status=active",
+			reqUser:            data.User{ID: 10},
+			expectedStatusCode: http.StatusOK,
+			expectedCycleIDs:   []int64{},
+		},
+		{
+			name:               "not a participant",
+			reqUser:            data.User{ID: 40},
+			expectedStatusCode: http.StatusOK,
+			expectedCycleIDs:   []int64{},
+		},
+		{
+			name:               "non valid status",
+			query:              "?status=accepted",
+			reqUser:            data.User{ID: 10},
+			expectedStatusCode: http.StatusUnprocessableEntity,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			app := &application{
+				logger: slog.New(slog.NewTextHandler(io.Discard, nil)),
+				models: data.Models{
+					SwapCycles: mocks.NewSwapCycleModelMock([]*data.SwapCycle{newTestSwapCycle()}),
+				},
+			}
+
+			setUser := func(next http.HandlerFunc) http.HandlerFunc {
+				return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+					r = app.contextSetUser(r, &tc.reqUser)
+
+					next.ServeHTTP(w, r)
+				})
+			}
+
+			mux := http.NewServeMux()
+			mux.HandleFunc("GET /", setUser(app.listSwapCyclesHandler))
+
+			ts := httptest.NewServer(mux)
+			defer ts.Close()
+
+			resp, err := http.Get(ts.URL + "/" + tc.query)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer func() {
+				err := resp.Body.Close()
+				if err != nil {
+					t.Fatal(err)
+				}
+			}()
+
+			if tc.expectedStatusCode != resp.StatusCode {
+				t.Errorf(`expected status code %d, got %d`, tc.expectedStatusCode, resp.StatusCode)
+			}
+
+			if tc.expectedStatusCode != http.StatusOK {
+				return
+			}
+
+			var respBody struct {
+				SwapCycles []*data.SwapCycle `json:"swap_cycles"`
+			}
+
+			err = json.NewDecoder(resp.Body).Decode(&respBody)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			cycleIDs := []int64{}
+			for _, cycle := range respBody.SwapCycles {
+				cycleIDs = append(cycleIDs, cycle.ID)
+			}
+
+			if !slices.Equal(tc.expectedCycleIDs, cycleIDs) {
+				t.Errorf(`expected swap cycles %v, got %v`, tc.expectedCycleIDs, cycleIDs)
+			}
+		})
+	}
+}
+
+// TestUpdateSwapCycleStatusHandler implements unit tests for updateSwapCycleStatusHandler.
+func TestUpdateSwapCycleStatusHandler(t *testing.T) {
+
+	type inputBodyType struct {
+		Status string `json:"status"`
+	}
+
+	type testCase struct {
+		name               string
+		pathParam          string
+		inputBody          inputBodyType
+		reqUser            data.User
+		acceptedBy         []int64
+		cycleStatus        string
+		expectedStatusCode int
+		expectedStatus     string
+	}
+
+	testCases := []testCase{
+		{
+			name:               "accepted by a participant",
+			pathParam:          "1",
+			inputBody:          inputBodyType{Status: data.SwapCycleStatusAccepted},
+			reqUser:            data.User{ID: 10},
+			expectedStatusCode: http.StatusOK,
+			expectedStatus:     data.SwapCycleStatusProposed,
+		},
+		{
+			name:               "accepted by the last participant",
+			pathParam:          "1",
+			inputBody:          inputBodyType{Status: data.SwapCycleStatusAccepted},
+			reqUser:            data.User{ID: 10},
+			acceptedBy:         []int64{20, 30},
+			expectedStatusCode: http.StatusOK,
+			expectedStatus:     data.SwapCycleStatusActive,
+		},
+		{
+			name:               "already accepted",
+			pathParam:          "1",
+			inputBody:          inputBodyType{Status: data.SwapCycleStatusAccepted},
+			reqUser:            data.User{ID: 10},
+			acceptedBy:         []int64{10},
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:               "rejected",
+			pathParam:          "1",
+			inputBody:          inputBodyType{Status: data.SwapCycleStatusRejected},
+			reqUser:            data.User{ID: 20},
+			acceptedBy:         []int64{10},
+			expectedStatusCode: http.StatusOK,
+			expectedStatus:     data.SwapCycleStatusRejected,
+		},
+		{
+			name:               "ended",
+			pathParam:          "1",
+			inputBody:          inputBodyType{Status: data.SwapCycleStatusEnded},
+			reqUser:            data.User{ID: 30},
+			cycleStatus:        data.SwapCycleStatusActive,
+			expectedStatusCode: http.StatusOK,
+			expectedStatus:     data.SwapCycleStatusEnded,
+		},
+		{
+			name:               "ending a proposed swap cycle",
+			pathParam:          "1",
+			inputBody:          inputBodyType{Status: data.SwapCycleStatusEnded},
+			reqUser:            data.User{ID: 30},
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:               "rejecting an active swap cycle",
+			pathParam:          "1",
+			inputBody:          inputBodyType{Status: data.SwapCycleStatusRejected},
+			reqUser:            data.User{ID: 30},
+			cycleStatus:        data.SwapCycleStatusActive,
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:               "non valid status",
+			pathParam:          "1",
+			inputBody:          inputBodyType{Status: data.SwapCycleStatusActive},
+			reqUser:            data.User{ID: 10},
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:               "not a participant",
+			pathParam:          "1",
+			inputBody:          inputBodyType{Status: data.SwapCycleStatusAccepted},
+			reqUser:            data.User{ID: 40},
+			expectedStatusCode: http.StatusForbidden,
+		},
+		{
+			name:               "non existent swap cycle",
+			pathParam:          "99",
+			inputBody:          inputBodyType{Status: data.SwapCycleStatusAccepted},
+			reqUser:            data.User{ID: 10},
+			expectedStatusCode: http.StatusNotFound,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			cycle := newTestSwapCycle()
+			if tc.cycleStatus != "" {
+				cycle.Status = tc.cycleStatus
+			}
+			for _, leg := range cycle.Legs {
+				leg.IsAccepted = slices.Contains(tc.acceptedBy, leg.GiverUserID)
+			}
+
+			app := &application{
+				logger: slog.New(slog.NewTextHandler(io.Discard, nil)),
+				models: data.Models{
+					SwapCycles: mocks.NewSwapCycleModelMock([]*data.SwapCycle{cycle}),
+				},
+			}
+
+			setUser := func(next http.HandlerFunc) http.HandlerFunc {
+				return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+					r = app.contextSetUser(r, &tc.reqUser)
+
+					next.ServeHTTP(w, r)
+				})
+			}
+
+			mux := http.NewServeMux()
+			mux.HandleFunc("PATCH /{id}", setUser(app.updateSwapCycleStatusHandler))
+
+			ts := httptest.NewServer(mux)
+			defer ts.Close()
+
+			bs, err := json.Marshal(tc.inputBody)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			req, err := http.NewRequest(http.MethodPatch, fmt.Sprintf("%s/%s", ts.URL, tc.pathParam), bytes.NewBuffer(bs))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			resp, err := ts.Client().Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer func() {
+				err := resp.Body.Close()
+				if err != nil {
+					t.Fatal(err)
+				}
+			}()
+
+			if tc.expectedStatusCode != resp.StatusCode {
+				t.Errorf(`expected status code %d, got %d`, tc.expectedStatusCode, resp.StatusCode)
+			}
+
+			if tc.expectedStatusCode != http.StatusOK {
+				return
+			}
+
+			var respBody struct {
+				SwapCycle data.SwapCycle `json:"swap_cycle"`
+			}
+
+			err = json.NewDecoder(resp.Body).Decode(&respBody)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if tc.expectedStatus != respBody.SwapCycle.Status {
+				t.Errorf(`expected status %q, got %q`, tc.expectedStatus, respBody.SwapCycle.Status)
+			}
+		})
+	}
+}
@@ -0,0 +1,188 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/ttarnok/instrument-swap-api/internal/data"
+	"github.com/ttarnok/instrument-swap-api/internal/validator"
+)
+
+// listSwapMessagesHandler handles listing the message thread of a swap with the given id.
+// Only the owners of the instruments of the swap can read its messages.
+// The listed messages sent to the authenticated user are marked as read,
+// the response contains the number of the messages that are still unread by the authenticated user.
+func (app *application) listSwapMessagesHandler(w http.ResponseWriter, r *http.Request) {
+	authUser := app.contextGetUser(r)
+
+	swapID, err := app.extractIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	var input struct {
+		data.Filters
+	}
+
+	v := validator.New()
+
+	qs := r.URL.Query()
+
+	input.Page = app.readQParamInt(qs, "page", 1, v)
+	input.PageSize = app.readQParamInt(qs, "page_size", 20, v)
+
+	input.Sort = app.readQParamString(qs, "sort", "id")
+	input.SortSafeList = []string{"id", "created_at", "-id", "-created_at"}
+
+	data.ValidateFilters(v, input.Filters)
+
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	swap, ok := app.swapForMessages(w, r, swapID)
+	if !ok {
+		return
+	}
+
+	messages, metadata, err := app.models.SwapMessages.GetAllForSwap(swap.ID, input.Filters)
+	if err != nil {
+		app.serverErrorLogResponse(w, r, err)
+		return
+	}
+
+	ids := make([]int64, 0, len(messages))
+	for _, message := range messages {
+		ids = append(ids, message.ID)
+	}
+
+	err = app.models.SwapMessages.MarkAsRead(swap.ID, authUser.ID, ids)
+	if err != nil {
+		app.serverErrorLogResponse(w, r, err)
+		return
+	}
+
+	unreadCount, err := app.models.SwapMessages.CountUnread(swap.ID, authUser.ID)
+	if err != nil {
+		app.serverErrorLogResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"messages": messages, "metadata": metadata, "unread_count": unreadCount}, nil)
+	if err != nil {
+		app.serverErrorLogResponse(w, r, err)
+		return
+	}
+}
+
+// showSwapMessagesUnreadCountHandler handles the retrieval of the number of the messages of a swap with the given id,
+// that are sent to the authenticated user and not read yet. The messages are not marked as read.
+func (app *application) showSwapMessagesUnreadCountHandler(w http.ResponseWriter, r *http.Request) {
+	authUser := app.contextGetUser(r)
+
+	swapID, err := app.extractIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	swap, ok := app.swapForMessages(w, r, swapID)
+	if !ok {
+		return
+	}
+
+	unreadCount, err := app.models.SwapMessages.CountUnread(swap.ID, authUser.ID)
+	if err != nil {
+		app.serverErrorLogResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"unread_count": unreadCount}, nil)
+	if err != nil {
+		app.serverErrorLogResponse(w, r, err)
+		return
+	}
+}
+
+// createSwapMessageHandler handles sending a new message in the message thread of a swap with the given id.
+// Only the owners of the instruments of the swap can send messages.
+func (app *application) createSwapMessageHandler(w http.ResponseWriter, r *http.Request) {
+	authUser := app.contextGetUser(r)
+
+	swapID, err := app.extractIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	var input struct {
+		Body string `json:"body"`
+	}
+
+	err = app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	message := &data.SwapMessage{
+		SwapID:       swapID,
+		SenderUserID: authUser.ID,
+		Body:         input.Body,
+	}
+
+	v := validator.New()
+
+	if data.ValidateSwapMessage(v, message); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	_, ok := app.swapForMessages(w, r, swapID)
+	if !ok {
+		return
+	}
+
+	err = app.models.SwapMessages.Insert(message)
+	if err != nil {
+		app.serverErrorLogResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusCreated, envelope{"message": message}, nil)
+	if err != nil {
+		app.serverErrorLogResponse(w, r, err)
+		return
+	}
+}
+
+// swapForMessages retrieves the swap with the given id, and checks that the authenticated user is a party of the swap.
+// Sends the error response and returns false, if the swap does not exist or the user is not a party of it.
+func (app *application) swapForMessages(w http.ResponseWriter, r *http.Request, swapID int64) (*data.Swap, bool) {
+	swap, err := app.models.Swaps.Get(swapID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorLogResponse(w, r, err)
+		}
+		return nil, false
+	}
+
+	requesterUserID, recipientUserID, err := app.swapOwnerIDs(swap)
+	if err != nil {
+		app.serverErrorLogResponse(w, r, err)
+		return nil, false
+	}
+
+	authUser := app.contextGetUser(r)
+	if authUser.ID != requesterUserID && authUser.ID != recipientUserID {
+		app.forbiddenResponse(w, r)
+		return nil, false
+	}
+
+	return swap, true
+}
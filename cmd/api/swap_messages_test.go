@@ -0,0 +1,423 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ttarnok/instrument-swap-api/internal/data"
+	"github.com/ttarnok/instrument-swap-api/internal/data/mocks"
+)
+
+// TestListSwapMessagesHandler implements unit tests for listSwapMessagesHandler.
+func TestListSwapMessagesHandler(t *testing.T) {
+
+	type testCase struct {
+		name                string
+		pathParam           string
+		query               string
+		reqUser             data.User
+		expectedStatusCode  int
+		expectedMessages    int
+		expectedUnreadCount int
+	}
+
+	testCases := []testCase{
+		{
+			name:                "happy path - requester",
+			pathParam:           "1",
+			reqUser:             data.User{ID: 10},
+			expectedStatusCode:  http.StatusOK,
+			expectedMessages:    3,
+			expectedUnreadCount: 0,
+		},
+		{
+			name:                "happy path - recipient",
+			pathParam:           "1",
+			reqUser:             data.User{ID: 20},
+			expectedStatusCode:  http.StatusOK,
+			expectedMessages:    3,
+			expectedUnreadCount: 0,
+		},
+		{
+			name:                "first page - recipient",
+			pathParam:           "1",
+			query:               "?page_size=2",
+			reqUser:             data.User{ID: 20},
+			expectedStatusCode:  http.StatusOK,
+			expectedMessages:    2,
+			expectedUnreadCount: 1,
+		},
+		{
+			name:                "second page - recipient",
+			pathParam:           "1",
+			query:               "?page=2&page_size=2",
+			reqUser:             data.User{ID: 20},
+			expectedStatusCode:  http.StatusOK,
+			expectedMessages:    1,
+			expectedUnreadCount: 1,
+		},
+		{
+			name:               "not a party of the swap",
+			pathParam:          "1",
+			reqUser:            data.User{ID: 30},
+			expectedStatusCode: http.StatusForbidden,
+		},
+		{
+			name:               "non existent swap",
+			pathParam:          "2",
+			reqUser:            data.User{ID: 10},
+			expectedStatusCode: http.StatusNotFound,
+		},
+		{
+			name:               "non valid path param",
+			pathParam:          "nonvalid",
+			reqUser:            data.User{ID: 10},
+			expectedStatusCode: http.StatusNotFound,
+		},
+		{
+			name:               "non valid sort",
+			pathParam:          "1",
+			query:              "?sort=body",
+			reqUser:            data.User{ID: 10},
+			expectedStatusCode: http.StatusUnprocessableEntity,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			messages := mocks.NewSwapMessageModelMock([]*data.SwapMessage{
+				{ID: 1, SwapID: 1, SenderUserID: 10, Body: "Hi!"},
+				{ID: 2, SwapID: 1, SenderUserID: 20, Body: "Hello!"},
+				{ID: 3, SwapID: 1, SenderUserID: 10, Body: "Is the synth still available?"},
+			})
+
+			app := &application{
+				logger: slog.New(slog.NewTextHandler(io.Discard, nil)),
+				models: data.Models{
+					Swaps:        mocks.NewSwapModelMock([]*data.Swap{{ID: 1, RequesterInstrumentID: 1, RecipientInstrumentID: 2}}),
+					SwapMessages: messages,
+					Instruments:  mocks.NewNonEmptyInstrumentModelMock([]*data.Instrument{{ID: 1, OwnerUserID: 10}, {ID: 2, OwnerUserID: 20}}),
+				},
+			}
+
+			setUser := func(next http.HandlerFunc) http.HandlerFunc {
+				return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+					r = app.contextSetUser(r, &tc.reqUser)
+
+					next.ServeHTTP(w, r)
+				})
+			}
+
+			mux := http.NewServeMux()
+			mux.HandleFunc("GET /{id}", setUser(app.listSwapMessagesHandler))
+
+			ts := httptest.NewServer(mux)
+			defer ts.Close()
+
+			resp, err := http.Get(fmt.Sprintf("%s/%s%s", ts.URL, tc.pathParam, tc.query))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer func() {
+				err := resp.Body.Close()
+				if err != nil {
+					t.Fatal(err)
+				}
+			}()
+
+			if tc.expectedStatusCode != resp.StatusCode {
+				t.Errorf(`expected status code %d, got %d`, tc.expectedStatusCode, resp.StatusCode)
+			}
+
+			if tc.expectedStatusCode != http.StatusOK {
+				return
+			}
+
+			var respBody struct {
+				Messages    []*data.SwapMessage `json:"messages"`
+				UnreadCount int                 `json:"unread_count"`
+			}
+
+			err = json.NewDecoder(resp.Body).Decode(&respBody)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(respBody.Messages) != tc.expectedMessages {
+				t.Errorf(`expected %d messages, got %d`, tc.expectedMessages, len(respBody.Messages))
+			}
+
+			if respBody.UnreadCount != tc.expectedUnreadCount {
+				t.Errorf(`expected unread count %d, got %d`, tc.expectedUnreadCount, respBody.UnreadCount)
+			}
+
+			for _, message := range respBody.Messages {
+				stored, err := messages.Get(message.ID)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if message.SenderUserID != tc.reqUser.ID && stored.ReadAt == nil {
+					t.Errorf(`expected listed message %d to be read`, message.ID)
+				}
+			}
+		})
+	}
+}
+
+// TestShowSwapMessagesUnreadCountHandler implements unit tests for showSwapMessagesUnreadCountHandler.
+func TestShowSwapMessagesUnreadCountHandler(t *testing.T) {
+
+	type testCase struct {
+		name                string
+		pathParam           string
+		reqUser             data.User
+		expectedStatusCode  int
+		expectedUnreadCount int
+	}
+
+	testCases := []testCase{
+		{
+			name:                "happy path - requester",
+			pathParam:           "1",
+			reqUser:             data.User{ID: 10},
+			expectedStatusCode:  http.StatusOK,
+			expectedUnreadCount: 1,
+		},
+		{
+			name:                "happy path - recipient",
+			pathParam:           "1",
+			reqUser:             data.User{ID: 20},
+			expectedStatusCode:  http.StatusOK,
+			expectedUnreadCount: 2,
+		},
+		{
+			name:               "not a party of the swap",
+			pathParam:          "1",
+			reqUser:            data.User{ID: 30},
+			expectedStatusCode: http.StatusForbidden,
+		},
+		{
+			name:               "non existent swap",
+			pathParam:          "2",
+			reqUser:            data.User{ID: 10},
+			expectedStatusCode: http.StatusNotFound,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			messages := mocks.NewSwapMessageModelMock([]*data.SwapMessage{
+				{ID: 1, SwapID: 1, SenderUserID: 10, Body: "Hi!"},
+				{ID: 2, SwapID: 1, SenderUserID: 20, Body: "Hello!"},
+				{ID: 3, SwapID: 1, SenderUserID: 10, Body: "Is the synth still available?"},
+			})
+
+			app := &application{
+				logger: slog.New(slog.NewTextHandler(io.Discard, nil)),
+				models: data.Models{
+					Swaps:        mocks.NewSwapModelMock([]*data.Swap{{ID: 1, RequesterInstrumentID: 1, RecipientInstrumentID: 2}}),
+					SwapMessages: messages,
+					Instruments:  mocks.NewNonEmptyInstrumentModelMock([]*data.Instrument{{ID: 1, OwnerUserID: 10}, {ID: 2, OwnerUserID: 20}}),
+				},
+			}
+
+			setUser := func(next http.HandlerFunc) http.HandlerFunc {
+				return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+					r = app.contextSetUser(r, &tc.reqUser)
+
+					next.ServeHTTP(w, r)
+				})
+			}
+
+			mux := http.NewServeMux()
+			mux.HandleFunc("GET /{id}", setUser(app.showSwapMessagesUnreadCountHandler))
+
+			ts := httptest.NewServer(mux)
+			defer ts.Close()
+
+			resp, err := http.Get(fmt.Sprintf("%s/%s", ts.URL, tc.pathParam))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer func() {
+				err := resp.Body.Close()
+				if err != nil {
+					t.Fatal(err)
+				}
+			}()
+
+			if tc.expectedStatusCode != resp.StatusCode {
+				t.Fatalf(`expected status code %d, got %d`, tc.expectedStatusCode, resp.StatusCode)
+			}
+
+			if tc.expectedStatusCode != http.StatusOK {
+				return
+			}
+
+			var respBody struct {
+				UnreadCount int `json:"unread_count"`
+			}
+
+			err = json.NewDecoder(resp.Body).Decode(&respBody)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if respBody.UnreadCount != tc.expectedUnreadCount {
+				t.Errorf(`expected unread count %d, got %d`, tc.expectedUnreadCount, respBody.UnreadCount)
+			}
+
+			unreadCount, err := messages.CountUnread(1, tc.reqUser.ID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if unreadCount != tc.expectedUnreadCount {
+				t.Errorf(`expected the messages to remain unread, got %d unread`, unreadCount)
+			}
+		})
+	}
+}
+
+// TestCreateSwapMessageHandler implements unit tests for createSwapMessageHandler.
+func TestCreateSwapMessageHandler(t *testing.T) {
+
+	type inputBodyType struct {
+		Body string `json:"body"`
+	}
+
+	type testCase struct {
+		name               string
+		pathParam          string
+		inputBody          inputBodyType
+		reqUser            data.User
+		expectedStatusCode int
+	}
+
+	testCases := []testCase{
+		{
+			name:               "happy path - requester",
+			pathParam:          "1",
+			inputBody:          inputBodyType{Body: "Is the synth still available?"},
+			reqUser:            data.User{ID: 10},
+			expectedStatusCode: http.StatusCreated,
+		},
+		{
+			name:               "happy path - recipient",
+			pathParam:          "1",
+			inputBody:          inputBodyType{Body: "Sure, come and try it!"},
+			reqUser:            data.User{ID: 20},
+			expectedStatusCode: http.StatusCreated,
+		},
+		{
+			name:               "not a party of the swap",
+			pathParam:          "1",
+			inputBody:          inputBodyType{Body: "Hi!"},
+			reqUser:            data.User{ID: 30},
+			expectedStatusCode: http.StatusForbidden,
+		},
+		{
+			name:               "empty body",
+			pathParam:          "1",
+			inputBody:          inputBodyType{Body: ""},
+			reqUser:            data.User{ID: 10},
+			expectedStatusCode: http.StatusUnprocessableEntity,
+		},
+		{
+			name:               "too long body",
+			pathParam:          "1",
+			inputBody:          inputBodyType{Body: string(bytes.Repeat([]byte("a"), 2001))},
+			reqUser:            data.User{ID: 10},
+			expectedStatusCode: http.StatusUnprocessableEntity,
+		},
+		{
+			name:               "non existent swap",
+			pathParam:          "2",
+			inputBody:          inputBodyType{Body: "Hi!"},
+			reqUser:            data.User{ID: 10},
+			expectedStatusCode: http.StatusNotFound,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			messages := mocks.NewSwapMessageModelMock(nil)
+
+			app := &application{
+				logger: slog.New(slog.NewTextHandler(io.Discard, nil)),
+				models: data.Models{
+					Swaps:        mocks.NewSwapModelMock([]*data.Swap{{ID: 1, RequesterInstrumentID: 1, RecipientInstrumentID: 2}}),
+					SwapMessages: messages,
+					Instruments:  mocks.NewNonEmptyInstrumentModelMock([]*data.Instrument{{ID: 1, OwnerUserID: 10}, {ID: 2, OwnerUserID: 20}}),
+				},
+			}
+
+			setUser := func(next http.HandlerFunc) http.HandlerFunc {
+				return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+					r = app.contextSetUser(r, &tc.reqUser)
+
+					next.ServeHTTP(w, r)
+				})
+			}
+
+			mux := http.NewServeMux()
+			mux.HandleFunc("POST /{id}", setUser(app.createSwapMessageHandler))
+
+			ts := httptest.NewServer(mux)
+			defer ts.Close()
+
+			bs, err := json.Marshal(tc.inputBody)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			resp, err := http.Post(fmt.Sprintf("%s/%s", ts.URL, tc.pathParam), "application/json", bytes.NewBuffer(bs))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer func() {
+				err := resp.Body.Close()
+				if err != nil {
+					t.Fatal(err)
+				}
+			}()
+
+			if tc.expectedStatusCode != resp.StatusCode {
+				t.Errorf(`expected status code %d, got %d`, tc.expectedStatusCode, resp.StatusCode)
+			}
+
+			stored, _, err := messages.GetAllForSwap(1, data.Filters{Page: 1, PageSize: 20})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if tc.expectedStatusCode != http.StatusCreated {
+				if len(stored) != 0 {
+					t.Errorf(`expected no stored messages, got %d`, len(stored))
+				}
+				return
+			}
+
+			if len(stored) != 1 {
+				t.Fatalf(`expected 1 stored message, got %d`, len(stored))
+			}
+			if stored[0].SenderUserID != tc.reqUser.ID || stored[0].Body != tc.inputBody.Body {
+				t.Errorf(`unexpected stored message %#v`, stored[0])
+			}
+		})
+	}
+}
@@ -4,30 +4,124 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
+	"slices"
 	"time"
 
 	"github.com/ttarnok/instrument-swap-api/internal/data"
 	"github.com/ttarnok/instrument-swap-api/internal/validator"
 )
 
-const (
-	StatusSwapAccepted = "accepted" // StatusSwapAccepted
-	StatusSwapRejected = "rejected" // StatusSwapRejected
-	StatusSwapEnded    = "ended"    // StatusSwapEnded
-)
-
-// listSwapsHandler handles listing all swaps for the user within the context.
+// listSwapsHandler handles listing the swaps for the user within the context.
+// The swaps can be filtered by status, by the role of the user, by an instrument and by the creation time.
 func (app *application) listSwapsHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Status       string
+		Role         string
+		InstrumentID int64
+		CreatedFrom  time.Time
+		CreatedTo    time.Time
+		data.Filters
+	}
 
 	user := app.contextGetUser(r)
 
-	swaps, err := app.models.Swaps.GetAllForUser(user.ID)
+	v := validator.New()
+
+	qs := r.URL.Query()
+
+	input.Status = app.readQParamString(qs, "status", "")
+	input.Role = app.readQParamString(qs, "role", "")
+
+	input.InstrumentID = int64(app.readQParamInt(qs, "instrument_id", 0, v))
+
+	input.CreatedFrom = app.readQParamTime(qs, "created_from", time.Time{}, v)
+	input.CreatedTo = app.readQParamTime(qs, "created_to", time.Time{}, v)
+
+	input.Page = app.readQParamInt(qs, "page", 1, v)
+	input.PageSize = app.readQParamInt(qs, "page_size", 20, v)
+
+	input.Sort = app.readQParamString(qs, "sort", "id")
+	input.SortSafeList = []string{"id", "created_at", "-id", "-created_at"}
+
+	v.Check(input.Status == "" || validator.PermittedValue(input.Status, data.SwapStatuses...), "status", "invalid status value")
+	v.Check(validator.PermittedValue(input.Role, "", data.SwapSideRequester, data.SwapSideRecipient), "role", "must be requester or recipient")
+	v.Check(input.InstrumentID >= 0, "instrument_id", "must not be negative")
+	v.Check(input.CreatedFrom.IsZero() || input.CreatedTo.IsZero() || input.CreatedFrom.Before(input.CreatedTo), "created_to", "must be after created_from")
+	data.ValidateFilters(v, input.Filters)
+
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	swaps, metadata, err := app.models.Swaps.GetAll(user.ID, input.Status, input.Role, input.InstrumentID, input.CreatedFrom, input.CreatedTo, input.Filters)
+	if err != nil {
+		app.serverErrorLogResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"swaps": swaps, "metadata": metadata}, nil)
+	if err != nil {
+		app.serverErrorLogResponse(w, r, err)
+		return
+	}
+}
+
+// listInstrumentSwapsHandler handles listing the whole swap history of the instrument with the given id.
+// Only the owner of the instrument can see its swap history.
+func (app *application) listInstrumentSwapsHandler(w http.ResponseWriter, r *http.Request) {
+	authUser := app.contextGetUser(r)
+
+	instrumentID, err := app.extractIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	var input struct {
+		data.Filters
+	}
+
+	v := validator.New()
+
+	qs := r.URL.Query()
+
+	input.Page = app.readQParamInt(qs, "page", 1, v)
+	input.PageSize = app.readQParamInt(qs, "page_size", 20, v)
+
+	input.Sort = app.readQParamString(qs, "sort", "id")
+	input.SortSafeList = []string{"id", "created_at", "-id", "-created_at"}
+
+	data.ValidateFilters(v, input.Filters)
+
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	instrument, err := app.models.Instruments.Get(instrumentID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorLogResponse(w, r, err)
+		}
+		return
+	}
+
+	if authUser.ID != instrument.OwnerUserID {
+		app.forbiddenResponse(w, r)
+		return
+	}
+
+	swaps, metadata, err := app.models.Swaps.GetAllForInstrument(instrument.ID, input.Filters)
 	if err != nil {
 		app.serverErrorLogResponse(w, r, err)
 		return
 	}
 
-	err = app.writeJSON(w, http.StatusOK, envelope{"swaps": swaps}, nil)
+	err = app.writeJSON(w, http.StatusOK, envelope{"swaps": swaps, "metadata": metadata}, nil)
 	if err != nil {
 		app.serverErrorLogResponse(w, r, err)
 		return
@@ -35,6 +129,8 @@ func (app *application) listSwapsHandler(w http.ResponseWriter, r *http.Request)
 }
 
 // showSwapHandler handles the retrieval of a swap with the given id for the user within the context.
+// The summaries of the instruments of the swap are embedded into the response with the expand=instruments parameter.
+// The ETag of the response is derived from the version of the swap, If-None-Match is honored.
 func (app *application) showSwapHandler(w http.ResponseWriter, r *http.Request) {
 
 	authUser := app.contextGetUser(r)
@@ -45,26 +141,36 @@ func (app *application) showSwapHandler(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	swapsForUser, err := app.models.Swaps.GetAllForUser(authUser.ID)
-	if err != nil {
-		app.serverErrorLogResponse(w, r, err)
+	v := validator.New()
+
+	expand := app.readQParamCSV(r.URL.Query(), "expand", []string{})
+
+	for _, field := range expand {
+		v.Check(validator.PermittedValue(field, "instruments"), "expand", "must be instruments")
+	}
+
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
 		return
 	}
 
-	var foundSwap *data.Swap
-	for _, swap := range swapsForUser {
-		if swap.ID == id {
-			foundSwap = swap
-			break
+	swap, err := app.models.Swaps.GetForUser(id, authUser.ID, slices.Contains(expand, "instruments"))
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorLogResponse(w, r, err)
 		}
+		return
 	}
 
-	if foundSwap == nil {
-		app.notFoundResponse(w, r)
+	// The embedded instruments change without a new version of the swap, so expanded responses are not tagged.
+	if len(expand) == 0 && app.notModified(w, r, etag(int64(swap.Version))) {
 		return
 	}
 
-	err = app.writeJSON(w, http.StatusOK, envelope{"swap": foundSwap}, nil)
+	err = app.writeJSON(w, http.StatusOK, envelope{"swap": swap}, nil)
 	if err != nil {
 		app.serverErrorLogResponse(w, r, err)
 		return
@@ -78,8 +184,14 @@ func (app *application) createSwapHandler(w http.ResponseWriter, r *http.Request
 	ownerUser := app.contextGetUser(r)
 
 	var input struct {
-		RequesterInstrumentID int64 `json:"requester_instrument_id"`
-		RecipientInstrumentID int64 `json:"recipient_instrument_id"`
+		RequesterInstrumentID  int64           `json:"requester_instrument_id"`
+		RecipientInstrumentID  int64           `json:"recipient_instrument_id"`
+		RequesterInstrumentIDs []int64         `json:"requester_instrument_ids"`
+		RecipientInstrumentIDs []int64         `json:"recipient_instrument_ids"`
+		TopUp                  *data.SwapTopUp `json:"top_up"`
+		StartsAt               *time.Time      `json:"starts_at"`
+		ReturnBy               *time.Time      `json:"return_by"`
+		Note                   string          `json:"note"`
 	}
 
 	err := app.readJSON(w, r, &input)
@@ -88,20 +200,26 @@ func (app *application) createSwapHandler(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	swap := &data.Swap{
-		RequesterInstrumentID: input.RequesterInstrumentID,
-		RecipientInstrumentID: input.RecipientInstrumentID,
-	}
+	swap := data.NewSwap(
+		swapInstrumentIDs(input.RequesterInstrumentID, input.RequesterInstrumentIDs),
+		swapInstrumentIDs(input.RecipientInstrumentID, input.RecipientInstrumentIDs),
+	)
+	swap.TopUp = input.TopUp
+	swap.StartsAt = input.StartsAt
+	swap.ReturnBy = input.ReturnBy
 
 	v := validator.New()
 
-	if data.ValidateSwap(v, swap); !v.Valid() {
+	data.ValidateSwap(v, swap)
+	data.ValidateSwapEventNote(v, input.Note)
+
+	if !v.Valid() {
 		app.failedValidationResponse(w, r, v.Errors)
 		return
 	}
 
 	// Check the instrument id-s are real.
-	_, err = app.models.Instruments.Get(input.RecipientInstrumentID)
+	recipientUserIDs, recipientValue, err := app.swapSideDetails(swap.RecipientInstrumentIDs)
 	if err != nil {
 		switch {
 		case errors.Is(err, data.ErrRecordNotFound):
@@ -112,7 +230,7 @@ func (app *application) createSwapHandler(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	requesterInstrument, err := app.models.Instruments.Get(input.RequesterInstrumentID)
+	requesterUserIDs, requesterValue, err := app.swapSideDetails(swap.RequesterInstrumentIDs)
 	if err != nil {
 		switch {
 		case errors.Is(err, data.ErrRecordNotFound):
@@ -124,35 +242,30 @@ func (app *application) createSwapHandler(w http.ResponseWriter, r *http.Request
 	}
 
 	// Only the authenticated user can be the requester.
-	if requesterInstrument.OwnerUserID != ownerUser.ID {
+	if !slices.Equal(requesterUserIDs, []int64{ownerUser.ID}) {
 		app.forbiddenResponse(w, r)
 		return
 	}
 
-	// Check the instrument are not swapped
-	recSwap, err := app.models.Swaps.GetByInstrumentID(input.RecipientInstrumentID)
-	if err != nil && !errors.Is(err, data.ErrRecordNotFound) {
-		app.serverErrorLogResponse(w, r, err)
-		return
-	}
-	if recSwap != nil {
-		app.badRequestResponse(w, r, errors.New("recipient instrument already in a swap"))
-		return
-	}
-	reqSwap, err := app.models.Swaps.GetByInstrumentID(input.RequesterInstrumentID)
-	if err != nil && !errors.Is(err, data.ErrRecordNotFound) {
-		app.serverErrorLogResponse(w, r, err)
-		return
-	}
-	if reqSwap != nil {
-		app.badRequestResponse(w, r, errors.New("requester instrument already in a swap"))
+	v.Check(len(recipientUserIDs) == 1, "recipient_instrument_ids", "must belong to the same user")
+	data.ValidateSwapTopUp(v, swap, requesterValue, recipientValue, app.config.swap.currency, app.config.swap.topUpTolerance)
+
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
 		return
 	}
 
 	// Create the swap
-	err = app.models.Swaps.Insert(swap)
+	err = app.models.Swaps.Create(swap, ownerUser.ID, input.Note)
 	if err != nil {
-		app.serverErrorLogResponse(w, r, err)
+		switch {
+		case errors.Is(err, data.ErrInstrumentAlreadySwapped), errors.Is(err, data.ErrInstrumentDisputed):
+			app.badRequestResponse(w, r, err)
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.badRequestResponse(w, r, errors.New("instrument not found"))
+		default:
+			app.serverErrorLogResponse(w, r, err)
+		}
 		return
 	}
 
@@ -167,65 +280,92 @@ func (app *application) createSwapHandler(w http.ResponseWriter, r *http.Request
 
 }
 
-// isValidInputSwapStatus checks whether the given status string is a uniform value that is accepted by the application.
-func isValidInputSwapStatus(status string) bool {
-	if status != StatusSwapAccepted && status != StatusSwapRejected && status != StatusSwapEnded {
-		return false
+// swapInstrumentIDs merges the single and the multiple instrument ids given for one side of a swap.
+func swapInstrumentIDs(id int64, ids []int64) []int64 {
+	if id == 0 {
+		return ids
 	}
-	return true
+	return append([]int64{id}, ids...)
+}
+
+// sameSwapInstruments checks whether the given instrument id lists contain the same instruments regardless of their order.
+func sameSwapInstruments(a []int64, b []int64) bool {
+	a, b = slices.Clone(a), slices.Clone(b)
+	slices.Sort(a)
+	slices.Sort(b)
+	return slices.Equal(a, b)
+}
+
+// swapSideDetails retrieves the distinct owner user ids and the total estimated value of the given instruments of one side of a swap.
+// Returns ErrRecordNotFound if any of the instruments does not exist.
+func (app *application) swapSideDetails(ids []int64) (ownerIDs []int64, estimatedValue int64, err error) {
+	ownerIDs = []int64{}
+	for _, id := range ids {
+		instrument, err := app.models.Instruments.Get(id)
+		if err != nil {
+			return nil, 0, err
+		}
+		if !slices.Contains(ownerIDs, instrument.OwnerUserID) {
+			ownerIDs = append(ownerIDs, instrument.OwnerUserID)
+		}
+		estimatedValue += instrument.EstimatedValue
+	}
+	return ownerIDs, estimatedValue, nil
 }
 
-// validateSwapStatusTransition checks whether the requested state transition is possible from the current state.
-// In case of any problem returns a corresponding error, otherwise returns nil.
-func validateSwapStatusTransition(currentIsAccepted, currentIsRejected, currentIsEnded bool, requestedSwapStatus string) error {
-	if requestedSwapStatus == StatusSwapRejected && (currentIsAccepted || currentIsRejected || currentIsEnded) {
-		return errors.New("swap is not rejectable")
+// swapOwnerIDs retrieves the owner user ids of the requester and the recipient instruments of the given swap.
+// All instruments of one side of a swap belong to the same user, so the first instrument of each side is checked.
+// Returns ErrRecordNotFound if any of the instruments does not exist.
+func (app *application) swapOwnerIDs(swap *data.Swap) (requesterUserID int64, recipientUserID int64, err error) {
+	requesterInstrument, err := app.models.Instruments.Get(swap.RequesterInstrumentID)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	recipientInstrument, err := app.models.Instruments.Get(swap.RecipientInstrumentID)
+	if err != nil {
+		return 0, 0, err
 	}
-	if requestedSwapStatus == StatusSwapAccepted && (currentIsAccepted || currentIsRejected || currentIsEnded) {
-		return errors.New("swap is not acceptable")
+
+	return requesterInstrument.OwnerUserID, recipientInstrument.OwnerUserID, nil
+}
+
+// isValidInputSwapStatus checks whether the given status string is a uniform value that is accepted by the application.
+func isValidInputSwapStatus(status string) bool {
+	if status != data.SwapStatusAccepted && status != data.SwapStatusRejected && status != data.SwapStatusEnded && status != data.SwapStatusCancelled {
+		return false
 	}
-	return nil
+	return true
 }
 
 // isValidSwapStatusTransitionUser validate whether the authorized user is permitted to perform the requested status transition.
 func isValidSwapStatusTransitionUser(requestedSwapStatus string, requesterUserID int64, recipientUserID int64, authUserID int64) bool {
-	if requestedSwapStatus == StatusSwapEnded {
+	if requestedSwapStatus == data.SwapStatusEnded {
 		if requesterUserID == authUserID || recipientUserID == authUserID {
 			return true
 		}
 	}
-	if requestedSwapStatus == StatusSwapAccepted || requestedSwapStatus == StatusSwapRejected {
+	if requestedSwapStatus == data.SwapStatusAccepted || requestedSwapStatus == data.SwapStatusRejected {
 		if recipientUserID == authUserID {
 			return true
 		}
 	}
-	return false
-}
-
-// doSwapStatusTransition performs the swap status transition.
-// Due to pointer semantics, the function mutates the given swap.
-func doSwapStatusTransition(swap *data.Swap, requestedSwapStatus string) {
-	now := time.Now()
-	if requestedSwapStatus == StatusSwapAccepted {
-		swap.IsAccepted = true
-		swap.AcceptedAt = &now
-	} else if requestedSwapStatus == StatusSwapRejected {
-		swap.IsRejected = true
-		swap.IsEnded = true
-		swap.RejectedAt = &now
-		swap.EndedAt = &now
-	} else if requestedSwapStatus == StatusSwapEnded {
-		swap.IsEnded = true
-		swap.EndedAt = &now
+	if requestedSwapStatus == data.SwapStatusCancelled {
+		if requesterUserID == authUserID {
+			return true
+		}
 	}
+	return false
 }
 
 // updateSwapStatusHandler handles the possible status changes of the swaps.
+// The If-Match header is checked against the ETag of the swap.
 func (app *application) updateSwapStatusHandler(w http.ResponseWriter, r *http.Request) {
 	authUser := app.contextGetUser(r)
 
 	var input struct {
 		Status string `json:"status"`
+		Note   string `json:"note"`
 	}
 
 	err := app.readJSON(w, r, &input)
@@ -238,6 +378,12 @@ func (app *application) updateSwapStatusHandler(w http.ResponseWriter, r *http.R
 		return
 	}
 
+	v := validator.New()
+	if data.ValidateSwapEventNote(v, input.Note); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
 	swapID, err := app.extractIDParam(r)
 	if err != nil {
 		app.notFoundResponse(w, r)
@@ -256,55 +402,223 @@ func (app *application) updateSwapStatusHandler(w http.ResponseWriter, r *http.R
 		}
 	}
 
-	if err := validateSwapStatusTransition(swap.IsAccepted, swap.IsRejected, swap.IsEnded, input.Status); err != nil {
-		app.badRequestResponse(w, r, err)
+	requesterUserID, recipientUserID, err := app.swapOwnerIDs(swap)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.badRequestResponse(w, r, errors.New("swap instrument not found"))
+		default:
+			app.serverErrorLogResponse(w, r, err)
+		}
 		return
 	}
 
-	recipientInstrument, err := app.models.Instruments.Get(swap.RecipientInstrumentID)
+	if !isValidSwapStatusTransitionUser(input.Status, requesterUserID, recipientUserID, authUser.ID) {
+		app.forbiddenResponse(w, r)
+		return
+	}
+
+	if !app.checkIfMatch(w, r, etag(int64(swap.Version))) {
+		return
+	}
+
+	swap, err = app.models.Swaps.Transition(swap.ID, swap.Version, input.Status, authUser.ID, input.Note)
 	if err != nil {
 		switch {
 		case errors.Is(err, data.ErrRecordNotFound):
-			app.badRequestResponse(w, r, errors.New("recipient instrument not found"))
+			app.notFoundResponse(w, r)
+		case errors.Is(err, data.ErrEditConflict):
+			app.versionConflictResponse(w, r)
+		case errors.Is(err, data.ErrInvalidSwapStatusTransition):
+			app.badRequestResponse(w, r, err)
 		default:
 			app.serverErrorLogResponse(w, r, err)
 		}
 		return
 	}
 
-	requesterInstrument, err := app.models.Instruments.Get(swap.RequesterInstrumentID)
+	headers := make(http.Header)
+	headers.Set("ETag", etag(int64(swap.Version)))
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"swap": swap}, headers)
+	if err != nil {
+		app.serverErrorLogResponse(w, r, err)
+		return
+	}
+
+}
+
+// listSwapEventsHandler handles listing the history of a swap with the given id.
+// Only the owners of the instruments of the swap can see its history.
+func (app *application) listSwapEventsHandler(w http.ResponseWriter, r *http.Request) {
+	authUser := app.contextGetUser(r)
+
+	swapID, err := app.extractIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	swap, err := app.models.Swaps.Get(swapID)
 	if err != nil {
 		switch {
 		case errors.Is(err, data.ErrRecordNotFound):
-			app.badRequestResponse(w, r, errors.New("recipient instrument not found"))
+			app.notFoundResponse(w, r)
 		default:
 			app.serverErrorLogResponse(w, r, err)
 		}
 		return
 	}
 
-	if !isValidSwapStatusTransitionUser(input.Status, requesterInstrument.OwnerUserID, recipientInstrument.OwnerUserID, authUser.ID) {
-		app.forbiddenResponse(w, r)
+	requesterUserID, recipientUserID, err := app.swapOwnerIDs(swap)
+	if err != nil {
+		app.serverErrorLogResponse(w, r, err)
+		return
+	}
+
+	if authUser.ID != requesterUserID && authUser.ID != recipientUserID {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	events, err := app.models.SwapEvents.GetAllForSwap(swap.ID)
+	if err != nil {
+		app.serverErrorLogResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"events": events}, nil)
+	if err != nil {
+		app.serverErrorLogResponse(w, r, err)
+		return
+	}
+}
+
+// createCounterOfferHandler handles the creation of a counter-offer for the swap with the given id.
+// Only the recipient of a pending swap can make a counter-offer. The counter swap is created with swapped roles,
+// the original recipient becomes its requester, so the original requester can accept or reject it.
+func (app *application) createCounterOfferHandler(w http.ResponseWriter, r *http.Request) {
+	authUser := app.contextGetUser(r)
+
+	swapID, err := app.extractIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
 		return
 	}
 
-	doSwapStatusTransition(swap, input.Status)
+	var input struct {
+		RequesterInstrumentID  int64           `json:"requester_instrument_id"`
+		RecipientInstrumentID  int64           `json:"recipient_instrument_id"`
+		RequesterInstrumentIDs []int64         `json:"requester_instrument_ids"`
+		RecipientInstrumentIDs []int64         `json:"recipient_instrument_ids"`
+		TopUp                  *data.SwapTopUp `json:"top_up"`
+		StartsAt               *time.Time      `json:"starts_at"`
+		ReturnBy               *time.Time      `json:"return_by"`
+		Note                   string          `json:"note"`
+	}
 
-	err = app.models.Swaps.Update(swap)
+	err = app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	counter := data.NewSwap(
+		swapInstrumentIDs(input.RequesterInstrumentID, input.RequesterInstrumentIDs),
+		swapInstrumentIDs(input.RecipientInstrumentID, input.RecipientInstrumentIDs),
+	)
+	counter.TopUp = input.TopUp
+	counter.StartsAt = input.StartsAt
+	counter.ReturnBy = input.ReturnBy
+
+	v := validator.New()
+
+	data.ValidateSwap(v, counter)
+	data.ValidateSwapEventNote(v, input.Note)
+
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	original, err := app.models.Swaps.Get(swapID)
 	if err != nil {
 		switch {
-		case errors.Is(err, data.ErrEditConflict):
-			app.editConflictResponse(w, r)
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
 		default:
 			app.serverErrorLogResponse(w, r, err)
 		}
 		return
 	}
 
-	err = app.writeJSON(w, http.StatusOK, envelope{"swap": swap}, nil)
+	originalRequesterUserID, originalRecipientUserID, err := app.swapOwnerIDs(original)
 	if err != nil {
 		app.serverErrorLogResponse(w, r, err)
 		return
 	}
 
+	if authUser.ID != originalRecipientUserID {
+		app.forbiddenResponse(w, r)
+		return
+	}
+
+	requesterUserIDs, requesterValue, err := app.swapSideDetails(counter.RequesterInstrumentIDs)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.badRequestResponse(w, r, errors.New("requester instrument not found"))
+		default:
+			app.serverErrorLogResponse(w, r, err)
+		}
+		return
+	}
+
+	recipientUserIDs, recipientValue, err := app.swapSideDetails(counter.RecipientInstrumentIDs)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.badRequestResponse(w, r, errors.New("recipient instrument not found"))
+		default:
+			app.serverErrorLogResponse(w, r, err)
+		}
+		return
+	}
+
+	v.Check(slices.Equal(requesterUserIDs, []int64{authUser.ID}), "requester_instrument_id", "must belong to the authenticated user")
+	v.Check(slices.Equal(recipientUserIDs, []int64{originalRequesterUserID}), "recipient_instrument_id", "must belong to the requester of the original swap")
+	v.Check(
+		!sameSwapInstruments(counter.RequesterInstrumentIDs, original.RecipientInstrumentIDs) ||
+			!sameSwapInstruments(counter.RecipientInstrumentIDs, original.RequesterInstrumentIDs),
+		"requester_instrument_id",
+		"counter-offer must differ from the original swap",
+	)
+	data.ValidateSwapTopUp(v, counter, requesterValue, recipientValue, app.config.swap.currency, app.config.swap.topUpTolerance)
+
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = app.models.Swaps.CounterOffer(original.ID, counter, authUser.ID, input.Note)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		case errors.Is(err, data.ErrInvalidSwapStatusTransition), errors.Is(err, data.ErrInstrumentAlreadySwapped), errors.Is(err, data.ErrInstrumentDisputed):
+			app.badRequestResponse(w, r, err)
+		default:
+			app.serverErrorLogResponse(w, r, err)
+		}
+		return
+	}
+
+	headers := make(http.Header)
+	headers.Set("Location", fmt.Sprintf("/v1/swaps/%d", counter.ID))
+
+	err = app.writeJSON(w, http.StatusCreated, envelope{"swap": counter}, headers)
+	if err != nil {
+		app.serverErrorLogResponse(w, r, err)
+		return
+	}
 }
@@ -10,6 +10,8 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"reflect"
+	"slices"
+	"strings"
 	"testing"
 	"time"
 
@@ -40,6 +42,8 @@ func TestListSwapsHandler(t *testing.T) {
 	type testCase struct {
 		name               string
 		user               *data.User
+		query              string
+		swaps              []*data.Swap
 		shouldCheckBody    bool
 		expectedStatusCode int
 		expectedTestSwaps  []*data.Swap
@@ -49,10 +53,74 @@ func TestListSwapsHandler(t *testing.T) {
 		{
 			name:               "happy path",
 			user:               &data.User{ID: 1},
+			swaps:              testSwaps,
 			shouldCheckBody:    true,
 			expectedStatusCode: http.StatusOK,
 			expectedTestSwaps:  testSwaps,
 		},
+		{
+			name:               "swaps of a status",
+			user:               &data.User{ID: 1},
+			query:              "?status=overdue",
+			swaps:              testSwaps,
+			shouldCheckBody:    true,
+			expectedStatusCode: http.StatusOK,
+			expectedTestSwaps:  testSwaps,
+		},
+		{
+			name:               "swaps of an instrument",
+			user:               &data.User{ID: 1},
+			query:              "?instrument_id=4&role=requester&sort=-created_at&page=1&page_size=10",
+			swaps:              testSwaps,
+			shouldCheckBody:    true,
+			expectedStatusCode: http.StatusOK,
+			expectedTestSwaps:  testSwaps[1:],
+		},
+		{
+			name:               "swaps created in a time range",
+			user:               &data.User{ID: 1},
+			query:              "?created_from=2000-01-01T00:00:00Z&created_to=2001-01-01T00:00:00Z",
+			swaps:              testSwaps,
+			shouldCheckBody:    true,
+			expectedStatusCode: http.StatusOK,
+			expectedTestSwaps:  []*data.Swap{},
+		},
+		{
+			name:               "non valid role",
+			user:               &data.User{ID: 1},
+			query:              "?role=owner",
+			swaps:              testSwaps,
+			shouldCheckBody:    false,
+			expectedStatusCode: http.StatusUnprocessableEntity,
+			expectedTestSwaps:  nil,
+		},
+		{
+			name:               "non valid time range",
+			user:               &data.User{ID: 1},
+			query:              "?created_from=2001-01-01T00:00:00Z&created_to=2000-01-01T00:00:00Z",
+			swaps:              testSwaps,
+			shouldCheckBody:    false,
+			expectedStatusCode: http.StatusUnprocessableEntity,
+			expectedTestSwaps:  nil,
+		},
+		{
+			name:               "non valid sort",
+			user:               &data.User{ID: 1},
+			query:              "?sort=requester_instrument_id",
+			swaps:              testSwaps,
+			shouldCheckBody:    false,
+			expectedStatusCode: http.StatusUnprocessableEntity,
+			expectedTestSwaps:  nil,
+		},
+		{
+			name:               "non valid status",
+			user:               &data.User{ID: 1},
+			query:              "?status=lost",
+			swaps:              testSwaps,
+			shouldCheckBody:    false,
+			expectedStatusCode: http.StatusUnprocessableEntity,
+			expectedTestSwaps:  nil,
+		},
 		{
 			name:               "non exitent",
 			user:               &data.User{ID: 1},
@@ -66,14 +134,14 @@ func TestListSwapsHandler(t *testing.T) {
 		t.Run(tc.name, func(t *testing.T) {
 
 			rr := httptest.NewRecorder()
-			req, err := http.NewRequest(http.MethodGet, "/", nil)
+			req, err := http.NewRequest(http.MethodGet, "/"+tc.query, nil)
 			if err != nil {
 				t.Fatal(err)
 			}
 
 			app := &application{
 				logger: slog.New(slog.NewTextHandler(io.Discard, nil)),
-				models: data.Models{Swaps: mocks.NewSwapModelMock(tc.expectedTestSwaps)},
+				models: data.Models{Swaps: mocks.NewSwapModelMock(tc.swaps)},
 			}
 
 			req = app.contextSetUser(req, tc.user)
@@ -147,9 +215,21 @@ func TestShowSwapHandler(t *testing.T) {
 		},
 	}
 
+	testInstruments := []*data.Instrument{
+		{ID: 1, Name: "TB303", Manufacturer: "Roland", OwnerUserID: 1},
+		{ID: 2, Name: "TR909", Manufacturer: "Roland", OwnerUserID: 2},
+	}
+
+	expandedSwap := *testSwaps[0]
+	expandedSwap.RequesterInstrumentIDs = []int64{1}
+	expandedSwap.RecipientInstrumentIDs = []int64{2}
+	expandedSwap.RequesterInstruments = []*data.SwapInstrument{{ID: 1, Name: "TB303", Manufacturer: "Roland", OwnerUserID: 1}}
+	expandedSwap.RecipientInstruments = []*data.SwapInstrument{{ID: 2, Name: "TR909", Manufacturer: "Roland", OwnerUserID: 2}}
+
 	type testCase struct {
 		name               string
 		pathParam          string
+		query              string
 		shouldCheckBody    bool
 		baseSwaps          []*data.Swap
 		user               data.User
@@ -167,6 +247,35 @@ func TestShowSwapHandler(t *testing.T) {
 			expectedStatusCode: http.StatusOK,
 			expectedTestSwap:   testSwaps[0],
 		},
+		{
+			name:               "happy path - recipient",
+			pathParam:          "1",
+			shouldCheckBody:    true,
+			baseSwaps:          testSwaps,
+			user:               data.User{ID: 2, Name: "Test User", Email: "testuser@example.com"},
+			expectedStatusCode: http.StatusOK,
+			expectedTestSwap:   testSwaps[0],
+		},
+		{
+			name:               "expanded instruments",
+			pathParam:          "1",
+			query:              "?expand=instruments",
+			shouldCheckBody:    true,
+			baseSwaps:          testSwaps,
+			user:               data.User{ID: 1, Name: "Test User", Email: "testuser@example.com"},
+			expectedStatusCode: http.StatusOK,
+			expectedTestSwap:   &expandedSwap,
+		},
+		{
+			name:               "non valid expand",
+			pathParam:          "1",
+			query:              "?expand=owners",
+			shouldCheckBody:    false,
+			baseSwaps:          testSwaps,
+			user:               data.User{ID: 1, Name: "Test User", Email: "testuser@example.com"},
+			expectedStatusCode: http.StatusUnprocessableEntity,
+			expectedTestSwap:   nil,
+		},
 		{
 			name:               "non numberic path param",
 			pathParam:          "non numberic",
@@ -189,7 +298,7 @@ func TestShowSwapHandler(t *testing.T) {
 			name:               "show swap for a different user",
 			pathParam:          "1",
 			shouldCheckBody:    false,
-			baseSwaps:          []*data.Swap{},
+			baseSwaps:          testSwaps,
 			user:               data.User{ID: 12, Name: "Test User", Email: "testuser@example.com"},
 			expectedStatusCode: http.StatusNotFound,
 			expectedTestSwap:   nil,
@@ -199,9 +308,12 @@ func TestShowSwapHandler(t *testing.T) {
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
 
+			swaps := mocks.NewSwapModelMock(tc.baseSwaps)
+			swaps.SetInstruments(testInstruments...)
+
 			app := &application{
 				logger: slog.New(slog.NewTextHandler(io.Discard, nil)),
-				models: data.Models{Swaps: mocks.NewSwapModelMock(tc.baseSwaps)},
+				models: data.Models{Swaps: swaps},
 			}
 
 			setUser := func(next http.HandlerFunc) http.HandlerFunc {
@@ -219,7 +331,7 @@ func TestShowSwapHandler(t *testing.T) {
 			ts := httptest.NewServer(mux)
 			defer ts.Close()
 
-			path := fmt.Sprintf("%s/%s", ts.URL, tc.pathParam)
+			path := fmt.Sprintf("%s/%s%s", ts.URL, tc.pathParam, tc.query)
 
 			req, err := http.NewRequest("GET", path, nil)
 			if err != nil {
@@ -287,15 +399,27 @@ func TestShowSwapHandler(t *testing.T) {
 func TestCreateSwapHandler(t *testing.T) {
 
 	type inputSwap struct {
-		RequesterInstrumentID int64 `json:"requester_instrument_id"`
-		RecipientInstrumentID int64 `json:"recipient_instrument_id"`
+		RequesterInstrumentID  int64           `json:"requester_instrument_id,omitempty"`
+		RecipientInstrumentID  int64           `json:"recipient_instrument_id,omitempty"`
+		RequesterInstrumentIDs []int64         `json:"requester_instrument_ids,omitempty"`
+		RecipientInstrumentIDs []int64         `json:"recipient_instrument_ids,omitempty"`
+		TopUp                  *data.SwapTopUp `json:"top_up,omitempty"`
+		StartsAt               *time.Time      `json:"starts_at,omitempty"`
+		ReturnBy               *time.Time      `json:"return_by,omitempty"`
 	}
 
+	startsAt := time.Now().Add(24 * time.Hour).UTC().Truncate(time.Second)
+	returnBy := time.Now().Add(30 * 24 * time.Hour).UTC().Truncate(time.Second)
+	pastReturnBy := time.Now().Add(-24 * time.Hour).UTC().Truncate(time.Second)
+
 	type testCase struct {
 		name               string
 		input              inputSwap
 		reqUser            data.User
 		instruments        []*data.Instrument
+		swaps              []*data.Swap
+		disputed           []int64
+		topUpTolerance     float64
 		expectedStatusCode int
 		shouldCheckBody    bool
 	}
@@ -329,6 +453,34 @@ func TestCreateSwapHandler(t *testing.T) {
 			OwnerUserID:     1,
 			Version:         1,
 		},
+		{
+			ID:              3,
+			CreatedAt:       time.Now().UTC(),
+			Name:            "Big Muff",
+			Manufacturer:    "Electro-Harmonix",
+			ManufactureYear: 1990,
+			Type:            "guitar",
+			EstimatedValue:  10000,
+			Condition:       "used",
+			Description:     "A fuzz pedal manufactured by Electro-Harmonix.",
+			FamousOwners:    []string{"Dinosaur Jr."},
+			OwnerUserID:     1,
+			Version:         1,
+		},
+		{
+			ID:              4,
+			CreatedAt:       time.Now().UTC(),
+			Name:            "Juno-106",
+			Manufacturer:    "Roland",
+			ManufactureYear: 1984,
+			Type:            "synthesizer",
+			EstimatedValue:  150000,
+			Condition:       "used",
+			Description:     "A polyphonic synth manufactured by Roland.",
+			FamousOwners:    []string{"Boards of Canada"},
+			OwnerUserID:     2,
+			Version:         1,
+		},
 	}
 
 	testCases := []testCase{
@@ -377,277 +529,278 @@ func TestCreateSwapHandler(t *testing.T) {
 			shouldCheckBody:    false,
 		},
 		{
-			name: "invalid RecipientInstrumentID",
+			name: "requester instrument already in a swap",
 			input: inputSwap{
 				RequesterInstrumentID: 1,
-				RecipientInstrumentID: -2,
+				RecipientInstrumentID: 2,
 			},
 			reqUser:            data.User{ID: 1, Name: "Test User", Email: "testuser@example.com"},
 			instruments:        testInstruments,
-			expectedStatusCode: http.StatusUnprocessableEntity,
+			swaps:              []*data.Swap{{ID: 1, RequesterInstrumentID: 3, RecipientInstrumentID: 1}},
+			expectedStatusCode: http.StatusBadRequest,
 			shouldCheckBody:    false,
 		},
-	}
-
-	for _, tc := range testCases {
-		t.Run(tc.name, func(t *testing.T) {
-
-			app := &application{
-				logger: slog.New(slog.NewTextHandler(io.Discard, nil)),
-				models: data.Models{
-					Swaps:       mocks.NewSwapModelMock(nil),
-					Instruments: mocks.NewNonEmptyInstrumentModelMock(tc.instruments),
-				},
-			}
-
-			setUser := func(next http.HandlerFunc) http.HandlerFunc {
-				return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-
-					r = app.contextSetUser(r, &tc.reqUser)
-
-					next.ServeHTTP(w, r)
-				})
-			}
-
-			mux := http.NewServeMux()
-			mux.HandleFunc("POST /", setUser(app.createSwapHandler))
-
-			ts := httptest.NewServer(mux)
-			defer ts.Close()
-
-			path := fmt.Sprintf("%s/", ts.URL)
-
-			bs, err := json.Marshal(tc.input)
-			if err != nil {
-				t.Fatal(err)
-			}
-
-			req, err := http.NewRequest("POST", path, bytes.NewBuffer(bs))
-			if err != nil {
-				t.Fatal(err)
-			}
-
-			client := &http.Client{}
-			resp, err := client.Do(req)
-			if err != nil {
-				log.Fatal(err)
-			}
-
-			if tc.expectedStatusCode != resp.StatusCode {
-				t.Errorf(`expected status code %d, got %d`, tc.expectedStatusCode, resp.StatusCode)
-			}
-
-			if tc.shouldCheckBody {
-				defer func() {
-					err := resp.Body.Close()
-					if err != nil {
-						t.Fatal(err)
-					}
-				}()
-
-				body, err := io.ReadAll(resp.Body)
-				if err != nil {
-					t.Fatal(err)
-				}
-
-				respEnvelope := make(envelope)
-
-				err = json.Unmarshal(body, &respEnvelope)
-				if err != nil {
-					t.Fatal(err)
-				}
-
-				respAnySlice, ok := respEnvelope["swap"]
-				if !ok {
-					t.Fatal(`the response does not contain enveloped swaps`)
-				}
-				buf := new(bytes.Buffer)
-				err = json.NewEncoder(buf).Encode(respAnySlice)
-				if err != nil {
-					t.Fatal(err)
-				}
-
-				var swap *data.Swap
-
-				err = json.NewDecoder(buf).Decode(&swap)
-				if err != nil {
-					t.Fatal(err)
-				}
-
-				if tc.input.RecipientInstrumentID != swap.RecipientInstrumentID {
-					t.Errorf(`Expected RecipientInstrumentID %d, got %d`, tc.input.RecipientInstrumentID, swap.RecipientInstrumentID)
-				}
-
-				if tc.input.RequesterInstrumentID != swap.RequesterInstrumentID {
-					t.Errorf(`Expected RequesterInstrumentID %d, got %d`, tc.input.RequesterInstrumentID, swap.RequesterInstrumentID)
-				}
-
-			}
-		})
-	}
-
-}
-
-// TestUpdateSwapStatusHandler implements unit tests for updateSwapStatusHandler.
-func TestUpdateSwapStatusHandler(t *testing.T) {
-
-	type inputBodyType struct {
-		Status string `json:"status"`
-	}
-
-	type testCase struct {
-		name               string
-		inputBody          inputBodyType
-		pathParam          string
-		swap               data.Swap
-		instrumentReq      data.Instrument
-		instrumentRec      data.Instrument
-		reqUser            data.User
-		expectedStatusCode int
-	}
-
-	testCases := []testCase{
 		{
-			name: "happy path - accept",
-			inputBody: inputBodyType{
-				Status: "accepted",
+			name: "instruments of ended swaps",
+			input: inputSwap{
+				RequesterInstrumentID: 1,
+				RecipientInstrumentID: 2,
 			},
-			pathParam:          "99",
-			swap:               data.Swap{ID: 99, RequesterInstrumentID: 1, RecipientInstrumentID: 2},
-			instrumentReq:      data.Instrument{ID: 1, OwnerUserID: 10},
-			instrumentRec:      data.Instrument{ID: 2, OwnerUserID: 20},
-			reqUser:            data.User{ID: 20, Name: "Test User", Email: "testuser@example.com"},
-			expectedStatusCode: http.StatusOK,
+			reqUser:     data.User{ID: 1, Name: "Test User", Email: "testuser@example.com"},
+			instruments: testInstruments,
+			swaps: []*data.Swap{
+				{ID: 1, RequesterInstrumentID: 1, RecipientInstrumentID: 4, IsAccepted: true, IsEnded: true},
+				{ID: 2, RequesterInstrumentID: 3, RecipientInstrumentID: 2, IsRejected: true, IsEnded: true},
+			},
+			expectedStatusCode: http.StatusCreated,
+			shouldCheckBody:    true,
 		},
 		{
-			name: "happy path - reject",
-			inputBody: inputBodyType{
-				Status: "rejected",
+			name: "recipient instrument already in a swap",
+			input: inputSwap{
+				RequesterInstrumentID: 1,
+				RecipientInstrumentID: 2,
 			},
-			pathParam:          "99",
-			swap:               data.Swap{ID: 99, RequesterInstrumentID: 1, RecipientInstrumentID: 2},
-			instrumentReq:      data.Instrument{ID: 1, OwnerUserID: 10},
-			instrumentRec:      data.Instrument{ID: 2, OwnerUserID: 20},
-			reqUser:            data.User{ID: 20, Name: "Test User", Email: "testuser@example.com"},
-			expectedStatusCode: http.StatusOK,
+			reqUser:            data.User{ID: 1, Name: "Test User", Email: "testuser@example.com"},
+			instruments:        testInstruments,
+			swaps:              []*data.Swap{{ID: 1, RequesterInstrumentID: 2, RecipientInstrumentID: 3}},
+			expectedStatusCode: http.StatusBadRequest,
+			shouldCheckBody:    false,
 		},
 		{
-			name: "happy path - end",
-			inputBody: inputBodyType{
-				Status: "ended",
+			name: "bundle swap",
+			input: inputSwap{
+				RequesterInstrumentIDs: []int64{1, 3},
+				RecipientInstrumentID:  2,
 			},
-			pathParam:          "99",
-			swap:               data.Swap{ID: 99, RequesterInstrumentID: 1, RecipientInstrumentID: 2},
-			instrumentReq:      data.Instrument{ID: 1, OwnerUserID: 10},
-			instrumentRec:      data.Instrument{ID: 2, OwnerUserID: 20},
-			reqUser:            data.User{ID: 20, Name: "Test User", Email: "testuser@example.com"},
-			expectedStatusCode: http.StatusOK,
+			reqUser:            data.User{ID: 1, Name: "Test User", Email: "testuser@example.com"},
+			instruments:        testInstruments,
+			expectedStatusCode: http.StatusCreated,
+			shouldCheckBody:    true,
 		},
 		{
-			name: "invalid status param",
-			inputBody: inputBodyType{
-				Status: "invalid",
+			name: "bundle swap with both single and multiple instrument ids",
+			input: inputSwap{
+				RequesterInstrumentID:  1,
+				RequesterInstrumentIDs: []int64{3},
+				RecipientInstrumentID:  2,
 			},
-			pathParam:          "99",
-			swap:               data.Swap{ID: 99, RequesterInstrumentID: 1, RecipientInstrumentID: 2},
-			instrumentReq:      data.Instrument{ID: 1, OwnerUserID: 10},
-			instrumentRec:      data.Instrument{ID: 2, OwnerUserID: 20},
-			reqUser:            data.User{ID: 20, Name: "Test User", Email: "testuser@example.com"},
-			expectedStatusCode: http.StatusBadRequest,
+			reqUser:            data.User{ID: 1, Name: "Test User", Email: "testuser@example.com"},
+			instruments:        testInstruments,
+			expectedStatusCode: http.StatusCreated,
+			shouldCheckBody:    true,
 		},
 		{
-			name: "non valid path param",
-			inputBody: inputBodyType{
-				Status: "ended",
+			name: "bundle swap with an instrument of another user",
+			input: inputSwap{
+				RequesterInstrumentIDs: []int64{1, 4},
+				RecipientInstrumentID:  2,
 			},
-			pathParam:          "nonvalid",
-			swap:               data.Swap{ID: 99, RequesterInstrumentID: 1, RecipientInstrumentID: 2},
-			instrumentReq:      data.Instrument{ID: 1, OwnerUserID: 10},
-			instrumentRec:      data.Instrument{ID: 2, OwnerUserID: 20},
-			reqUser:            data.User{ID: 20, Name: "Test User", Email: "testuser@example.com"},
-			expectedStatusCode: http.StatusNotFound,
+			reqUser:            data.User{ID: 1, Name: "Test User", Email: "testuser@example.com"},
+			instruments:        testInstruments,
+			expectedStatusCode: http.StatusForbidden,
+			shouldCheckBody:    false,
 		},
 		{
-			name: "non existing swap",
-			inputBody: inputBodyType{
-				Status: "ended",
+			name: "bundle swap with recipient instruments of different users",
+			input: inputSwap{
+				RequesterInstrumentID:  1,
+				RecipientInstrumentIDs: []int64{2, 4},
 			},
-			pathParam:          "999",
-			swap:               data.Swap{ID: 99, RequesterInstrumentID: 1, RecipientInstrumentID: 2},
-			instrumentReq:      data.Instrument{ID: 1, OwnerUserID: 10},
-			instrumentRec:      data.Instrument{ID: 2, OwnerUserID: 20},
-			reqUser:            data.User{ID: 20, Name: "Test User", Email: "testuser@example.com"},
-			expectedStatusCode: http.StatusNotFound,
+			reqUser:            data.User{ID: 1, Name: "Test User", Email: "testuser@example.com"},
+			instruments:        testInstruments,
+			expectedStatusCode: http.StatusUnprocessableEntity,
+			shouldCheckBody:    false,
 		},
 		{
-			name: "non valid status change",
-			inputBody: inputBodyType{
-				Status: "rejected",
+			name: "bundle swap with duplicate instruments",
+			input: inputSwap{
+				RequesterInstrumentIDs: []int64{1, 1},
+				RecipientInstrumentID:  2,
 			},
-			pathParam:          "99",
-			swap:               data.Swap{ID: 99, RequesterInstrumentID: 1, RecipientInstrumentID: 2, IsAccepted: true},
-			instrumentReq:      data.Instrument{ID: 1, OwnerUserID: 10},
-			instrumentRec:      data.Instrument{ID: 2, OwnerUserID: 20},
-			reqUser:            data.User{ID: 20, Name: "Test User", Email: "testuser@example.com"},
-			expectedStatusCode: http.StatusBadRequest,
+			reqUser:            data.User{ID: 1, Name: "Test User", Email: "testuser@example.com"},
+			instruments:        testInstruments,
+			expectedStatusCode: http.StatusUnprocessableEntity,
+			shouldCheckBody:    false,
 		},
 		{
-			name: "non existent instrumentReq",
-			inputBody: inputBodyType{
-				Status: "accepted",
+			name: "bundle swap with an instrument already in a swap",
+			input: inputSwap{
+				RequesterInstrumentIDs: []int64{1, 3},
+				RecipientInstrumentID:  2,
 			},
-			pathParam:          "99",
-			swap:               data.Swap{ID: 99, RequesterInstrumentID: 1, RecipientInstrumentID: 2},
-			instrumentReq:      data.Instrument{ID: 199, OwnerUserID: 10},
-			instrumentRec:      data.Instrument{ID: 2, OwnerUserID: 20},
-			reqUser:            data.User{ID: 20, Name: "Test User", Email: "testuser@example.com"},
+			reqUser:            data.User{ID: 1, Name: "Test User", Email: "testuser@example.com"},
+			instruments:        testInstruments,
+			swaps:              []*data.Swap{{ID: 1, RequesterInstrumentID: 4, RecipientInstrumentID: 3}},
 			expectedStatusCode: http.StatusBadRequest,
+			shouldCheckBody:    false,
 		},
 		{
-			name: "non existent instrumentRec",
-			inputBody: inputBodyType{
-				Status: "accepted",
+			name: "bundle swap with top-up",
+			input: inputSwap{
+				RequesterInstrumentIDs: []int64{1, 3},
+				RecipientInstrumentID:  2,
+				TopUp:                  &data.SwapTopUp{Amount: 10000, Currency: "EUR", Payer: data.SwapSideRecipient},
 			},
-			pathParam:          "99",
-			swap:               data.Swap{ID: 99, RequesterInstrumentID: 1, RecipientInstrumentID: 2},
-			instrumentReq:      data.Instrument{ID: 1, OwnerUserID: 10},
-			instrumentRec:      data.Instrument{ID: 299, OwnerUserID: 20},
-			reqUser:            data.User{ID: 20, Name: "Test User", Email: "testuser@example.com"},
+			reqUser:            data.User{ID: 1, Name: "Test User", Email: "testuser@example.com"},
+			instruments:        testInstruments,
+			expectedStatusCode: http.StatusCreated,
+			shouldCheckBody:    true,
+		},
+		{
+			name: "top-up within tolerance",
+			input: inputSwap{
+				RequesterInstrumentID: 1,
+				RecipientInstrumentID: 4,
+				TopUp:                 &data.SwapTopUp{Amount: 45000, Currency: "EUR", Payer: data.SwapSideRequester},
+			},
+			reqUser:            data.User{ID: 1, Name: "Test User", Email: "testuser@example.com"},
+			instruments:        testInstruments,
+			topUpTolerance:     0.1,
+			expectedStatusCode: http.StatusCreated,
+			shouldCheckBody:    true,
+		},
+		{
+			name: "top-up out of tolerance",
+			input: inputSwap{
+				RequesterInstrumentID: 1,
+				RecipientInstrumentID: 4,
+				TopUp:                 &data.SwapTopUp{Amount: 20000, Currency: "EUR", Payer: data.SwapSideRequester},
+			},
+			reqUser:            data.User{ID: 1, Name: "Test User", Email: "testuser@example.com"},
+			instruments:        testInstruments,
+			topUpTolerance:     0.1,
+			expectedStatusCode: http.StatusUnprocessableEntity,
+			shouldCheckBody:    false,
+		},
+		{
+			name: "top-up paid by the more valuable side",
+			input: inputSwap{
+				RequesterInstrumentID: 1,
+				RecipientInstrumentID: 4,
+				TopUp:                 &data.SwapTopUp{Amount: 50000, Currency: "EUR", Payer: data.SwapSideRecipient},
+			},
+			reqUser:            data.User{ID: 1, Name: "Test User", Email: "testuser@example.com"},
+			instruments:        testInstruments,
+			topUpTolerance:     0.1,
+			expectedStatusCode: http.StatusUnprocessableEntity,
+			shouldCheckBody:    false,
+		},
+		{
+			name: "top-up in another currency",
+			input: inputSwap{
+				RequesterInstrumentID: 1,
+				RecipientInstrumentID: 4,
+				TopUp:                 &data.SwapTopUp{Amount: 45000, Currency: "JPY", Payer: data.SwapSideRequester},
+			},
+			reqUser:            data.User{ID: 1, Name: "Test User", Email: "testuser@example.com"},
+			instruments:        testInstruments,
+			topUpTolerance:     0.1,
+			expectedStatusCode: http.StatusUnprocessableEntity,
+			shouldCheckBody:    false,
+		},
+		{
+			name: "invalid top-up",
+			input: inputSwap{
+				RequesterInstrumentID: 1,
+				RecipientInstrumentID: 2,
+				TopUp:                 &data.SwapTopUp{Amount: -1, Currency: "euro", Payer: "nobody"},
+			},
+			reqUser:            data.User{ID: 1, Name: "Test User", Email: "testuser@example.com"},
+			instruments:        testInstruments,
+			expectedStatusCode: http.StatusUnprocessableEntity,
+			shouldCheckBody:    false,
+		},
+		{
+			name: "time-boxed swap",
+			input: inputSwap{
+				RequesterInstrumentID: 1,
+				RecipientInstrumentID: 2,
+				StartsAt:              &startsAt,
+				ReturnBy:              &returnBy,
+			},
+			reqUser:            data.User{ID: 1, Name: "Test User", Email: "testuser@example.com"},
+			instruments:        testInstruments,
+			expectedStatusCode: http.StatusCreated,
+			shouldCheckBody:    true,
+		},
+		{
+			name: "return by in the past",
+			input: inputSwap{
+				RequesterInstrumentID: 1,
+				RecipientInstrumentID: 2,
+				ReturnBy:              &pastReturnBy,
+			},
+			reqUser:            data.User{ID: 1, Name: "Test User", Email: "testuser@example.com"},
+			instruments:        testInstruments,
+			expectedStatusCode: http.StatusUnprocessableEntity,
+			shouldCheckBody:    false,
+		},
+		{
+			name: "return by before starts at",
+			input: inputSwap{
+				RequesterInstrumentID: 1,
+				RecipientInstrumentID: 2,
+				StartsAt:              &returnBy,
+				ReturnBy:              &startsAt,
+			},
+			reqUser:            data.User{ID: 1, Name: "Test User", Email: "testuser@example.com"},
+			instruments:        testInstruments,
+			expectedStatusCode: http.StatusUnprocessableEntity,
+			shouldCheckBody:    false,
+		},
+		{
+			name: "instrument under an open dispute",
+			input: inputSwap{
+				RequesterInstrumentIDs: []int64{1, 3},
+				RecipientInstrumentID:  2,
+			},
+			reqUser:            data.User{ID: 1, Name: "Test User", Email: "testuser@example.com"},
+			instruments:        testInstruments,
+			disputed:           []int64{3},
 			expectedStatusCode: http.StatusBadRequest,
+			shouldCheckBody:    false,
 		},
 		{
-			name: "non valid recipient",
-			inputBody: inputBodyType{
-				Status: "accepted",
+			name: "missing instruments",
+			input: inputSwap{
+				RecipientInstrumentID: 2,
 			},
-			pathParam:          "99",
-			swap:               data.Swap{ID: 99, RequesterInstrumentID: 1, RecipientInstrumentID: 2},
-			instrumentReq:      data.Instrument{ID: 1, OwnerUserID: 10},
-			instrumentRec:      data.Instrument{ID: 2, OwnerUserID: 200},
-			reqUser:            data.User{ID: 20, Name: "Test User", Email: "testuser@example.com"},
-			expectedStatusCode: http.StatusForbidden,
+			reqUser:            data.User{ID: 1, Name: "Test User", Email: "testuser@example.com"},
+			instruments:        testInstruments,
+			expectedStatusCode: http.StatusUnprocessableEntity,
+			shouldCheckBody:    false,
 		},
 		{
-			name: "non valid recipient2",
-			inputBody: inputBodyType{
-				Status: "rejected",
+			name: "invalid RecipientInstrumentID",
+			input: inputSwap{
+				RequesterInstrumentID: 1,
+				RecipientInstrumentID: -2,
 			},
-			pathParam:          "99",
-			swap:               data.Swap{ID: 99, RequesterInstrumentID: 1, RecipientInstrumentID: 2},
-			instrumentReq:      data.Instrument{ID: 1, OwnerUserID: 10},
-			instrumentRec:      data.Instrument{ID: 2, OwnerUserID: 20},
-			reqUser:            data.User{ID: 10, Name: "Test User", Email: "testuser@example.com"},
-			expectedStatusCode: http.StatusForbidden,
+			reqUser:            data.User{ID: 1, Name: "Test User", Email: "testuser@example.com"},
+			instruments:        testInstruments,
+			expectedStatusCode: http.StatusUnprocessableEntity,
+			shouldCheckBody:    false,
 		},
 	}
 
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
-			t.Parallel()
+
+			var cfg config
+			cfg.swap.topUpTolerance = tc.topUpTolerance
+			cfg.swap.currency = "EUR"
+
+			swaps := mocks.NewSwapModelMock(tc.swaps)
+			swaps.DisputeInstruments(tc.disputed...)
 
 			app := &application{
+				config: cfg,
 				logger: slog.New(slog.NewTextHandler(io.Discard, nil)),
-				models: data.Models{Swaps: mocks.NewSwapModelMock([]*data.Swap{&tc.swap}), Instruments: mocks.NewNonEmptyInstrumentModelMock([]*data.Instrument{&tc.instrumentRec, &tc.instrumentReq})},
+				models: data.Models{
+					Swaps:       swaps,
+					Instruments: mocks.NewNonEmptyInstrumentModelMock(tc.instruments),
+				},
 			}
 
 			setUser := func(next http.HandlerFunc) http.HandlerFunc {
@@ -660,14 +813,14 @@ func TestUpdateSwapStatusHandler(t *testing.T) {
 			}
 
 			mux := http.NewServeMux()
-			mux.HandleFunc("POST /{id}", setUser(app.updateSwapStatusHandler))
+			mux.HandleFunc("POST /", setUser(app.createSwapHandler))
 
 			ts := httptest.NewServer(mux)
 			defer ts.Close()
 
-			path := fmt.Sprintf("%s/%s", ts.URL, tc.pathParam)
+			path := fmt.Sprintf("%s/", ts.URL)
 
-			bs, err := json.Marshal(tc.inputBody)
+			bs, err := json.Marshal(tc.input)
 			if err != nil {
 				t.Fatal(err)
 			}
@@ -686,7 +839,850 @@ func TestUpdateSwapStatusHandler(t *testing.T) {
 			if tc.expectedStatusCode != resp.StatusCode {
 				t.Errorf(`expected status code %d, got %d`, tc.expectedStatusCode, resp.StatusCode)
 			}
+
+			if tc.shouldCheckBody {
+				defer func() {
+					err := resp.Body.Close()
+					if err != nil {
+						t.Fatal(err)
+					}
+				}()
+
+				body, err := io.ReadAll(resp.Body)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				respEnvelope := make(envelope)
+
+				err = json.Unmarshal(body, &respEnvelope)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				respAnySlice, ok := respEnvelope["swap"]
+				if !ok {
+					t.Fatal(`the response does not contain enveloped swaps`)
+				}
+				buf := new(bytes.Buffer)
+				err = json.NewEncoder(buf).Encode(respAnySlice)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				var swap *data.Swap
+
+				err = json.NewDecoder(buf).Decode(&swap)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				expectedRequesterIDs := swapInstrumentIDs(tc.input.RequesterInstrumentID, tc.input.RequesterInstrumentIDs)
+				expectedRecipientIDs := swapInstrumentIDs(tc.input.RecipientInstrumentID, tc.input.RecipientInstrumentIDs)
+
+				if expectedRecipientIDs[0] != swap.RecipientInstrumentID {
+					t.Errorf(`Expected RecipientInstrumentID %d, got %d`, expectedRecipientIDs[0], swap.RecipientInstrumentID)
+				}
+
+				if expectedRequesterIDs[0] != swap.RequesterInstrumentID {
+					t.Errorf(`Expected RequesterInstrumentID %d, got %d`, expectedRequesterIDs[0], swap.RequesterInstrumentID)
+				}
+
+				if !slices.Equal(expectedRecipientIDs, swap.RecipientInstrumentIDs) {
+					t.Errorf(`Expected RecipientInstrumentIDs %v, got %v`, expectedRecipientIDs, swap.RecipientInstrumentIDs)
+				}
+
+				if !slices.Equal(expectedRequesterIDs, swap.RequesterInstrumentIDs) {
+					t.Errorf(`Expected RequesterInstrumentIDs %v, got %v`, expectedRequesterIDs, swap.RequesterInstrumentIDs)
+				}
+
+				if !reflect.DeepEqual(tc.input.TopUp, swap.TopUp) {
+					t.Errorf(`Expected TopUp %v, got %v`, tc.input.TopUp, swap.TopUp)
+				}
+
+				if !reflect.DeepEqual(tc.input.StartsAt, swap.StartsAt) || !reflect.DeepEqual(tc.input.ReturnBy, swap.ReturnBy) {
+					t.Errorf(`Expected StartsAt %v and ReturnBy %v, got %v and %v`, tc.input.StartsAt, tc.input.ReturnBy, swap.StartsAt, swap.ReturnBy)
+				}
+
+			}
 		})
 	}
 
 }
+
+// TestUpdateSwapStatusHandler implements unit tests for updateSwapStatusHandler.
+func TestUpdateSwapStatusHandler(t *testing.T) {
+
+	type inputBodyType struct {
+		Status string `json:"status"`
+		Note   string `json:"note"`
+	}
+
+	type testCase struct {
+		name               string
+		inputBody          inputBodyType
+		pathParam          string
+		swap               data.Swap
+		instrumentReq      data.Instrument
+		instrumentRec      data.Instrument
+		reqUser            data.User
+		ifMatch            string
+		expectedStatusCode int
+	}
+
+	testCases := []testCase{
+		{
+			name: "happy path - accept",
+			inputBody: inputBodyType{
+				Status: "accepted",
+			},
+			pathParam:          "99",
+			swap:               data.Swap{ID: 99, RequesterInstrumentID: 1, RecipientInstrumentID: 2},
+			instrumentReq:      data.Instrument{ID: 1, OwnerUserID: 10},
+			instrumentRec:      data.Instrument{ID: 2, OwnerUserID: 20},
+			reqUser:            data.User{ID: 20, Name: "Test User", Email: "testuser@example.com"},
+			expectedStatusCode: http.StatusOK,
+		},
+		{
+			name: "happy path - reject",
+			inputBody: inputBodyType{
+				Status: "rejected",
+			},
+			pathParam:          "99",
+			swap:               data.Swap{ID: 99, RequesterInstrumentID: 1, RecipientInstrumentID: 2},
+			instrumentReq:      data.Instrument{ID: 1, OwnerUserID: 10},
+			instrumentRec:      data.Instrument{ID: 2, OwnerUserID: 20},
+			reqUser:            data.User{ID: 20, Name: "Test User", Email: "testuser@example.com"},
+			expectedStatusCode: http.StatusOK,
+		},
+		{
+			name: "happy path - end",
+			inputBody: inputBodyType{
+				Status: "ended",
+			},
+			pathParam:          "99",
+			swap:               data.Swap{ID: 99, RequesterInstrumentID: 1, RecipientInstrumentID: 2, IsAccepted: true},
+			instrumentReq:      data.Instrument{ID: 1, OwnerUserID: 10},
+			instrumentRec:      data.Instrument{ID: 2, OwnerUserID: 20},
+			reqUser:            data.User{ID: 20, Name: "Test User", Email: "testuser@example.com"},
+			expectedStatusCode: http.StatusOK,
+		},
+		{
+			name: "end a pending swap",
+			inputBody: inputBodyType{
+				Status: "ended",
+			},
+			pathParam:          "99",
+			swap:               data.Swap{ID: 99, RequesterInstrumentID: 1, RecipientInstrumentID: 2},
+			instrumentReq:      data.Instrument{ID: 1, OwnerUserID: 10},
+			instrumentRec:      data.Instrument{ID: 2, OwnerUserID: 20},
+			reqUser:            data.User{ID: 10, Name: "Test User", Email: "testuser@example.com"},
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name: "invalid status param",
+			inputBody: inputBodyType{
+				Status: "invalid",
+			},
+			pathParam:          "99",
+			swap:               data.Swap{ID: 99, RequesterInstrumentID: 1, RecipientInstrumentID: 2},
+			instrumentReq:      data.Instrument{ID: 1, OwnerUserID: 10},
+			instrumentRec:      data.Instrument{ID: 2, OwnerUserID: 20},
+			reqUser:            data.User{ID: 20, Name: "Test User", Email: "testuser@example.com"},
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name: "non valid path param",
+			inputBody: inputBodyType{
+				Status: "ended",
+			},
+			pathParam:          "nonvalid",
+			swap:               data.Swap{ID: 99, RequesterInstrumentID: 1, RecipientInstrumentID: 2},
+			instrumentReq:      data.Instrument{ID: 1, OwnerUserID: 10},
+			instrumentRec:      data.Instrument{ID: 2, OwnerUserID: 20},
+			reqUser:            data.User{ID: 20, Name: "Test User", Email: "testuser@example.com"},
+			expectedStatusCode: http.StatusNotFound,
+		},
+		{
+			name: "non existing swap",
+			inputBody: inputBodyType{
+				Status: "ended",
+			},
+			pathParam:          "999",
+			swap:               data.Swap{ID: 99, RequesterInstrumentID: 1, RecipientInstrumentID: 2},
+			instrumentReq:      data.Instrument{ID: 1, OwnerUserID: 10},
+			instrumentRec:      data.Instrument{ID: 2, OwnerUserID: 20},
+			reqUser:            data.User{ID: 20, Name: "Test User", Email: "testuser@example.com"},
+			expectedStatusCode: http.StatusNotFound,
+		},
+		{
+			name: "non valid status change",
+			inputBody: inputBodyType{
+				Status: "rejected",
+			},
+			pathParam:          "99",
+			swap:               data.Swap{ID: 99, RequesterInstrumentID: 1, RecipientInstrumentID: 2, IsAccepted: true},
+			instrumentReq:      data.Instrument{ID: 1, OwnerUserID: 10},
+			instrumentRec:      data.Instrument{ID: 2, OwnerUserID: 20},
+			reqUser:            data.User{ID: 20, Name: "Test User", Email: "testuser@example.com"},
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name: "happy path - cancel",
+			inputBody: inputBodyType{
+				Status: "cancelled",
+				Note:   "changed my mind",
+			},
+			pathParam:          "99",
+			swap:               data.Swap{ID: 99, RequesterInstrumentID: 1, RecipientInstrumentID: 2},
+			instrumentReq:      data.Instrument{ID: 1, OwnerUserID: 10},
+			instrumentRec:      data.Instrument{ID: 2, OwnerUserID: 20},
+			reqUser:            data.User{ID: 10, Name: "Test User", Email: "testuser@example.com"},
+			expectedStatusCode: http.StatusOK,
+		},
+		{
+			name: "cancel by the recipient",
+			inputBody: inputBodyType{
+				Status: "cancelled",
+			},
+			pathParam:          "99",
+			swap:               data.Swap{ID: 99, RequesterInstrumentID: 1, RecipientInstrumentID: 2},
+			instrumentReq:      data.Instrument{ID: 1, OwnerUserID: 10},
+			instrumentRec:      data.Instrument{ID: 2, OwnerUserID: 20},
+			reqUser:            data.User{ID: 20, Name: "Test User", Email: "testuser@example.com"},
+			expectedStatusCode: http.StatusForbidden,
+		},
+		{
+			name: "cancel an accepted swap",
+			inputBody: inputBodyType{
+				Status: "cancelled",
+			},
+			pathParam:          "99",
+			swap:               data.Swap{ID: 99, RequesterInstrumentID: 1, RecipientInstrumentID: 2, IsAccepted: true},
+			instrumentReq:      data.Instrument{ID: 1, OwnerUserID: 10},
+			instrumentRec:      data.Instrument{ID: 2, OwnerUserID: 20},
+			reqUser:            data.User{ID: 10, Name: "Test User", Email: "testuser@example.com"},
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name: "expire via the api",
+			inputBody: inputBodyType{
+				Status: "expired",
+			},
+			pathParam:          "99",
+			swap:               data.Swap{ID: 99, RequesterInstrumentID: 1, RecipientInstrumentID: 2},
+			instrumentReq:      data.Instrument{ID: 1, OwnerUserID: 10},
+			instrumentRec:      data.Instrument{ID: 2, OwnerUserID: 20},
+			reqUser:            data.User{ID: 10, Name: "Test User", Email: "testuser@example.com"},
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name: "too long note",
+			inputBody: inputBodyType{
+				Status: "accepted",
+				Note:   strings.Repeat("a", 501),
+			},
+			pathParam:          "99",
+			swap:               data.Swap{ID: 99, RequesterInstrumentID: 1, RecipientInstrumentID: 2},
+			instrumentReq:      data.Instrument{ID: 1, OwnerUserID: 10},
+			instrumentRec:      data.Instrument{ID: 2, OwnerUserID: 20},
+			reqUser:            data.User{ID: 20, Name: "Test User", Email: "testuser@example.com"},
+			expectedStatusCode: http.StatusUnprocessableEntity,
+		},
+		{
+			name: "end an ended swap",
+			inputBody: inputBodyType{
+				Status: "ended",
+			},
+			pathParam:          "99",
+			swap:               data.Swap{ID: 99, RequesterInstrumentID: 1, RecipientInstrumentID: 2, IsAccepted: true, IsEnded: true},
+			instrumentReq:      data.Instrument{ID: 1, OwnerUserID: 10},
+			instrumentRec:      data.Instrument{ID: 2, OwnerUserID: 20},
+			reqUser:            data.User{ID: 10, Name: "Test User", Email: "testuser@example.com"},
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name: "non existent instrumentReq",
+			inputBody: inputBodyType{
+				Status: "accepted",
+			},
+			pathParam:          "99",
+			swap:               data.Swap{ID: 99, RequesterInstrumentID: 1, RecipientInstrumentID: 2},
+			instrumentReq:      data.Instrument{ID: 199, OwnerUserID: 10},
+			instrumentRec:      data.Instrument{ID: 2, OwnerUserID: 20},
+			reqUser:            data.User{ID: 20, Name: "Test User", Email: "testuser@example.com"},
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name: "non existent instrumentRec",
+			inputBody: inputBodyType{
+				Status: "accepted",
+			},
+			pathParam:          "99",
+			swap:               data.Swap{ID: 99, RequesterInstrumentID: 1, RecipientInstrumentID: 2},
+			instrumentReq:      data.Instrument{ID: 1, OwnerUserID: 10},
+			instrumentRec:      data.Instrument{ID: 299, OwnerUserID: 20},
+			reqUser:            data.User{ID: 20, Name: "Test User", Email: "testuser@example.com"},
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name: "non valid recipient",
+			inputBody: inputBodyType{
+				Status: "accepted",
+			},
+			pathParam:          "99",
+			swap:               data.Swap{ID: 99, RequesterInstrumentID: 1, RecipientInstrumentID: 2},
+			instrumentReq:      data.Instrument{ID: 1, OwnerUserID: 10},
+			instrumentRec:      data.Instrument{ID: 2, OwnerUserID: 200},
+			reqUser:            data.User{ID: 20, Name: "Test User", Email: "testuser@example.com"},
+			expectedStatusCode: http.StatusForbidden,
+		},
+		{
+			name: "matching If-Match",
+			inputBody: inputBodyType{
+				Status: "accepted",
+			},
+			pathParam:          "99",
+			swap:               data.Swap{ID: 99, RequesterInstrumentID: 1, RecipientInstrumentID: 2, Version: 2},
+			instrumentReq:      data.Instrument{ID: 1, OwnerUserID: 10},
+			instrumentRec:      data.Instrument{ID: 2, OwnerUserID: 20},
+			reqUser:            data.User{ID: 20, Name: "Test User", Email: "testuser@example.com"},
+			ifMatch:            `"2"`,
+			expectedStatusCode: http.StatusOK,
+		},
+		{
+			name: "stale If-Match",
+			inputBody: inputBodyType{
+				Status: "accepted",
+			},
+			pathParam:          "99",
+			swap:               data.Swap{ID: 99, RequesterInstrumentID: 1, RecipientInstrumentID: 2, Version: 2},
+			instrumentReq:      data.Instrument{ID: 1, OwnerUserID: 10},
+			instrumentRec:      data.Instrument{ID: 2, OwnerUserID: 20},
+			reqUser:            data.User{ID: 20, Name: "Test User", Email: "testuser@example.com"},
+			ifMatch:            `"1"`,
+			expectedStatusCode: http.StatusPreconditionFailed,
+		},
+		{
+			name: "non valid recipient2",
+			inputBody: inputBodyType{
+				Status: "rejected",
+			},
+			pathParam:          "99",
+			swap:               data.Swap{ID: 99, RequesterInstrumentID: 1, RecipientInstrumentID: 2},
+			instrumentReq:      data.Instrument{ID: 1, OwnerUserID: 10},
+			instrumentRec:      data.Instrument{ID: 2, OwnerUserID: 20},
+			reqUser:            data.User{ID: 10, Name: "Test User", Email: "testuser@example.com"},
+			expectedStatusCode: http.StatusForbidden,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			swaps := mocks.NewSwapModelMock([]*data.Swap{&tc.swap})
+
+			app := &application{
+				logger: slog.New(slog.NewTextHandler(io.Discard, nil)),
+				models: data.Models{Swaps: swaps, Instruments: mocks.NewNonEmptyInstrumentModelMock([]*data.Instrument{&tc.instrumentRec, &tc.instrumentReq})},
+			}
+
+			setUser := func(next http.HandlerFunc) http.HandlerFunc {
+				return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+					r = app.contextSetUser(r, &tc.reqUser)
+
+					next.ServeHTTP(w, r)
+				})
+			}
+
+			mux := http.NewServeMux()
+			mux.HandleFunc("POST /{id}", setUser(app.updateSwapStatusHandler))
+
+			ts := httptest.NewServer(mux)
+			defer ts.Close()
+
+			path := fmt.Sprintf("%s/%s", ts.URL, tc.pathParam)
+
+			bs, err := json.Marshal(tc.inputBody)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			req, err := http.NewRequest("POST", path, bytes.NewBuffer(bs))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tc.ifMatch != "" {
+				req.Header.Set("If-Match", tc.ifMatch)
+			}
+
+			client := &http.Client{}
+			resp, err := client.Do(req)
+			if err != nil {
+				log.Fatal(err)
+			}
+
+			if tc.expectedStatusCode != resp.StatusCode {
+				t.Errorf(`expected status code %d, got %d`, tc.expectedStatusCode, resp.StatusCode)
+			}
+
+			events, err := swaps.GetAllForSwap(tc.swap.ID)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if tc.expectedStatusCode != http.StatusOK {
+				if len(events) != 0 {
+					t.Errorf(`expected no swap events, got %d`, len(events))
+				}
+				return
+			}
+
+			if len(events) != 1 {
+				t.Fatalf(`expected 1 swap event, got %d`, len(events))
+			}
+			if events[0].Type != tc.inputBody.Status || *events[0].ActorUserID != tc.reqUser.ID || events[0].Note != tc.inputBody.Note {
+				t.Errorf(`unexpected swap event %#v`, events[0])
+			}
+		})
+	}
+
+}
+
+// TestListSwapEventsHandler implements unit tests for listSwapEventsHandler.
+func TestListSwapEventsHandler(t *testing.T) {
+
+	type testCase struct {
+		name               string
+		pathParam          string
+		reqUser            data.User
+		expectedStatusCode int
+		expectedEventTypes []string
+	}
+
+	testCases := []testCase{
+		{
+			name:               "happy path - requester",
+			pathParam:          "1",
+			reqUser:            data.User{ID: 10},
+			expectedStatusCode: http.StatusOK,
+			expectedEventTypes: []string{data.SwapEventCreated, data.SwapEventAccepted},
+		},
+		{
+			name:               "happy path - recipient",
+			pathParam:          "1",
+			reqUser:            data.User{ID: 20},
+			expectedStatusCode: http.StatusOK,
+			expectedEventTypes: []string{data.SwapEventCreated, data.SwapEventAccepted},
+		},
+		{
+			name:               "not a party of the swap",
+			pathParam:          "1",
+			reqUser:            data.User{ID: 30},
+			expectedStatusCode: http.StatusNotFound,
+		},
+		{
+			name:               "non existent swap",
+			pathParam:          "2",
+			reqUser:            data.User{ID: 10},
+			expectedStatusCode: http.StatusNotFound,
+		},
+		{
+			name:               "non valid path param",
+			pathParam:          "nonvalid",
+			reqUser:            data.User{ID: 10},
+			expectedStatusCode: http.StatusNotFound,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			swaps := mocks.NewSwapModelMock(nil)
+
+			err := swaps.Create(&data.Swap{RequesterInstrumentID: 1, RecipientInstrumentID: 2}, 10, "")
+			if err != nil {
+				t.Fatal(err)
+			}
+			_, err = swaps.Transition(1, 1, data.SwapStatusAccepted, 20, "see you soon")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			app := &application{
+				logger: slog.New(slog.NewTextHandler(io.Discard, nil)),
+				models: data.Models{
+					Swaps:       swaps,
+					SwapEvents:  swaps,
+					Instruments: mocks.NewNonEmptyInstrumentModelMock([]*data.Instrument{{ID: 1, OwnerUserID: 10}, {ID: 2, OwnerUserID: 20}}),
+				},
+			}
+
+			setUser := func(next http.HandlerFunc) http.HandlerFunc {
+				return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+					r = app.contextSetUser(r, &tc.reqUser)
+
+					next.ServeHTTP(w, r)
+				})
+			}
+
+			mux := http.NewServeMux()
+			mux.HandleFunc("GET /{id}", setUser(app.listSwapEventsHandler))
+
+			ts := httptest.NewServer(mux)
+			defer ts.Close()
+
+			resp, err := http.Get(fmt.Sprintf("%s/%s", ts.URL, tc.pathParam))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer func() {
+				err := resp.Body.Close()
+				if err != nil {
+					t.Fatal(err)
+				}
+			}()
+
+			if tc.expectedStatusCode != resp.StatusCode {
+				t.Errorf(`expected status code %d, got %d`, tc.expectedStatusCode, resp.StatusCode)
+			}
+
+			if tc.expectedStatusCode != http.StatusOK {
+				return
+			}
+
+			var respBody struct {
+				Events []*data.SwapEvent `json:"events"`
+			}
+
+			err = json.NewDecoder(resp.Body).Decode(&respBody)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			eventTypes := []string{}
+			for _, event := range respBody.Events {
+				eventTypes = append(eventTypes, event.Type)
+			}
+
+			if !reflect.DeepEqual(tc.expectedEventTypes, eventTypes) {
+				t.Errorf(`expected event types %v, got %v`, tc.expectedEventTypes, eventTypes)
+			}
+		})
+	}
+}
+
+// TestCreateCounterOfferHandler implements unit tests for createCounterOfferHandler.
+func TestCreateCounterOfferHandler(t *testing.T) {
+
+	type inputBodyType struct {
+		RequesterInstrumentID int64  `json:"requester_instrument_id"`
+		RecipientInstrumentID int64  `json:"recipient_instrument_id"`
+		Note                  string `json:"note"`
+	}
+
+	type testCase struct {
+		name               string
+		inputBody          inputBodyType
+		pathParam          string
+		swaps              []*data.Swap
+		reqUser            data.User
+		expectedStatusCode int
+	}
+
+	// Instrument 1 and 3 belong to the requester of swap 1, instrument 2 and 4 belong to the recipient of swap 1.
+	testInstruments := []*data.Instrument{
+		{ID: 1, OwnerUserID: 10},
+		{ID: 2, OwnerUserID: 20},
+		{ID: 3, OwnerUserID: 10},
+		{ID: 4, OwnerUserID: 20},
+		{ID: 5, OwnerUserID: 30},
+	}
+
+	testCases := []testCase{
+		{
+			name:               "happy path - offer another own instrument",
+			inputBody:          inputBodyType{RequesterInstrumentID: 4, RecipientInstrumentID: 1, Note: "what about this one?"},
+			pathParam:          "1",
+			swaps:              []*data.Swap{{ID: 1, RequesterInstrumentID: 1, RecipientInstrumentID: 2}},
+			reqUser:            data.User{ID: 20},
+			expectedStatusCode: http.StatusCreated,
+		},
+		{
+			name:               "happy path - ask for another instrument",
+			inputBody:          inputBodyType{RequesterInstrumentID: 2, RecipientInstrumentID: 3},
+			pathParam:          "1",
+			swaps:              []*data.Swap{{ID: 1, RequesterInstrumentID: 1, RecipientInstrumentID: 2}},
+			reqUser:            data.User{ID: 20},
+			expectedStatusCode: http.StatusCreated,
+		},
+		{
+			name:               "same instruments as the original swap",
+			inputBody:          inputBodyType{RequesterInstrumentID: 2, RecipientInstrumentID: 1},
+			pathParam:          "1",
+			swaps:              []*data.Swap{{ID: 1, RequesterInstrumentID: 1, RecipientInstrumentID: 2}},
+			reqUser:            data.User{ID: 20},
+			expectedStatusCode: http.StatusUnprocessableEntity,
+		},
+		{
+			name:               "counter-offer by the requester",
+			inputBody:          inputBodyType{RequesterInstrumentID: 3, RecipientInstrumentID: 2},
+			pathParam:          "1",
+			swaps:              []*data.Swap{{ID: 1, RequesterInstrumentID: 1, RecipientInstrumentID: 2}},
+			reqUser:            data.User{ID: 10},
+			expectedStatusCode: http.StatusForbidden,
+		},
+		{
+			name:               "offered instrument of another user",
+			inputBody:          inputBodyType{RequesterInstrumentID: 3, RecipientInstrumentID: 1},
+			pathParam:          "1",
+			swaps:              []*data.Swap{{ID: 1, RequesterInstrumentID: 1, RecipientInstrumentID: 2}},
+			reqUser:            data.User{ID: 20},
+			expectedStatusCode: http.StatusUnprocessableEntity,
+		},
+		{
+			name:               "asked instrument of a third user",
+			inputBody:          inputBodyType{RequesterInstrumentID: 2, RecipientInstrumentID: 5},
+			pathParam:          "1",
+			swaps:              []*data.Swap{{ID: 1, RequesterInstrumentID: 1, RecipientInstrumentID: 2}},
+			reqUser:            data.User{ID: 20},
+			expectedStatusCode: http.StatusUnprocessableEntity,
+		},
+		{
+			name:               "non existent instrument",
+			inputBody:          inputBodyType{RequesterInstrumentID: 2, RecipientInstrumentID: 99},
+			pathParam:          "1",
+			swaps:              []*data.Swap{{ID: 1, RequesterInstrumentID: 1, RecipientInstrumentID: 2}},
+			reqUser:            data.User{ID: 20},
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:               "accepted swap",
+			inputBody:          inputBodyType{RequesterInstrumentID: 4, RecipientInstrumentID: 1},
+			pathParam:          "1",
+			swaps:              []*data.Swap{{ID: 1, RequesterInstrumentID: 1, RecipientInstrumentID: 2, IsAccepted: true}},
+			reqUser:            data.User{ID: 20},
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:      "instrument already in another swap",
+			inputBody: inputBodyType{RequesterInstrumentID: 2, RecipientInstrumentID: 3},
+			pathParam: "1",
+			swaps: []*data.Swap{
+				{ID: 1, RequesterInstrumentID: 1, RecipientInstrumentID: 2},
+				{ID: 2, RequesterInstrumentID: 3, RecipientInstrumentID: 5},
+			},
+			reqUser:            data.User{ID: 20},
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:               "non existent swap",
+			inputBody:          inputBodyType{RequesterInstrumentID: 4, RecipientInstrumentID: 1},
+			pathParam:          "2",
+			swaps:              []*data.Swap{{ID: 1, RequesterInstrumentID: 1, RecipientInstrumentID: 2}},
+			reqUser:            data.User{ID: 20},
+			expectedStatusCode: http.StatusNotFound,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			app := &application{
+				logger: slog.New(slog.NewTextHandler(io.Discard, nil)),
+				models: data.Models{
+					Swaps:       mocks.NewSwapModelMock(tc.swaps),
+					Instruments: mocks.NewNonEmptyInstrumentModelMock(testInstruments),
+				},
+			}
+
+			setUser := func(next http.HandlerFunc) http.HandlerFunc {
+				return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+					r = app.contextSetUser(r, &tc.reqUser)
+
+					next.ServeHTTP(w, r)
+				})
+			}
+
+			mux := http.NewServeMux()
+			mux.HandleFunc("POST /{id}", setUser(app.createCounterOfferHandler))
+
+			ts := httptest.NewServer(mux)
+			defer ts.Close()
+
+			bs, err := json.Marshal(tc.inputBody)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			resp, err := http.Post(fmt.Sprintf("%s/%s", ts.URL, tc.pathParam), "application/json", bytes.NewBuffer(bs))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer func() {
+				err := resp.Body.Close()
+				if err != nil {
+					t.Fatal(err)
+				}
+			}()
+
+			if tc.expectedStatusCode != resp.StatusCode {
+				t.Errorf(`expected status code %d, got %d`, tc.expectedStatusCode, resp.StatusCode)
+			}
+
+			original, err := app.models.Swaps.Get(tc.swaps[0].ID)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if tc.expectedStatusCode != http.StatusCreated {
+				if original.IsSuperseded {
+					t.Error(`expected the original swap not to be superseded`)
+				}
+				return
+			}
+
+			if !original.IsSuperseded || !original.IsEnded {
+				t.Error(`expected the original swap to be superseded`)
+			}
+
+			var respBody struct {
+				Swap *data.Swap `json:"swap"`
+			}
+
+			err = json.NewDecoder(resp.Body).Decode(&respBody)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if respBody.Swap.ParentSwapID == nil || *respBody.Swap.ParentSwapID != original.ID {
+				t.Errorf(`expected parent swap id %d, got %v`, original.ID, respBody.Swap.ParentSwapID)
+			}
+			if tc.inputBody.RequesterInstrumentID != respBody.Swap.RequesterInstrumentID || tc.inputBody.RecipientInstrumentID != respBody.Swap.RecipientInstrumentID {
+				t.Errorf(`expected instruments %d-%d, got %d-%d`, tc.inputBody.RequesterInstrumentID, tc.inputBody.RecipientInstrumentID, respBody.Swap.RequesterInstrumentID, respBody.Swap.RecipientInstrumentID)
+			}
+		})
+	}
+}
+
+// TestListInstrumentSwapsHandler implements unit tests for listInstrumentSwapsHandler.
+func TestListInstrumentSwapsHandler(t *testing.T) {
+
+	now := time.Now()
+
+	testSwaps := []*data.Swap{
+		{ID: 1, RequesterInstrumentID: 1, RecipientInstrumentID: 2, IsAccepted: true, AcceptedAt: &now, IsEnded: true, EndedAt: &now},
+		{ID: 2, RequesterInstrumentID: 3, RecipientInstrumentID: 1, IsRejected: true, RejectedAt: &now, IsEnded: true, EndedAt: &now},
+		{ID: 3, RequesterInstrumentID: 1, RecipientInstrumentID: 4},
+		{ID: 4, RequesterInstrumentID: 3, RecipientInstrumentID: 4},
+	}
+
+	type testCase struct {
+		name               string
+		pathParam          string
+		query              string
+		reqUser            data.User
+		expectedStatusCode int
+		expectedSwapIDs    []int64
+	}
+
+	testCases := []testCase{
+		{
+			name:               "happy path",
+			pathParam:          "1",
+			reqUser:            data.User{ID: 10},
+			expectedStatusCode: http.StatusOK,
+			expectedSwapIDs:    []int64{1, 2, 3},
+		},
+		{
+			name:               "not the owner of the instrument",
+			pathParam:          "1",
+			reqUser:            data.User{ID: 20},
+			expectedStatusCode: http.StatusForbidden,
+		},
+		{
+			name:               "non valid sort",
+			pathParam:          "1",
+			query:              "?sort=status",
+			reqUser:            data.User{ID: 10},
+			expectedStatusCode: http.StatusUnprocessableEntity,
+		},
+		{
+			name:               "non existent instrument",
+			pathParam:          "99",
+			reqUser:            data.User{ID: 10},
+			expectedStatusCode: http.StatusNotFound,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+
+			app := &application{
+				logger: slog.New(slog.NewTextHandler(io.Discard, nil)),
+				models: data.Models{
+					Swaps:       mocks.NewSwapModelMock(testSwaps),
+					Instruments: mocks.NewNonEmptyInstrumentModelMock([]*data.Instrument{{ID: 1, OwnerUserID: 10}, {ID: 2, OwnerUserID: 20}}),
+				},
+			}
+
+			setUser := func(next http.HandlerFunc) http.HandlerFunc {
+				return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+					r = app.contextSetUser(r, &tc.reqUser)
+
+					next.ServeHTTP(w, r)
+				})
+			}
+
+			mux := http.NewServeMux()
+			mux.HandleFunc("GET /{id}", setUser(app.listInstrumentSwapsHandler))
+
+			ts := httptest.NewServer(mux)
+			defer ts.Close()
+
+			resp, err := http.Get(fmt.Sprintf("%s/%s%s", ts.URL, tc.pathParam, tc.query))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer func() {
+				err := resp.Body.Close()
+				if err != nil {
+					t.Fatal(err)
+				}
+			}()
+
+			if tc.expectedStatusCode != resp.StatusCode {
+				t.Errorf(`expected status code %d, got %d`, tc.expectedStatusCode, resp.StatusCode)
+			}
+
+			if tc.expectedStatusCode != http.StatusOK {
+				return
+			}
+
+			var respBody struct {
+				Swaps []*data.Swap `json:"swaps"`
+			}
+
+			err = json.NewDecoder(resp.Body).Decode(&respBody)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			swapIDs := []int64{}
+			for _, swap := range respBody.Swaps {
+				swapIDs = append(swapIDs, swap.ID)
+			}
+
+			if !slices.Equal(tc.expectedSwapIDs, swapIDs) {
+				t.Errorf(`expected swaps %v, got %v`, tc.expectedSwapIDs, swapIDs)
+			}
+		})
+	}
+}
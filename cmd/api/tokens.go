@@ -4,6 +4,7 @@ import (
 	"errors"
 	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/ttarnok/instrument-swap-api/internal/data"
 	"github.com/ttarnok/instrument-swap-api/internal/validator"
@@ -148,6 +149,17 @@ func (app *application) refreshHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Check if the refresh token is blacklisted along with all other tokens of the user.
+	isBlacklisted, err = app.auth.BlacklistToken.IsUserTokenBlacklisted(userID, refreshClaims.Issued)
+	if err != nil {
+		app.serverErrorLogResponse(w, r, err)
+		return
+	}
+	if isBlacklisted {
+		app.invalidCredentialsResponse(w, r)
+		return
+	}
+
 	user, err := app.models.Users.GetByID(userID)
 	if err != nil {
 		switch {
@@ -348,3 +360,63 @@ func (app *application) logoutHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 }
+
+// createPasswordResetTokenHandler sends a single use password reset token to the given email address.
+// Responds the same way whether or not the email address belongs to a user.
+func (app *application) createPasswordResetTokenHandler(w http.ResponseWriter, r *http.Request) {
+
+	var input struct {
+		Email string `json:"email"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+	if data.ValidateEmail(v, input.Email); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	env := envelope{"message": "an email will be sent to you containing password reset instructions"}
+
+	user, err := app.models.Users.GetByEmail(input.Email)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			err = app.writeJSON(w, http.StatusAccepted, env, nil)
+			if err != nil {
+				app.serverErrorLogResponse(w, r, err)
+			}
+		default:
+			app.serverErrorLogResponse(w, r, err)
+		}
+		return
+	}
+
+	token, err := app.models.Tokens.New(user.ID, 45*time.Minute, data.ScopePasswordReset)
+	if err != nil {
+		app.serverErrorLogResponse(w, r, err)
+		return
+	}
+
+	app.background(func() {
+		mailData := map[string]any{
+			"passwordResetToken": token.Plaintext,
+		}
+
+		err := app.mailer.Send(user.Email, "token_password_reset.tmpl", mailData)
+		if err != nil {
+			app.logger.Error(err.Error())
+		}
+	})
+
+	err = app.writeJSON(w, http.StatusAccepted, env, nil)
+	if err != nil {
+		app.serverErrorLogResponse(w, r, err)
+		return
+	}
+}
@@ -247,6 +247,10 @@ func TestRefreshHandler(t *testing.T) {
 	// testTokenID3 := uuid.NewString()
 	// testTokenID4 := uuid.NewString()
 
+	// issuedInBlacklistingSecond is the issue time of the tokens issued after the user token blacklisting, in the same second.
+	// The issue times have second precision, so these tokens can not be told apart from the ones issued before the blacklisting.
+	issuedInBlacklistingSecond := time.Now().Add(-time.Hour).Truncate(time.Second).Add(500 * time.Millisecond)
+
 	type testCase struct {
 		name                 string
 		users                []*data.User
@@ -256,6 +260,7 @@ func TestRefreshHandler(t *testing.T) {
 		shouldValidateResult bool
 		expectedUserID       int64
 		blacklist            []string
+		userBlacklistedAt    time.Time
 	}
 
 	testCases := []testCase{
@@ -359,6 +364,39 @@ func TestRefreshHandler(t *testing.T) {
 			expectedUserID:       0,
 			blacklist:            nil,
 		},
+		{
+			name:                 "refresh token issued before user token blacklisting",
+			users:                []*data.User{testUser},
+			accessToken:          GenerateTestToken(testSecret, testTokenID1, testUser.ID, time.Now().Add(-time.Hour), time.Now().Add(-time.Hour), time.Now().Add(-time.Hour).Add(5*time.Minute), "instrument-swap.example.example", "access"),
+			refreshToken:         GenerateTestToken(testSecret, testTokenID2, testUser.ID, time.Now().Add(-time.Hour), time.Now().Add(-time.Hour), time.Now().Add(-time.Hour).Add(24*time.Hour), "instrument-swap.example.example", "refresh"),
+			expectedStatusCode:   http.StatusUnauthorized,
+			shouldValidateResult: false,
+			expectedUserID:       0,
+			blacklist:            nil,
+			userBlacklistedAt:    time.Now().Add(-time.Minute),
+		},
+		{
+			name:                 "refresh token issued after user token blacklisting",
+			users:                []*data.User{testUser},
+			accessToken:          GenerateTestToken(testSecret, testTokenID1, testUser.ID, time.Now().Add(-time.Hour), time.Now().Add(-time.Hour), time.Now().Add(-time.Hour).Add(5*time.Minute), "instrument-swap.example.example", "access"),
+			refreshToken:         GenerateTestToken(testSecret, testTokenID2, testUser.ID, time.Now().Add(-time.Hour), time.Now().Add(-time.Hour), time.Now().Add(-time.Hour).Add(24*time.Hour), "instrument-swap.example.example", "refresh"),
+			expectedStatusCode:   http.StatusCreated,
+			shouldValidateResult: true,
+			expectedUserID:       testUser.ID,
+			blacklist:            nil,
+			userBlacklistedAt:    time.Now().Add(-2 * time.Hour),
+		},
+		{
+			name:                 "refresh token issued in the second of user token blacklisting, after it",
+			users:                []*data.User{testUser},
+			accessToken:          GenerateTestToken(testSecret, testTokenID1, testUser.ID, issuedInBlacklistingSecond, issuedInBlacklistingSecond, issuedInBlacklistingSecond.Add(5*time.Minute), "instrument-swap.example.example", "access"),
+			refreshToken:         GenerateTestToken(testSecret, testTokenID2, testUser.ID, issuedInBlacklistingSecond, issuedInBlacklistingSecond, issuedInBlacklistingSecond.Add(24*time.Hour), "instrument-swap.example.example", "refresh"),
+			expectedStatusCode:   http.StatusUnauthorized,
+			shouldValidateResult: false,
+			expectedUserID:       0,
+			blacklist:            nil,
+			userBlacklistedAt:    issuedInBlacklistingSecond.Truncate(time.Second).Add(100 * time.Millisecond),
+		},
 		{
 			name:                 "wrong issuer",
 			users:                []*data.User{testUser},
@@ -374,10 +412,18 @@ func TestRefreshHandler(t *testing.T) {
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
 			t.Parallel()
+			blacklist := mocks.NewBlacklistServiceMockWithData(tc.blacklist)
+			if !tc.userBlacklistedAt.IsZero() {
+				err := blacklist.BlacklistUserTokens(testUser.ID, tc.userBlacklistedAt)
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+
 			app := &application{
 				logger: slog.New(slog.NewTextHandler(io.Discard, nil)),
 				models: data.Models{Users: mocks.NewUserModelMock(tc.users)},
-				auth:   auth.NewAuth(testSecret, mocks.NewBlacklistServiceMockWithData(tc.blacklist)),
+				auth:   auth.NewAuth(testSecret, blacklist),
 			}
 
 			mux := http.NewServeMux()
@@ -758,3 +804,104 @@ func TestLogoutHandler(t *testing.T) {
 	}
 
 }
+
+// TestCreatePasswordResetTokenHandler unit tests createPasswordResetTokenHandler.
+func TestCreatePasswordResetTokenHandler(t *testing.T) {
+
+	testUser := &data.User{
+		ID:    1,
+		Name:  "Dummy Username",
+		Email: "test@example.com",
+	}
+
+	type input struct {
+		Email string `json:"email"`
+	}
+
+	type testCase struct {
+		name               string
+		input              input
+		expectedStatusCode int
+		expectedSentMails  int
+	}
+
+	testCases := []testCase{
+		{
+			name:               "happy path",
+			input:              input{Email: "test@example.com"},
+			expectedStatusCode: http.StatusAccepted,
+			expectedSentMails:  1,
+		},
+		{
+			name:               "unknown email",
+			input:              input{Email: "unknown@example.com"},
+			expectedStatusCode: http.StatusAccepted,
+			expectedSentMails:  0,
+		},
+		{
+			name:               "invalid email",
+			input:              input{Email: "invalid"},
+			expectedStatusCode: http.StatusUnprocessableEntity,
+			expectedSentMails:  0,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			mailer := mocks.NewMailerMock()
+			tokens := mocks.NewTokenModelMock()
+
+			app := &application{
+				logger: slog.New(slog.NewTextHandler(io.Discard, nil)),
+				models: data.Models{Users: mocks.NewUserModelMock([]*data.User{testUser}), Tokens: tokens},
+				mailer: mailer,
+			}
+
+			mux := http.NewServeMux()
+			mux.HandleFunc("POST /", app.createPasswordResetTokenHandler)
+
+			ts := httptest.NewServer(mux)
+			defer ts.Close()
+
+			reqBody, err := json.Marshal(tc.input)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			req, err := http.NewRequest("POST", ts.URL+"/", bytes.NewBuffer(reqBody))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			client := &http.Client{}
+			resp, err := client.Do(req)
+			if err != nil {
+				log.Fatal(err)
+			}
+
+			if tc.expectedStatusCode != resp.StatusCode {
+				t.Errorf(`expected status code %d, got %d`, tc.expectedStatusCode, resp.StatusCode)
+			}
+
+			app.wg.Wait()
+
+			sent := mailer.Sent()
+			if tc.expectedSentMails != len(sent) {
+				t.Fatalf(`expected %d sent emails, got %d`, tc.expectedSentMails, len(sent))
+			}
+
+			if tc.expectedSentMails > 0 {
+				if sent[0].Recipient != tc.input.Email {
+					t.Errorf(`expected email recipient "%s", got "%s"`, tc.input.Email, sent[0].Recipient)
+				}
+
+				storedTokens := tokens.Tokens()
+				if len(storedTokens) != 1 || storedTokens[0].Scope != data.ScopePasswordReset {
+					t.Errorf(`expected a single stored password reset token, got %v`, storedTokens)
+				}
+			}
+		})
+	}
+}
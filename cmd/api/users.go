@@ -3,6 +3,7 @@ package main
 import (
 	"errors"
 	"net/http"
+	"time"
 
 	"github.com/ttarnok/instrument-swap-api/internal/data"
 	"github.com/ttarnok/instrument-swap-api/internal/validator"
@@ -24,8 +25,9 @@ func (app *application) listUsersHandler(w http.ResponseWriter, r *http.Request)
 	}
 }
 
-// updateUserHandler handles the updation of the user with the given id.
-func (app *application) updateUserHandler(w http.ResponseWriter, r *http.Request) {
+// showUserHandler handles the retrieval of the user with the given id.
+// The ETag of the response is derived from the version of the user, If-None-Match is honored.
+func (app *application) showUserHandler(w http.ResponseWriter, r *http.Request) {
 
 	id, err := app.extractIDParam(r)
 	if err != nil {
@@ -44,24 +46,77 @@ func (app *application) updateUserHandler(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	var input struct {
-		Name  *string `json:"name"`
-		Email *string `json:"email"`
+	if app.notModified(w, r, etag(int64(user.Version))) {
+		return
 	}
 
-	err = app.readJSON(w, r, &input)
+	user.Reputation, err = app.models.Reviews.GetReputation(user.ID)
 	if err != nil {
-		app.badRequestResponse(w, r, err)
+		app.serverErrorLogResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"user": user}, nil)
+	if err != nil {
+		app.serverErrorLogResponse(w, r, err)
+		return
+	}
+}
+
+// userPatchDocument is the representation of a user that PATCH requests are applied to.
+// Members set to null or removed by a patch clear the corresponding fields.
+type userPatchDocument struct {
+	Name  *string `json:"name"`
+	Email *string `json:"email"`
+}
+
+// newUserPatchDocument returns the patch document of the given user.
+func newUserPatchDocument(user *data.User) userPatchDocument {
+	return userPatchDocument{
+		Name:  &user.Name,
+		Email: &user.Email,
+	}
+}
+
+// updateUserHandler handles the updation of the user with the given id.
+// The request body is a JSON merge patch or a JSON patch applied to the patch document of the user.
+// The If-Match header is checked against the ETag of the user.
+func (app *application) updateUserHandler(w http.ResponseWriter, r *http.Request) {
+
+	id, err := app.extractIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	user, err := app.models.Users.GetByID(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorLogResponse(w, r, err)
+		}
 		return
 	}
 
-	if input.Name != nil {
-		user.Name = *input.Name
+	if !app.checkIfMatch(w, r, etag(int64(user.Version))) {
+		return
 	}
-	if input.Email != nil {
-		user.Email = *input.Email
+
+	current := newUserPatchDocument(user)
+
+	var input userPatchDocument
+
+	err = app.readPatch(w, r, current, &input)
+	if err != nil {
+		app.patchErrorResponse(w, r, err)
+		return
 	}
 
+	user.Name = valueOrZero(input.Name)
+	user.Email = valueOrZero(input.Email)
+
 	v := validator.New()
 
 	if data.ValidateUser(v, user); !v.Valid() {
@@ -73,7 +128,7 @@ func (app *application) updateUserHandler(w http.ResponseWriter, r *http.Request
 	if err != nil {
 		switch {
 		case errors.Is(err, data.ErrEditConflict):
-			app.editConflictResponse(w, r)
+			app.versionConflictResponse(w, r)
 		case errors.Is(err, data.ErrDuplicateEmail):
 			app.badRequestResponse(w, r, errors.New("email already exist"))
 		default:
@@ -82,7 +137,16 @@ func (app *application) updateUserHandler(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	err = app.writeJSON(w, http.StatusOK, envelope{"user": user}, nil)
+	user.Reputation, err = app.models.Reviews.GetReputation(user.ID)
+	if err != nil {
+		app.serverErrorLogResponse(w, r, err)
+		return
+	}
+
+	headers := make(http.Header)
+	headers.Set("ETag", etag(int64(user.Version)))
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"user": user}, headers)
 	if err != nil {
 		app.serverErrorLogResponse(w, r, err)
 		return
@@ -90,6 +154,7 @@ func (app *application) updateUserHandler(w http.ResponseWriter, r *http.Request
 }
 
 // deleteUserHandler implements deletion of a user with the given id.
+// The If-Match header is checked against the ETag of the user.
 func (app *application) deleteUserHandler(w http.ResponseWriter, r *http.Request) {
 
 	id, err := app.extractIDParam(r)
@@ -98,9 +163,26 @@ func (app *application) deleteUserHandler(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	err = app.models.Users.Delete(id)
+	user, err := app.models.Users.GetByID(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorLogResponse(w, r, err)
+		}
+		return
+	}
+
+	if !app.checkIfMatch(w, r, etag(int64(user.Version))) {
+		return
+	}
+
+	err = app.models.Users.Delete(id, user.Version)
 	if err != nil {
 		switch {
+		case errors.Is(err, data.ErrEditConflict):
+			app.versionConflictResponse(w, r)
 		case errors.Is(err, data.ErrRecordNotFound):
 			app.notFoundResponse(w, r)
 		default:
@@ -137,7 +219,7 @@ func (app *application) registerUserHandler(w http.ResponseWriter, r *http.Reque
 	user := &data.User{
 		Name:      input.Name,
 		Email:     input.Email,
-		Activated: true, // TODO: implement user activation
+		Activated: false,
 	}
 
 	err = user.Password.Set(input.Password)
@@ -164,6 +246,24 @@ func (app *application) registerUserHandler(w http.ResponseWriter, r *http.Reque
 		return
 	}
 
+	token, err := app.models.Tokens.New(user.ID, 3*24*time.Hour, data.ScopeActivation)
+	if err != nil {
+		app.serverErrorLogResponse(w, r, err)
+		return
+	}
+
+	app.background(func() {
+		mailData := map[string]any{
+			"activationToken": token.Plaintext,
+			"userID":          user.ID,
+		}
+
+		err := app.mailer.Send(user.Email, "user_welcome.tmpl", mailData)
+		if err != nil {
+			app.logger.Error(err.Error())
+		}
+	})
+
 	err = app.writeJSON(w, http.StatusCreated, envelope{"user": user}, nil)
 	if err != nil {
 		app.serverErrorLogResponse(w, r, err)
@@ -172,6 +272,69 @@ func (app *application) registerUserHandler(w http.ResponseWriter, r *http.Reque
 
 }
 
+// activateUserHandler handles the activation of a user with a single use activation token.
+func (app *application) activateUserHandler(w http.ResponseWriter, r *http.Request) {
+
+	var input struct {
+		TokenPlaintext string `json:"token"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+	if data.ValidateTokenPlaintext(v, input.TokenPlaintext); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	user, err := app.models.Users.GetForToken(data.ScopeActivation, input.TokenPlaintext)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			v.AddError("token", "invalid or expired activation token")
+			app.failedValidationResponse(w, r, v.Errors)
+		default:
+			app.serverErrorLogResponse(w, r, err)
+		}
+		return
+	}
+
+	user.Activated = true
+
+	err = app.models.Users.Update(user)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrEditConflict):
+			app.editConflictResponse(w, r)
+		default:
+			app.serverErrorLogResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.models.Tokens.DeleteAllForUser(data.ScopeActivation, user.ID)
+	if err != nil {
+		app.serverErrorLogResponse(w, r, err)
+		return
+	}
+
+	user.Reputation, err = app.models.Reviews.GetReputation(user.ID)
+	if err != nil {
+		app.serverErrorLogResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"user": user}, nil)
+	if err != nil {
+		app.serverErrorLogResponse(w, r, err)
+		return
+	}
+}
+
 // updatePasswordHandler handles the updation of a given users password.
 func (app *application) updatePasswordHandler(w http.ResponseWriter, r *http.Request) {
 	id, err := app.extractIDParam(r)
@@ -241,3 +404,224 @@ func (app *application) updatePasswordHandler(w http.ResponseWriter, r *http.Req
 		return
 	}
 }
+
+// resetPasswordHandler handles the password reset of a user with a single use password reset token.
+// On success, all previously issued tokens of the user get blacklisted.
+func (app *application) resetPasswordHandler(w http.ResponseWriter, r *http.Request) {
+
+	var input struct {
+		Password       string `json:"password"`
+		TokenPlaintext string `json:"token"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+	data.ValidatePasswordPlaintext(v, input.Password)
+	data.ValidateTokenPlaintext(v, input.TokenPlaintext)
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	user, err := app.models.Users.GetForToken(data.ScopePasswordReset, input.TokenPlaintext)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			v.AddError("token", "invalid or expired password reset token")
+			app.failedValidationResponse(w, r, v.Errors)
+		default:
+			app.serverErrorLogResponse(w, r, err)
+		}
+		return
+	}
+
+	err = user.Password.Set(input.Password)
+	if err != nil {
+		app.serverErrorLogResponse(w, r, err)
+		return
+	}
+
+	err = app.models.Users.Update(user)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrEditConflict):
+			app.editConflictResponse(w, r)
+		default:
+			app.serverErrorLogResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.models.Tokens.DeleteAllForUser(data.ScopePasswordReset, user.ID)
+	if err != nil {
+		app.serverErrorLogResponse(w, r, err)
+		return
+	}
+
+	err = app.auth.BlacklistToken.BlacklistUserTokens(user.ID, time.Now())
+	if err != nil {
+		app.serverErrorLogResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"message": "your password was successfully reset"}, nil)
+	if err != nil {
+		app.serverErrorLogResponse(w, r, err)
+		return
+	}
+}
+
+// listUserRolesHandler handles listing the roles of the user with the given id.
+func (app *application) listUserRolesHandler(w http.ResponseWriter, r *http.Request) {
+
+	id, err := app.extractIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	_, err = app.models.Users.GetByID(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorLogResponse(w, r, err)
+		}
+		return
+	}
+
+	roles, err := app.models.Permissions.GetRolesForUser(id)
+	if err != nil {
+		app.serverErrorLogResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"roles": roles}, nil)
+	if err != nil {
+		app.serverErrorLogResponse(w, r, err)
+		return
+	}
+}
+
+// updateUserRolesHandler handles replacing the roles of the user with the given id.
+func (app *application) updateUserRolesHandler(w http.ResponseWriter, r *http.Request) {
+
+	id, err := app.extractIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	var input struct {
+		Roles []string `json:"roles"`
+	}
+
+	err = app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+	if data.ValidateRoles(v, input.Roles); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	_, err = app.models.Users.GetByID(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorLogResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.models.Permissions.SetRolesForUser(id, input.Roles)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrUnknownRole):
+			v.AddError("roles", "must contain only existing roles")
+			app.failedValidationResponse(w, r, v.Errors)
+		default:
+			app.serverErrorLogResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"roles": input.Roles}, nil)
+	if err != nil {
+		app.serverErrorLogResponse(w, r, err)
+		return
+	}
+}
+
+// updateUserActivationHandler handles the activation and deactivation of the user with the given id by a moderator.
+func (app *application) updateUserActivationHandler(w http.ResponseWriter, r *http.Request) {
+
+	id, err := app.extractIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	var input struct {
+		Activated *bool `json:"activated"`
+	}
+
+	err = app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+	if v.Check(input.Activated != nil, "activated", "must be provided"); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	user, err := app.models.Users.GetByID(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorLogResponse(w, r, err)
+		}
+		return
+	}
+
+	user.Activated = *input.Activated
+
+	err = app.models.Users.Update(user)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrEditConflict):
+			app.editConflictResponse(w, r)
+		default:
+			app.serverErrorLogResponse(w, r, err)
+		}
+		return
+	}
+
+	user.Reputation, err = app.models.Reviews.GetReputation(user.ID)
+	if err != nil {
+		app.serverErrorLogResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"user": user}, nil)
+	if err != nil {
+		app.serverErrorLogResponse(w, r, err)
+		return
+	}
+}
@@ -12,7 +12,9 @@ import (
 	"reflect"
 	"strconv"
 	"testing"
+	"time"
 
+	"github.com/ttarnok/instrument-swap-api/internal/auth"
 	"github.com/ttarnok/instrument-swap-api/internal/data"
 	"github.com/ttarnok/instrument-swap-api/internal/data/mocks"
 )
@@ -177,6 +179,9 @@ func TestUpdateUserHandler(t *testing.T) {
 		name               string
 		pathParam          int
 		input              inputBody
+		contentType        string
+		rawBody            string
+		ifMatch            string
 		expectedStatusCode int
 		expectedUser       *data.User
 	}
@@ -232,6 +237,75 @@ func TestUpdateUserHandler(t *testing.T) {
 				Email: "NewEmail@example.com",
 			},
 		},
+		{
+			name:               "merge patch",
+			pathParam:          1,
+			contentType:        "application/merge-patch+json",
+			rawBody:            `{"email": "NewEmail@example.com"}`,
+			expectedStatusCode: http.StatusOK,
+			expectedUser: &data.User{
+				ID:    1,
+				Name:  "Dummy Username",
+				Email: "NewEmail@example.com",
+			},
+		},
+		{
+			name:               "merge patch clearing the name",
+			pathParam:          1,
+			contentType:        "application/merge-patch+json",
+			rawBody:            `{"name": null}`,
+			expectedStatusCode: http.StatusUnprocessableEntity,
+			expectedUser:       nil,
+		},
+		{
+			name:               "json patch",
+			pathParam:          1,
+			contentType:        "application/json-patch+json",
+			rawBody:            `[{"op": "test", "path": "/email", "value": "test@example.com"}, {"op": "replace", "path": "/name", "value": "NewName"}]`,
+			expectedStatusCode: http.StatusOK,
+			expectedUser: &data.User{
+				ID:    1,
+				Name:  "NewName",
+				Email: "test@example.com",
+			},
+		},
+		{
+			name:               "json patch with failed test",
+			pathParam:          1,
+			contentType:        "application/json-patch+json",
+			rawBody:            `[{"op": "test", "path": "/email", "value": "other@example.com"}]`,
+			expectedStatusCode: http.StatusConflict,
+			expectedUser:       nil,
+		},
+		{
+			// the reviews of the user do not change its entity tag
+			name:               "if-match of the current version",
+			pathParam:          1,
+			input:              inputBody{Name: "NewName", Email: "test@example.com"},
+			ifMatch:            `"2"`,
+			expectedStatusCode: http.StatusOK,
+			expectedUser: &data.User{
+				ID:    1,
+				Name:  "NewName",
+				Email: "test@example.com",
+			},
+		},
+		{
+			name:               "if-match of a previous version",
+			pathParam:          1,
+			input:              inputBody{Name: "NewName", Email: "test@example.com"},
+			ifMatch:            `"1"`,
+			expectedStatusCode: http.StatusPreconditionFailed,
+			expectedUser:       nil,
+		},
+		{
+			name:               "unsupported media type",
+			pathParam:          1,
+			contentType:        "application/xml",
+			rawBody:            `<user><name>NewName</name></user>`,
+			expectedStatusCode: http.StatusUnsupportedMediaType,
+			expectedUser:       nil,
+		},
 	}
 
 	for _, tc := range testCases {
@@ -239,9 +313,10 @@ func TestUpdateUserHandler(t *testing.T) {
 			t.Parallel()
 
 			testUser := data.User{
-				ID:    1,
-				Name:  "Dummy Username",
-				Email: "test@example.com",
+				ID:      1,
+				Name:    "Dummy Username",
+				Email:   "test@example.com",
+				Version: 2,
 			}
 
 			err := testUser.Password.Set("asd123asd123")
@@ -253,7 +328,10 @@ func TestUpdateUserHandler(t *testing.T) {
 
 			app := &application{
 				logger: slog.New(slog.NewTextHandler(io.Discard, nil)),
-				models: data.Models{Users: mocks.NewUserModelMock(users)},
+				models: data.Models{
+					Users:   mocks.NewUserModelMock(users),
+					Reviews: mocks.NewReviewModelMock([]*data.Review{{ID: 1, SwapID: 1, ReviewerUserID: 2, RevieweeUserID: 1, Rating: 4}}),
+				},
 			}
 
 			mux := http.NewServeMux()
@@ -268,11 +346,20 @@ func TestUpdateUserHandler(t *testing.T) {
 			if err != nil {
 				t.Fatal(err)
 			}
+			if tc.rawBody != "" {
+				bs = []byte(tc.rawBody)
+			}
 
 			req, err := http.NewRequest("POST", path, bytes.NewBuffer(bs))
 			if err != nil {
 				t.Fatal(err)
 			}
+			if tc.contentType != "" {
+				req.Header.Set("Content-Type", tc.contentType)
+			}
+			if tc.ifMatch != "" {
+				req.Header.Set("If-Match", tc.ifMatch)
+			}
 
 			client := &http.Client{}
 			resp, err := client.Do(req)
@@ -286,6 +373,10 @@ func TestUpdateUserHandler(t *testing.T) {
 
 			if tc.expectedUser != nil {
 
+				if etag := resp.Header.Get("ETag"); etag != `"2"` {
+					t.Errorf(`expected ETag %q, got %q`, `"2"`, etag)
+				}
+
 				defer func() {
 					err := resp.Body.Close()
 					if err != nil {
@@ -333,6 +424,103 @@ func TestUpdateUserHandler(t *testing.T) {
 
 }
 
+// TestShowUserHandler implements unit tests for showUserHandler.
+func TestShowUserHandler(t *testing.T) {
+
+	type testCase struct {
+		name               string
+		pathParam          string
+		ifNoneMatch        string
+		expectedStatusCode int
+		expectedETag       string
+	}
+
+	testCases := []testCase{
+		{
+			name:               "happy path",
+			pathParam:          "1",
+			expectedStatusCode: http.StatusOK,
+			expectedETag:       `"2"`,
+		},
+		{
+			name:               "matching if-none-match",
+			pathParam:          "1",
+			ifNoneMatch:        `"2"`,
+			expectedStatusCode: http.StatusNotModified,
+			expectedETag:       `"2"`,
+		},
+		{
+			name:               "if-none-match of a previous version",
+			pathParam:          "1",
+			ifNoneMatch:        `"1"`,
+			expectedStatusCode: http.StatusOK,
+			expectedETag:       `"2"`,
+		},
+		{
+			name:               "non existent user",
+			pathParam:          "11",
+			expectedStatusCode: http.StatusNotFound,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			reviews := []*data.Review{}
+			for i, rating := range []int{4, 4, 5, 4, 4} {
+				reviews = append(reviews, &data.Review{ID: int64(i + 1), SwapID: int64(i + 1), ReviewerUserID: 2, RevieweeUserID: 1, Rating: rating})
+			}
+
+			app := &application{
+				logger: slog.New(slog.NewTextHandler(io.Discard, nil)),
+				models: data.Models{
+					Users: mocks.NewUserModelMock([]*data.User{{
+						ID:      1,
+						Name:    "Dummy Username",
+						Email:   "test@example.com",
+						Version: 2,
+					}}),
+					Reviews: mocks.NewReviewModelMock(reviews),
+				},
+			}
+
+			mux := http.NewServeMux()
+			mux.HandleFunc("GET /{id}", app.showUserHandler)
+
+			ts := httptest.NewServer(mux)
+			defer ts.Close()
+
+			req, err := http.NewRequest(http.MethodGet, ts.URL+"/"+tc.pathParam, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tc.ifNoneMatch != "" {
+				req.Header.Set("If-None-Match", tc.ifNoneMatch)
+			}
+
+			resp, err := ts.Client().Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer func() {
+				err := resp.Body.Close()
+				if err != nil {
+					t.Fatal(err)
+				}
+			}()
+
+			if tc.expectedStatusCode != resp.StatusCode {
+				t.Errorf(`expected status code %d, got %d`, tc.expectedStatusCode, resp.StatusCode)
+			}
+
+			if etag := resp.Header.Get("ETag"); etag != tc.expectedETag {
+				t.Errorf(`expected ETag %q, got %q`, tc.expectedETag, etag)
+			}
+		})
+	}
+}
+
 // TestDeleteUserHandler implements unit tests for deleteUserHandler.
 func TestDeleteUserHandler(t *testing.T) {
 
@@ -521,9 +709,12 @@ func TestRegisterUserHandler(t *testing.T) {
 		t.Run(tc.name, func(t *testing.T) {
 			t.Parallel()
 
+			mailer := mocks.NewMailerMock()
+
 			app := &application{
 				logger: slog.New(slog.NewTextHandler(io.Discard, nil)),
-				models: data.Models{Users: mocks.NewUserModelMock(testUsers)},
+				models: data.Models{Users: mocks.NewUserModelMock(testUsers), Tokens: mocks.NewTokenModelMock()},
+				mailer: mailer,
 			}
 
 			mux := http.NewServeMux()
@@ -613,6 +804,30 @@ func TestRegisterUserHandler(t *testing.T) {
 					t.Errorf(`expected matching passwords`)
 				}
 
+				if user.Activated {
+					t.Errorf(`expected a newly registered user to be not activated`)
+				}
+
+				app.wg.Wait()
+
+				sent := mailer.Sent()
+				if len(sent) != 1 {
+					t.Fatalf(`expected 1 sent email, got %d`, len(sent))
+				}
+
+				if sent[0].Recipient != tc.input.Email {
+					t.Errorf(`expected email recipient "%s", got "%s"`, tc.input.Email, sent[0].Recipient)
+				}
+
+				mailData, ok := sent[0].Data.(map[string]any)
+				if !ok {
+					t.Fatal(`the sent email data should be a map`)
+				}
+
+				if mailData["activationToken"] != mocks.TokenPlaintext {
+					t.Errorf(`expected activation token "%s", got "%v"`, mocks.TokenPlaintext, mailData["activationToken"])
+				}
+
 			}
 
 		})
@@ -620,6 +835,117 @@ func TestRegisterUserHandler(t *testing.T) {
 
 }
 
+// TestActivateUserHandler implements unit tests for activateUserHandler.
+func TestActivateUserHandler(t *testing.T) {
+
+	validToken := "ABCDEFGHIJKLMNOPQRSTUVWXYZ"
+
+	type inputType struct {
+		Token string `json:"token"`
+	}
+
+	type testCase struct {
+		name               string
+		input              inputType
+		expectedStatusCode int
+		expectedActivated  bool
+	}
+
+	testCases := []testCase{
+		{
+			name:               "happy path",
+			input:              inputType{Token: validToken},
+			expectedStatusCode: http.StatusOK,
+			expectedActivated:  true,
+		},
+		{
+			name:               "empty token",
+			input:              inputType{Token: ""},
+			expectedStatusCode: http.StatusUnprocessableEntity,
+			expectedActivated:  false,
+		},
+		{
+			name:               "malformed token",
+			input:              inputType{Token: "ABC"},
+			expectedStatusCode: http.StatusUnprocessableEntity,
+			expectedActivated:  false,
+		},
+		{
+			name:               "unknown token",
+			input:              inputType{Token: "ZZZZZZZZZZZZZZZZZZZZZZZZZZ"},
+			expectedStatusCode: http.StatusUnprocessableEntity,
+			expectedActivated:  false,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			testUser := &data.User{
+				ID:    1,
+				Name:  "Dummy Username",
+				Email: "test@example.com",
+			}
+
+			tokens := mocks.NewTokenModelMock()
+			err := tokens.Insert(&data.Token{Plaintext: validToken, UserID: 1, Scope: data.ScopeActivation})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			app := &application{
+				logger: slog.New(slog.NewTextHandler(io.Discard, nil)),
+				models: data.Models{
+					Users:   mocks.NewUserModelMock([]*data.User{testUser}).AddToken(data.ScopeActivation, validToken, 1),
+					Tokens:  tokens,
+					Reviews: mocks.NewReviewModelMock(nil),
+				},
+			}
+
+			mux := http.NewServeMux()
+			mux.HandleFunc("PUT /", app.activateUserHandler)
+
+			ts := httptest.NewServer(mux)
+			defer ts.Close()
+
+			bs, err := json.Marshal(tc.input)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			req, err := http.NewRequest("PUT", ts.URL+"/", bytes.NewBuffer(bs))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			client := &http.Client{}
+			resp, err := client.Do(req)
+			if err != nil {
+				log.Fatal(err)
+			}
+
+			if tc.expectedStatusCode != resp.StatusCode {
+				t.Errorf(`expected status code %d, got %d`, tc.expectedStatusCode, resp.StatusCode)
+			}
+
+			user, err := app.models.Users.GetByID(1)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if tc.expectedActivated != user.Activated {
+				t.Errorf(`expected activated %t, got %t`, tc.expectedActivated, user.Activated)
+			}
+
+			if tc.expectedActivated && len(tokens.Tokens()) != 0 {
+				t.Errorf(`expected activation tokens to be deleted, got %d tokens`, len(tokens.Tokens()))
+			}
+		})
+	}
+
+}
+
 // TestUpdatePasswordHandler implements unit tests for updatePasswordHandler.
 func TestUpdatePasswordHandler(t *testing.T) {
 
@@ -765,3 +1091,455 @@ func TestUpdatePasswordHandler(t *testing.T) {
 		})
 	}
 }
+
+// TestResetPasswordHandler implements unit tests for resetPasswordHandler.
+func TestResetPasswordHandler(t *testing.T) {
+
+	validToken := "ABCDEFGHIJKLMNOPQRSTUVWXYZ"
+
+	type inputType struct {
+		Password string `json:"password"`
+		Token    string `json:"token"`
+	}
+
+	type testCase struct {
+		name               string
+		input              inputType
+		expectedStatusCode int
+		shouldCheckModel   bool
+	}
+
+	testCases := []testCase{
+		{
+			name:               "happy path",
+			input:              inputType{Password: "newpass1111", Token: validToken},
+			expectedStatusCode: http.StatusOK,
+			shouldCheckModel:   true,
+		},
+		{
+			name:               "unknown token",
+			input:              inputType{Password: "newpass1111", Token: "ZZZZZZZZZZZZZZZZZZZZZZZZZZ"},
+			expectedStatusCode: http.StatusUnprocessableEntity,
+			shouldCheckModel:   false,
+		},
+		{
+			name:               "activation token",
+			input:              inputType{Password: "newpass1111", Token: "AAAAAAAAAAAAAAAAAAAAAAAAAA"},
+			expectedStatusCode: http.StatusUnprocessableEntity,
+			shouldCheckModel:   false,
+		},
+		{
+			name:               "not valid password",
+			input:              inputType{Password: "new", Token: validToken},
+			expectedStatusCode: http.StatusUnprocessableEntity,
+			shouldCheckModel:   false,
+		},
+		{
+			name:               "empty token",
+			input:              inputType{Password: "newpass1111", Token: ""},
+			expectedStatusCode: http.StatusUnprocessableEntity,
+			shouldCheckModel:   false,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			testUser := &data.User{
+				ID:    1,
+				Name:  "Dummy Username",
+				Email: "test@example.com",
+			}
+			err := testUser.Password.Set("asd123asd123")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			tokens := mocks.NewTokenModelMock()
+			err = tokens.Insert(&data.Token{Plaintext: validToken, UserID: 1, Scope: data.ScopePasswordReset})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			users := mocks.NewUserModelMock([]*data.User{testUser}).
+				AddToken(data.ScopePasswordReset, validToken, 1).
+				AddToken(data.ScopeActivation, "AAAAAAAAAAAAAAAAAAAAAAAAAA", 1)
+
+			blacklist := mocks.NewBlacklistServiceMock()
+
+			app := &application{
+				logger: slog.New(slog.NewTextHandler(io.Discard, nil)),
+				models: data.Models{Users: users, Tokens: tokens},
+				auth:   auth.NewAuth("secret", blacklist),
+			}
+
+			mux := http.NewServeMux()
+			mux.HandleFunc("PUT /", app.resetPasswordHandler)
+
+			ts := httptest.NewServer(mux)
+			defer ts.Close()
+
+			bs, err := json.Marshal(tc.input)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			req, err := http.NewRequest("PUT", ts.URL+"/", bytes.NewBuffer(bs))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			client := &http.Client{}
+			resp, err := client.Do(req)
+			if err != nil {
+				log.Fatal(err)
+			}
+
+			if tc.expectedStatusCode != resp.StatusCode {
+				t.Errorf(`expected status code %d, got %d`, tc.expectedStatusCode, resp.StatusCode)
+			}
+
+			if tc.shouldCheckModel {
+				user, err := app.models.Users.GetByID(1)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				isPassMatch, err := user.Password.Matches(tc.input.Password)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !isPassMatch {
+					t.Error(`updated password should match the new password`)
+				}
+
+				if len(tokens.Tokens()) != 0 {
+					t.Errorf(`expected password reset tokens to be deleted, got %d tokens`, len(tokens.Tokens()))
+				}
+
+				isBlacklisted, err := blacklist.IsUserTokenBlacklisted(1, time.Now().Add(-time.Minute))
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !isBlacklisted {
+					t.Error(`tokens issued before the password reset should be blacklisted`)
+				}
+			}
+		})
+	}
+}
+
+// TestListUserRolesHandler implements unit tests for listUserRolesHandler.
+func TestListUserRolesHandler(t *testing.T) {
+
+	type testCase struct {
+		name               string
+		userID             string
+		roles              map[int64][]string
+		expectedStatusCode int
+		expectedRoles      []string
+	}
+
+	testCases := []testCase{
+		{
+			name:               "happy path",
+			userID:             "1",
+			roles:              map[int64][]string{1: {data.RoleAdmin}},
+			expectedStatusCode: http.StatusOK,
+			expectedRoles:      []string{data.RoleAdmin},
+		},
+		{
+			name:               "user without roles",
+			userID:             "2",
+			roles:              map[int64][]string{1: {data.RoleAdmin}},
+			expectedStatusCode: http.StatusOK,
+			expectedRoles:      []string{},
+		},
+		{
+			name:               "non existent user",
+			userID:             "100",
+			roles:              nil,
+			expectedStatusCode: http.StatusNotFound,
+		},
+		{
+			name:               "invalid id",
+			userID:             "abc",
+			roles:              nil,
+			expectedStatusCode: http.StatusNotFound,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			app := &application{
+				logger: slog.New(slog.NewTextHandler(io.Discard, nil)),
+				models: data.Models{
+					Users:       mocks.NewEmptyUserModelMock(),
+					Permissions: mocks.NewPermissionModelMock(tc.roles),
+				},
+			}
+
+			mux := http.NewServeMux()
+			mux.HandleFunc("GET /{id}", app.listUserRolesHandler)
+
+			ts := httptest.NewServer(mux)
+			defer ts.Close()
+
+			req, err := http.NewRequest("GET", ts.URL+"/"+tc.userID, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			client := &http.Client{}
+			resp, err := client.Do(req)
+			if err != nil {
+				log.Fatal(err)
+			}
+			defer func() {
+				err := resp.Body.Close()
+				if err != nil {
+					t.Fatal(err)
+				}
+			}()
+
+			if tc.expectedStatusCode != resp.StatusCode {
+				t.Errorf(`expected status code %d, got %d`, tc.expectedStatusCode, resp.StatusCode)
+			}
+
+			if tc.expectedStatusCode != http.StatusOK {
+				return
+			}
+
+			var respBody struct {
+				Roles []string `json:"roles"`
+			}
+
+			err = json.NewDecoder(resp.Body).Decode(&respBody)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !reflect.DeepEqual(tc.expectedRoles, respBody.Roles) {
+				t.Errorf(`expected roles %v, got %v`, tc.expectedRoles, respBody.Roles)
+			}
+		})
+	}
+}
+
+// TestUpdateUserRolesHandler implements unit tests for updateUserRolesHandler.
+func TestUpdateUserRolesHandler(t *testing.T) {
+
+	type testCase struct {
+		name               string
+		userID             string
+		input              string
+		expectedStatusCode int
+		expectedRoles      []string
+	}
+
+	testCases := []testCase{
+		{
+			name:               "grant admin role",
+			userID:             "1",
+			input:              `{"roles": ["admin"]}`,
+			expectedStatusCode: http.StatusOK,
+			expectedRoles:      []string{data.RoleAdmin},
+		},
+		{
+			name:               "revoke all roles",
+			userID:             "1",
+			input:              `{"roles": []}`,
+			expectedStatusCode: http.StatusOK,
+			expectedRoles:      []string{},
+		},
+		{
+			name:               "unknown role",
+			userID:             "1",
+			input:              `{"roles": ["superuser"]}`,
+			expectedStatusCode: http.StatusUnprocessableEntity,
+			expectedRoles:      []string{},
+		},
+		{
+			name:               "duplicated roles",
+			userID:             "1",
+			input:              `{"roles": ["admin", "admin"]}`,
+			expectedStatusCode: http.StatusUnprocessableEntity,
+			expectedRoles:      []string{},
+		},
+		{
+			name:               "missing roles",
+			userID:             "1",
+			input:              `{}`,
+			expectedStatusCode: http.StatusUnprocessableEntity,
+			expectedRoles:      []string{},
+		},
+		{
+			name:               "unknown field",
+			userID:             "1",
+			input:              `{"roles": ["admin"], "permissions": ["users:read"]}`,
+			expectedStatusCode: http.StatusBadRequest,
+			expectedRoles:      []string{},
+		},
+		{
+			name:               "non existent user",
+			userID:             "100",
+			input:              `{"roles": ["admin"]}`,
+			expectedStatusCode: http.StatusNotFound,
+			expectedRoles:      []string{},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			app := &application{
+				logger: slog.New(slog.NewTextHandler(io.Discard, nil)),
+				models: data.Models{
+					Users:       mocks.NewEmptyUserModelMock(),
+					Permissions: mocks.NewPermissionModelMock(nil),
+				},
+			}
+
+			mux := http.NewServeMux()
+			mux.HandleFunc("PUT /{id}", app.updateUserRolesHandler)
+
+			ts := httptest.NewServer(mux)
+			defer ts.Close()
+
+			req, err := http.NewRequest("PUT", ts.URL+"/"+tc.userID, bytes.NewBufferString(tc.input))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			client := &http.Client{}
+			resp, err := client.Do(req)
+			if err != nil {
+				log.Fatal(err)
+			}
+
+			if tc.expectedStatusCode != resp.StatusCode {
+				t.Errorf(`expected status code %d, got %d`, tc.expectedStatusCode, resp.StatusCode)
+			}
+
+			userID, err := strconv.ParseInt(tc.userID, 10, 64)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			roles, err := app.models.Permissions.GetRolesForUser(userID)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !reflect.DeepEqual(tc.expectedRoles, roles) {
+				t.Errorf(`expected roles %v, got %v`, tc.expectedRoles, roles)
+			}
+		})
+	}
+}
+
+// TestUpdateUserActivationHandler implements unit tests for updateUserActivationHandler.
+func TestUpdateUserActivationHandler(t *testing.T) {
+
+	type testCase struct {
+		name               string
+		userID             string
+		activated          bool
+		input              string
+		expectedStatusCode int
+		expectedActivated  bool
+	}
+
+	testCases := []testCase{
+		{
+			name:               "activate user",
+			userID:             "1",
+			activated:          false,
+			input:              `{"activated": true}`,
+			expectedStatusCode: http.StatusOK,
+			expectedActivated:  true,
+		},
+		{
+			name:               "deactivate user",
+			userID:             "1",
+			activated:          true,
+			input:              `{"activated": false}`,
+			expectedStatusCode: http.StatusOK,
+			expectedActivated:  false,
+		},
+		{
+			name:               "missing activated",
+			userID:             "1",
+			activated:          true,
+			input:              `{}`,
+			expectedStatusCode: http.StatusUnprocessableEntity,
+			expectedActivated:  true,
+		},
+		{
+			name:               "invalid activated",
+			userID:             "1",
+			activated:          true,
+			input:              `{"activated": "no"}`,
+			expectedStatusCode: http.StatusBadRequest,
+			expectedActivated:  true,
+		},
+		{
+			name:               "non existent user",
+			userID:             "2",
+			activated:          true,
+			input:              `{"activated": false}`,
+			expectedStatusCode: http.StatusNotFound,
+			expectedActivated:  true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			testUser := &data.User{
+				ID:        1,
+				Name:      "Dummy Username",
+				Email:     "test@example.com",
+				Activated: tc.activated,
+			}
+
+			app := &application{
+				logger: slog.New(slog.NewTextHandler(io.Discard, nil)),
+				models: data.Models{
+					Users:   mocks.NewUserModelMock([]*data.User{testUser}),
+					Reviews: mocks.NewReviewModelMock(nil),
+				},
+			}
+
+			mux := http.NewServeMux()
+			mux.HandleFunc("PUT /{id}", app.updateUserActivationHandler)
+
+			ts := httptest.NewServer(mux)
+			defer ts.Close()
+
+			req, err := http.NewRequest("PUT", ts.URL+"/"+tc.userID, bytes.NewBufferString(tc.input))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			client := &http.Client{}
+			resp, err := client.Do(req)
+			if err != nil {
+				log.Fatal(err)
+			}
+
+			if tc.expectedStatusCode != resp.StatusCode {
+				t.Errorf(`expected status code %d, got %d`, tc.expectedStatusCode, resp.StatusCode)
+			}
+
+			if tc.expectedActivated != testUser.Activated {
+				t.Errorf(`expected activated %t, got %t`, tc.expectedActivated, testUser.Activated)
+			}
+		})
+	}
+}
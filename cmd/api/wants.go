@@ -0,0 +1,131 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/ttarnok/instrument-swap-api/internal/data"
+	"github.com/ttarnok/instrument-swap-api/internal/validator"
+)
+
+// listWantsHandler lists the wants of the authenticated user.
+func (app *application) listWantsHandler(w http.ResponseWriter, r *http.Request) {
+	authUser := app.contextGetUser(r)
+
+	wants, err := app.models.Wants.GetAllForUser(authUser.ID)
+	if err != nil {
+		app.serverErrorLogResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"wants": wants}, nil)
+	if err != nil {
+		app.serverErrorLogResponse(w, r, err)
+		return
+	}
+}
+
+// createWantHandler registers a want of the authenticated user.
+// A want referring a specific instrument must refer an existing instrument of another user.
+func (app *application) createWantHandler(w http.ResponseWriter, r *http.Request) {
+	authUser := app.contextGetUser(r)
+
+	var input struct {
+		InstrumentType string `json:"instrument_type"`
+		Manufacturer   string `json:"manufacturer"`
+		InstrumentID   *int64 `json:"instrument_id"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	want := &data.Want{
+		UserID:         authUser.ID,
+		InstrumentType: strings.ToLower(input.InstrumentType),
+		Manufacturer:   input.Manufacturer,
+		InstrumentID:   input.InstrumentID,
+	}
+
+	v := validator.New()
+
+	if data.ValidateWant(v, want); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	if want.InstrumentType != "" {
+		err = app.validateInstrumentType(v, "instrument_type", want.InstrumentType)
+		if err != nil {
+			app.serverErrorLogResponse(w, r, err)
+			return
+		}
+		if !v.Valid() {
+			app.failedValidationResponse(w, r, v.Errors)
+			return
+		}
+	}
+
+	if want.InstrumentID != nil {
+		instrument, err := app.models.Instruments.Get(*want.InstrumentID)
+		if err != nil {
+			switch {
+			case errors.Is(err, data.ErrRecordNotFound):
+				v.AddError("instrument_id", "must be an existing instrument")
+			default:
+				app.serverErrorLogResponse(w, r, err)
+				return
+			}
+		} else {
+			v.Check(instrument.OwnerUserID != authUser.ID, "instrument_id", "must not be an own instrument")
+		}
+
+		if !v.Valid() {
+			app.failedValidationResponse(w, r, v.Errors)
+			return
+		}
+	}
+
+	err = app.models.Wants.Insert(want)
+	if err != nil {
+		app.serverErrorLogResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusCreated, envelope{"want": want}, nil)
+	if err != nil {
+		app.serverErrorLogResponse(w, r, err)
+		return
+	}
+}
+
+// deleteWantHandler removes a want of the authenticated user.
+func (app *application) deleteWantHandler(w http.ResponseWriter, r *http.Request) {
+	authUser := app.contextGetUser(r)
+
+	id, err := app.extractIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	err = app.models.Wants.Delete(id, authUser.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorLogResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"message": "want successfully deleted"}, nil)
+	if err != nil {
+		app.serverErrorLogResponse(w, r, err)
+		return
+	}
+}
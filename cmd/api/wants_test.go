@@ -0,0 +1,259 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+
+	"github.com/ttarnok/instrument-swap-api/internal/data"
+	"github.com/ttarnok/instrument-swap-api/internal/data/mocks"
+)
+
+// TestCreateWantHandler implements unit tests for createWantHandler.
+func TestCreateWantHandler(t *testing.T) {
+
+	type inputBodyType struct {
+		InstrumentType string `json:"instrument_type,omitempty"`
+		Manufacturer   string `json:"manufacturer,omitempty"`
+		InstrumentID   *int64 `json:"instrument_id,omitempty"`
+	}
+
+	ownInstrumentID := int64(1)
+	otherInstrumentID := int64(2)
+	nonExistentInstrumentID := int64(99)
+
+	type testCase struct {
+		name               string
+		inputBody          inputBodyType
+		expectedStatusCode int
+		expectedType       string
+	}
+
+	testCases := []testCase{
+		{
+			name:               "happy path",
+			inputBody:          inputBodyType{InstrumentType: "guitar", Manufacturer: "Fender"},
+			expectedStatusCode: http.StatusCreated,
+			expectedType:       "guitar",
+		},
+		{
+			name:               "instrument type in upper case",
+			inputBody:          inputBodyType{InstrumentType: "Guitar"},
+			expectedStatusCode: http.StatusCreated,
+			expectedType:       "guitar",
+		},
+		{
+			name:               "specific instrument",
+			inputBody:          inputBodyType{InstrumentID: &otherInstrumentID},
+			expectedStatusCode: http.StatusCreated,
+		},
+		{
+			name:               "empty want",
+			expectedStatusCode: http.StatusUnprocessableEntity,
+		},
+		{
+			name:               "non valid instrument type",
+			inputBody:          inputBodyType{InstrumentType: "drum"},
+			expectedStatusCode: http.StatusUnprocessableEntity,
+		},
+		{
+			name:               "own instrument",
+			inputBody:          inputBodyType{InstrumentID: &ownInstrumentID},
+			expectedStatusCode: http.StatusUnprocessableEntity,
+		},
+		{
+			name:               "non existent instrument",
+			inputBody:          inputBodyType{InstrumentID: &nonExistentInstrumentID},
+			expectedStatusCode: http.StatusUnprocessableEntity,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			app := &application{
+				logger: slog.New(slog.NewTextHandler(io.Discard, nil)),
+				models: data.Models{
+					Wants:       mocks.NewWantModelMock(nil),
+					Instruments: mocks.NewNonEmptyInstrumentModelMock([]*data.Instrument{{ID: 1, OwnerUserID: 10}, {ID: 2, OwnerUserID: 20}}),
+					Categories:  newTestCategoryModelMock(),
+				},
+			}
+
+			setUser := func(next http.HandlerFunc) http.HandlerFunc {
+				return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+					r = app.contextSetUser(r, &data.User{ID: 10})
+
+					next.ServeHTTP(w, r)
+				})
+			}
+
+			mux := http.NewServeMux()
+			mux.HandleFunc("POST /", setUser(app.createWantHandler))
+
+			ts := httptest.NewServer(mux)
+			defer ts.Close()
+
+			bs, err := json.Marshal(tc.inputBody)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			resp, err := http.Post(ts.URL, "application/json", bytes.NewBuffer(bs))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer func() {
+				err := resp.Body.Close()
+				if err != nil {
+					t.Fatal(err)
+				}
+			}()
+
+			if tc.expectedStatusCode != resp.StatusCode {
+				t.Errorf(`expected status code %d, got %d`, tc.expectedStatusCode, resp.StatusCode)
+			}
+
+			if tc.expectedStatusCode != http.StatusCreated {
+				return
+			}
+
+			wants, err := app.models.Wants.GetAllForUser(10)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(wants) != 1 {
+				t.Fatalf(`expected 1 stored want, got %d`, len(wants))
+			}
+			if wants[0].InstrumentType != tc.expectedType {
+				t.Errorf(`expected instrument type %q, got %q`, tc.expectedType, wants[0].InstrumentType)
+			}
+		})
+	}
+}
+
+// TestDeleteWantHandler implements unit tests for deleteWantHandler.
+func TestDeleteWantHandler(t *testing.T) {
+
+	type testCase struct {
+		name               string
+		pathParam          string
+		reqUser            data.User
+		expectedStatusCode int
+		expectedWantIDs    []int64
+	}
+
+	testCases := []testCase{
+		{
+			name:               "happy path",
+			pathParam:          "1",
+			reqUser:            data.User{ID: 10},
+			expectedStatusCode: http.StatusOK,
+			expectedWantIDs:    []int64{2},
+		},
+		{
+			name:               "want of another user",
+			pathParam:          "3",
+			reqUser:            data.User{ID: 10},
+			expectedStatusCode: http.StatusNotFound,
+			expectedWantIDs:    []int64{1, 2},
+		},
+		{
+			name:               "non existent want",
+			pathParam:          "99",
+			reqUser:            data.User{ID: 10},
+			expectedStatusCode: http.StatusNotFound,
+			expectedWantIDs:    []int64{1, 2},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			app := &application{
+				logger: slog.New(slog.NewTextHandler(io.Discard, nil)),
+				models: data.Models{
+					Wants: mocks.NewWantModelMock([]*data.Want{
+						{ID: 1, UserID: 10, InstrumentType: "guitar"},
+						{ID: 2, UserID: 10, Manufacturer: "Moog"},
+						{ID: 3, UserID: 20, InstrumentType: "synthesizer"},
+					}),
+				},
+			}
+
+			setUser := func(next http.HandlerFunc) http.HandlerFunc {
+				return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+					r = app.contextSetUser(r, &tc.reqUser)
+
+					next.ServeHTTP(w, r)
+				})
+			}
+
+			mux := http.NewServeMux()
+			mux.HandleFunc("DELETE /{id}", setUser(app.deleteWantHandler))
+			mux.HandleFunc("GET /", setUser(app.listWantsHandler))
+
+			ts := httptest.NewServer(mux)
+			defer ts.Close()
+
+			req, err := http.NewRequest(http.MethodDelete, fmt.Sprintf("%s/%s", ts.URL, tc.pathParam), nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			resp, err := ts.Client().Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer func() {
+				err := resp.Body.Close()
+				if err != nil {
+					t.Fatal(err)
+				}
+			}()
+
+			if tc.expectedStatusCode != resp.StatusCode {
+				t.Errorf(`expected status code %d, got %d`, tc.expectedStatusCode, resp.StatusCode)
+			}
+
+			listResp, err := http.Get(ts.URL)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer func() {
+				err := listResp.Body.Close()
+				if err != nil {
+					t.Fatal(err)
+				}
+			}()
+
+			var respBody struct {
+				Wants []*data.Want `json:"wants"`
+			}
+
+			err = json.NewDecoder(listResp.Body).Decode(&respBody)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			wantIDs := []int64{}
+			for _, want := range respBody.Wants {
+				wantIDs = append(wantIDs, want.ID)
+			}
+
+			if !slices.Equal(tc.expectedWantIDs, wantIDs) {
+				t.Errorf(`expected wants %v, got %v`, tc.expectedWantIDs, wantIDs)
+			}
+		})
+	}
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// Times between two runs of the swap workers.
+const (
+	swapExpiryInterval  = time.Minute
+	swapOverdueInterval = time.Minute
+)
+
+// startWorker starts a background worker that runs the given job immediately and then periodically,
+// with the given interval between the runs.
+// The worker stops when the given context is done, app.wg can be used to wait for it.
+func (app *application) startWorker(ctx context.Context, interval time.Duration, job func()) {
+	app.wg.Add(1)
+
+	go func() {
+		defer app.wg.Done()
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			job()
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// startSwapExpiryWorker starts a background worker that periodically expires the swaps and the swap cycles,
+// that are pending or proposed for longer than the configured swap pending ttl.
+// The worker stops when the given context is done, app.wg can be used to wait for it.
+func (app *application) startSwapExpiryWorker(ctx context.Context, interval time.Duration) {
+	app.startWorker(ctx, interval, func() {
+		app.expirePendingSwaps()
+		app.expireProposedSwapCycles()
+	})
+}
+
+// expirePendingSwaps expires the swaps that are pending for longer than the configured swap pending ttl.
+func (app *application) expirePendingSwaps() {
+	defer func() {
+		if err := recover(); err != nil {
+			app.logger.Error(fmt.Sprintf("%v", err))
+		}
+	}()
+
+	count, err := app.models.Swaps.ExpirePending(time.Now().Add(-app.config.swap.pendingTTL))
+	if err != nil {
+		app.logger.Error(err.Error())
+		return
+	}
+
+	if count > 0 {
+		app.logger.Info("pending swaps expired", "count", count)
+	}
+}
+
+// expireProposedSwapCycles expires the swap cycles that are proposed for longer than the configured swap pending ttl.
+func (app *application) expireProposedSwapCycles() {
+	defer func() {
+		if err := recover(); err != nil {
+			app.logger.Error(fmt.Sprintf("%v", err))
+		}
+	}()
+
+	count, err := app.models.SwapCycles.ExpireProposed(time.Now().Add(-app.config.swap.pendingTTL))
+	if err != nil {
+		app.logger.Error(err.Error())
+		return
+	}
+
+	if count > 0 {
+		app.logger.Info("proposed swap cycles expired", "count", count)
+	}
+}
+
+// startSwapOverdueWorker starts a background worker that periodically flags the accepted swaps,
+// whose agreed return by date has passed.
+// The worker stops when the given context is done, app.wg can be used to wait for it.
+func (app *application) startSwapOverdueWorker(ctx context.Context, interval time.Duration) {
+	app.startWorker(ctx, interval, app.flagOverdueSwaps)
+}
+
+// flagOverdueSwaps flags the accepted swaps, whose agreed return by date has passed.
+func (app *application) flagOverdueSwaps() {
+	defer func() {
+		if err := recover(); err != nil {
+			app.logger.Error(fmt.Sprintf("%v", err))
+		}
+	}()
+
+	count, err := app.models.Swaps.FlagOverdue(time.Now())
+	if err != nil {
+		app.logger.Error(err.Error())
+		return
+	}
+
+	if count > 0 {
+		app.logger.Info("overdue swaps flagged", "count", count)
+	}
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/ttarnok/instrument-swap-api/internal/data"
+	"github.com/ttarnok/instrument-swap-api/internal/data/mocks"
+)
+
+// TestSwapExpiryWorker implements unit tests for the swap expiry worker.
+func TestSwapExpiryWorker(t *testing.T) {
+
+	now := time.Now()
+
+	oldPending := &data.Swap{ID: 1, CreatedAt: now.Add(-48 * time.Hour), RequesterInstrumentID: 1, RecipientInstrumentID: 2}
+	newPending := &data.Swap{ID: 2, CreatedAt: now.Add(-1 * time.Hour), RequesterInstrumentID: 3, RecipientInstrumentID: 4}
+	oldAccepted := &data.Swap{ID: 3, CreatedAt: now.Add(-48 * time.Hour), RequesterInstrumentID: 5, RecipientInstrumentID: 6, IsAccepted: true, AcceptedAt: &now}
+	oldRejected := &data.Swap{ID: 4, CreatedAt: now.Add(-48 * time.Hour), RequesterInstrumentID: 7, RecipientInstrumentID: 8, IsRejected: true, IsEnded: true}
+
+	swaps := mocks.NewSwapModelMock([]*data.Swap{oldPending, newPending, oldAccepted, oldRejected})
+
+	var cfg config
+	cfg.swap.pendingTTL = 24 * time.Hour
+
+	app := &application{
+		config: cfg,
+		logger: slog.New(slog.NewTextHandler(io.Discard, nil)),
+		models: data.Models{Swaps: swaps},
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	app.startSwapExpiryWorker(ctx, 10*time.Millisecond)
+	time.Sleep(50 * time.Millisecond)
+	cancel()
+	app.wg.Wait()
+
+	if !oldPending.IsExpired || !oldPending.IsEnded || oldPending.ExpiredAt == nil {
+		t.Errorf(`expected the old pending swap to be expired, got %#v`, oldPending)
+	}
+
+	for _, swap := range []*data.Swap{newPending, oldAccepted, oldRejected} {
+		if swap.IsExpired {
+			t.Errorf(`expected swap %d not to be expired`, swap.ID)
+		}
+	}
+
+	events, err := swaps.GetAllForSwap(oldPending.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 1 || events[0].Type != data.SwapEventExpired || events[0].ActorUserID != nil {
+		t.Errorf(`expected exactly one expired event without actor, got %#v`, events)
+	}
+}
+
+// TestSwapCycleExpiryWorker implements unit tests for expiring the proposed swap cycles by the swap expiry worker.
+func TestSwapCycleExpiryWorker(t *testing.T) {
+
+	now := time.Now()
+
+	oldProposed := &data.SwapCycle{ID: 1, Status: data.SwapCycleStatusProposed, CreatedAt: now.Add(-48 * time.Hour), Version: 1}
+	newProposed := &data.SwapCycle{ID: 2, Status: data.SwapCycleStatusProposed, CreatedAt: now.Add(-1 * time.Hour), Version: 1}
+	oldActive := &data.SwapCycle{ID: 3, Status: data.SwapCycleStatusActive, CreatedAt: now.Add(-48 * time.Hour), Version: 1}
+	oldRejected := &data.SwapCycle{ID: 4, Status: data.SwapCycleStatusRejected, CreatedAt: now.Add(-48 * time.Hour), Version: 1}
+
+	var cfg config
+	cfg.swap.pendingTTL = 24 * time.Hour
+
+	app := &application{
+		config: cfg,
+		logger: slog.New(slog.NewTextHandler(io.Discard, nil)),
+		models: data.Models{
+			Swaps:      mocks.NewSwapModelMock(nil),
+			SwapCycles: mocks.NewSwapCycleModelMock([]*data.SwapCycle{oldProposed, newProposed, oldActive, oldRejected}),
+		},
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	app.startSwapExpiryWorker(ctx, 10*time.Millisecond)
+	time.Sleep(50 * time.Millisecond)
+	cancel()
+	app.wg.Wait()
+
+	if oldProposed.Status != data.SwapCycleStatusExpired || oldProposed.ExpiredAt == nil {
+		t.Errorf(`expected the old proposed swap cycle to be expired, got %#v`, oldProposed)
+	}
+
+	for _, cycle := range []*data.SwapCycle{newProposed, oldActive, oldRejected} {
+		if cycle.Status == data.SwapCycleStatusExpired {
+			t.Errorf(`expected swap cycle %d not to be expired`, cycle.ID)
+		}
+	}
+}
+
+// TestSwapOverdueWorker implements unit tests for the swap overdue worker.
+func TestSwapOverdueWorker(t *testing.T) {
+
+	now := time.Now()
+	past := now.Add(-24 * time.Hour)
+	future := now.Add(24 * time.Hour)
+
+	overdue := &data.Swap{ID: 1, RequesterInstrumentID: 1, RecipientInstrumentID: 2, IsAccepted: true, AcceptedAt: &past, ReturnBy: &past}
+	notDue := &data.Swap{ID: 2, RequesterInstrumentID: 3, RecipientInstrumentID: 4, IsAccepted: true, AcceptedAt: &past, ReturnBy: &future}
+	returned := &data.Swap{ID: 3, RequesterInstrumentID: 5, RecipientInstrumentID: 6, IsAccepted: true, AcceptedAt: &past, IsEnded: true, EndedAt: &now, ReturnBy: &past}
+	pending := &data.Swap{ID: 4, RequesterInstrumentID: 7, RecipientInstrumentID: 8, ReturnBy: &past}
+	notTimeBoxed := &data.Swap{ID: 5, RequesterInstrumentID: 9, RecipientInstrumentID: 10, IsAccepted: true, AcceptedAt: &past}
+
+	swaps := mocks.NewSwapModelMock([]*data.Swap{overdue, notDue, returned, pending, notTimeBoxed})
+
+	app := &application{
+		logger: slog.New(slog.NewTextHandler(io.Discard, nil)),
+		models: data.Models{Swaps: swaps},
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	app.startSwapOverdueWorker(ctx, 10*time.Millisecond)
+	time.Sleep(50 * time.Millisecond)
+	cancel()
+	app.wg.Wait()
+
+	if !overdue.IsOverdue || overdue.OverdueAt == nil || !overdue.Overdue() {
+		t.Errorf(`expected the swap to be overdue, got %#v`, overdue)
+	}
+
+	for _, swap := range []*data.Swap{notDue, returned, pending, notTimeBoxed} {
+		if swap.IsOverdue {
+			t.Errorf(`expected swap %d not to be overdue`, swap.ID)
+		}
+	}
+
+	events, err := swaps.GetAllForSwap(overdue.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 1 || events[0].Type != data.SwapEventOverdue || events[0].ActorUserID != nil {
+		t.Errorf(`expected exactly one overdue event without actor, got %#v`, events)
+	}
+}
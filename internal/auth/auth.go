@@ -3,10 +3,14 @@ package auth
 
 import "time"
 
+// RefreshTokenExpiration is the lifetime of the refresh tokens.
+const RefreshTokenExpiration = 24 * time.Hour
+
 // Claims contains the claims from a Token.
 type Claims struct {
 	ID      string
 	Subject string
+	Issued  time.Time
 }
 
 // TokenProvider is an interface for JWT Token functionality.
@@ -20,6 +24,8 @@ type TokenProvider interface {
 type BlacklistProvider interface {
 	BlacklistToken(token string) error
 	IsTokenBlacklisted(token string) (bool, error)
+	BlacklistUserTokens(userID int64, issuedBefore time.Time) error
+	IsUserTokenBlacklisted(userID int64, issued time.Time) (bool, error)
 }
 
 // Auth provides authentication functionality for the application.
@@ -33,7 +39,7 @@ type Auth struct {
 func NewAuth(secret string, blacklistToken BlacklistProvider) *Auth {
 	return &Auth{
 		AccessToken:    NewJwtTokenFactory(secret, "access", 5*time.Minute),
-		RefreshToken:   NewJwtTokenFactory(secret, "refresh", 24*time.Hour),
+		RefreshToken:   NewJwtTokenFactory(secret, "refresh", RefreshTokenExpiration),
 		BlacklistToken: blacklistToken,
 	}
 }
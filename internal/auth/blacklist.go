@@ -1,5 +1,7 @@
 package auth
 
+import "time"
+
 // BlacklistService implements the functionality of handling token blacklisting.
 type BlacklistService struct {
 	redisClient *BlacklistRedisClient
@@ -25,3 +27,30 @@ func (s *BlacklistService) IsTokenBlacklisted(token string) (bool, error) {
 
 	return blacklisted, nil
 }
+
+// BlacklistUserTokens blacklists all tokens of the given user that were issued before the given time.
+func (s *BlacklistService) BlacklistUserTokens(userID int64, issuedBefore time.Time) error {
+	// Every token issued before the given time expires within the refresh token lifetime,
+	// so the blacklisting does not need to outlive it.
+	return s.redisClient.BlacklistUserTokens(userID, issuedBefore, RefreshTokenExpiration)
+}
+
+// IsUserTokenBlacklisted checks whether a token of the given user with the given issue time is blacklisted.
+func (s *BlacklistService) IsUserTokenBlacklisted(userID int64, issued time.Time) (bool, error) {
+	issuedBefore, found, err := s.redisClient.UserTokensBlacklistedBefore(userID)
+	if err != nil {
+		return false, err
+	}
+	if !found {
+		return false, nil
+	}
+
+	return IssuedBefore(issued, issuedBefore), nil
+}
+
+// IssuedBefore reports whether a token with the given issue time was issued before the given blacklisting time.
+// The issue times of the tokens can not be relied on below a second, so the blacklisting time is rounded up
+// to the next whole second, the tokens issued in the same second as the blacklisting are rejected too.
+func IssuedBefore(issued time.Time, issuedBefore time.Time) bool {
+	return issued.Before(issuedBefore.Truncate(time.Second).Add(time.Second))
+}
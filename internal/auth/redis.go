@@ -3,6 +3,7 @@ package auth
 import (
 	"context"
 	"fmt"
+	"strconv"
 	"time"
 
 	"github.com/go-redis/redis/v8"
@@ -52,3 +53,36 @@ func (r *BlacklistRedisClient) IsTokenBlacklisted(token string) (bool, error) {
 
 	return val == "blacklisted", nil
 }
+
+// userTokensKey returns the key that stores the user related token blacklisting.
+func userTokensKey(userID int64) string {
+	return fmt.Sprintf("user-tokens-blacklisted-before:%d", userID)
+}
+
+// BlacklistUserTokens blacklists the tokens of the given user issued before the given time, with the specified expiration time.
+func (r *BlacklistRedisClient) BlacklistUserTokens(userID int64, issuedBefore time.Time, expiration time.Duration) error {
+	err := r.Client.Set(ctx, userTokensKey(userID), issuedBefore.UnixNano(), expiration).Err()
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// UserTokensBlacklistedBefore returns the time before which the tokens of the given user are blacklisted.
+// The returned bool value is false if there is no user related blacklisting.
+func (r *BlacklistRedisClient) UserTokensBlacklistedBefore(userID int64) (time.Time, bool, error) {
+	val, err := r.Client.Get(ctx, userTokensKey(userID)).Result()
+	if err == redis.Nil {
+		return time.Time{}, false, nil
+	}
+	if err != nil {
+		return time.Time{}, false, err
+	}
+
+	nanos, err := strconv.ParseInt(val, 10, 64)
+	if err != nil {
+		return time.Time{}, false, err
+	}
+
+	return time.Unix(0, nanos), true, nil
+}
@@ -0,0 +1,380 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/ttarnok/instrument-swap-api/internal/validator"
+)
+
+// Category related errors.
+// These errors can be tested using errors.Is.
+var (
+	ErrDuplicateCategoryName = errors.New("duplicate category name")              // "duplicate category name"
+	ErrCategoryInUse         = errors.New("category is in use")                   // "category is in use"
+	ErrCategoryCycle         = errors.New("category can not be its own ancestor") // "category can not be its own ancestor"
+)
+
+// CategoryNameRX is the pattern of the category names, lower case words separated by hyphens.
+var CategoryNameRX = regexp.MustCompile(`^[a-z0-9]+(-[a-z0-9]+)*$`)
+
+// Category represents a node of the instrument type taxonomy, e.g. the synthesizer subtype of the keyboard category.
+// The type of an instrument is the name of a category.
+type Category struct {
+	ID        int64     `json:"id"`
+	ParentID  *int64    `json:"parent_id"`
+	CreatedAt time.Time `json:"created_at"`
+	Name      string    `json:"name"`
+	Version   int32     `json:"version"`
+}
+
+// ValidateCategory checks the validity of a category,
+// adds all found validation errors into the validator.
+func ValidateCategory(v *validator.Validator, category *Category) {
+	v.Check(category.Name != "", "name", "must be provided")
+	v.Check(len(category.Name) <= 100, "name", "must not be more than 100 bytes long")
+	v.Check(validator.Matches(category.Name, CategoryNameRX), "name", "must contain only lower case letters, digits and hyphens")
+
+	if category.ParentID != nil {
+		v.Check(*category.ParentID > 0, "parent_id", "must be a positive integer")
+		v.Check(*category.ParentID != category.ID, "parent_id", "must not be the category itself")
+	}
+}
+
+// categoryColumns lists the columns of the categories table in the order expected by scanCategory.
+const categoryColumns = `id, parent_id, created_at, name, version`
+
+// scanCategory scans a row selected with categoryColumns into the given category.
+func scanCategory(row rowScanner, category *Category) error {
+	return row.Scan(
+		&category.ID,
+		&category.ParentID,
+		&category.CreatedAt,
+		&category.Name,
+		&category.Version,
+	)
+}
+
+// CategoryModel represents the category model, that stores the instrument type taxonomy in a database.
+type CategoryModel struct {
+	DB *sql.DB
+}
+
+// Insert stores the given category.
+// Returns ErrDuplicateCategoryName if a category with the same name already exists.
+func (m *CategoryModel) Insert(category *Category) error {
+	query := `
+		INSERT INTO categories (parent_id, name)
+			VALUES ($1, $2)
+		RETURNING id, created_at, version`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, category.ParentID, category.Name).Scan(&category.ID, &category.CreatedAt, &category.Version)
+	if err != nil {
+		switch {
+		case err.Error() == `pq: duplicate key value violates unique constraint "categories_name_key"`:
+			return ErrDuplicateCategoryName
+		default:
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Get retrieves the category with the given id.
+// Returns ErrRecordNotFound if the category does not exist.
+func (m *CategoryModel) Get(id int64) (*Category, error) {
+	if id < 1 {
+		return nil, ErrRecordNotFound
+	}
+
+	query := `
+		SELECT ` + categoryColumns + `
+		FROM categories
+		WHERE id = $1`
+
+	return m.get(query, id)
+}
+
+// GetByName retrieves the category with the given name.
+// Returns ErrRecordNotFound if the category does not exist.
+func (m *CategoryModel) GetByName(name string) (*Category, error) {
+	query := `
+		SELECT ` + categoryColumns + `
+		FROM categories
+		WHERE name = $1`
+
+	return m.get(query, name)
+}
+
+// get retrieves the category selected by the given query.
+func (m *CategoryModel) get(query string, args ...any) (*Category, error) {
+	var category Category
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := scanCategory(m.DB.QueryRowContext(ctx, query, args...), &category)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return &category, nil
+}
+
+// GetAll retrieves every category, ordered by their ids.
+func (m *CategoryModel) GetAll() (categories []*Category, err error) {
+	query := `
+		SELECT ` + categoryColumns + `
+		FROM categories
+		ORDER BY id`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		errClose := rows.Close()
+		if err == nil {
+			err = errClose
+		}
+	}()
+
+	categories = []*Category{}
+
+	for rows.Next() {
+		var category Category
+
+		err := scanCategory(rows, &category)
+		if err != nil {
+			return nil, err
+		}
+
+		categories = append(categories, &category)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return categories, nil
+}
+
+// GetSubtreeNames retrieves the names of the given categories and all of their descendants.
+// The unknown names are ignored.
+func (m *CategoryModel) GetSubtreeNames(names ...string) ([]string, error) {
+	query := `
+		WITH RECURSIVE subtree AS (
+				SELECT id, name
+				FROM categories
+				WHERE name = ANY($1)
+			UNION
+				SELECT c.id, c.name
+				FROM categories c
+				JOIN subtree s ON c.parent_id = s.id
+		)
+		SELECT name
+		FROM subtree
+		ORDER BY name`
+
+	return m.getNames(query, pq.Array(names))
+}
+
+// GetLineageNames retrieves the names of the given category and all of its ancestors.
+// Returns an empty slice if the category does not exist.
+func (m *CategoryModel) GetLineageNames(name string) ([]string, error) {
+	query := `
+		WITH RECURSIVE lineage AS (
+				SELECT id, parent_id, name
+				FROM categories
+				WHERE name = $1
+			UNION
+				SELECT c.id, c.parent_id, c.name
+				FROM categories c
+				JOIN lineage l ON c.id = l.parent_id
+		)
+		SELECT name
+		FROM lineage
+		ORDER BY name`
+
+	return m.getNames(query, name)
+}
+
+// getNames retrieves the category names selected by the given query.
+func (m *CategoryModel) getNames(query string, args ...any) (names []string, err error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		errClose := rows.Close()
+		if err == nil {
+			err = errClose
+		}
+	}()
+
+	names = []string{}
+
+	for rows.Next() {
+		var name string
+
+		err := rows.Scan(&name)
+		if err != nil {
+			return nil, err
+		}
+
+		names = append(names, name)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return names, nil
+}
+
+// Update updates the name and the parent of the given category within a transaction.
+// A renamed category is renamed in the instruments, the wants and the saved searches too.
+// The replaced states of the renamed instruments are recorded as revisions changed by the given user, and their versions are bumped.
+// Returns ErrCategoryCycle if the new parent is the category itself or one of its descendants,
+// ErrDuplicateCategoryName if a category with the same name already exists,
+// ErrEditConflict if there was a race condition during update.
+func (m *CategoryModel) Update(category *Category, changedByUserID int64) (err error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			err = errors.Join(err, tx.Rollback())
+		}
+	}()
+
+	if category.ParentID != nil {
+		query := `
+			WITH RECURSIVE lineage AS (
+					SELECT id, parent_id
+					FROM categories
+					WHERE id = $1
+				UNION
+					SELECT c.id, c.parent_id
+					FROM categories c
+					JOIN lineage l ON c.id = l.parent_id
+			)
+			SELECT EXISTS (SELECT 1 FROM lineage WHERE id = $2)`
+
+		var cycle bool
+		err = tx.QueryRowContext(ctx, query, *category.ParentID, category.ID).Scan(&cycle)
+		if err != nil {
+			return err
+		}
+		if cycle {
+			return ErrCategoryCycle
+		}
+	}
+
+	var oldName string
+	err = tx.QueryRowContext(ctx, `SELECT name FROM categories WHERE id = $1 AND version = $2 FOR UPDATE`, category.ID, category.Version).Scan(&oldName)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrEditConflict
+		default:
+			return err
+		}
+	}
+
+	if oldName != category.Name {
+		err = renameInstrumentTypes(ctx, tx, oldName, changedByUserID)
+		if err != nil {
+			return err
+		}
+	}
+
+	query := `
+		UPDATE categories
+			SET parent_id = $1,
+					name = $2,
+					version = version + 1
+		WHERE id = $3
+		RETURNING version`
+
+	err = tx.QueryRowContext(ctx, query, category.ParentID, category.Name, category.ID).Scan(&category.Version)
+	if err != nil {
+		switch {
+		case err.Error() == `pq: duplicate key value violates unique constraint "categories_name_key"`:
+			return ErrDuplicateCategoryName
+		default:
+			return err
+		}
+	}
+
+	if oldName != category.Name {
+		_, err = tx.ExecContext(ctx, `UPDATE wants SET instrument_type = $1 WHERE lower(instrument_type) = $2`, category.Name, oldName)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.ExecContext(ctx, `UPDATE saved_searches SET type = $1 WHERE lower(type) = $2`, category.Name, oldName)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// Delete removes the category with the given id.
+// Returns ErrRecordNotFound if the category does not exist,
+// ErrCategoryInUse if the category has subcategories or instruments.
+func (m *CategoryModel) Delete(id int64) error {
+	if id < 1 {
+		return ErrRecordNotFound
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, `DELETE FROM categories WHERE id = $1`, id)
+	if err != nil {
+		switch {
+		case strings.HasPrefix(err.Error(), `pq: update or delete on table "categories" violates foreign key constraint`):
+			return ErrCategoryInUse
+		default:
+			return err
+		}
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+
+	return nil
+}
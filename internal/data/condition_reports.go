@@ -0,0 +1,211 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/ttarnok/instrument-swap-api/internal/validator"
+)
+
+// Stages of a swap, when the parties can report the condition of the swapped instruments.
+const (
+	ConditionStageHandover = "handover"
+	ConditionStageReturn   = "return"
+)
+
+// Grades of an instrument condition, from the best to the worst.
+const (
+	ConditionGradeMint      = "mint"
+	ConditionGradeExcellent = "excellent"
+	ConditionGradeGood      = "good"
+	ConditionGradeFair      = "fair"
+	ConditionGradePoor      = "poor"
+)
+
+// ConditionDefects lists the defects that can be checked in a condition report.
+var ConditionDefects = []string{
+	"scratches",
+	"dents",
+	"cracks",
+	"finish_wear",
+	"missing_parts",
+	"broken_hardware",
+	"electronic_fault",
+	"tuning_instability",
+}
+
+// Condition report related errors.
+// These errors can be tested using errors.Is.
+var (
+	ErrDuplicateConditionReport = errors.New("condition already reported")                       // "condition already reported"
+	ErrSwapNotReportable        = errors.New("swap is not in the stage of the condition report") // "swap is not in the stage of the condition report"
+)
+
+// ConditionReport represents the condition of an instrument of a swap, as seen by one party of the swap
+// at the handover or at the return of the instrument.
+type ConditionReport struct {
+	ID             int64     `json:"id"`
+	SwapID         int64     `json:"swap_id"`
+	InstrumentID   int64     `json:"instrument_id"`
+	ReporterUserID int64     `json:"reporter_user_id"`
+	CreatedAt      time.Time `json:"created_at"`
+	Stage          string    `json:"stage"`
+	Grade          string    `json:"grade"`
+	Defects        []string  `json:"defects"`
+	Notes          string    `json:"notes"`
+}
+
+// ConditionComparison holds the handover and return condition reports of one instrument of a swap side-by-side.
+type ConditionComparison struct {
+	InstrumentID int64              `json:"instrument_id"`
+	Handover     []*ConditionReport `json:"handover"`
+	Return       []*ConditionReport `json:"return"`
+}
+
+// ValidateConditionReport checks the validity of a condition report,
+// adds all found validation errors into the validator.
+func ValidateConditionReport(v *validator.Validator, report *ConditionReport) {
+	v.Check(report.InstrumentID > 0, "instrument_id", "must be greater than 0")
+	v.Check(validator.PermittedValue(report.Stage, ConditionStageHandover, ConditionStageReturn), "stage", "must be handover or return")
+	v.Check(
+		validator.PermittedValue(report.Grade, ConditionGradeMint, ConditionGradeExcellent, ConditionGradeGood, ConditionGradeFair, ConditionGradePoor),
+		"grade",
+		"must be mint, excellent, good, fair or poor",
+	)
+	v.Check(validator.Unique(report.Defects), "defects", "must not contain duplicate values")
+	for _, defect := range report.Defects {
+		v.Check(validator.PermittedValue(defect, ConditionDefects...), "defects", "must contain known defects only")
+	}
+	v.Check(len(report.Notes) <= 2000, "notes", "must not be more than 2000 bytes long")
+}
+
+// IsConditionReportable checks whether the condition of the swapped instruments can be reported at the given stage.
+// The handover can be reported while an accepted swap is ongoing, the return after an accepted swap was ended.
+func IsConditionReportable(swap *Swap, stage string) bool {
+	switch stage {
+	case ConditionStageHandover:
+		return swap.IsAccepted && !swap.IsEnded
+	case ConditionStageReturn:
+		return swap.IsAccepted && swap.IsEnded
+	default:
+		return false
+	}
+}
+
+// CompareConditionReports groups the given condition reports by the instruments of the given swap,
+// in the order of the instruments of the swap.
+func CompareConditionReports(swap *Swap, reports []*ConditionReport) []*ConditionComparison {
+	comparisons := make([]*ConditionComparison, 0, len(swap.InstrumentIDs()))
+	byInstrument := make(map[int64]*ConditionComparison)
+
+	for _, id := range swap.InstrumentIDs() {
+		comparison := &ConditionComparison{InstrumentID: id, Handover: []*ConditionReport{}, Return: []*ConditionReport{}}
+		comparisons = append(comparisons, comparison)
+		byInstrument[id] = comparison
+	}
+
+	for _, report := range reports {
+		comparison, ok := byInstrument[report.InstrumentID]
+		if !ok {
+			continue
+		}
+		switch report.Stage {
+		case ConditionStageHandover:
+			comparison.Handover = append(comparison.Handover, report)
+		case ConditionStageReturn:
+			comparison.Return = append(comparison.Return, report)
+		}
+	}
+
+	return comparisons
+}
+
+// ConditionReportModel represents the condition report model, that stores the condition reports of the swaps in a database.
+type ConditionReportModel struct {
+	DB *sql.DB
+}
+
+// Insert stores the given condition report.
+// Returns ErrDuplicateConditionReport if the reporter has already reported the instrument at the same stage of the swap.
+func (m *ConditionReportModel) Insert(report *ConditionReport) error {
+	query := `
+		INSERT INTO condition_reports (swap_id, instrument_id, reporter_user_id, stage, grade, defects, notes)
+			VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING id, created_at`
+
+	if report.Defects == nil {
+		report.Defects = []string{}
+	}
+
+	args := []any{report.SwapID, report.InstrumentID, report.ReporterUserID, report.Stage, report.Grade, pq.Array(report.Defects), report.Notes}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, args...).Scan(&report.ID, &report.CreatedAt)
+	if err != nil {
+		switch {
+		case err.Error() == `pq: duplicate key value violates unique constraint "condition_reports_swap_instrument_reporter_stage_key"`:
+			return ErrDuplicateConditionReport
+		default:
+			return err
+		}
+	}
+
+	return nil
+}
+
+// GetAllForSwap retrieves the condition reports of the given swap in chronological order.
+func (m *ConditionReportModel) GetAllForSwap(swapID int64) (reports []*ConditionReport, err error) {
+	query := `
+		SELECT id, swap_id, instrument_id, reporter_user_id, created_at, stage, grade, defects, notes
+		FROM condition_reports
+		WHERE swap_id = $1
+		ORDER BY created_at, id`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, swapID)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		errClose := rows.Close()
+		if err == nil {
+			err = errClose
+		}
+	}()
+
+	reports = []*ConditionReport{}
+
+	for rows.Next() {
+		var report ConditionReport
+
+		err := rows.Scan(
+			&report.ID,
+			&report.SwapID,
+			&report.InstrumentID,
+			&report.ReporterUserID,
+			&report.CreatedAt,
+			&report.Stage,
+			&report.Grade,
+			pq.Array(&report.Defects),
+			&report.Notes,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		reports = append(reports, &report)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return reports, nil
+}
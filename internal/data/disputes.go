@@ -0,0 +1,356 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/ttarnok/instrument-swap-api/internal/validator"
+)
+
+// Dispute statuses.
+const (
+	DisputeStatusOpen        = "open"
+	DisputeStatusUnderReview = "under_review"
+	DisputeStatusResolved    = "resolved"
+)
+
+// Dispute related errors.
+// These errors can be tested using errors.Is.
+var (
+	ErrInstrumentDisputed             = errors.New("instrument under an open dispute")    // "instrument under an open dispute"
+	ErrRequesterInstrumentDisputed    = fmt.Errorf("requester %w", ErrInstrumentDisputed) // "requester instrument under an open dispute"
+	ErrRecipientInstrumentDisputed    = fmt.Errorf("recipient %w", ErrInstrumentDisputed) // "recipient instrument under an open dispute"
+	ErrSwapNotDisputable              = errors.New("swap is not disputable")              // "swap is not disputable"
+	ErrDuplicateDispute               = errors.New("swap already has an open dispute")    // "swap already has an open dispute"
+	ErrDisputeResolved                = errors.New("dispute is already resolved")         // "dispute is already resolved"
+	ErrInvalidDisputeStatusTransition = errors.New("invalid dispute status transition")   // "invalid dispute status transition"
+)
+
+// Dispute represents a dispute opened by a party of an ended swap, e.g. because an instrument came back damaged.
+type Dispute struct {
+	ID               int64          `json:"id"`
+	SwapID           int64          `json:"swap_id"`
+	OpenedByUserID   int64          `json:"opened_by_user_id"`
+	CreatedAt        time.Time      `json:"created_at"`
+	Status           string         `json:"status"`
+	Reason           string         `json:"reason"`
+	Resolution       string         `json:"resolution"`
+	ResolvedAt       *time.Time     `json:"resolved_at"`
+	ResolvedByUserID *int64         `json:"resolved_by_user_id"`
+	Notes            []*DisputeNote `json:"notes,omitempty"`
+	Version          int32          `json:"version"`
+}
+
+// DisputeNote represents an evidence note attached to a dispute.
+type DisputeNote struct {
+	ID           int64     `json:"id"`
+	DisputeID    int64     `json:"dispute_id"`
+	AuthorUserID int64     `json:"author_user_id"`
+	CreatedAt    time.Time `json:"created_at"`
+	Body         string    `json:"body"`
+}
+
+// ValidateDispute checks the validity of a dispute,
+// adds all found validation errors into the validator.
+func ValidateDispute(v *validator.Validator, dispute *Dispute) {
+	v.Check(dispute.Reason != "", "reason", "must be provided")
+	v.Check(len(dispute.Reason) <= 2000, "reason", "must not be more than 2000 bytes long")
+
+	v.Check(validator.PermittedValue(dispute.Status, DisputeStatusOpen, DisputeStatusUnderReview, DisputeStatusResolved), "status", "must be open, under_review or resolved")
+
+	if dispute.Status == DisputeStatusResolved {
+		v.Check(dispute.Resolution != "", "resolution", "must be provided")
+	}
+	v.Check(len(dispute.Resolution) <= 2000, "resolution", "must not be more than 2000 bytes long")
+}
+
+// ValidateDisputeNote checks the validity of an evidence note,
+// adds all found validation errors into the validator.
+func ValidateDisputeNote(v *validator.Validator, note *DisputeNote) {
+	v.Check(note.Body != "", "body", "must be provided")
+	v.Check(len(note.Body) <= 2000, "body", "must not be more than 2000 bytes long")
+}
+
+// IsSwapDisputable checks whether a dispute can be opened for the swap.
+// Only the swaps that were accepted and ended can be disputed.
+func IsSwapDisputable(swap *Swap) bool {
+	return swap.IsAccepted && swap.IsEnded
+}
+
+// ValidateDisputeStatusTransition checks whether the requested status transition is possible from the current status of the dispute.
+// An open dispute can be taken under review or resolved, a dispute under review can be resolved, a resolved dispute is final.
+// Returns an error wrapping ErrInvalidDisputeStatusTransition if the transition is not possible, otherwise returns nil.
+func ValidateDisputeStatusTransition(dispute *Dispute, status string) error {
+	switch {
+	case dispute.Status == DisputeStatusOpen && (status == DisputeStatusUnderReview || status == DisputeStatusResolved):
+		return nil
+	case dispute.Status == DisputeStatusUnderReview && status == DisputeStatusResolved:
+		return nil
+	default:
+		return fmt.Errorf("%w: from %q to %q", ErrInvalidDisputeStatusTransition, dispute.Status, status)
+	}
+}
+
+// disputeColumns lists the columns of the disputes table in the order expected by scanDispute.
+const disputeColumns = `id, swap_id, opened_by_user_id, created_at, status, reason, resolution,
+		resolved_at, resolved_by_user_id, version`
+
+// scanDispute scans a row selected with disputeColumns into the given dispute.
+func scanDispute(row rowScanner, dispute *Dispute) error {
+	return row.Scan(
+		&dispute.ID,
+		&dispute.SwapID,
+		&dispute.OpenedByUserID,
+		&dispute.CreatedAt,
+		&dispute.Status,
+		&dispute.Reason,
+		&dispute.Resolution,
+		&dispute.ResolvedAt,
+		&dispute.ResolvedByUserID,
+		&dispute.Version,
+	)
+}
+
+// instrumentDisputedCondition is the SQL condition selecting the instruments aliased as i,
+// that are part of a swap with an unresolved dispute.
+const instrumentDisputedCondition = `EXISTS (
+			SELECT 1
+			FROM swap_items si
+			JOIN disputes d ON d.swap_id = si.swap_id
+			WHERE si.instrument_id = i.id
+			  AND d.status <> '` + DisputeStatusResolved + `'
+		)`
+
+// isInstrumentDisputed checks whether the given instrument is part of a swap with an unresolved dispute.
+// The instrument is expected to exist.
+func isInstrumentDisputed(ctx context.Context, tx *sql.Tx, instrumentID int64) (bool, error) {
+	query := `
+		SELECT ` + instrumentDisputedCondition + `
+		FROM instruments i
+		WHERE i.id = $1`
+
+	var disputed bool
+	err := tx.QueryRowContext(ctx, query, instrumentID).Scan(&disputed)
+	return disputed, err
+}
+
+// DisputeModel represents the dispute model, that stores the disputes of the swaps in a database.
+type DisputeModel struct {
+	DB *sql.DB
+}
+
+// Insert stores the given dispute.
+// Returns ErrDuplicateDispute if the swap already has an unresolved dispute.
+func (m *DisputeModel) Insert(dispute *Dispute) error {
+	query := `
+		INSERT INTO disputes (swap_id, opened_by_user_id, status, reason)
+			VALUES ($1, $2, $3, $4)
+		RETURNING id, created_at, version`
+
+	args := []any{dispute.SwapID, dispute.OpenedByUserID, dispute.Status, dispute.Reason}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, args...).Scan(&dispute.ID, &dispute.CreatedAt, &dispute.Version)
+	if err != nil {
+		switch {
+		case err.Error() == `pq: duplicate key value violates unique constraint "disputes_swap_id_unresolved_idx"`:
+			return ErrDuplicateDispute
+		default:
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Get retrieves the dispute with the given id together with its evidence notes.
+// Returns ErrRecordNotFound if the dispute does not exist.
+func (m *DisputeModel) Get(id int64) (*Dispute, error) {
+	if id < 1 {
+		return nil, ErrRecordNotFound
+	}
+
+	query := `
+		SELECT ` + disputeColumns + `
+		FROM disputes
+		WHERE id = $1`
+
+	var dispute Dispute
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := scanDispute(m.DB.QueryRowContext(ctx, query, id), &dispute)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	dispute.Notes, err = m.getNotes(ctx, dispute.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &dispute, nil
+}
+
+// getNotes retrieves the evidence notes of the given dispute in chronological order.
+func (m *DisputeModel) getNotes(ctx context.Context, disputeID int64) (notes []*DisputeNote, err error) {
+	query := `
+		SELECT id, dispute_id, author_user_id, created_at, body
+		FROM dispute_notes
+		WHERE dispute_id = $1
+		ORDER BY created_at, id`
+
+	rows, err := m.DB.QueryContext(ctx, query, disputeID)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		errClose := rows.Close()
+		if err == nil {
+			err = errClose
+		}
+	}()
+
+	notes = []*DisputeNote{}
+
+	for rows.Next() {
+		var note DisputeNote
+
+		err := rows.Scan(&note.ID, &note.DisputeID, &note.AuthorUserID, &note.CreatedAt, &note.Body)
+		if err != nil {
+			return nil, err
+		}
+
+		notes = append(notes, &note)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return notes, nil
+}
+
+// GetAll retrieves the disputes with the given status, or all disputes if the status is empty,
+// paginated and sorted based on the given filters. The evidence notes of the disputes are not retrieved.
+func (m *DisputeModel) GetAll(status string, filters Filters) (disputes []*Dispute, metaData MetaData, err error) {
+
+	//nolint:gosec
+	query := fmt.Sprintf(`
+		SELECT count(*) over(), `+disputeColumns+`
+		FROM disputes
+		WHERE (status = $1 OR $1 = '')
+		ORDER BY %s %s, id ASC
+		LIMIT $2 OFFSET $3`, filters.sortColumn(), filters.sortDirection())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, status, filters.limit(), filters.offset())
+	if err != nil {
+		return nil, MetaData{}, err
+	}
+	defer func() {
+		errClose := rows.Close()
+		if err == nil {
+			err = errClose
+		}
+	}()
+
+	totalRecords := 0
+	disputes = []*Dispute{}
+
+	for rows.Next() {
+		var dispute Dispute
+
+		err := rows.Scan(
+			&totalRecords,
+			&dispute.ID,
+			&dispute.SwapID,
+			&dispute.OpenedByUserID,
+			&dispute.CreatedAt,
+			&dispute.Status,
+			&dispute.Reason,
+			&dispute.Resolution,
+			&dispute.ResolvedAt,
+			&dispute.ResolvedByUserID,
+			&dispute.Version,
+		)
+		if err != nil {
+			return nil, MetaData{}, err
+		}
+
+		disputes = append(disputes, &dispute)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, MetaData{}, err
+	}
+
+	return disputes, calculateMetadata(totalRecords, filters.Page, filters.PageSize), nil
+}
+
+// Update updates the status and the resolution of the given dispute.
+// Returns ErrEditConflict if there was a race condition during update.
+func (m *DisputeModel) Update(dispute *Dispute) error {
+	query := `
+		UPDATE disputes
+			SET status = $1,
+					resolution = $2,
+					resolved_at = $3,
+					resolved_by_user_id = $4,
+					version = version + 1
+		WHERE id = $5
+			AND version = $6
+		RETURNING version`
+
+	args := []any{
+		dispute.Status,
+		dispute.Resolution,
+		dispute.ResolvedAt,
+		dispute.ResolvedByUserID,
+		dispute.ID,
+		dispute.Version,
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, args...).Scan(&dispute.Version)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrEditConflict
+		default:
+			return err
+		}
+	}
+
+	return nil
+}
+
+// InsertNote stores the given evidence note.
+func (m *DisputeModel) InsertNote(note *DisputeNote) error {
+	query := `
+		INSERT INTO dispute_notes (dispute_id, author_user_id, body)
+			VALUES ($1, $2, $3)
+		RETURNING id, created_at`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	return m.DB.
+		QueryRowContext(ctx, query, note.DisputeID, note.AuthorUserID, note.Body).
+		Scan(&note.ID, &note.CreatedAt)
+}
@@ -0,0 +1,327 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"slices"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/ttarnok/instrument-swap-api/internal/validator"
+)
+
+// MaxInstrumentPhotos is the maximum number of photos of an instrument.
+const MaxInstrumentPhotos = 10
+
+// Instrument photo related errors.
+// These errors can be tested using errors.Is.
+var (
+	ErrTooManyInstrumentPhotos     = errors.New("too many instrument photos")     // "too many instrument photos"
+	ErrInvalidInstrumentPhotoOrder = errors.New("invalid instrument photo order") // "invalid instrument photo order"
+)
+
+// InstrumentPhoto represents a photo of an instrument.
+// The image and its thumbnail are kept in the blob storage under the given keys,
+// the photos of an instrument are ordered by their positions starting from 1.
+type InstrumentPhoto struct {
+	ID              int64     `json:"id"`
+	InstrumentID    int64     `json:"instrument_id"`
+	CreatedAt       time.Time `json:"created_at"`
+	Position        int32     `json:"position"`
+	ContentType     string    `json:"content_type"`
+	Size            int64     `json:"size"`
+	Width           int32     `json:"width"`
+	Height          int32     `json:"height"`
+	ThumbnailWidth  int32     `json:"thumbnail_width"`
+	ThumbnailHeight int32     `json:"thumbnail_height"`
+	ImageKey        string    `json:"-"`
+	ThumbnailKey    string    `json:"-"`
+}
+
+// ValidateInstrumentPhotoOrder checks that the given photo ids are a reordering of the given photos,
+// adds all found validation errors into the validator.
+func ValidateInstrumentPhotoOrder(v *validator.Validator, photoIDs []int64, photos []*InstrumentPhoto) {
+	v.Check(validator.Unique(photoIDs), "photo_ids", "must be unique")
+	v.Check(len(photoIDs) == len(photos), "photo_ids", "must list every photo of the instrument")
+
+	for _, photo := range photos {
+		v.Check(slices.Contains(photoIDs, photo.ID), "photo_ids", "must list every photo of the instrument")
+	}
+}
+
+// instrumentPhotoColumns lists the columns of the instrument_photos table in the order expected by scanInstrumentPhoto.
+const instrumentPhotoColumns = `id, instrument_id, created_at, position, content_type, size, width, height,
+	thumbnail_width, thumbnail_height, image_key, thumbnail_key`
+
+// scanInstrumentPhoto scans a row selected with instrumentPhotoColumns into the given photo.
+func scanInstrumentPhoto(row rowScanner, photo *InstrumentPhoto) error {
+	return row.Scan(
+		&photo.ID,
+		&photo.InstrumentID,
+		&photo.CreatedAt,
+		&photo.Position,
+		&photo.ContentType,
+		&photo.Size,
+		&photo.Width,
+		&photo.Height,
+		&photo.ThumbnailWidth,
+		&photo.ThumbnailHeight,
+		&photo.ImageKey,
+		&photo.ThumbnailKey,
+	)
+}
+
+// InstrumentPhotoModel represents the instrument photo model, that stores the photo records of the instruments in a database.
+type InstrumentPhotoModel struct {
+	DB *sql.DB
+}
+
+// Insert stores the given photo as the last photo of its instrument within a transaction.
+// Returns ErrRecordNotFound if the instrument does not exist,
+// ErrTooManyInstrumentPhotos if the instrument already has MaxInstrumentPhotos photos.
+func (m *InstrumentPhotoModel) Insert(photo *InstrumentPhoto) (err error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			err = errors.Join(err, tx.Rollback())
+		}
+	}()
+
+	err = lockInstruments(ctx, tx, photo.InstrumentID)
+	if err != nil {
+		return err
+	}
+
+	var count int
+	err = tx.QueryRowContext(ctx, `SELECT count(*) FROM instrument_photos WHERE instrument_id = $1`, photo.InstrumentID).Scan(&count)
+	if err != nil {
+		return err
+	}
+	if count >= MaxInstrumentPhotos {
+		return ErrTooManyInstrumentPhotos
+	}
+
+	query := `
+		INSERT INTO instrument_photos (instrument_id, position, content_type, size, width, height,
+				thumbnail_width, thumbnail_height, image_key, thumbnail_key)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+		RETURNING id, created_at, position`
+
+	args := []any{
+		photo.InstrumentID, count + 1, photo.ContentType, photo.Size, photo.Width, photo.Height,
+		photo.ThumbnailWidth, photo.ThumbnailHeight, photo.ImageKey, photo.ThumbnailKey,
+	}
+
+	err = tx.QueryRowContext(ctx, query, args...).Scan(&photo.ID, &photo.CreatedAt, &photo.Position)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// Get retrieves the photo with the given id of the given instrument.
+// Returns ErrRecordNotFound if the instrument has no photo with the given id.
+func (m *InstrumentPhotoModel) Get(id int64, instrumentID int64) (*InstrumentPhoto, error) {
+	if id < 1 {
+		return nil, ErrRecordNotFound
+	}
+
+	query := `
+		SELECT ` + instrumentPhotoColumns + `
+		FROM instrument_photos
+		WHERE id = $1
+		  AND instrument_id = $2`
+
+	var photo InstrumentPhoto
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := scanInstrumentPhoto(m.DB.QueryRowContext(ctx, query, id, instrumentID), &photo)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return &photo, nil
+}
+
+// GetAllForInstrument retrieves the photos of the given instrument, ordered by their positions.
+func (m *InstrumentPhotoModel) GetAllForInstrument(instrumentID int64) ([]*InstrumentPhoto, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	return getInstrumentPhotos(ctx, m.DB, instrumentID)
+}
+
+// getInstrumentPhotos retrieves the photos of the given instrument, ordered by their positions.
+func getInstrumentPhotos(ctx context.Context, q queryer, instrumentID int64) (photos []*InstrumentPhoto, err error) {
+	query := `
+		SELECT ` + instrumentPhotoColumns + `
+		FROM instrument_photos
+		WHERE instrument_id = $1
+		ORDER BY position`
+
+	rows, err := q.QueryContext(ctx, query, instrumentID)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		errClose := rows.Close()
+		if err == nil {
+			err = errClose
+		}
+	}()
+
+	photos = []*InstrumentPhoto{}
+
+	for rows.Next() {
+		var photo InstrumentPhoto
+
+		err := scanInstrumentPhoto(rows, &photo)
+		if err != nil {
+			return nil, err
+		}
+
+		photos = append(photos, &photo)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return photos, nil
+}
+
+// Reorder sets the order of the photos of the given instrument within a transaction, the photo ids are listed in the new order.
+// Returns ErrRecordNotFound if the instrument does not exist,
+// ErrInvalidInstrumentPhotoOrder if the photo ids are not a reordering of the photos of the instrument.
+func (m *InstrumentPhotoModel) Reorder(instrumentID int64, photoIDs []int64) (photos []*InstrumentPhoto, err error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err != nil {
+			err = errors.Join(err, tx.Rollback())
+		}
+	}()
+
+	err = lockInstruments(ctx, tx, instrumentID)
+	if err != nil {
+		return nil, err
+	}
+
+	photos, err = getInstrumentPhotos(ctx, tx, instrumentID)
+	if err != nil {
+		return nil, err
+	}
+
+	v := validator.New()
+	if ValidateInstrumentPhotoOrder(v, photoIDs, photos); !v.Valid() {
+		return nil, ErrInvalidInstrumentPhotoOrder
+	}
+
+	// The unique position constraint is deferred, so the positions can be swapped.
+	query := `
+		UPDATE instrument_photos p
+			SET position = o.position
+		FROM unnest($2::bigint[]) WITH ORDINALITY AS o (id, position)
+		WHERE p.id = o.id
+		  AND p.instrument_id = $1`
+
+	_, err = tx.ExecContext(ctx, query, instrumentID, pq.Array(photoIDs))
+	if err != nil {
+		return nil, err
+	}
+
+	photos, err = getInstrumentPhotos(ctx, tx, instrumentID)
+	if err != nil {
+		return nil, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return nil, err
+	}
+
+	return photos, nil
+}
+
+// Delete removes the photo with the given id of the given instrument within a transaction,
+// the following photos of the instrument are moved forward. The removed photo is returned, so its blobs can be removed.
+// Returns ErrRecordNotFound if the instrument has no photo with the given id.
+func (m *InstrumentPhotoModel) Delete(id int64, instrumentID int64) (photo *InstrumentPhoto, err error) {
+	if id < 1 {
+		return nil, ErrRecordNotFound
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err != nil {
+			err = errors.Join(err, tx.Rollback())
+		}
+	}()
+
+	err = lockInstruments(ctx, tx, instrumentID)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `
+		DELETE FROM instrument_photos
+		WHERE id = $1
+		  AND instrument_id = $2
+		RETURNING ` + instrumentPhotoColumns
+
+	photo = &InstrumentPhoto{}
+
+	err = scanInstrumentPhoto(tx.QueryRowContext(ctx, query, id, instrumentID), photo)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	query = `
+		UPDATE instrument_photos
+			SET position = position - 1
+		WHERE instrument_id = $1
+		  AND position > $2`
+
+	_, err = tx.ExecContext(ctx, query, instrumentID, photo.Position)
+	if err != nil {
+		return nil, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return nil, err
+	}
+
+	return photo, nil
+}
@@ -0,0 +1,240 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// InstrumentRevision represents a previous state of an instrument.
+// A revision is recorded every time an instrument is updated, it holds the state of the instrument at the replaced version
+// together with the time of the update and the user who made it.
+type InstrumentRevision struct {
+	ID              int64       `json:"id"`
+	ChangedAt       time.Time   `json:"changed_at"`
+	ChangedByUserID *int64      `json:"changed_by_user_id"` // nil if the user has been deleted since
+	Instrument      *Instrument `json:"instrument"`
+}
+
+// InstrumentChange represents the change of a single field of an instrument between two versions.
+type InstrumentChange struct {
+	Field string `json:"field"`
+	From  any    `json:"from"`
+	To    any    `json:"to"`
+}
+
+// InstrumentDiff represents the changes of an instrument between two of its versions.
+type InstrumentDiff struct {
+	InstrumentID int64               `json:"instrument_id"`
+	FromVersion  int32               `json:"from_version"`
+	ToVersion    int32               `json:"to_version"`
+	Changes      []*InstrumentChange `json:"changes"`
+}
+
+// DiffInstruments compares two versions of an instrument, and returns the changes of their fields.
+// The fields are named after their JSON properties, the identity, creation time and version fields are not compared.
+func DiffInstruments(from *Instrument, to *Instrument) *InstrumentDiff {
+	diff := &InstrumentDiff{
+		InstrumentID: to.ID,
+		FromVersion:  from.Version,
+		ToVersion:    to.Version,
+		Changes:      []*InstrumentChange{},
+	}
+
+	check := func(field string, changed bool, fromValue any, toValue any) {
+		if changed {
+			diff.Changes = append(diff.Changes, &InstrumentChange{Field: field, From: fromValue, To: toValue})
+		}
+	}
+
+	check("name", from.Name != to.Name, from.Name, to.Name)
+	check("manufacturer", from.Manufacturer != to.Manufacturer, from.Manufacturer, to.Manufacturer)
+	check("manufacture_year", from.ManufactureYear != to.ManufactureYear, from.ManufactureYear, to.ManufactureYear)
+	check("type", from.Type != to.Type, from.Type, to.Type)
+	check("estimated_value", from.EstimatedValue != to.EstimatedValue, from.EstimatedValue, to.EstimatedValue)
+	check("condition", from.Condition != to.Condition, from.Condition, to.Condition)
+	check("description", from.Description != to.Description, from.Description, to.Description)
+	check("famous_owners", !slices.Equal(from.FamousOwners, to.FamousOwners), from.FamousOwners, to.FamousOwners)
+	check("owner_user_id", from.OwnerUserID != to.OwnerUserID, from.OwnerUserID, to.OwnerUserID)
+
+	return diff
+}
+
+// insertInstrumentRevision records the current state of the instrument with the given id and version within the given transaction.
+// The instrument is locked until the end of the transaction.
+// Returns ErrEditConflict if the instrument does not exist, has been deleted or its version differs from the given one.
+func insertInstrumentRevision(ctx context.Context, tx *sql.Tx, id int64, version int32, changedByUserID int64) error {
+	query := `
+		INSERT INTO instrument_revisions (instrument_id, version, changed_by_user_id, name, manufacturer, manufacture_year,
+				type, estimated_value, condition, description, famous_owners, owner_user_id)
+			SELECT id, version, $3, name, manufacturer, manufacture_year,
+				type, estimated_value, condition, description, famous_owners, owner_user_id
+			FROM instruments
+			WHERE id = $1
+			  AND version = $2
+			  AND is_deleted = FALSE
+			FOR UPDATE`
+
+	result, err := tx.ExecContext(ctx, query, id, version, changedByUserID)
+	if err != nil {
+		switch {
+		case err.Error() == `pq: duplicate key value violates unique constraint "instrument_revisions_instrument_id_version_key"`:
+			return ErrEditConflict
+		default:
+			return err
+		}
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return ErrEditConflict
+	}
+
+	return nil
+}
+
+// instrumentRevisionColumns lists the columns of the revisions aliased as r and their instruments aliased as i,
+// in the order expected by scanInstrumentRevision.
+const instrumentRevisionColumns = `r.id, r.changed_at, r.changed_by_user_id, r.instrument_id, i.created_at, r.name, r.manufacturer,
+	r.manufacture_year, r.type, r.estimated_value, r.condition, r.description, r.famous_owners, r.owner_user_id, r.version`
+
+// scanInstrumentRevision scans a row selected with instrumentRevisionColumns into the given revision.
+// The leading destinations are scanned from the columns preceding instrumentRevisionColumns.
+func scanInstrumentRevision(row rowScanner, revision *InstrumentRevision, leading ...any) error {
+	revision.Instrument = &Instrument{}
+
+	return row.Scan(append(leading,
+		&revision.ID,
+		&revision.ChangedAt,
+		&revision.ChangedByUserID,
+		&revision.Instrument.ID,
+		&revision.Instrument.CreatedAt,
+		&revision.Instrument.Name,
+		&revision.Instrument.Manufacturer,
+		&revision.Instrument.ManufactureYear,
+		&revision.Instrument.Type,
+		&revision.Instrument.EstimatedValue,
+		&revision.Instrument.Condition,
+		&revision.Instrument.Description,
+		pq.Array(&revision.Instrument.FamousOwners),
+		&revision.Instrument.OwnerUserID,
+		&revision.Instrument.Version,
+	)...)
+}
+
+// InstrumentRevisionModel represents the instrument revision model, that stores the previous states of the instruments in a database.
+// The revisions are recorded by InstrumentModel.Update.
+type InstrumentRevisionModel struct {
+	DB *sql.DB
+}
+
+// Get retrieves the revision of the given instrument at the given version.
+// Returns ErrRecordNotFound if the instrument has no revision at the given version.
+func (m *InstrumentRevisionModel) Get(instrumentID int64, version int32) (*InstrumentRevision, error) {
+	query := `
+		SELECT ` + instrumentRevisionColumns + `
+		FROM instrument_revisions r
+		JOIN instruments i ON i.id = r.instrument_id
+		WHERE r.instrument_id = $1
+		  AND r.version = $2`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	var revision InstrumentRevision
+
+	err := scanInstrumentRevision(m.DB.QueryRowContext(ctx, query, instrumentID, version), &revision)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return &revision, nil
+}
+
+// GetAllForInstrument retrieves the revisions of the given instrument, paginated and sorted based on the given filters.
+func (m *InstrumentRevisionModel) GetAllForInstrument(instrumentID int64, filters Filters) (revisions []*InstrumentRevision, metaData MetaData, err error) {
+
+	//nolint:gosec
+	query := fmt.Sprintf(`
+		SELECT count(*) over(), `+instrumentRevisionColumns+`
+		FROM instrument_revisions r
+		JOIN instruments i ON i.id = r.instrument_id
+		WHERE r.instrument_id = $1
+		ORDER BY r.%s %s, r.id ASC
+		LIMIT $2 OFFSET $3`, filters.sortColumn(), filters.sortDirection())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, instrumentID, filters.limit(), filters.offset())
+	if err != nil {
+		return nil, MetaData{}, err
+	}
+	defer func() {
+		errClose := rows.Close()
+		if err == nil {
+			err = errClose
+		}
+	}()
+
+	totalRecords := 0
+	revisions = []*InstrumentRevision{}
+
+	for rows.Next() {
+		var revision InstrumentRevision
+
+		err := scanInstrumentRevision(rows, &revision, &totalRecords)
+		if err != nil {
+			return nil, MetaData{}, err
+		}
+
+		revisions = append(revisions, &revision)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, MetaData{}, err
+	}
+
+	return revisions, calculateMetadata(totalRecords, filters.Page, filters.PageSize), nil
+}
+
+// renameInstrumentTypes prepares renaming the instrument type with the given name within the given transaction.
+// The current states of the instruments of the type are recorded as revisions changed by the given user and their versions are bumped,
+// the type itself is renamed by the cascading foreign key when its category is renamed.
+func renameInstrumentTypes(ctx context.Context, tx *sql.Tx, name string, changedByUserID int64) error {
+	query := `
+		INSERT INTO instrument_revisions (instrument_id, version, changed_by_user_id, name, manufacturer, manufacture_year,
+				type, estimated_value, condition, description, famous_owners, owner_user_id)
+			SELECT id, version, $2, name, manufacturer, manufacture_year,
+				type, estimated_value, condition, description, famous_owners, owner_user_id
+			FROM instruments
+			WHERE type = $1
+			  AND is_deleted = FALSE`
+
+	_, err := tx.ExecContext(ctx, query, name, changedByUserID)
+	if err != nil {
+		return err
+	}
+
+	query = `
+		UPDATE instruments
+			SET version = version + 1
+		WHERE type = $1
+		  AND is_deleted = FALSE`
+
+	_, err = tx.ExecContext(ctx, query, name)
+	return err
+}
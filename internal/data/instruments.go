@@ -27,6 +27,23 @@ type Instrument struct {
 	Version         int32     `json:"version"`
 }
 
+// InstrumentMatch represents an instrument of another user, that is suggested as a swap partner for an instrument.
+type InstrumentMatch struct {
+	Instrument      *Instrument `json:"instrument"`
+	OwnerReputation Reputation  `json:"owner_reputation"`
+	Score           float64     `json:"score"`
+}
+
+// Weights of the aspects of a match score, they add up to 1.
+const (
+	matchValueWeight      = 0.5
+	matchTypeWeight       = 0.3
+	matchReputationWeight = 0.2
+)
+
+// matchNeutralRating is the rating assumed for the owners without reviews.
+const matchNeutralRating = 3
+
 // ValidateInstrument checks the validity of an Instrument,
 // adds all found validtaion errors into the validator.
 func ValidateInstrument(v *validator.Validator, instrument *Instrument) {
@@ -42,7 +59,6 @@ func ValidateInstrument(v *validator.Validator, instrument *Instrument) {
 	v.Check(instrument.ManufactureYear <= int32(time.Now().Year()), "manufacture_year", "must not be in the future")
 
 	v.Check(instrument.Type != "", "type", "must not be empty")
-	v.Check(validator.PermittedValue(instrument.Type, "synthesizer", "guitar"), "type", "must be synthesizer or guitar")
 
 	v.Check(instrument.EstimatedValue != 0, "estimated_value", "must not be empty")
 	v.Check(instrument.EstimatedValue >= 0, "estimated_value", "must be greater than 0")
@@ -133,6 +149,7 @@ func (i *InstrumentModel) Get(id int64) (*Instrument, error) {
 }
 
 // GetAll returns all instrumets stored in the database.
+// The type filter matches the instruments of the given category and of all of its descendant categories.
 func (i *InstrumentModel) GetAll(name string, manufacturer string, iType string, famousOwners []string, ownerUserID int64, filters Filters) (instruments []*Instrument, metaData MetaData, err error) {
 
 	//nolint:gosec
@@ -142,7 +159,17 @@ func (i *InstrumentModel) GetAll(name string, manufacturer string, iType string,
 		FROM instruments
 		WHERE (to_tsvector('simple', name) @@ plainto_tsquery('simple', $1) OR $1 = '')
 		  AND (lower(manufacturer) = lower($2) OR $2 = '')
-			AND (lower(type) = lower($3) OR $3 = '')
+			AND (type IN (
+				WITH RECURSIVE subtree AS (
+						SELECT id, name
+						FROM categories
+						WHERE name = lower($3)
+					UNION
+						SELECT c.id, c.name
+						FROM categories c
+						JOIN subtree s ON c.parent_id = s.id
+				)
+				SELECT name FROM subtree) OR $3 = '')
 			AND (famous_owners @> $4 OR $4 = '{}')
 			AND (owner_user_id = $5 OR $5 = 0)
 		  AND is_deleted = FALSE
@@ -207,10 +234,128 @@ func (i *InstrumentModel) GetAll(name string, manufacturer string, iType string,
 	return instruments, metadata, nil
 }
 
-// Update updates the matching instrument in the database with the provided field values.
-// Returns ErrRecordnotFound if no target data found during update.
-// Returns ErrEditConflict if there was a race condidion during update.
-func (i *InstrumentModel) Update(instrument *Instrument) error {
+// availableInstrumentCondition is the SQL condition selecting the instruments aliased as i, that are available for swapping.
+// An instrument is available if it is not deleted, not in an active swap or swap cycle and not under an unresolved dispute.
+const availableInstrumentCondition = `i.is_deleted = FALSE
+		AND NOT ` + instrumentSwappedCondition + `
+		AND NOT ` + instrumentDisputedCondition
+
+// GetMatches ranks the instruments of the other users as swap partners of the given instrument, the scores are between 0 and 1.
+// The score is based on the proximity of the estimated values, on whether the type of the candidate is among the preferred types
+// and on the reputation of the owner of the candidate. Only the instruments available for swapping are suggested.
+// The preferred types are expected in lower case. The matches are paginated based on the given filters.
+func (i *InstrumentModel) GetMatches(instrument *Instrument, preferredTypes []string, filters Filters) (matches []*InstrumentMatch, metaData MetaData, err error) {
+
+	//nolint:gosec
+	query := fmt.Sprintf(`
+		SELECT count(*) over(), id, created_at, name, manufacturer, manufacture_year, type, estimated_value,
+			condition, description, famous_owners, owner_user_id, version, reputation_average, reputation_count, score
+		FROM (
+				SELECT i.*, r.reputation_average, r.reputation_count,
+					$3 * (1 - abs(i.estimated_value - $2)::float8 / greatest(i.estimated_value, $2, 1))
+					+ $4 * (CASE WHEN lower(i.type) = ANY($5) THEN 1 ELSE 0 END)
+					+ $6 * (CASE WHEN r.reputation_count = 0 THEN $7 ELSE r.reputation_average END)::float8 / 5 AS score
+				FROM instruments i
+				CROSS JOIN LATERAL (
+					SELECT ROUND(COALESCE(avg(rating), 0), 2) reputation_average, count(*) reputation_count
+					FROM reviews
+					WHERE reviewee_user_id = i.owner_user_id
+				) r
+				WHERE i.owner_user_id <> $1
+				  AND `+availableInstrumentCondition+`
+			) matches
+		ORDER BY %s %s, id ASC
+		LIMIT $8 OFFSET $9`, filters.sortColumn(), filters.sortDirection())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	args := []any{
+		instrument.OwnerUserID,
+		instrument.EstimatedValue,
+		matchValueWeight,
+		matchTypeWeight,
+		pq.Array(preferredTypes),
+		matchReputationWeight,
+		matchNeutralRating,
+		filters.limit(),
+		filters.offset(),
+	}
+
+	rows, err := i.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, MetaData{}, err
+	}
+
+	defer func() {
+		errRows := rows.Close()
+		if err == nil {
+			err = errRows
+		}
+	}()
+
+	totalRecords := 0
+	matches = []*InstrumentMatch{}
+
+	for rows.Next() {
+
+		match := InstrumentMatch{Instrument: &Instrument{}}
+
+		err := rows.Scan(
+			&totalRecords,
+			&match.Instrument.ID,
+			&match.Instrument.CreatedAt,
+			&match.Instrument.Name,
+			&match.Instrument.Manufacturer,
+			&match.Instrument.ManufactureYear,
+			&match.Instrument.Type,
+			&match.Instrument.EstimatedValue,
+			&match.Instrument.Condition,
+			&match.Instrument.Description,
+			pq.Array(&match.Instrument.FamousOwners),
+			&match.Instrument.OwnerUserID,
+			&match.Instrument.Version,
+			&match.OwnerReputation.Average,
+			&match.OwnerReputation.Count,
+			&match.Score,
+		)
+
+		if err != nil {
+			return nil, MetaData{}, err
+		}
+
+		matches = append(matches, &match)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, MetaData{}, err
+	}
+
+	return matches, calculateMetadata(totalRecords, filters.Page, filters.PageSize), nil
+}
+
+// Update updates the matching instrument in the database with the provided field values within a transaction.
+// The replaced state of the instrument is recorded as a revision, changed by the given user.
+// Returns ErrEditConflict if the instrument does not exist or there was a race condidion during update.
+func (i *InstrumentModel) Update(instrument *Instrument, changedByUserID int64) (err error) {
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	tx, err := i.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			err = errors.Join(err, tx.Rollback())
+		}
+	}()
+
+	err = insertInstrumentRevision(ctx, tx, instrument.ID, instrument.Version, changedByUserID)
+	if err != nil {
+		return err
+	}
 
 	query := `
 		UPDATE instruments
@@ -243,10 +388,7 @@ func (i *InstrumentModel) Update(instrument *Instrument) error {
 		instrument.Version,
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
-	defer cancel()
-
-	err := i.DB.QueryRowContext(ctx, query, args...).Scan(&instrument.Version)
+	err = tx.QueryRowContext(ctx, query, args...).Scan(&instrument.Version)
 	if err != nil {
 		switch {
 		case errors.Is(err, sql.ErrNoRows):
@@ -256,14 +398,16 @@ func (i *InstrumentModel) Update(instrument *Instrument) error {
 		}
 	}
 
-	return nil
+	return tx.Commit()
 
 }
 
-// Delete deletes the corresponding instrument record with the provided id in the database.
+// Delete deletes the corresponding instrument record with the provided id and version in the database within a transaction.
+// The instrument is locked, so it can not be put into a swap while it is deleted.
 // Returns ErrRecordnotFound if no target data found to delete.
-// Returns ErrConflict if the deleted instrument is swapped.
-func (i *InstrumentModel) Delete(id int64) error {
+// Returns ErrEditConflict if the instrument has been modified or deleted since the given version was retrieved.
+// Returns ErrConflict if the deleted instrument is in an active swap or in a proposed or active swap cycle.
+func (i *InstrumentModel) Delete(id int64, version int32) (err error) {
 
 	if id < 1 {
 		return ErrRecordNotFound
@@ -272,13 +416,37 @@ func (i *InstrumentModel) Delete(id int64) error {
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
 
+	tx, err := i.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			err = errors.Join(err, tx.Rollback())
+		}
+	}()
+
+	err = lockInstruments(ctx, tx, id)
+	if err != nil {
+		return err
+	}
+
+	swapped, err := isInstrumentSwapped(ctx, tx, id)
+	if err != nil {
+		return err
+	}
+	if swapped {
+		return ErrConflict
+	}
+
 	query := `
 		UPDATE instruments
-			SET is_deleted = TRUE, deleted_at = NOW()
+			SET is_deleted = TRUE, deleted_at = NOW(), version = version + 1
 		WHERE ID = $1
+		  AND version = $2
 		  AND is_deleted = FALSE`
 
-	result, err := i.DB.ExecContext(ctx, query, id)
+	result, err := tx.ExecContext(ctx, query, id, version)
 	if err != nil {
 		return err
 	}
@@ -289,9 +457,9 @@ func (i *InstrumentModel) Delete(id int64) error {
 	}
 
 	if rowsAffected == 0 {
-		return ErrRecordNotFound
+		return ErrEditConflict
 	}
 
-	return nil
+	return tx.Commit()
 
 }
@@ -2,18 +2,23 @@ package mocks
 
 import (
 	"sync"
+	"time"
+
+	"github.com/ttarnok/instrument-swap-api/internal/auth"
 )
 
 // BlacklistServiceMock represents a Mock for BlacklistService.
 type BlacklistServiceMock struct {
-	store map[string]string
+	store     map[string]string
+	userStore map[int64]time.Time
 	sync.Mutex
 }
 
 // NewBlacklistServiceMock creates a new empty *BlacklistServiceMock.
 func NewBlacklistServiceMock() *BlacklistServiceMock {
 	return &BlacklistServiceMock{
-		store: make(map[string]string),
+		store:     make(map[string]string),
+		userStore: make(map[int64]time.Time),
 	}
 }
 
@@ -24,7 +29,8 @@ func NewBlacklistServiceMockWithData(data []string) *BlacklistServiceMock {
 		store[tokenID] = "blacklisted"
 	}
 	return &BlacklistServiceMock{
-		store: store,
+		store:     store,
+		userStore: make(map[int64]time.Time),
 	}
 }
 
@@ -38,9 +44,31 @@ func (b *BlacklistServiceMock) BlacklistToken(token string) error {
 
 // IsTokenBlacklisted checks wheather the given token ID is blacklisted.
 func (b *BlacklistServiceMock) IsTokenBlacklisted(token string) (bool, error) {
+	b.Lock()
+	defer b.Unlock()
 	if _, ok := b.store[token]; !ok {
 		return false, nil
 	}
 
 	return true, nil
 }
+
+// BlacklistUserTokens blacklists the tokens of the given user issued before the given time.
+func (b *BlacklistServiceMock) BlacklistUserTokens(userID int64, issuedBefore time.Time) error {
+	b.Lock()
+	defer b.Unlock()
+	b.userStore[userID] = issuedBefore
+	return nil
+}
+
+// IsUserTokenBlacklisted checks whether a token of the given user with the given issue time is blacklisted.
+func (b *BlacklistServiceMock) IsUserTokenBlacklisted(userID int64, issued time.Time) (bool, error) {
+	b.Lock()
+	defer b.Unlock()
+	issuedBefore, ok := b.userStore[userID]
+	if !ok {
+		return false, nil
+	}
+
+	return auth.IssuedBefore(issued, issuedBefore), nil
+}
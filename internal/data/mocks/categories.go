@@ -0,0 +1,191 @@
+package mocks
+
+import (
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/ttarnok/instrument-swap-api/internal/data"
+)
+
+// CategoryModelMock is a mock implementation for a CategoryModeler interface.
+type CategoryModelMock struct {
+	db []*data.Category
+	sync.Mutex
+}
+
+// NewCategoryModelMock returns a new CategoryModelMock based on the given db slice.
+func NewCategoryModelMock(db []*data.Category) *CategoryModelMock {
+	return &CategoryModelMock{db: db}
+}
+
+// Insert is a mocked method for CategoryModelMock.
+// Stores the given category, returns data.ErrDuplicateCategoryName if the name is already taken.
+func (m *CategoryModelMock) Insert(category *data.Category) error {
+	m.Lock()
+	defer m.Unlock()
+
+	if m.getByName(category.Name) != nil {
+		return data.ErrDuplicateCategoryName
+	}
+
+	var maxID int64
+	for _, stored := range m.db {
+		maxID = max(maxID, stored.ID)
+	}
+
+	category.ID = maxID + 1
+	category.CreatedAt = time.Now()
+	category.Version = 1
+	m.db = append(m.db, category)
+	return nil
+}
+
+// Get is a mocked method for CategoryModelMock.
+// Returns a copy of the stored category with the given id, data.ErrRecordNotFound otherwise.
+func (m *CategoryModelMock) Get(id int64) (*data.Category, error) {
+	m.Lock()
+	defer m.Unlock()
+
+	category := m.get(id)
+	if category == nil {
+		return nil, data.ErrRecordNotFound
+	}
+	c := *category
+	return &c, nil
+}
+
+// GetByName is a mocked method for CategoryModelMock.
+// Returns a copy of the stored category with the given name, data.ErrRecordNotFound otherwise.
+func (m *CategoryModelMock) GetByName(name string) (*data.Category, error) {
+	m.Lock()
+	defer m.Unlock()
+
+	category := m.getByName(name)
+	if category == nil {
+		return nil, data.ErrRecordNotFound
+	}
+	c := *category
+	return &c, nil
+}
+
+// GetAll is a mocked method for CategoryModelMock.
+// Returns every stored category.
+func (m *CategoryModelMock) GetAll() ([]*data.Category, error) {
+	m.Lock()
+	defer m.Unlock()
+
+	return slices.Clone(m.db), nil
+}
+
+// GetSubtreeNames is a mocked method for CategoryModelMock.
+// Returns the sorted names of the given stored categories and all of their descendants.
+func (m *CategoryModelMock) GetSubtreeNames(names ...string) ([]string, error) {
+	m.Lock()
+	defer m.Unlock()
+
+	subtree := []string{}
+	for _, category := range m.db {
+		for ancestor := category; ancestor != nil; ancestor = m.parent(ancestor) {
+			if slices.Contains(names, ancestor.Name) {
+				subtree = append(subtree, category.Name)
+				break
+			}
+		}
+	}
+	slices.Sort(subtree)
+	return subtree, nil
+}
+
+// GetLineageNames is a mocked method for CategoryModelMock.
+// Returns the sorted names of the given stored category and all of its ancestors.
+func (m *CategoryModelMock) GetLineageNames(name string) ([]string, error) {
+	m.Lock()
+	defer m.Unlock()
+
+	lineage := []string{}
+	for ancestor := m.getByName(name); ancestor != nil; ancestor = m.parent(ancestor) {
+		lineage = append(lineage, ancestor.Name)
+	}
+	slices.Sort(lineage)
+	return lineage, nil
+}
+
+// Update is a mocked method for CategoryModelMock.
+// Updates the stored category, returns data.ErrCategoryCycle if the new parent is a descendant of the category,
+// data.ErrDuplicateCategoryName if the name is already taken, data.ErrEditConflict if the versions differ.
+// The instruments of the category are not renamed.
+func (m *CategoryModelMock) Update(category *data.Category, changedByUserID int64) error {
+	m.Lock()
+	defer m.Unlock()
+
+	if category.ParentID != nil {
+		for ancestor := m.get(*category.ParentID); ancestor != nil; ancestor = m.parent(ancestor) {
+			if ancestor.ID == category.ID {
+				return data.ErrCategoryCycle
+			}
+		}
+	}
+
+	stored := m.get(category.ID)
+	if stored == nil || stored.Version != category.Version {
+		return data.ErrEditConflict
+	}
+	if other := m.getByName(category.Name); other != nil && other.ID != category.ID {
+		return data.ErrDuplicateCategoryName
+	}
+
+	category.Version++
+	*stored = *category
+	return nil
+}
+
+// Delete is a mocked method for CategoryModelMock.
+// Removes the stored category with the given id, returns data.ErrCategoryInUse if it has subcategories,
+// data.ErrRecordNotFound if it does not exist. The instruments are not checked.
+func (m *CategoryModelMock) Delete(id int64) error {
+	m.Lock()
+	defer m.Unlock()
+
+	for _, category := range m.db {
+		if category.ParentID != nil && *category.ParentID == id {
+			return data.ErrCategoryInUse
+		}
+	}
+
+	for index, category := range m.db {
+		if category.ID == id {
+			m.db = slices.Delete(m.db, index, index+1)
+			return nil
+		}
+	}
+	return data.ErrRecordNotFound
+}
+
+// get returns the stored category with the given id, nil otherwise.
+func (m *CategoryModelMock) get(id int64) *data.Category {
+	for _, category := range m.db {
+		if category.ID == id {
+			return category
+		}
+	}
+	return nil
+}
+
+// getByName returns the stored category with the given name, nil otherwise.
+func (m *CategoryModelMock) getByName(name string) *data.Category {
+	for _, category := range m.db {
+		if category.Name == name {
+			return category
+		}
+	}
+	return nil
+}
+
+// parent returns the stored parent of the given category, nil otherwise.
+func (m *CategoryModelMock) parent(category *data.Category) *data.Category {
+	if category.ParentID == nil {
+		return nil
+	}
+	return m.get(*category.ParentID)
+}
@@ -0,0 +1,60 @@
+package mocks
+
+import (
+	"sync"
+	"time"
+
+	"github.com/ttarnok/instrument-swap-api/internal/data"
+)
+
+// ConditionReportModelMock is a mock implementation for a ConditionReportModeler interface.
+type ConditionReportModelMock struct {
+	db []*data.ConditionReport
+	sync.Mutex
+}
+
+// NewConditionReportModelMock returns a new ConditionReportModelMock based on the given db slice.
+func NewConditionReportModelMock(db []*data.ConditionReport) *ConditionReportModelMock {
+	return &ConditionReportModelMock{db: db}
+}
+
+// Insert is a mocked method for ConditionReportModelMock.
+// Stores the given condition report, returns data.ErrDuplicateConditionReport
+// if the reporter has already reported the instrument at the same stage of the swap.
+func (m *ConditionReportModelMock) Insert(report *data.ConditionReport) error {
+	m.Lock()
+	defer m.Unlock()
+
+	for _, stored := range m.db {
+		if stored.SwapID == report.SwapID &&
+			stored.InstrumentID == report.InstrumentID &&
+			stored.ReporterUserID == report.ReporterUserID &&
+			stored.Stage == report.Stage {
+			return data.ErrDuplicateConditionReport
+		}
+	}
+
+	if report.Defects == nil {
+		report.Defects = []string{}
+	}
+
+	report.ID = int64(len(m.db) + 1)
+	report.CreatedAt = time.Now()
+	m.db = append(m.db, report)
+	return nil
+}
+
+// GetAllForSwap is a mocked method for ConditionReportModelMock.
+// Returns the stored condition reports of the given swap.
+func (m *ConditionReportModelMock) GetAllForSwap(swapID int64) ([]*data.ConditionReport, error) {
+	m.Lock()
+	defer m.Unlock()
+
+	reports := []*data.ConditionReport{}
+	for _, report := range m.db {
+		if report.SwapID == swapID {
+			reports = append(reports, report)
+		}
+	}
+	return reports, nil
+}
@@ -0,0 +1,105 @@
+package mocks
+
+import (
+	"sync"
+	"time"
+
+	"github.com/ttarnok/instrument-swap-api/internal/data"
+)
+
+// DisputeModelMock is a mock implementation for a DisputeModeler interface.
+type DisputeModelMock struct {
+	db    []*data.Dispute
+	notes []*data.DisputeNote
+	sync.Mutex
+}
+
+// NewDisputeModelMock returns a new DisputeModelMock based on the given db slice.
+func NewDisputeModelMock(db []*data.Dispute) *DisputeModelMock {
+	return &DisputeModelMock{db: db}
+}
+
+// Insert is a mocked method for DisputeModelMock.
+// Stores the given dispute, returns data.ErrDuplicateDispute if the swap already has an unresolved dispute.
+func (m *DisputeModelMock) Insert(dispute *data.Dispute) error {
+	m.Lock()
+	defer m.Unlock()
+
+	for _, stored := range m.db {
+		if stored.SwapID == dispute.SwapID && stored.Status != data.DisputeStatusResolved {
+			return data.ErrDuplicateDispute
+		}
+	}
+
+	dispute.ID = int64(len(m.db) + 1)
+	dispute.CreatedAt = time.Now()
+	dispute.Version = 1
+	m.db = append(m.db, dispute)
+	return nil
+}
+
+// Get is a mocked method for DisputeModelMock.
+// Returns the stored dispute with the given id together with its notes, returns data.ErrRecordNotFound otherwise.
+func (m *DisputeModelMock) Get(id int64) (*data.Dispute, error) {
+	m.Lock()
+	defer m.Unlock()
+
+	for _, stored := range m.db {
+		if stored.ID == id {
+			dispute := *stored
+			dispute.Notes = []*data.DisputeNote{}
+			for _, note := range m.notes {
+				if note.DisputeID == id {
+					dispute.Notes = append(dispute.Notes, note)
+				}
+			}
+			return &dispute, nil
+		}
+	}
+	return nil, data.ErrRecordNotFound
+}
+
+// GetAll is a mocked method for DisputeModelMock.
+// Returns the stored disputes with the given status, the filters are ignored.
+func (m *DisputeModelMock) GetAll(status string, filters data.Filters) ([]*data.Dispute, data.MetaData, error) {
+	m.Lock()
+	defer m.Unlock()
+
+	disputes := []*data.Dispute{}
+	for _, dispute := range m.db {
+		if status == "" || dispute.Status == status {
+			disputes = append(disputes, dispute)
+		}
+	}
+	return disputes, data.MetaData{}, nil
+}
+
+// Update is a mocked method for DisputeModelMock.
+// Replaces the stored dispute, returns data.ErrEditConflict if the versions do not match.
+func (m *DisputeModelMock) Update(dispute *data.Dispute) error {
+	m.Lock()
+	defer m.Unlock()
+
+	for index, stored := range m.db {
+		if stored.ID == dispute.ID && stored.Version == dispute.Version {
+			dispute.Version++
+			updated := *dispute
+			updated.Notes = nil
+			m.db[index] = &updated
+			return nil
+		}
+	}
+	return data.ErrEditConflict
+}
+
+// InsertNote is a mocked method for DisputeModelMock.
+// Stores the given evidence note.
+func (m *DisputeModelMock) InsertNote(note *data.DisputeNote) error {
+	m.Lock()
+	defer m.Unlock()
+
+	note.ID = int64(len(m.notes) + 1)
+	note.CreatedAt = time.Now()
+	m.notes = append(m.notes, note)
+	return nil
+}
@@ -0,0 +1,134 @@
+package mocks
+
+import (
+	"cmp"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/ttarnok/instrument-swap-api/internal/data"
+	"github.com/ttarnok/instrument-swap-api/internal/validator"
+)
+
+// InstrumentPhotoModelMock is a mock implementation for an InstrumentPhotoModeler interface.
+type InstrumentPhotoModelMock struct {
+	db     []*data.InstrumentPhoto
+	nextID int64
+	sync.Mutex
+}
+
+// NewInstrumentPhotoModelMock returns a new InstrumentPhotoModelMock based on the given db slice.
+func NewInstrumentPhotoModelMock(db []*data.InstrumentPhoto) *InstrumentPhotoModelMock {
+	m := &InstrumentPhotoModelMock{db: db}
+	for _, photo := range db {
+		m.nextID = max(m.nextID, photo.ID)
+	}
+	return m
+}
+
+// Insert is a mocked method for InstrumentPhotoModelMock.
+// Stores the given photo as the last photo of its instrument,
+// returns data.ErrTooManyInstrumentPhotos if the instrument already has data.MaxInstrumentPhotos photos.
+// The existence of the instrument is not checked.
+func (m *InstrumentPhotoModelMock) Insert(photo *data.InstrumentPhoto) error {
+	m.Lock()
+	defer m.Unlock()
+
+	photos := m.getAllForInstrument(photo.InstrumentID)
+	if len(photos) >= data.MaxInstrumentPhotos {
+		return data.ErrTooManyInstrumentPhotos
+	}
+
+	m.nextID++
+	photo.ID = m.nextID
+	photo.CreatedAt = time.Now()
+	photo.Position = int32(len(photos) + 1) //nolint:gosec // The number of photos is limited.
+	m.db = append(m.db, photo)
+	return nil
+}
+
+// Get is a mocked method for InstrumentPhotoModelMock.
+// Returns the stored photo with the given id of the given instrument, data.ErrRecordNotFound otherwise.
+func (m *InstrumentPhotoModelMock) Get(id int64, instrumentID int64) (*data.InstrumentPhoto, error) {
+	m.Lock()
+	defer m.Unlock()
+
+	for _, photo := range m.db {
+		if photo.ID == id && photo.InstrumentID == instrumentID {
+			return photo, nil
+		}
+	}
+	return nil, data.ErrRecordNotFound
+}
+
+// GetAllForInstrument is a mocked method for InstrumentPhotoModelMock.
+// Returns the stored photos of the given instrument ordered by their positions.
+func (m *InstrumentPhotoModelMock) GetAllForInstrument(instrumentID int64) ([]*data.InstrumentPhoto, error) {
+	m.Lock()
+	defer m.Unlock()
+
+	return m.getAllForInstrument(instrumentID), nil
+}
+
+// Reorder is a mocked method for InstrumentPhotoModelMock.
+// Sets the positions of the stored photos of the given instrument in the order of the given ids,
+// returns data.ErrInvalidInstrumentPhotoOrder if the ids are not a reordering of the photos.
+func (m *InstrumentPhotoModelMock) Reorder(instrumentID int64, photoIDs []int64) ([]*data.InstrumentPhoto, error) {
+	m.Lock()
+	defer m.Unlock()
+
+	photos := m.getAllForInstrument(instrumentID)
+
+	v := validator.New()
+	if data.ValidateInstrumentPhotoOrder(v, photoIDs, photos); !v.Valid() {
+		return nil, data.ErrInvalidInstrumentPhotoOrder
+	}
+
+	for _, photo := range photos {
+		photo.Position = int32(slices.Index(photoIDs, photo.ID) + 1) //nolint:gosec // The number of photos is limited.
+	}
+
+	return m.getAllForInstrument(instrumentID), nil
+}
+
+// Delete is a mocked method for InstrumentPhotoModelMock.
+// Removes the stored photo with the given id of the given instrument and moves the following photos forward,
+// returns data.ErrRecordNotFound if it does not exist.
+func (m *InstrumentPhotoModelMock) Delete(id int64, instrumentID int64) (*data.InstrumentPhoto, error) {
+	m.Lock()
+	defer m.Unlock()
+
+	index := slices.IndexFunc(m.db, func(photo *data.InstrumentPhoto) bool {
+		return photo.ID == id && photo.InstrumentID == instrumentID
+	})
+	if index < 0 {
+		return nil, data.ErrRecordNotFound
+	}
+
+	deleted := m.db[index]
+	m.db = slices.Delete(m.db, index, index+1)
+
+	for _, photo := range m.db {
+		if photo.InstrumentID == instrumentID && photo.Position > deleted.Position {
+			photo.Position--
+		}
+	}
+
+	return deleted, nil
+}
+
+// getAllForInstrument returns the stored photos of the given instrument ordered by their positions.
+func (m *InstrumentPhotoModelMock) getAllForInstrument(instrumentID int64) []*data.InstrumentPhoto {
+	photos := []*data.InstrumentPhoto{}
+	for _, photo := range m.db {
+		if photo.InstrumentID == instrumentID {
+			photos = append(photos, photo)
+		}
+	}
+
+	slices.SortFunc(photos, func(a, b *data.InstrumentPhoto) int {
+		return cmp.Compare(a.Position, b.Position)
+	})
+
+	return photos
+}
@@ -0,0 +1,55 @@
+package mocks
+
+import (
+	"cmp"
+	"slices"
+	"sync"
+
+	"github.com/ttarnok/instrument-swap-api/internal/data"
+)
+
+// InstrumentRevisionModelMock is a mock implementation for an InstrumentRevisionModeler interface.
+type InstrumentRevisionModelMock struct {
+	db []*data.InstrumentRevision
+	sync.Mutex
+}
+
+// NewInstrumentRevisionModelMock returns a new InstrumentRevisionModelMock based on the given db slice.
+func NewInstrumentRevisionModelMock(db []*data.InstrumentRevision) *InstrumentRevisionModelMock {
+	return &InstrumentRevisionModelMock{db: db}
+}
+
+// Get is a mocked method for InstrumentRevisionModelMock.
+// Returns the stored revision of the given instrument at the given version, data.ErrRecordNotFound otherwise.
+func (m *InstrumentRevisionModelMock) Get(instrumentID int64, version int32) (*data.InstrumentRevision, error) {
+	m.Lock()
+	defer m.Unlock()
+
+	for _, revision := range m.db {
+		if revision.Instrument.ID == instrumentID && revision.Instrument.Version == version {
+			return revision, nil
+		}
+	}
+
+	return nil, data.ErrRecordNotFound
+}
+
+// GetAllForInstrument is a mocked method for InstrumentRevisionModelMock.
+// Returns the stored revisions of the given instrument ordered by their versions, the filters are ignored.
+func (m *InstrumentRevisionModelMock) GetAllForInstrument(instrumentID int64, filters data.Filters) ([]*data.InstrumentRevision, data.MetaData, error) {
+	m.Lock()
+	defer m.Unlock()
+
+	revisions := []*data.InstrumentRevision{}
+	for _, revision := range m.db {
+		if revision.Instrument.ID == instrumentID {
+			revisions = append(revisions, revision)
+		}
+	}
+
+	slices.SortFunc(revisions, func(a, b *data.InstrumentRevision) int {
+		return cmp.Compare(a.Instrument.Version, b.Instrument.Version)
+	})
+
+	return revisions, data.MetaData{}, nil
+}
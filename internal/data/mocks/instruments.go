@@ -10,7 +10,9 @@ import (
 
 // InstrumentModelMock is a mock implementation for an instrument model.
 type InstrumentModelMock struct {
-	db []*data.Instrument
+	db             []*data.Instrument
+	matches        []*data.InstrumentMatch
+	preferredTypes []string
 	sync.Mutex
 }
 
@@ -39,7 +41,9 @@ func (im *InstrumentModelMock) Insert(instrument *data.Instrument) error {
 func (im *InstrumentModelMock) Get(id int64) (*data.Instrument, error) {
 	for _, i := range im.db {
 		if i.ID == id {
-			return i, nil
+			// A copy is returned, so changes are only stored by Update, as in the database.
+			instrument := *i
+			return &instrument, nil
 		}
 	}
 
@@ -51,8 +55,31 @@ func (im *InstrumentModelMock) GetAll(name string, manufacturer string, iType st
 	return im.db, data.MetaData{}, nil
 }
 
-// Update updates an instrument record in the mocked database.
-func (im *InstrumentModelMock) Update(instrument *data.Instrument) error {
+// SetMatches sets the canned matches returned by GetMatches.
+func (im *InstrumentModelMock) SetMatches(matches ...*data.InstrumentMatch) *InstrumentModelMock {
+	im.Lock()
+	defer im.Unlock()
+	im.matches = matches
+	return im
+}
+
+// PreferredTypes returns the preferred types GetMatches was last called with.
+func (im *InstrumentModelMock) PreferredTypes() []string {
+	im.Lock()
+	defer im.Unlock()
+	return im.preferredTypes
+}
+
+// GetMatches returns the matches set by SetMatches and records the given preferred types, the filters are ignored.
+func (im *InstrumentModelMock) GetMatches(instrument *data.Instrument, preferredTypes []string, filters data.Filters) ([]*data.InstrumentMatch, data.MetaData, error) {
+	im.Lock()
+	defer im.Unlock()
+	im.preferredTypes = preferredTypes
+	return append([]*data.InstrumentMatch{}, im.matches...), data.MetaData{}, nil
+}
+
+// Update updates an instrument record in the mocked database, the revisions of the instrument are not recorded.
+func (im *InstrumentModelMock) Update(instrument *data.Instrument, changedByUserID int64) error {
 	im.Lock()
 	defer im.Unlock()
 	for index, i := range im.db {
@@ -66,8 +93,9 @@ func (im *InstrumentModelMock) Update(instrument *data.Instrument) error {
 
 // Delete deletes an instrument from the mocked database.
 // If the provided id is not found, returns data.ErrRecordNotFound.
+// If the version of the instrument differs from the provided one, returns data.ErrEditConflict.
 // If the provided id is 999, returns data.ErrConflict.
-func (im *InstrumentModelMock) Delete(id int64) error {
+func (im *InstrumentModelMock) Delete(id int64, version int32) error {
 
 	if id == 999 {
 		return data.ErrConflict
@@ -86,6 +114,9 @@ func (im *InstrumentModelMock) Delete(id int64) error {
 	if indexToDel == -1 {
 		return data.ErrRecordNotFound
 	}
+	if im.db[indexToDel].Version != version {
+		return data.ErrEditConflict
+	}
 	im.db = slices.Delete(im.db, indexToDel, indexToDel+1)
 	return nil
 }
@@ -0,0 +1,42 @@
+package mocks
+
+import (
+	"sync"
+)
+
+// SentMail represents an email sent via MailerMock.
+type SentMail struct {
+	Recipient    string
+	TemplateFile string
+	Data         any
+}
+
+// MailerMock is a mock implementation for a mailer.Mailer interface.
+type MailerMock struct {
+	sent []SentMail
+	sync.Mutex
+}
+
+// NewMailerMock creates a new MailerMock.
+func NewMailerMock() *MailerMock {
+	return &MailerMock{}
+}
+
+// Send records the given email instead of sending it.
+func (m *MailerMock) Send(recipient string, templateFile string, data any) error {
+	m.Lock()
+	defer m.Unlock()
+
+	m.sent = append(m.sent, SentMail{Recipient: recipient, TemplateFile: templateFile, Data: data})
+	return nil
+}
+
+// Sent returns all emails recorded by the mock.
+func (m *MailerMock) Sent() []SentMail {
+	m.Lock()
+	defer m.Unlock()
+
+	sent := make([]SentMail, len(m.sent))
+	copy(sent, m.sent)
+	return sent
+}
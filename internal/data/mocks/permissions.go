@@ -0,0 +1,73 @@
+package mocks
+
+import (
+	"slices"
+	"sync"
+
+	"github.com/ttarnok/instrument-swap-api/internal/data"
+)
+
+// rolePermissions maps the known roles to their permissions.
+var rolePermissions = map[string]data.Permissions{
+	data.RoleAdmin: {data.PermissionUsersModerate, data.PermissionUsersRead, data.PermissionDisputesModerate, data.PermissionCategoriesWrite},
+}
+
+// PermissionModelMock is a mock implementation for a PermissionModeler interface.
+type PermissionModelMock struct {
+	roles map[int64][]string
+	sync.Mutex
+}
+
+// NewPermissionModelMock creates a new PermissionModelMock based on the given user id - role codes map.
+func NewPermissionModelMock(roles map[int64][]string) *PermissionModelMock {
+	rc := make(map[int64][]string, len(roles))
+	for userID, r := range roles {
+		rc[userID] = slices.Clone(r)
+	}
+	return &PermissionModelMock{roles: rc}
+}
+
+// GetAllForUser mocks the retrieval of the permissions of the given user.
+func (m *PermissionModelMock) GetAllForUser(userID int64) (data.Permissions, error) {
+	m.Lock()
+	defer m.Unlock()
+
+	permissions := data.Permissions{}
+	for _, role := range m.roles[userID] {
+		for _, p := range rolePermissions[role] {
+			if !permissions.Include(p) {
+				permissions = append(permissions, p)
+			}
+		}
+	}
+	slices.Sort(permissions)
+	return permissions, nil
+}
+
+// GetRolesForUser mocks the retrieval of the roles of the given user.
+func (m *PermissionModelMock) GetRolesForUser(userID int64) ([]string, error) {
+	m.Lock()
+	defer m.Unlock()
+
+	roles := slices.Clone(m.roles[userID])
+	if roles == nil {
+		roles = []string{}
+	}
+	slices.Sort(roles)
+	return roles, nil
+}
+
+// SetRolesForUser mocks the replacement of the roles of the given user.
+// Returns data.ErrUnknownRole if any of the given roles is unknown.
+func (m *PermissionModelMock) SetRolesForUser(userID int64, roles []string) error {
+	m.Lock()
+	defer m.Unlock()
+
+	for _, role := range roles {
+		if _, ok := rolePermissions[role]; !ok {
+			return data.ErrUnknownRole
+		}
+	}
+	m.roles[userID] = slices.Clone(roles)
+	return nil
+}
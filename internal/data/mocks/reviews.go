@@ -0,0 +1,73 @@
+package mocks
+
+import (
+	"math"
+	"sync"
+	"time"
+
+	"github.com/ttarnok/instrument-swap-api/internal/data"
+)
+
+// ReviewModelMock is a mock implementation for a ReviewModeler interface.
+type ReviewModelMock struct {
+	db []*data.Review
+	sync.Mutex
+}
+
+// NewReviewModelMock returns a new ReviewModelMock based on the given db slice.
+func NewReviewModelMock(db []*data.Review) *ReviewModelMock {
+	return &ReviewModelMock{db: db}
+}
+
+// Insert is a mocked method for ReviewModelMock.
+// Stores the given review, returns data.ErrDuplicateReview if the reviewer has already reviewed the swap.
+func (m *ReviewModelMock) Insert(review *data.Review) error {
+	m.Lock()
+	defer m.Unlock()
+
+	for _, stored := range m.db {
+		if stored.SwapID == review.SwapID && stored.ReviewerUserID == review.ReviewerUserID {
+			return data.ErrDuplicateReview
+		}
+	}
+
+	review.ID = int64(len(m.db) + 1)
+	review.CreatedAt = time.Now()
+	m.db = append(m.db, review)
+	return nil
+}
+
+// GetAllForUser is a mocked method for ReviewModelMock.
+// Returns the stored reviews of the given user, the filters are ignored.
+func (m *ReviewModelMock) GetAllForUser(revieweeUserID int64, filters data.Filters) ([]*data.Review, data.MetaData, error) {
+	m.Lock()
+	defer m.Unlock()
+
+	reviews := []*data.Review{}
+	for _, review := range m.db {
+		if review.RevieweeUserID == revieweeUserID {
+			reviews = append(reviews, review)
+		}
+	}
+	return reviews, data.MetaData{}, nil
+}
+
+// GetReputation is a mocked method for ReviewModelMock.
+// Returns the reputation aggregated from the stored reviews of the given user.
+func (m *ReviewModelMock) GetReputation(revieweeUserID int64) (data.Reputation, error) {
+	m.Lock()
+	defer m.Unlock()
+
+	reputation := data.Reputation{}
+	sum := 0
+	for _, review := range m.db {
+		if review.RevieweeUserID == revieweeUserID {
+			sum += review.Rating
+			reputation.Count++
+		}
+	}
+	if reputation.Count > 0 {
+		reputation.Average = math.Round(float64(sum)/float64(reputation.Count)*100) / 100
+	}
+	return reputation, nil
+}
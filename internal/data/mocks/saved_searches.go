@@ -0,0 +1,79 @@
+package mocks
+
+import (
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/ttarnok/instrument-swap-api/internal/data"
+)
+
+// SavedSearchModelMock is a mock implementation for a SavedSearchModeler interface.
+type SavedSearchModelMock struct {
+	db       []*data.SavedSearch
+	matching map[string][]*data.SavedSearch
+	sync.Mutex
+}
+
+// NewSavedSearchModelMock returns a new SavedSearchModelMock based on the given db slice.
+func NewSavedSearchModelMock(db []*data.SavedSearch) *SavedSearchModelMock {
+	return &SavedSearchModelMock{db: db, matching: make(map[string][]*data.SavedSearch)}
+}
+
+// SetMatching sets the canned saved searches returned by GetAllMatching for the instruments with the given name.
+func (m *SavedSearchModelMock) SetMatching(instrumentName string, searches ...*data.SavedSearch) *SavedSearchModelMock {
+	m.Lock()
+	defer m.Unlock()
+	m.matching[instrumentName] = searches
+	return m
+}
+
+// Insert is a mocked method for SavedSearchModelMock.
+// Stores the given saved search.
+func (m *SavedSearchModelMock) Insert(search *data.SavedSearch) error {
+	m.Lock()
+	defer m.Unlock()
+
+	search.ID = int64(len(m.db) + 1)
+	search.CreatedAt = time.Now()
+	m.db = append(m.db, search)
+	return nil
+}
+
+// GetAllForUser is a mocked method for SavedSearchModelMock.
+// Returns the stored saved searches of the given user.
+func (m *SavedSearchModelMock) GetAllForUser(userID int64) ([]*data.SavedSearch, error) {
+	m.Lock()
+	defer m.Unlock()
+
+	searches := []*data.SavedSearch{}
+	for _, search := range m.db {
+		if search.UserID == userID {
+			searches = append(searches, search)
+		}
+	}
+	return searches, nil
+}
+
+// GetAllMatching is a mocked method for SavedSearchModelMock.
+// Returns the saved searches set by SetMatching for the name of the given instrument, the instrument types are ignored.
+func (m *SavedSearchModelMock) GetAllMatching(instrument *data.Instrument, instrumentTypes []string) ([]*data.SavedSearch, error) {
+	m.Lock()
+	defer m.Unlock()
+	return append([]*data.SavedSearch{}, m.matching[instrument.Name]...), nil
+}
+
+// Delete is a mocked method for SavedSearchModelMock.
+// Removes the stored saved search with the given id of the given user, returns data.ErrRecordNotFound otherwise.
+func (m *SavedSearchModelMock) Delete(id int64, userID int64) error {
+	m.Lock()
+	defer m.Unlock()
+
+	for index, search := range m.db {
+		if search.ID == id && search.UserID == userID {
+			m.db = slices.Delete(m.db, index, index+1)
+			return nil
+		}
+	}
+	return data.ErrRecordNotFound
+}
@@ -0,0 +1,65 @@
+package mocks
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"slices"
+	"sync"
+
+	"github.com/ttarnok/instrument-swap-api/internal/storage"
+)
+
+// StorageMock is a mock implementation for a storage.Storage interface, it keeps the objects in memory.
+type StorageMock struct {
+	objects map[string][]byte
+	sync.Mutex
+}
+
+// NewStorageMock creates a new, empty StorageMock.
+func NewStorageMock() *StorageMock {
+	return &StorageMock{objects: make(map[string][]byte)}
+}
+
+// Put stores a copy of the given data under the given key.
+func (m *StorageMock) Put(_ context.Context, key string, data []byte, _ string) error {
+	m.Lock()
+	defer m.Unlock()
+
+	m.objects[key] = slices.Clone(data)
+	return nil
+}
+
+// Get returns the data stored under the given key, storage.ErrNotFound otherwise.
+func (m *StorageMock) Get(_ context.Context, key string) (io.ReadCloser, error) {
+	m.Lock()
+	defer m.Unlock()
+
+	data, ok := m.objects[key]
+	if !ok {
+		return nil, storage.ErrNotFound
+	}
+	return io.NopCloser(bytes.NewReader(data)), nil
+}
+
+// Delete removes the data stored under the given key.
+func (m *StorageMock) Delete(_ context.Context, key string) error {
+	m.Lock()
+	defer m.Unlock()
+
+	delete(m.objects, key)
+	return nil
+}
+
+// Keys returns the sorted keys of the stored objects.
+func (m *StorageMock) Keys() []string {
+	m.Lock()
+	defer m.Unlock()
+
+	keys := make([]string, 0, len(m.objects))
+	for key := range m.objects {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	return keys
+}
@@ -0,0 +1,152 @@
+package mocks
+
+import (
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/ttarnok/instrument-swap-api/internal/data"
+)
+
+// SwapCycleModelMock is a mock implementation for a SwapCycleModeler interface.
+type SwapCycleModelMock struct {
+	db         []*data.SwapCycle
+	candidates []*data.SwapCycleLeg
+	sync.Mutex
+}
+
+// NewSwapCycleModelMock returns a new SwapCycleModelMock based on the given db slice.
+func NewSwapCycleModelMock(db []*data.SwapCycle) *SwapCycleModelMock {
+	return &SwapCycleModelMock{db: db}
+}
+
+// Create is a mocked method for SwapCycleModelMock.
+// Stores the given swap cycle, returns data.ErrInstrumentAlreadySwapped if an instrument is in a proposed or an active swap cycle.
+func (m *SwapCycleModelMock) Create(cycle *data.SwapCycle) error {
+	m.Lock()
+	defer m.Unlock()
+
+	for _, stored := range m.db {
+		if stored.Status != data.SwapCycleStatusProposed && stored.Status != data.SwapCycleStatusActive {
+			continue
+		}
+		for _, id := range cycle.InstrumentIDs() {
+			if slices.Contains(stored.InstrumentIDs(), id) {
+				return data.ErrInstrumentAlreadySwapped
+			}
+		}
+	}
+
+	cycle.ID = int64(len(m.db) + 1)
+	cycle.CreatedAt = time.Now()
+	cycle.Version = 1
+	m.db = append(m.db, cycle)
+	return nil
+}
+
+// SetCandidateLegs sets the canned candidate legs returned by GetCandidateLegs.
+func (m *SwapCycleModelMock) SetCandidateLegs(legs ...*data.SwapCycleLeg) *SwapCycleModelMock {
+	m.Lock()
+	defer m.Unlock()
+
+	m.candidates = legs
+	return m
+}
+
+// GetCandidateLegs is a mocked method for SwapCycleModelMock.
+// Returns the candidate legs set by SetCandidateLegs, regardless of the given user.
+func (m *SwapCycleModelMock) GetCandidateLegs(userID int64) ([]*data.SwapCycleLeg, error) {
+	m.Lock()
+	defer m.Unlock()
+
+	return append([]*data.SwapCycleLeg{}, m.candidates...), nil
+}
+
+// GetRecentParticipantSets is a mocked method for SwapCycleModelMock.
+// Returns the participants of the stored swap cycles of the given user,
+// that are proposed or active, or were rejected or expired after the given time.
+func (m *SwapCycleModelMock) GetRecentParticipantSets(userID int64, since time.Time) ([][]int64, error) {
+	m.Lock()
+	defer m.Unlock()
+
+	sets := [][]int64{}
+	for _, cycle := range m.db {
+		if !cycle.HasParticipant(userID) {
+			continue
+		}
+		switch {
+		case cycle.Status == data.SwapCycleStatusProposed, cycle.Status == data.SwapCycleStatusActive,
+			cycle.RejectedAt != nil && cycle.RejectedAt.After(since),
+			cycle.ExpiredAt != nil && cycle.ExpiredAt.After(since):
+			sets = append(sets, cycle.ParticipantUserIDs())
+		}
+	}
+	return sets, nil
+}
+
+// Get is a mocked method for SwapCycleModelMock.
+// Returns the stored swap cycle with the given id, returns data.ErrRecordNotFound otherwise.
+func (m *SwapCycleModelMock) Get(id int64) (*data.SwapCycle, error) {
+	m.Lock()
+	defer m.Unlock()
+
+	for _, cycle := range m.db {
+		if cycle.ID == id {
+			return cycle, nil
+		}
+	}
+	return nil, data.ErrRecordNotFound
+}
+
+// GetAllForUser is a mocked method for SwapCycleModelMock.
+// Returns the stored swap cycles of the given participant with the given status, the filters are ignored.
+func (m *SwapCycleModelMock) GetAllForUser(userID int64, status string, filters data.Filters) ([]*data.SwapCycle, data.MetaData, error) {
+	m.Lock()
+	defer m.Unlock()
+
+	cycles := []*data.SwapCycle{}
+	for _, cycle := range m.db {
+		if cycle.HasParticipant(userID) && (status == "" || cycle.Status == status) {
+			cycles = append(cycles, cycle)
+		}
+	}
+	return cycles, data.MetaData{}, nil
+}
+
+// Transition is a mocked method for SwapCycleModelMock.
+// Applies the requested status transition of the given user on the stored swap cycle with the given id.
+func (m *SwapCycleModelMock) Transition(id int64, status string, userID int64) (*data.SwapCycle, error) {
+	m.Lock()
+	defer m.Unlock()
+
+	for _, cycle := range m.db {
+		if cycle.ID == id {
+			err := data.TransitionSwapCycle(cycle, status, userID, time.Now())
+			if err != nil {
+				return nil, err
+			}
+			cycle.Version++
+			return cycle, nil
+		}
+	}
+	return nil, data.ErrRecordNotFound
+}
+
+// ExpireProposed is a mocked method for SwapCycleModelMock.
+// Expires the stored proposed swap cycles created before the given time.
+func (m *SwapCycleModelMock) ExpireProposed(createdBefore time.Time) (int64, error) {
+	m.Lock()
+	defer m.Unlock()
+
+	var count int64
+	now := time.Now()
+	for _, cycle := range m.db {
+		if cycle.Status == data.SwapCycleStatusProposed && cycle.CreatedAt.Before(createdBefore) {
+			cycle.Status = data.SwapCycleStatusExpired
+			cycle.ExpiredAt = &now
+			cycle.Version++
+			count++
+		}
+	}
+	return count, nil
+}
@@ -0,0 +1,94 @@
+package mocks
+
+import (
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/ttarnok/instrument-swap-api/internal/data"
+)
+
+// SwapMessageModelMock is a mock implementation for a SwapMessageModeler interface.
+type SwapMessageModelMock struct {
+	db []*data.SwapMessage
+	sync.Mutex
+}
+
+// NewSwapMessageModelMock returns a new SwapMessageModelMock based on the given db slice.
+func NewSwapMessageModelMock(db []*data.SwapMessage) *SwapMessageModelMock {
+	return &SwapMessageModelMock{db: db}
+}
+
+// Insert is a mocked method for SwapMessageModelMock.
+// Stores the given message.
+func (m *SwapMessageModelMock) Insert(message *data.SwapMessage) error {
+	m.Lock()
+	defer m.Unlock()
+
+	message.ID = int64(len(m.db) + 1)
+	message.CreatedAt = time.Now()
+	m.db = append(m.db, message)
+	return nil
+}
+
+// GetAllForSwap is a mocked method for SwapMessageModelMock.
+// Returns the requested page of the stored messages of the given swap in their stored order, the sorting is ignored.
+func (m *SwapMessageModelMock) GetAllForSwap(swapID int64, filters data.Filters) ([]*data.SwapMessage, data.MetaData, error) {
+	m.Lock()
+	defer m.Unlock()
+
+	messages := []*data.SwapMessage{}
+	for _, message := range m.db {
+		if message.SwapID == swapID {
+			messages = append(messages, message)
+		}
+	}
+
+	start := min((filters.Page-1)*filters.PageSize, len(messages))
+	end := min(start+filters.PageSize, len(messages))
+
+	return messages[start:end], data.MetaData{}, nil
+}
+
+// Get returns the stored message with the given id, it is not part of the SwapMessageModeler interface.
+func (m *SwapMessageModelMock) Get(id int64) (*data.SwapMessage, error) {
+	m.Lock()
+	defer m.Unlock()
+
+	for _, message := range m.db {
+		if message.ID == id {
+			return message, nil
+		}
+	}
+	return nil, data.ErrRecordNotFound
+}
+
+// CountUnread is a mocked method for SwapMessageModelMock.
+// Counts the unread stored messages of the given swap sent to the given user.
+func (m *SwapMessageModelMock) CountUnread(swapID int64, readerUserID int64) (int, error) {
+	m.Lock()
+	defer m.Unlock()
+
+	count := 0
+	for _, message := range m.db {
+		if message.SwapID == swapID && message.SenderUserID != readerUserID && message.ReadAt == nil {
+			count++
+		}
+	}
+	return count, nil
+}
+
+// MarkAsRead is a mocked method for SwapMessageModelMock.
+// Marks the stored messages of the given swap with the given ids sent to the given user as read.
+func (m *SwapMessageModelMock) MarkAsRead(swapID int64, readerUserID int64, ids []int64) error {
+	m.Lock()
+	defer m.Unlock()
+
+	now := time.Now()
+	for _, message := range m.db {
+		if message.SwapID == swapID && message.SenderUserID != readerUserID && slices.Contains(ids, message.ID) && message.ReadAt == nil {
+			message.ReadAt = &now
+		}
+	}
+	return nil
+}
@@ -2,27 +2,75 @@ package mocks
 
 import (
 	"errors"
+	"slices"
+	"sync"
+	"time"
 
 	"github.com/ttarnok/instrument-swap-api/internal/data"
 )
 
 // SwapModelMock is a mock implementation for an instrument model.
+// It also mocks the SwapEventModeler interface, the events are recorded by Create and Transition.
 type SwapModelMock struct {
-	db []*data.Swap
+	db          []*data.Swap
+	events      []*data.SwapEvent
+	disputed    []int64
+	instruments []*data.Instrument
+	sync.Mutex
 }
 
 // NewSwapModelMock returns a new SwapModelMock based on the given db slice.
+// The instrument lists of the given swaps are filled from their single instrument ids, if they are not set.
 func NewSwapModelMock(db []*data.Swap) *SwapModelMock {
+	for _, swap := range db {
+		if swap.RequesterInstrumentIDs == nil {
+			swap.RequesterInstrumentIDs = []int64{swap.RequesterInstrumentID}
+		}
+		if swap.RecipientInstrumentIDs == nil {
+			swap.RecipientInstrumentIDs = []int64{swap.RecipientInstrumentID}
+		}
+	}
 	return &SwapModelMock{db: db}
 }
 
-// GetAllForUser is a mocked method for SwapModelMock.
-// Returns all swaps stored in the struct.
-func (s *SwapModelMock) GetAllForUser(userID int64) ([]*data.Swap, error) {
+// DisputeInstruments marks the given instruments as being under an open dispute, so they can not be put into new swaps.
+func (s *SwapModelMock) DisputeInstruments(ids ...int64) {
+	s.Lock()
+	defer s.Unlock()
+
+	s.disputed = append(s.disputed, ids...)
+}
+
+// SetInstruments sets the instruments of the stored swaps, that are used to authorize the users and to expand the instruments.
+func (s *SwapModelMock) SetInstruments(instruments ...*data.Instrument) {
+	s.Lock()
+	defer s.Unlock()
+
+	s.instruments = instruments
+}
+
+// GetAll is a mocked method for SwapModelMock.
+// Returns the stored swaps filtered by instrument and creation time,
+// the user, the status, the role, the pagination and the sorting are ignored.
+func (s *SwapModelMock) GetAll(userID int64, status string, role string, instrumentID int64, createdFrom time.Time, createdTo time.Time, filters data.Filters) ([]*data.Swap, data.MetaData, error) {
 	if s.db == nil {
-		return nil, errors.New("error")
+		return nil, data.MetaData{}, errors.New("error")
 	}
-	return s.db, nil
+
+	swaps := []*data.Swap{}
+	for _, swap := range s.db {
+		if instrumentID != 0 && !slices.Contains(swap.InstrumentIDs(), instrumentID) {
+			continue
+		}
+		if !createdFrom.IsZero() && swap.CreatedAt.Before(createdFrom) {
+			continue
+		}
+		if !createdTo.IsZero() && !swap.CreatedAt.Before(createdTo) {
+			continue
+		}
+		swaps = append(swaps, swap)
+	}
+	return swaps, data.MetaData{}, nil
 }
 
 // Get is a mocked method for SwapModelMock.
@@ -36,22 +84,245 @@ func (s *SwapModelMock) Get(id int64) (*data.Swap, error) {
 	return nil, data.ErrRecordNotFound
 }
 
-// GetByInstrumentID is a mocked method for SwapModelMock.
-func (s *SwapModelMock) GetByInstrumentID(id int64) (*data.Swap, error) {
+// GetForUser is a mocked method for SwapModelMock.
+// Returns the stored swap with the given id, if the owner of its first requester or recipient instrument is the given user.
+// The instruments are looked up from the ones given to SetInstruments.
+func (s *SwapModelMock) GetForUser(id int64, userID int64, expandInstruments bool) (*data.Swap, error) {
+	s.Lock()
+	defer s.Unlock()
+
 	for _, swap := range s.db {
-		if swap.ID == id {
+		if swap.ID != id {
+			continue
+		}
+
+		if s.ownerUserID(swap.RequesterInstrumentID) != userID && s.ownerUserID(swap.RecipientInstrumentID) != userID {
+			return nil, data.ErrRecordNotFound
+		}
+
+		if !expandInstruments {
 			return swap, nil
 		}
+
+		expanded := *swap
+		expanded.RequesterInstruments = s.instrumentSummaries(swap.RequesterInstrumentIDs)
+		expanded.RecipientInstruments = s.instrumentSummaries(swap.RecipientInstrumentIDs)
+		return &expanded, nil
 	}
 	return nil, data.ErrRecordNotFound
 }
 
-// Insert is a mocked method for SwapModelMock.
-func (s *SwapModelMock) Insert(swap *data.Swap) error {
-	return nil
+// ownerUserID returns the owner of the given instrument, or 0 if the instrument is unknown, the caller must hold the lock.
+func (s *SwapModelMock) ownerUserID(instrumentID int64) int64 {
+	for _, instrument := range s.instruments {
+		if instrument.ID == instrumentID {
+			return instrument.OwnerUserID
+		}
+	}
+	return 0
+}
+
+// instrumentSummaries returns the summaries of the given known instruments, the caller must hold the lock.
+func (s *SwapModelMock) instrumentSummaries(ids []int64) []*data.SwapInstrument {
+	summaries := []*data.SwapInstrument{}
+	for _, id := range ids {
+		for _, instrument := range s.instruments {
+			if instrument.ID == id {
+				summaries = append(summaries, &data.SwapInstrument{
+					ID:           instrument.ID,
+					Name:         instrument.Name,
+					Manufacturer: instrument.Manufacturer,
+					OwnerUserID:  instrument.OwnerUserID,
+				})
+			}
+		}
+	}
+	return summaries
+}
+
+// GetAllForInstrument is a mocked method for SwapModelMock.
+// Returns the stored swaps of the given instrument, the filters are ignored.
+func (s *SwapModelMock) GetAllForInstrument(instrumentID int64, filters data.Filters) ([]*data.Swap, data.MetaData, error) {
+	s.Lock()
+	defer s.Unlock()
+
+	swaps := []*data.Swap{}
+	for _, swap := range s.db {
+		if slices.Contains(swap.InstrumentIDs(), instrumentID) {
+			swaps = append(swaps, swap)
+		}
+	}
+	return swaps, data.MetaData{}, nil
+}
+
+// Create is a mocked method for SwapModelMock.
+// Stores the given swap, returns an error if any of its instruments is already in an active stored swap or under an open dispute.
+func (s *SwapModelMock) Create(swap *data.Swap, actorUserID int64, note string) error {
+	s.Lock()
+	defer s.Unlock()
+
+	return s.create(swap, actorUserID, note)
 }
 
-// Update is a mocked method for SwapModelMock.
-func (s *SwapModelMock) Update(swap *data.Swap) error {
+// create stores the given swap, the caller must hold the lock.
+func (s *SwapModelMock) create(swap *data.Swap, actorUserID int64, note string) error {
+	var maxID int64
+	for _, stored := range s.db {
+		maxID = max(maxID, stored.ID)
+		if stored.IsEnded {
+			continue
+		}
+		for _, id := range stored.InstrumentIDs() {
+			if slices.Contains(swap.RequesterInstrumentIDs, id) {
+				return data.ErrRequesterInstrumentAlreadySwapped
+			}
+			if slices.Contains(swap.RecipientInstrumentIDs, id) {
+				return data.ErrRecipientInstrumentAlreadySwapped
+			}
+		}
+	}
+
+	for _, id := range s.disputed {
+		if slices.Contains(swap.RequesterInstrumentIDs, id) {
+			return data.ErrRequesterInstrumentDisputed
+		}
+		if slices.Contains(swap.RecipientInstrumentIDs, id) {
+			return data.ErrRecipientInstrumentDisputed
+		}
+	}
+
+	swap.ID = maxID + 1
+	swap.CreatedAt = time.Now()
+	swap.Version = 1
+
+	s.db = append(s.db, swap)
+	s.addEvent(swap.ID, data.SwapEventCreated, &actorUserID, note).TopUp = swap.TopUp
 	return nil
 }
+
+// Transition is a mocked method for SwapModelMock.
+// Performs the status transition on the stored swap with the given id.
+func (s *SwapModelMock) Transition(id int64, version int32, status string, actorUserID int64, note string) (*data.Swap, error) {
+	s.Lock()
+	defer s.Unlock()
+
+	for _, swap := range s.db {
+		if swap.ID == id {
+			if swap.Version != version {
+				return nil, data.ErrEditConflict
+			}
+			err := data.TransitionSwapStatus(swap, status, time.Now())
+			if err != nil {
+				return nil, err
+			}
+			swap.Version++
+			s.addEvent(swap.ID, status, &actorUserID, note)
+			return swap, nil
+		}
+	}
+	return nil, data.ErrRecordNotFound
+}
+
+// CounterOffer is a mocked method for SwapModelMock.
+// Supersedes the stored swap with the given id and stores the given counter swap.
+func (s *SwapModelMock) CounterOffer(id int64, counter *data.Swap, actorUserID int64, note string) error {
+	s.Lock()
+	defer s.Unlock()
+
+	for _, original := range s.db {
+		if original.ID != id {
+			continue
+		}
+
+		// The original swap is restored if the counter swap can not be stored, as a rolled back transaction would do.
+		backup := *original
+
+		err := data.TransitionSwapStatus(original, data.SwapStatusSuperseded, time.Now())
+		if err != nil {
+			return err
+		}
+
+		counter.ParentSwapID = &original.ID
+		err = s.create(counter, actorUserID, note)
+		if err != nil {
+			*original = backup
+			return err
+		}
+
+		original.Version++
+		s.addEvent(original.ID, data.SwapEventSuperseded, &actorUserID, "")
+		return nil
+	}
+	return data.ErrRecordNotFound
+}
+
+// ExpirePending is a mocked method for SwapModelMock.
+// Expires the stored pending swaps created before the given time.
+func (s *SwapModelMock) ExpirePending(createdBefore time.Time) (int64, error) {
+	s.Lock()
+	defer s.Unlock()
+
+	var count int64
+	for _, swap := range s.db {
+		if !swap.CreatedAt.Before(createdBefore) {
+			continue
+		}
+		if data.TransitionSwapStatus(swap, data.SwapStatusExpired, time.Now()) != nil {
+			continue
+		}
+		swap.Version++
+		s.addEvent(swap.ID, data.SwapEventExpired, nil, "")
+		count++
+	}
+	return count, nil
+}
+
+// FlagOverdue is a mocked method for SwapModelMock.
+// Flags the stored accepted swaps, whose return by date is before the given time.
+func (s *SwapModelMock) FlagOverdue(at time.Time) (int64, error) {
+	s.Lock()
+	defer s.Unlock()
+
+	var count int64
+	for _, swap := range s.db {
+		if swap.ReturnBy == nil || !swap.ReturnBy.Before(at) {
+			continue
+		}
+		if data.TransitionSwapStatus(swap, data.SwapStatusOverdue, time.Now()) != nil {
+			continue
+		}
+		swap.Version++
+		s.addEvent(swap.ID, data.SwapEventOverdue, nil, "")
+		count++
+	}
+	return count, nil
+}
+
+// addEvent records a new swap event and returns it, the caller must hold the lock.
+func (s *SwapModelMock) addEvent(swapID int64, eventType string, actorUserID *int64, note string) *data.SwapEvent {
+	event := &data.SwapEvent{
+		ID:          int64(len(s.events) + 1),
+		SwapID:      swapID,
+		CreatedAt:   time.Now(),
+		Type:        eventType,
+		ActorUserID: actorUserID,
+		Note:        note,
+	}
+	s.events = append(s.events, event)
+	return event
+}
+
+// GetAllForSwap is a mocked method for SwapModelMock.
+// Returns the recorded events of the given swap.
+func (s *SwapModelMock) GetAllForSwap(swapID int64) ([]*data.SwapEvent, error) {
+	s.Lock()
+	defer s.Unlock()
+
+	events := []*data.SwapEvent{}
+	for _, event := range s.events {
+		if event.SwapID == swapID {
+			events = append(events, event)
+		}
+	}
+	return events, nil
+}
@@ -0,0 +1,69 @@
+package mocks
+
+import (
+	"sync"
+	"time"
+
+	"github.com/ttarnok/instrument-swap-api/internal/data"
+)
+
+// TokenPlaintext is the plaintext of every token created by TokenModelMock.
+const TokenPlaintext = "TESTTOKENTESTTOKENTESTTOKE"
+
+// TokenModelMock is a mock implementation for a TokenModeler interface.
+type TokenModelMock struct {
+	tokens []*data.Token
+	sync.Mutex
+}
+
+// NewTokenModelMock creates a new empty TokenModelMock.
+func NewTokenModelMock() *TokenModelMock {
+	return &TokenModelMock{}
+}
+
+// New mocks the creation of a new token.
+// The plaintext of the created token is always TokenPlaintext.
+func (m *TokenModelMock) New(userID int64, ttl time.Duration, scope string) (*data.Token, error) {
+	token := &data.Token{
+		Plaintext: TokenPlaintext,
+		UserID:    userID,
+		Expiry:    time.Now().Add(ttl),
+		Scope:     scope,
+	}
+
+	return token, m.Insert(token)
+}
+
+// Insert mocks the insertion of a token.
+func (m *TokenModelMock) Insert(token *data.Token) error {
+	m.Lock()
+	defer m.Unlock()
+
+	m.tokens = append(m.tokens, token)
+	return nil
+}
+
+// DeleteAllForUser mocks the deletion of all tokens of the given scope for the given user.
+func (m *TokenModelMock) DeleteAllForUser(scope string, userID int64) error {
+	m.Lock()
+	defer m.Unlock()
+
+	kept := m.tokens[:0]
+	for _, t := range m.tokens {
+		if t.Scope != scope || t.UserID != userID {
+			kept = append(kept, t)
+		}
+	}
+	m.tokens = kept
+	return nil
+}
+
+// Tokens returns the tokens stored in the mock.
+func (m *TokenModelMock) Tokens() []*data.Token {
+	m.Lock()
+	defer m.Unlock()
+
+	tokens := make([]*data.Token, len(m.tokens))
+	copy(tokens, m.tokens)
+	return tokens
+}
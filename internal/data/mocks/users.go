@@ -9,7 +9,8 @@ import (
 
 // UserModelMock is a mock implementation for an UserModeler interface.
 type UserModelMock struct {
-	users []*data.User
+	users  []*data.User
+	tokens map[string]int64
 	sync.Mutex
 }
 
@@ -86,6 +87,38 @@ func (u *UserModelMock) GetByID(id int64) (*data.User, error) {
 	return nil, data.ErrRecordNotFound
 }
 
+// AddToken registers a token with the given scope and plaintext for the given user id.
+// Registered tokens can be used by GetForToken.
+func (u *UserModelMock) AddToken(tokenScope, tokenPlaintext string, userID int64) *UserModelMock {
+	u.Lock()
+	defer u.Unlock()
+
+	if u.tokens == nil {
+		u.tokens = make(map[string]int64)
+	}
+	u.tokens[tokenScope+":"+tokenPlaintext] = userID
+	return u
+}
+
+// GetForToken mocks the retrieval of a user based on a token registered by AddToken.
+// Returns data.ErrRecordNotFound error if the token or its user is not stored.
+func (u *UserModelMock) GetForToken(tokenScope, tokenPlaintext string) (*data.User, error) {
+	u.Lock()
+	defer u.Unlock()
+
+	userID, ok := u.tokens[tokenScope+":"+tokenPlaintext]
+	if !ok {
+		return nil, data.ErrRecordNotFound
+	}
+
+	for _, uRec := range u.users {
+		if uRec.ID == userID {
+			return uRec, nil
+		}
+	}
+	return nil, data.ErrRecordNotFound
+}
+
 // Update mocks the update of a user from the model.
 func (u *UserModelMock) Update(user *data.User) error {
 	u.Lock()
@@ -103,12 +136,16 @@ func (u *UserModelMock) Update(user *data.User) error {
 
 // Delete mocks the deletion of a user from the model.
 // If the given id is not found, returns data.ErrRecordNotFound.
-func (u *UserModelMock) Delete(id int64) error {
+// If the version of the user differs from the given one, returns data.ErrEditConflict.
+func (u *UserModelMock) Delete(id int64, version int) error {
 	u.Lock()
 	defer u.Unlock()
 
 	for i, user := range u.users {
 		if user.ID == id {
+			if user.Version != version {
+				return data.ErrEditConflict
+			}
 			u.users = slices.Delete(u.users, i, i+1)
 			return nil
 		}
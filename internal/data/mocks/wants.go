@@ -0,0 +1,62 @@
+package mocks
+
+import (
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/ttarnok/instrument-swap-api/internal/data"
+)
+
+// WantModelMock is a mock implementation for a WantModeler interface.
+type WantModelMock struct {
+	db []*data.Want
+	sync.Mutex
+}
+
+// NewWantModelMock returns a new WantModelMock based on the given db slice.
+func NewWantModelMock(db []*data.Want) *WantModelMock {
+	return &WantModelMock{db: db}
+}
+
+// Insert is a mocked method for WantModelMock.
+// Stores the given want.
+func (m *WantModelMock) Insert(want *data.Want) error {
+	m.Lock()
+	defer m.Unlock()
+
+	want.ID = int64(len(m.db) + 1)
+	want.CreatedAt = time.Now()
+	m.db = append(m.db, want)
+	return nil
+}
+
+// GetAllForUser is a mocked method for WantModelMock.
+// Returns the stored wants of the given user.
+func (m *WantModelMock) GetAllForUser(userID int64) ([]*data.Want, error) {
+	m.Lock()
+	defer m.Unlock()
+
+	wants := []*data.Want{}
+	for _, want := range m.db {
+		if want.UserID == userID {
+			wants = append(wants, want)
+		}
+	}
+	return wants, nil
+}
+
+// Delete is a mocked method for WantModelMock.
+// Removes the stored want with the given id of the given user, returns data.ErrRecordNotFound otherwise.
+func (m *WantModelMock) Delete(id int64, userID int64) error {
+	m.Lock()
+	defer m.Unlock()
+
+	for index, want := range m.db {
+		if want.ID == id && want.UserID == userID {
+			m.db = slices.Delete(m.db, index, index+1)
+			return nil
+		}
+	}
+	return data.ErrRecordNotFound
+}
@@ -4,6 +4,7 @@ package data
 import (
 	"database/sql"
 	"errors"
+	"time"
 )
 
 // Generic data related errors.
@@ -19,8 +20,9 @@ type InstrumentModeler interface {
 	Insert(instrument *Instrument) error
 	Get(id int64) (*Instrument, error)
 	GetAll(name string, manufacturer string, iType string, famousOwners []string, ownerUserID int64, filters Filters) (instruments []*Instrument, metaData MetaData, err error)
-	Update(instrument *Instrument) error
-	Delete(id int64) error
+	GetMatches(instrument *Instrument, preferredTypes []string, filters Filters) (matches []*InstrumentMatch, metaData MetaData, err error)
+	Update(instrument *Instrument, changedByUserID int64) error
+	Delete(id int64, version int32) error
 }
 
 // UserModeler interface abstracts a model for users.
@@ -29,31 +31,164 @@ type UserModeler interface {
 	GetAll() (users []*User, err error)
 	GetByEmail(email string) (*User, error)
 	GetByID(id int64) (*User, error)
+	GetForToken(tokenScope, tokenPlaintext string) (*User, error)
 	Update(user *User) error
-	Delete(id int64) error
+	Delete(id int64, version int) error
 }
 
 // SwapModeler abstract the model for swaps.
 type SwapModeler interface {
-	GetAllForUser(userID int64) ([]*Swap, error)
+	GetAll(userID int64, status string, role string, instrumentID int64, createdFrom time.Time, createdTo time.Time, filters Filters) (swaps []*Swap, metaData MetaData, err error)
 	Get(id int64) (*Swap, error)
-	GetByInstrumentID(id int64) (*Swap, error)
-	Insert(swap *Swap) error
-	Update(swap *Swap) error
+	GetForUser(id int64, userID int64, expandInstruments bool) (*Swap, error)
+	GetAllForInstrument(instrumentID int64, filters Filters) (swaps []*Swap, metaData MetaData, err error)
+	Create(swap *Swap, actorUserID int64, note string) error
+	Transition(id int64, version int32, status string, actorUserID int64, note string) (*Swap, error)
+	CounterOffer(id int64, counter *Swap, actorUserID int64, note string) error
+	ExpirePending(createdBefore time.Time) (int64, error)
+	FlagOverdue(at time.Time) (int64, error)
+}
+
+// SwapEventModeler abstracts the model for the history of the swaps.
+type SwapEventModeler interface {
+	GetAllForSwap(swapID int64) ([]*SwapEvent, error)
+}
+
+// SwapMessageModeler abstracts the model for the message threads of the swaps.
+type SwapMessageModeler interface {
+	Insert(message *SwapMessage) error
+	GetAllForSwap(swapID int64, filters Filters) (messages []*SwapMessage, metaData MetaData, err error)
+	CountUnread(swapID int64, readerUserID int64) (int, error)
+	MarkAsRead(swapID int64, readerUserID int64, ids []int64) error
+}
+
+// ReviewModeler abstracts the model for the reviews of the users.
+type ReviewModeler interface {
+	Insert(review *Review) error
+	GetAllForUser(revieweeUserID int64, filters Filters) (reviews []*Review, metaData MetaData, err error)
+	GetReputation(revieweeUserID int64) (Reputation, error)
+}
+
+// ConditionReportModeler abstracts the model for the condition reports of the swapped instruments.
+type ConditionReportModeler interface {
+	Insert(report *ConditionReport) error
+	GetAllForSwap(swapID int64) ([]*ConditionReport, error)
+}
+
+// DisputeModeler abstracts the model for the disputes of the swaps.
+type DisputeModeler interface {
+	Insert(dispute *Dispute) error
+	Get(id int64) (*Dispute, error)
+	GetAll(status string, filters Filters) (disputes []*Dispute, metaData MetaData, err error)
+	Update(dispute *Dispute) error
+	InsertNote(note *DisputeNote) error
+}
+
+// WantModeler abstracts the model for the wants of the users.
+type WantModeler interface {
+	Insert(want *Want) error
+	GetAllForUser(userID int64) ([]*Want, error)
+	Delete(id int64, userID int64) error
+}
+
+// SavedSearchModeler abstracts the model for the saved instrument searches of the users.
+type SavedSearchModeler interface {
+	Insert(search *SavedSearch) error
+	GetAllForUser(userID int64) ([]*SavedSearch, error)
+	GetAllMatching(instrument *Instrument, instrumentTypes []string) ([]*SavedSearch, error)
+	Delete(id int64, userID int64) error
+}
+
+// InstrumentPhotoModeler abstracts the model for the photos of the instruments.
+type InstrumentPhotoModeler interface {
+	Insert(photo *InstrumentPhoto) error
+	Get(id int64, instrumentID int64) (*InstrumentPhoto, error)
+	GetAllForInstrument(instrumentID int64) ([]*InstrumentPhoto, error)
+	Reorder(instrumentID int64, photoIDs []int64) ([]*InstrumentPhoto, error)
+	Delete(id int64, instrumentID int64) (*InstrumentPhoto, error)
+}
+
+// InstrumentRevisionModeler abstracts the model for the previous states of the instruments.
+type InstrumentRevisionModeler interface {
+	Get(instrumentID int64, version int32) (*InstrumentRevision, error)
+	GetAllForInstrument(instrumentID int64, filters Filters) (revisions []*InstrumentRevision, metaData MetaData, err error)
+}
+
+// CategoryModeler abstracts the model for the instrument type taxonomy.
+type CategoryModeler interface {
+	Insert(category *Category) error
+	Get(id int64) (*Category, error)
+	GetByName(name string) (*Category, error)
+	GetAll() ([]*Category, error)
+	GetSubtreeNames(names ...string) ([]string, error)
+	GetLineageNames(name string) ([]string, error)
+	Update(category *Category, changedByUserID int64) error
+	Delete(id int64) error
+}
+
+// SwapCycleModeler abstracts the model for the multi-party swaps.
+type SwapCycleModeler interface {
+	Create(cycle *SwapCycle) error
+	Get(id int64) (*SwapCycle, error)
+	GetAllForUser(userID int64, status string, filters Filters) (cycles []*SwapCycle, metaData MetaData, err error)
+	GetCandidateLegs(userID int64) (legs []*SwapCycleLeg, err error)
+	GetRecentParticipantSets(userID int64, since time.Time) (sets [][]int64, err error)
+	Transition(id int64, status string, userID int64) (*SwapCycle, error)
+	ExpireProposed(createdBefore time.Time) (int64, error)
+}
+
+// TokenModeler abstracts the model for single use tokens.
+type TokenModeler interface {
+	New(userID int64, ttl time.Duration, scope string) (*Token, error)
+	Insert(token *Token) error
+	DeleteAllForUser(scope string, userID int64) error
+}
+
+// PermissionModeler abstracts the model for roles and permissions.
+type PermissionModeler interface {
+	GetAllForUser(userID int64) (Permissions, error)
+	GetRolesForUser(userID int64) ([]string, error)
+	SetRolesForUser(userID int64, roles []string) error
 }
 
 // Models wraps all database models used in the application.
 type Models struct {
-	Instruments InstrumentModeler
-	Users       UserModeler
-	Swaps       SwapModeler
+	Instruments         InstrumentModeler
+	InstrumentPhotos    InstrumentPhotoModeler
+	InstrumentRevisions InstrumentRevisionModeler
+	Categories          CategoryModeler
+	Users               UserModeler
+	Swaps               SwapModeler
+	SwapEvents          SwapEventModeler
+	SwapMessages        SwapMessageModeler
+	Reviews             ReviewModeler
+	ConditionReports    ConditionReportModeler
+	Disputes            DisputeModeler
+	Wants               WantModeler
+	SavedSearches       SavedSearchModeler
+	SwapCycles          SwapCycleModeler
+	Tokens              TokenModeler
+	Permissions         PermissionModeler
 }
 
 // NewModel rerturn a newly created model based on the specified database connection.
 func NewModel(db *sql.DB) Models {
 	return Models{
-		Instruments: &InstrumentModel{DB: db},
-		Users:       &UserModel{DB: db},
-		Swaps:       &SwapModel{DB: db},
+		Instruments:         &InstrumentModel{DB: db},
+		InstrumentPhotos:    &InstrumentPhotoModel{DB: db},
+		InstrumentRevisions: &InstrumentRevisionModel{DB: db},
+		Categories:          &CategoryModel{DB: db},
+		Users:               &UserModel{DB: db},
+		Swaps:               &SwapModel{DB: db},
+		SwapEvents:          &SwapEventModel{DB: db},
+		SwapMessages:        &SwapMessageModel{DB: db},
+		Reviews:             &ReviewModel{DB: db},
+		ConditionReports:    &ConditionReportModel{DB: db},
+		Disputes:            &DisputeModel{DB: db},
+		Wants:               &WantModel{DB: db},
+		SavedSearches:       &SavedSearchModel{DB: db},
+		SwapCycles:          &SwapCycleModel{DB: db},
+		Tokens:              &TokenModel{DB: db},
+		Permissions:         &PermissionModel{DB: db},
 	}
 }
@@ -0,0 +1,477 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+	"github.com/ttarnok/instrument-swap-api/internal/testhelpers"
+)
+
+// ModelsTestSuite is the testsuite for testing the queries of the database models with full database integration.
+type ModelsTestSuite struct {
+	suite.Suite
+	pgContainer *testhelpers.PostgresContainer
+	db          *sql.DB
+	models      Models
+	ctx         context.Context
+}
+
+// SetupSuite sets up the testsuite, creates a migrated database and the models using it.
+func (suite *ModelsTestSuite) SetupSuite() {
+
+	suite.ctx = context.Background()
+	pgContainer, err := testhelpers.CreatePostgresContainer(suite.ctx)
+	if err != nil {
+		log.Fatal(err)
+	}
+	suite.pgContainer = pgContainer
+
+	db, err := sql.Open("postgres", suite.pgContainer.ConnectionString)
+	if err != nil {
+		log.Fatal(err)
+	}
+	suite.db = db
+
+	suite.models = NewModel(db)
+}
+
+// TearDownSuite tears down the testsuit. Release all used resources.
+func (suite *ModelsTestSuite) TearDownSuite() {
+	if err := suite.db.Close(); err != nil {
+		log.Fatalf("error closing database: %s", err)
+	}
+	if err := suite.pgContainer.Terminate(suite.ctx); err != nil {
+		log.Fatalf("error terminating postgres container: %s", err)
+	}
+}
+
+// SetupTest removes the users and everything referencing them, so every test starts from an empty database.
+func (suite *ModelsTestSuite) SetupTest() {
+	_, err := suite.db.ExecContext(suite.ctx, "TRUNCATE users RESTART IDENTITY CASCADE")
+	require.NoError(suite.T(), err)
+}
+
+// insertUser stores a new activated user with the given name.
+func (suite *ModelsTestSuite) insertUser(name string) *User {
+	t := suite.T()
+
+	user := &User{Name: name, Email: fmt.Sprintf("%s@example.com", name), Activated: true}
+	require.NoError(t, user.Password.Set("asd123asd1234"))
+	require.NoError(t, suite.models.Users.Insert(user))
+
+	return user
+}
+
+// insertInstrument stores the given instrument of the given owner, the not set mandatory fields are filled.
+func (suite *ModelsTestSuite) insertInstrument(ownerUserID int64, instrument *Instrument) *Instrument {
+	t := suite.T()
+
+	instrument.OwnerUserID = ownerUserID
+	if instrument.Manufacturer == "" {
+		instrument.Manufacturer = "Roland"
+	}
+	if instrument.ManufactureYear == 0 {
+		instrument.ManufactureYear = 1982
+	}
+	if instrument.Type == "" {
+		instrument.Type = "synthesizer"
+	}
+	if instrument.Condition == "" {
+		instrument.Condition = "excellent"
+	}
+	if instrument.FamousOwners == nil {
+		instrument.FamousOwners = []string{}
+	}
+	require.NoError(t, suite.models.Instruments.Insert(instrument))
+
+	return instrument
+}
+
+// TestSwapStatusFilters tests that listing the swaps by status selects exactly the swaps in the given status.
+func (suite *ModelsTestSuite) TestSwapStatusFilters() {
+	t := suite.T()
+
+	requester := suite.insertUser("requester")
+	recipient := suite.insertUser("recipient")
+
+	// one swap is created in each status, by setting its flags after the creation
+	flags := []struct {
+		status string
+		set    string
+	}{
+		{status: SwapStatusPending},
+		{status: SwapStatusAccepted, set: "is_accepted = TRUE"},
+		{status: SwapStatusRejected, set: "is_rejected = TRUE"},
+		{status: SwapStatusEnded, set: "is_accepted = TRUE, is_ended = TRUE"},
+		{status: SwapStatusCancelled, set: "is_cancelled = TRUE"},
+		{status: SwapStatusExpired, set: "is_expired = TRUE"},
+		{status: SwapStatusSuperseded, set: "is_superseded = TRUE"},
+		{status: SwapStatusOverdue, set: "is_accepted = TRUE, is_overdue = TRUE"},
+	}
+
+	swapIDs := map[string]int64{}
+	for _, f := range flags {
+		requesterInstrument := suite.insertInstrument(requester.ID, &Instrument{Name: "Juno " + f.status})
+		recipientInstrument := suite.insertInstrument(recipient.ID, &Instrument{Name: "Jupiter " + f.status})
+
+		swap := NewSwap([]int64{requesterInstrument.ID}, []int64{recipientInstrument.ID})
+		require.NoError(t, suite.models.Swaps.Create(swap, requester.ID, ""))
+
+		if f.set != "" {
+			//nolint:gosec
+			_, err := suite.db.ExecContext(suite.ctx, "UPDATE swaps SET "+f.set+" WHERE id = $1", swap.ID)
+			require.NoError(t, err)
+		}
+
+		swapIDs[f.status] = swap.ID
+	}
+
+	// a swap returned late is ended, but it is not overdue any more
+	lateRequesterInstrument := suite.insertInstrument(requester.ID, &Instrument{Name: "Juno late"})
+	lateRecipientInstrument := suite.insertInstrument(recipient.ID, &Instrument{Name: "Jupiter late"})
+	lateSwap := NewSwap([]int64{lateRequesterInstrument.ID}, []int64{lateRecipientInstrument.ID})
+	require.NoError(t, suite.models.Swaps.Create(lateSwap, requester.ID, ""))
+	_, err := suite.db.ExecContext(suite.ctx, "UPDATE swaps SET is_accepted = TRUE, is_overdue = TRUE, is_ended = TRUE WHERE id = $1", lateSwap.ID)
+	require.NoError(t, err)
+
+	// the rejected, cancelled, expired and superseded swaps were never accepted, so they are pending as well
+	expected := map[string][]int64{
+		SwapStatusPending: {
+			swapIDs[SwapStatusPending], swapIDs[SwapStatusRejected], swapIDs[SwapStatusCancelled],
+			swapIDs[SwapStatusExpired], swapIDs[SwapStatusSuperseded],
+		},
+		SwapStatusAccepted:   {swapIDs[SwapStatusAccepted], swapIDs[SwapStatusOverdue]},
+		SwapStatusRejected:   {swapIDs[SwapStatusRejected]},
+		SwapStatusEnded:      {swapIDs[SwapStatusEnded], lateSwap.ID},
+		SwapStatusCancelled:  {swapIDs[SwapStatusCancelled]},
+		SwapStatusExpired:    {swapIDs[SwapStatusExpired]},
+		SwapStatusSuperseded: {swapIDs[SwapStatusSuperseded]},
+		SwapStatusOverdue:    {swapIDs[SwapStatusOverdue]},
+	}
+
+	filters := Filters{Page: 1, PageSize: 20, Sort: "id", SortSafeList: []string{"id"}}
+
+	for _, status := range SwapStatuses {
+		for _, userID := range []int64{requester.ID, recipient.ID} {
+			swaps, _, err := suite.models.Swaps.GetAll(userID, status, "", 0, time.Time{}, time.Time{}, filters)
+			require.NoError(t, err)
+
+			ids := []int64{}
+			for _, swap := range swaps {
+				ids = append(ids, swap.ID)
+			}
+			assert.ElementsMatch(t, expected[status], ids, "swaps with status %q of user %d mismatch", status, userID)
+		}
+	}
+}
+
+// TestInstrumentMatchScores tests the ranking of the swap partners suggested for an instrument.
+func (suite *ModelsTestSuite) TestInstrumentMatchScores() {
+	t := suite.T()
+
+	owner := suite.insertUser("owner")
+	seller := suite.insertUser("seller")
+	reviewed := suite.insertUser("reviewed")
+
+	instrument := suite.insertInstrument(owner.ID, &Instrument{Name: "Juno", EstimatedValue: 1000})
+	ownInstrument := suite.insertInstrument(owner.ID, &Instrument{Name: "Jupiter", EstimatedValue: 1000})
+	reviewedSwapInstrument := suite.insertInstrument(owner.ID, &Instrument{Name: "Polysix", EstimatedValue: 1000})
+
+	sameValue := suite.insertInstrument(seller.ID, &Instrument{Name: "Prophet", EstimatedValue: 1000})
+	halfValue := suite.insertInstrument(seller.ID, &Instrument{Name: "Mono/Poly", EstimatedValue: 500})
+	otherType := suite.insertInstrument(seller.ID, &Instrument{Name: "Telecaster", Type: "electric-guitar", EstimatedValue: 1000})
+	swapped := suite.insertInstrument(seller.ID, &Instrument{Name: "OB-X", EstimatedValue: 1000})
+
+	reviewedOtherType := suite.insertInstrument(reviewed.ID, &Instrument{Name: "Stratocaster", Type: "electric-guitar", EstimatedValue: 1000})
+	reviewedExpensive := suite.insertInstrument(reviewed.ID, &Instrument{Name: "Les Paul", Type: "electric-guitar", EstimatedValue: 3000})
+
+	// an instrument in an active swap is not available
+	activeSwap := NewSwap([]int64{swapped.ID}, []int64{ownInstrument.ID})
+	require.NoError(t, suite.models.Swaps.Create(activeSwap, seller.ID, ""))
+
+	// the owner of the reviewed instruments got a 5 rating after an ended swap
+	endedSwap := NewSwap([]int64{reviewedExpensive.ID}, []int64{reviewedSwapInstrument.ID})
+	require.NoError(t, suite.models.Swaps.Create(endedSwap, reviewed.ID, ""))
+	_, err := suite.db.ExecContext(suite.ctx, "UPDATE swaps SET is_accepted = TRUE, is_ended = TRUE WHERE id = $1", endedSwap.ID)
+	require.NoError(t, err)
+	require.NoError(t, suite.models.Reviews.Insert(&Review{SwapID: endedSwap.ID, ReviewerUserID: owner.ID, RevieweeUserID: reviewed.ID, Rating: 5}))
+
+	// the score is 0.5 * value proximity + 0.3 * preferred type + 0.2 * rating / 5, the owners without reviews are rated 3
+	expected := []struct {
+		instrument *Instrument
+		reputation Reputation
+		score      float64
+	}{
+		{instrument: sameValue, score: 0.5 + 0.3 + 0.2*3/5},
+		{instrument: reviewedOtherType, reputation: Reputation{Average: 5, Count: 1}, score: 0.5 + 0.2},
+		{instrument: halfValue, score: 0.5*0.5 + 0.3 + 0.2*3/5},
+		{instrument: otherType, score: 0.5 + 0.2*3/5},
+		{instrument: reviewedExpensive, reputation: Reputation{Average: 5, Count: 1}, score: 0.5*(1-2000.0/3000) + 0.2},
+	}
+
+	filters := Filters{Page: 1, PageSize: 20, Sort: "-score", SortSafeList: []string{"-score"}}
+
+	matches, metaData, err := suite.models.Instruments.GetMatches(instrument, []string{"synthesizer"}, filters)
+	require.NoError(t, err)
+	require.Len(t, matches, len(expected), "number of matches mismatch")
+	assert.Equal(t, len(expected), metaData.TotalRecords, "total records mismatch")
+
+	for i, match := range matches {
+		assert.Equal(t, expected[i].instrument.ID, match.Instrument.ID, "instrument of match %d mismatch", i)
+		assert.Equal(t, expected[i].reputation, match.OwnerReputation, "owner reputation of match %d mismatch", i)
+		assert.InDelta(t, expected[i].score, match.Score, 0.0001, "score of match %d mismatch", i)
+	}
+}
+
+// TestSwapCycleCandidates tests collecting the candidate legs of the swap cycles,
+// the participant sets of the recent swap cycles and the expiry of the proposed swap cycles.
+func (suite *ModelsTestSuite) TestSwapCycleCandidates() {
+	t := suite.T()
+
+	alice := suite.insertUser("alice")
+	bob := suite.insertUser("bob")
+	carol := suite.insertUser("carol")
+	dave := suite.insertUser("dave")
+
+	aliceInstrument := suite.insertInstrument(alice.ID, &Instrument{Name: "Juno"})
+	bobInstrument := suite.insertInstrument(bob.ID, &Instrument{Name: "Jupiter"})
+	carolInstrument := suite.insertInstrument(carol.ID, &Instrument{Name: "MS-20", Manufacturer: "Korg"})
+	suite.insertInstrument(dave.ID, &Instrument{Name: "Telecaster", Manufacturer: "Fender", Type: "electric-guitar"})
+
+	// the wants for a category are satisfied by the instruments of its subtypes
+	aliceWant := &Want{UserID: alice.ID, InstrumentType: "keyboard"}
+	bobWant := &Want{UserID: bob.ID, Manufacturer: "korg"}
+	carolWant := &Want{UserID: carol.ID, InstrumentID: &aliceInstrument.ID}
+	// nobody wants the instrument of dave, so dave can not be part of a cycle of alice
+	daveWant := &Want{UserID: dave.ID, InstrumentType: "synthesizer"}
+	for _, want := range []*Want{aliceWant, bobWant, carolWant, daveWant} {
+		require.NoError(t, suite.models.Wants.Insert(want))
+	}
+
+	expected := []*SwapCycleLeg{
+		{ReceiverUserID: alice.ID, GiverUserID: bob.ID, InstrumentID: bobInstrument.ID, WantID: &aliceWant.ID},
+		{ReceiverUserID: alice.ID, GiverUserID: carol.ID, InstrumentID: carolInstrument.ID, WantID: &aliceWant.ID},
+		{ReceiverUserID: bob.ID, GiverUserID: carol.ID, InstrumentID: carolInstrument.ID, WantID: &bobWant.ID},
+		{ReceiverUserID: carol.ID, GiverUserID: alice.ID, InstrumentID: aliceInstrument.ID, WantID: &carolWant.ID},
+	}
+
+	candidates, err := suite.models.SwapCycles.GetCandidateLegs(alice.ID)
+	require.NoError(t, err)
+	assert.Equal(t, expected, candidates, "candidate legs mismatch")
+
+	legs := FindSwapCycle(alice.ID, candidates, nil)
+	require.Len(t, legs, 3, "number of legs mismatch")
+
+	cycle := &SwapCycle{Status: SwapCycleStatusProposed, Legs: legs}
+	require.NoError(t, suite.models.SwapCycles.Create(cycle))
+
+	// the instruments of the proposed swap cycle are not available any more
+	candidates, err = suite.models.SwapCycles.GetCandidateLegs(alice.ID)
+	require.NoError(t, err)
+	assert.Empty(t, candidates, "candidate legs of unavailable instruments")
+
+	participants := []int64{alice.ID, bob.ID, carol.ID}
+	sets, err := suite.models.SwapCycles.GetRecentParticipantSets(alice.ID, time.Now().Add(time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, [][]int64{participants}, sets, "participant sets of the proposed cycle mismatch")
+
+	count, err := suite.models.SwapCycles.ExpireProposed(time.Now().Add(-time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), count, "number of expired cycles created after the given time mismatch")
+
+	count, err = suite.models.SwapCycles.ExpireProposed(time.Now().Add(time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), count, "number of expired cycles mismatch")
+
+	expired, err := suite.models.SwapCycles.Get(cycle.ID)
+	require.NoError(t, err)
+	assert.Equal(t, SwapCycleStatusExpired, expired.Status, "status of the expired cycle mismatch")
+	assert.NotNil(t, expired.ExpiredAt, "expired at of the expired cycle")
+
+	// an expired swap cycle counts only within the cooldown
+	sets, err = suite.models.SwapCycles.GetRecentParticipantSets(alice.ID, time.Now().Add(-time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, [][]int64{participants}, sets, "participant sets of the recently expired cycle mismatch")
+
+	sets, err = suite.models.SwapCycles.GetRecentParticipantSets(alice.ID, time.Now().Add(time.Hour))
+	require.NoError(t, err)
+	assert.Empty(t, sets, "participant sets of the cycle expired before the cooldown")
+
+	// the instruments of the expired swap cycle are available again
+	candidates, err = suite.models.SwapCycles.GetCandidateLegs(alice.ID)
+	require.NoError(t, err)
+	assert.Equal(t, expected, candidates, "candidate legs after the expiry mismatch")
+}
+
+// TestSavedSearchMatching tests selecting the saved searches of the other users matching an instrument.
+func (suite *ModelsTestSuite) TestSavedSearchMatching() {
+	t := suite.T()
+
+	owner := suite.insertUser("owner")
+	searcher := suite.insertUser("searcher")
+
+	instrument := suite.insertInstrument(owner.ID, &Instrument{
+		Name:         "M1 Ex",
+		Manufacturer: "Korg",
+		FamousOwners: []string{"The Orb", "Vangelis"},
+	})
+
+	searches := []struct {
+		search   *SavedSearch
+		matching bool
+	}{
+		{search: &SavedSearch{UserID: searcher.ID, Name: "m1"}, matching: true},
+		{search: &SavedSearch{UserID: searcher.ID, Name: "ex M1"}, matching: true},
+		{search: &SavedSearch{UserID: searcher.ID, Name: "M1 Plus"}, matching: false},
+		{search: &SavedSearch{UserID: searcher.ID, Manufacturer: "KORG"}, matching: true},
+		{search: &SavedSearch{UserID: searcher.ID, Manufacturer: "Roland"}, matching: false},
+		{search: &SavedSearch{UserID: searcher.ID, Type: "Synthesizer"}, matching: true},
+		{search: &SavedSearch{UserID: searcher.ID, Type: "keyboard"}, matching: true},
+		{search: &SavedSearch{UserID: searcher.ID, Type: "guitar"}, matching: false},
+		{search: &SavedSearch{UserID: searcher.ID, FamousOwners: []string{"Vangelis"}}, matching: true},
+		{search: &SavedSearch{UserID: searcher.ID, FamousOwners: []string{"The Orb", "Jean-Michel Jarre"}}, matching: false},
+		{search: &SavedSearch{UserID: searcher.ID, Name: "M1", Manufacturer: "Korg", Type: "synthesizer", FamousOwners: []string{"The Orb"}}, matching: true},
+		{search: &SavedSearch{UserID: searcher.ID, Name: "M1", Manufacturer: "Roland"}, matching: false},
+		{search: &SavedSearch{UserID: owner.ID, Type: "synthesizer"}, matching: false},
+	}
+
+	expected := []int64{}
+	for _, s := range searches {
+		require.NoError(t, suite.models.SavedSearches.Insert(s.search))
+		if s.matching {
+			expected = append(expected, s.search.ID)
+		}
+	}
+
+	instrumentTypes, err := suite.models.Categories.GetLineageNames(instrument.Type)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"keyboard", "synthesizer"}, instrumentTypes, "instrument types mismatch")
+
+	matching, err := suite.models.SavedSearches.GetAllMatching(instrument, instrumentTypes)
+	require.NoError(t, err)
+
+	ids := []int64{}
+	for _, search := range matching {
+		ids = append(ids, search.ID)
+	}
+	assert.Equal(t, expected, ids, "matching saved searches mismatch")
+}
+
+// TestCategoryRename tests that renaming a category renames the instrument types, the wants and the saved searches,
+// and records the replaced states of the renamed instruments as revisions.
+func (suite *ModelsTestSuite) TestCategoryRename() {
+	t := suite.T()
+
+	admin := suite.insertUser("admin")
+	owner := suite.insertUser("owner")
+
+	synthesizer := suite.insertInstrument(owner.ID, &Instrument{Name: "Juno"})
+	guitar := suite.insertInstrument(owner.ID, &Instrument{Name: "Telecaster", Manufacturer: "Fender", Type: "electric-guitar"})
+
+	// a want stored before the instrument types were normalised to lower case
+	want := &Want{UserID: admin.ID, InstrumentType: "Synthesizer"}
+	require.NoError(t, suite.models.Wants.Insert(want))
+	search := &SavedSearch{UserID: admin.ID, Type: "synthesizer"}
+	require.NoError(t, suite.models.SavedSearches.Insert(search))
+
+	category, err := suite.models.Categories.GetByName("synthesizer")
+	require.NoError(t, err)
+	category.Name = "synth"
+	require.NoError(t, suite.models.Categories.Update(category, admin.ID))
+
+	renamed, err := suite.models.Instruments.Get(synthesizer.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "synth", renamed.Type, "type of the renamed instrument mismatch")
+	assert.Equal(t, synthesizer.Version+1, renamed.Version, "version of the renamed instrument mismatch")
+
+	revision, err := suite.models.InstrumentRevisions.Get(synthesizer.ID, synthesizer.Version)
+	require.NoError(t, err)
+	assert.Equal(t, "synthesizer", revision.Instrument.Type, "type of the revision mismatch")
+	assert.Equal(t, &admin.ID, revision.ChangedByUserID, "changer of the revision mismatch")
+
+	other, err := suite.models.Instruments.Get(guitar.ID)
+	require.NoError(t, err)
+	assert.Equal(t, guitar.Version, other.Version, "version of the instrument of another type mismatch")
+
+	wants, err := suite.models.Wants.GetAllForUser(admin.ID)
+	require.NoError(t, err)
+	require.Len(t, wants, 1, "number of wants mismatch")
+	assert.Equal(t, "synth", wants[0].InstrumentType, "type of the want mismatch")
+
+	searches, err := suite.models.SavedSearches.GetAllForUser(admin.ID)
+	require.NoError(t, err)
+	require.Len(t, searches, 1, "number of saved searches mismatch")
+	assert.Equal(t, "synth", searches[0].Type, "type of the saved search mismatch")
+}
+
+// TestInstrumentDeleteSwapped tests that the instruments in active swaps and swap cycles can not be deleted.
+func (suite *ModelsTestSuite) TestInstrumentDeleteSwapped() {
+	t := suite.T()
+
+	owner := suite.insertUser("owner")
+	other := suite.insertUser("other")
+	third := suite.insertUser("third")
+
+	pending := suite.insertInstrument(owner.ID, &Instrument{Name: "Juno"})
+	ended := suite.insertInstrument(owner.ID, &Instrument{Name: "Jupiter"})
+	inCycle := suite.insertInstrument(owner.ID, &Instrument{Name: "Polysix"})
+	free := suite.insertInstrument(owner.ID, &Instrument{Name: "Prophet"})
+
+	otherPending := suite.insertInstrument(other.ID, &Instrument{Name: "M1"})
+	otherEnded := suite.insertInstrument(other.ID, &Instrument{Name: "MS-20"})
+	otherInCycle := suite.insertInstrument(other.ID, &Instrument{Name: "OB-X"})
+	thirdInCycle := suite.insertInstrument(third.ID, &Instrument{Name: "CS-80"})
+
+	pendingSwap := NewSwap([]int64{pending.ID}, []int64{otherPending.ID})
+	require.NoError(t, suite.models.Swaps.Create(pendingSwap, owner.ID, ""))
+
+	endedSwap := NewSwap([]int64{ended.ID}, []int64{otherEnded.ID})
+	require.NoError(t, suite.models.Swaps.Create(endedSwap, owner.ID, ""))
+	_, err := suite.db.ExecContext(suite.ctx, "UPDATE swaps SET is_rejected = TRUE, is_ended = TRUE WHERE id = $1", endedSwap.ID)
+	require.NoError(t, err)
+
+	cycle := &SwapCycle{
+		Status: SwapCycleStatusProposed,
+		Legs: []*SwapCycleLeg{
+			{Position: 1, GiverUserID: other.ID, ReceiverUserID: owner.ID, InstrumentID: otherInCycle.ID},
+			{Position: 2, GiverUserID: third.ID, ReceiverUserID: other.ID, InstrumentID: thirdInCycle.ID},
+			{Position: 3, GiverUserID: owner.ID, ReceiverUserID: third.ID, InstrumentID: inCycle.ID},
+		},
+	}
+	require.NoError(t, suite.models.SwapCycles.Create(cycle))
+
+	expected := []struct {
+		instrument *Instrument
+		err        error
+	}{
+		{instrument: pending, err: ErrConflict},
+		{instrument: inCycle, err: ErrConflict},
+		{instrument: ended},
+		{instrument: free},
+	}
+
+	for _, e := range expected {
+		err := suite.models.Instruments.Delete(e.instrument.ID, e.instrument.Version)
+		if e.err != nil {
+			assert.ErrorIs(t, err, e.err, "deleting instrument %q", e.instrument.Name)
+		} else {
+			assert.NoError(t, err, "deleting instrument %q", e.instrument.Name)
+		}
+	}
+
+	// a deleted instrument can not be deleted again
+	err = suite.models.Instruments.Delete(free.ID, free.Version)
+	assert.ErrorIs(t, err, ErrEditConflict, "deleting a deleted instrument")
+}
+
+// TestModelsTestSuite runs the ModelsTestSuite related tests.
+func TestModelsTestSuite(t *testing.T) {
+	suite.Run(t, new(ModelsTestSuite))
+}
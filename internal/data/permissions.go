@@ -0,0 +1,184 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"slices"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/ttarnok/instrument-swap-api/internal/validator"
+)
+
+// Permission codes.
+const (
+	PermissionUsersRead        = "users:read"
+	PermissionUsersModerate    = "users:moderate"
+	PermissionDisputesModerate = "disputes:moderate"
+	PermissionCategoriesWrite  = "categories:write"
+)
+
+// Role codes.
+const (
+	RoleAdmin = "admin"
+)
+
+// Role related errors.
+// These errors can be tested using errors.Is.
+var (
+	ErrUnknownRole = errors.New("unknown role") // "unknown role"
+)
+
+// Permissions holds the permission codes of a user.
+type Permissions []string
+
+// Include returns true if the given permission code is included in the permissions.
+func (p Permissions) Include(code string) bool {
+	return slices.Contains(p, code)
+}
+
+// ValidateRoles validates the given role codes.
+func ValidateRoles(v *validator.Validator, roles []string) {
+	v.Check(roles != nil, "roles", "must be provided")
+	v.Check(validator.Unique(roles), "roles", "must be unique")
+	for _, role := range roles {
+		v.Check(role != "", "roles", "must not contain empty values")
+	}
+}
+
+// PermissionModel represents the permission model, that stores roles and permissions in a database.
+type PermissionModel struct {
+	DB *sql.DB
+}
+
+// GetAllForUser retrieves all permission codes the given user has through its roles.
+func (m *PermissionModel) GetAllForUser(userID int64) (permissions Permissions, err error) {
+	query := `
+		SELECT DISTINCT permissions.code
+			FROM permissions
+			INNER JOIN roles_permissions ON roles_permissions.permission_id = permissions.id
+			INNER JOIN users_roles ON users_roles.role_id = roles_permissions.role_id
+		WHERE users_roles.user_id = $1
+		ORDER BY permissions.code`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		errClose := rows.Close()
+		if err == nil {
+			err = errClose
+		}
+	}()
+
+	permissions = Permissions{}
+
+	for rows.Next() {
+		var permission string
+
+		err := rows.Scan(&permission)
+		if err != nil {
+			return nil, err
+		}
+
+		permissions = append(permissions, permission)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return permissions, nil
+}
+
+// GetRolesForUser retrieves the role codes of the given user.
+func (m *PermissionModel) GetRolesForUser(userID int64) (roles []string, err error) {
+	query := `
+		SELECT roles.code
+			FROM roles
+			INNER JOIN users_roles ON users_roles.role_id = roles.id
+		WHERE users_roles.user_id = $1
+		ORDER BY roles.code`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		errClose := rows.Close()
+		if err == nil {
+			err = errClose
+		}
+	}()
+
+	roles = []string{}
+
+	for rows.Next() {
+		var role string
+
+		err := rows.Scan(&role)
+		if err != nil {
+			return nil, err
+		}
+
+		roles = append(roles, role)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return roles, nil
+}
+
+// SetRolesForUser replaces the roles of the given user with the given role codes.
+// Returns ErrUnknownRole if any of the given role codes does not exist, in this case the roles remain unchanged.
+func (m *PermissionModel) SetRolesForUser(userID int64, roles []string) (err error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			err = errors.Join(err, tx.Rollback())
+		}
+	}()
+
+	_, err = tx.ExecContext(ctx, `DELETE FROM users_roles WHERE user_id = $1`, userID)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		INSERT INTO users_roles (user_id, role_id)
+			SELECT $1, roles.id
+				FROM roles
+			WHERE roles.code = ANY($2)`
+
+	result, err := tx.ExecContext(ctx, query, userID, pq.Array(roles))
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected != int64(len(roles)) {
+		return ErrUnknownRole
+	}
+
+	return tx.Commit()
+}
@@ -0,0 +1,154 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/ttarnok/instrument-swap-api/internal/validator"
+)
+
+// Review related errors.
+// These errors can be tested using errors.Is.
+var (
+	ErrDuplicateReview   = errors.New("swap already reviewed")  // "swap already reviewed"
+	ErrSwapNotReviewable = errors.New("swap is not reviewable") // "swap is not reviewable"
+)
+
+// Review represents a rating and review left by one party of an ended swap for the other party.
+type Review struct {
+	ID             int64     `json:"id"`
+	SwapID         int64     `json:"swap_id"`
+	ReviewerUserID int64     `json:"reviewer_user_id"`
+	RevieweeUserID int64     `json:"reviewee_user_id"`
+	CreatedAt      time.Time `json:"created_at"`
+	Rating         int       `json:"rating"`
+	Comment        string    `json:"comment"`
+}
+
+// Reputation represents the aggregated ratings a user received.
+type Reputation struct {
+	Average float64 `json:"average"`
+	Count   int     `json:"count"`
+}
+
+// ValidateReview checks the validity of a review,
+// adds all found validation errors into the validator.
+func ValidateReview(v *validator.Validator, review *Review) {
+	v.Check(review.Rating >= 1 && review.Rating <= 5, "rating", "must be between 1 and 5")
+	v.Check(len(review.Comment) <= 2000, "comment", "must not be more than 2000 bytes long")
+}
+
+// IsSwapReviewable checks whether the parties of the swap can review each other.
+// Only the swaps that were accepted and ended can be reviewed.
+func IsSwapReviewable(swap *Swap) bool {
+	return swap.IsAccepted && swap.IsEnded
+}
+
+// ReviewModel represents the review model, that stores the reviews of the users in a database.
+type ReviewModel struct {
+	DB *sql.DB
+}
+
+// Insert stores the given review.
+// Returns ErrDuplicateReview if the reviewer has already reviewed the swap.
+func (m *ReviewModel) Insert(review *Review) error {
+	query := `
+		INSERT INTO reviews (swap_id, reviewer_user_id, reviewee_user_id, rating, comment)
+			VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, created_at`
+
+	args := []any{review.SwapID, review.ReviewerUserID, review.RevieweeUserID, review.Rating, review.Comment}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, args...).Scan(&review.ID, &review.CreatedAt)
+	if err != nil {
+		switch {
+		case err.Error() == `pq: duplicate key value violates unique constraint "reviews_swap_id_reviewer_user_id_key"`:
+			return ErrDuplicateReview
+		default:
+			return err
+		}
+	}
+
+	return nil
+}
+
+// GetAllForUser retrieves the reviews the given user received, paginated and sorted based on the given filters.
+func (m *ReviewModel) GetAllForUser(revieweeUserID int64, filters Filters) (reviews []*Review, metaData MetaData, err error) {
+
+	//nolint:gosec
+	query := fmt.Sprintf(`
+		SELECT count(*) over(), id, swap_id, reviewer_user_id, reviewee_user_id, created_at, rating, comment
+		FROM reviews
+		WHERE reviewee_user_id = $1
+		ORDER BY %s %s, id ASC
+		LIMIT $2 OFFSET $3`, filters.sortColumn(), filters.sortDirection())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, revieweeUserID, filters.limit(), filters.offset())
+	if err != nil {
+		return nil, MetaData{}, err
+	}
+	defer func() {
+		errClose := rows.Close()
+		if err == nil {
+			err = errClose
+		}
+	}()
+
+	totalRecords := 0
+	reviews = []*Review{}
+
+	for rows.Next() {
+		var review Review
+
+		err := rows.Scan(
+			&totalRecords,
+			&review.ID,
+			&review.SwapID,
+			&review.ReviewerUserID,
+			&review.RevieweeUserID,
+			&review.CreatedAt,
+			&review.Rating,
+			&review.Comment,
+		)
+		if err != nil {
+			return nil, MetaData{}, err
+		}
+
+		reviews = append(reviews, &review)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, MetaData{}, err
+	}
+
+	return reviews, calculateMetadata(totalRecords, filters.Page, filters.PageSize), nil
+}
+
+// GetReputation retrieves the aggregated reputation of the given user.
+func (m *ReviewModel) GetReputation(revieweeUserID int64) (Reputation, error) {
+	query := `
+		SELECT ROUND(COALESCE(avg(rating), 0), 2), count(*)
+		FROM reviews
+		WHERE reviewee_user_id = $1`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	var reputation Reputation
+
+	err := m.DB.QueryRowContext(ctx, query, revieweeUserID).Scan(&reputation.Average, &reputation.Count)
+	if err != nil {
+		return Reputation{}, err
+	}
+
+	return reputation, nil
+}
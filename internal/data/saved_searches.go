@@ -0,0 +1,174 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/ttarnok/instrument-swap-api/internal/validator"
+)
+
+// SavedSearch represents a search for instruments saved by a user, with the same filters as the instrument list.
+// The user is notified when a newly created or updated instrument matches the search.
+type SavedSearch struct {
+	ID           int64     `json:"id"`
+	UserID       int64     `json:"user_id"`
+	CreatedAt    time.Time `json:"created_at"`
+	Name         string    `json:"name"`
+	Manufacturer string    `json:"manufacturer"`
+	Type         string    `json:"type"`
+	FamousOwners []string  `json:"famous_owners"`
+}
+
+// ValidateSavedSearch checks the validity of a saved search,
+// adds all found validation errors into the validator.
+func ValidateSavedSearch(v *validator.Validator, search *SavedSearch) {
+	v.Check(search.Name != "" || search.Manufacturer != "" || search.Type != "" || len(search.FamousOwners) > 0, "search", "must provide a name, a manufacturer, a type or famous_owners")
+
+	v.Check(len(search.Name) <= 500, "name", "must not be more than 500 bytes long")
+	v.Check(len(search.Manufacturer) <= 500, "manufacturer", "must not be more than 500 bytes long")
+	v.Check(len(search.Type) <= 100, "type", "must not be more than 100 bytes long")
+
+	v.Check(validator.Unique(search.FamousOwners), "famous_owners", "must be unique")
+}
+
+// savedSearchColumns lists the columns of the saved_searches table in the order expected by scanSavedSearch.
+const savedSearchColumns = `id, user_id, created_at, name, manufacturer, type, famous_owners`
+
+// scanSavedSearch scans a row selected with savedSearchColumns into the given saved search.
+func scanSavedSearch(row rowScanner, search *SavedSearch) error {
+	return row.Scan(
+		&search.ID,
+		&search.UserID,
+		&search.CreatedAt,
+		&search.Name,
+		&search.Manufacturer,
+		&search.Type,
+		pq.Array(&search.FamousOwners),
+	)
+}
+
+// SavedSearchModel represents the saved search model, that stores the saved searches of the users in a database.
+type SavedSearchModel struct {
+	DB *sql.DB
+}
+
+// Insert stores the given saved search.
+func (m *SavedSearchModel) Insert(search *SavedSearch) error {
+	query := `
+		INSERT INTO saved_searches (user_id, name, manufacturer, type, famous_owners)
+			VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, created_at`
+
+	if search.FamousOwners == nil {
+		search.FamousOwners = []string{}
+	}
+
+	args := []any{search.UserID, search.Name, search.Manufacturer, search.Type, pq.Array(search.FamousOwners)}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	return m.DB.QueryRowContext(ctx, query, args...).Scan(&search.ID, &search.CreatedAt)
+}
+
+// GetAllForUser retrieves the saved searches of the given user, ordered by their ids.
+func (m *SavedSearchModel) GetAllForUser(userID int64) ([]*SavedSearch, error) {
+	query := `
+		SELECT ` + savedSearchColumns + `
+		FROM saved_searches
+		WHERE user_id = $1
+		ORDER BY id`
+
+	return m.getAll(query, userID)
+}
+
+// GetAllMatching retrieves the saved searches of the users other than the owner of the given instrument,
+// that match the given instrument, ordered by their users and ids.
+// The instrument types are the type of the instrument and its ancestor categories, so a search for a category
+// matches the instruments of its subtypes too. The name is matched word by word, the manufacturer and the type
+// case insensitively, and every famous owner of the search has to be a famous owner of the instrument.
+func (m *SavedSearchModel) GetAllMatching(instrument *Instrument, instrumentTypes []string) ([]*SavedSearch, error) {
+	query := `
+		SELECT ` + savedSearchColumns + `
+		FROM saved_searches
+		WHERE user_id <> $1
+		  AND (to_tsvector('simple', $2) @@ plainto_tsquery('simple', name) OR name = '')
+		  AND (lower(manufacturer) = lower($3) OR manufacturer = '')
+		  AND (lower(type) = ANY($4) OR type = '')
+		  AND COALESCE($5::text[], '{}') @> famous_owners
+		ORDER BY user_id, id`
+
+	args := []any{instrument.OwnerUserID, instrument.Name, instrument.Manufacturer, pq.Array(instrumentTypes), pq.Array(instrument.FamousOwners)}
+
+	return m.getAll(query, args...)
+}
+
+// getAll retrieves the saved searches selected by the given query.
+func (m *SavedSearchModel) getAll(query string, args ...any) (searches []*SavedSearch, err error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		errClose := rows.Close()
+		if err == nil {
+			err = errClose
+		}
+	}()
+
+	searches = []*SavedSearch{}
+
+	for rows.Next() {
+		var search SavedSearch
+
+		err := scanSavedSearch(rows, &search)
+		if err != nil {
+			return nil, err
+		}
+
+		searches = append(searches, &search)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return searches, nil
+}
+
+// Delete removes the saved search with the given id of the given user.
+// Returns ErrRecordNotFound if the user has no saved search with the given id.
+func (m *SavedSearchModel) Delete(id int64, userID int64) error {
+	if id < 1 {
+		return ErrRecordNotFound
+	}
+
+	query := `
+		DELETE FROM saved_searches
+		WHERE id = $1
+		  AND user_id = $2`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, query, id, userID)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+
+	return nil
+}
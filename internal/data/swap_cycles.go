@@ -0,0 +1,703 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// The number of participants of a swap cycle.
+// Two participants can swap directly, so the cycles start at three participants.
+const (
+	SwapCycleMinParticipants = 3
+	SwapCycleMaxParticipants = 6
+)
+
+// Swap cycle statuses.
+const (
+	SwapCycleStatusProposed = "proposed"
+	SwapCycleStatusAccepted = "accepted"
+	SwapCycleStatusActive   = "active"
+	SwapCycleStatusRejected = "rejected"
+	SwapCycleStatusExpired  = "expired"
+	SwapCycleStatusEnded    = "ended"
+)
+
+// SwapCycleStatuses lists the statuses a swap cycle can be in.
+// A swap cycle is never in the accepted status, it is only used to record the acceptance of a participant.
+var SwapCycleStatuses = []string{
+	SwapCycleStatusProposed,
+	SwapCycleStatusActive,
+	SwapCycleStatusRejected,
+	SwapCycleStatusExpired,
+	SwapCycleStatusEnded,
+}
+
+// Swap cycle related errors.
+// These errors can be tested using errors.Is.
+var (
+	ErrNoSwapCycle                      = errors.New("no swap cycle found")                                                                  // "no swap cycle found"
+	ErrInvalidSwapCycleStatusTransition = errors.New("invalid swap cycle status transition")                                                 // "invalid swap cycle status transition"
+	ErrSwapCycleNotAcceptable           = fmt.Errorf("%w: swap cycle is not acceptable", ErrInvalidSwapCycleStatusTransition)                // "invalid swap cycle status transition: swap cycle is not acceptable"
+	ErrSwapCycleAlreadyAccepted         = fmt.Errorf("%w: swap cycle is already accepted", ErrInvalidSwapCycleStatusTransition)              // "invalid swap cycle status transition: swap cycle is already accepted"
+	ErrSwapCycleNotRejectable           = fmt.Errorf("%w: swap cycle is not rejectable", ErrInvalidSwapCycleStatusTransition)                // "invalid swap cycle status transition: swap cycle is not rejectable"
+	ErrSwapCycleNotEndable              = fmt.Errorf("%w: swap cycle is not endable", ErrInvalidSwapCycleStatusTransition)                   // "invalid swap cycle status transition: swap cycle is not endable"
+	ErrNotSwapCycleParticipant          = fmt.Errorf("%w: user is not a participant of the swap cycle", ErrInvalidSwapCycleStatusTransition) // "invalid swap cycle status transition: user is not a participant of the swap cycle"
+)
+
+// SwapCycle represents a multi-party swap, in which every participant gives an instrument to the next participant.
+// A proposed swap cycle becomes active when every participant accepted it, or expires if it is not accepted in time.
+type SwapCycle struct {
+	ID          int64           `json:"id"`
+	CreatedAt   time.Time       `json:"created_at"`
+	Status      string          `json:"status"`
+	ActivatedAt *time.Time      `json:"activated_at"`
+	RejectedAt  *time.Time      `json:"rejected_at"`
+	ExpiredAt   *time.Time      `json:"expired_at"`
+	EndedAt     *time.Time      `json:"ended_at"`
+	Legs        []*SwapCycleLeg `json:"legs"`
+	Version     int32           `json:"version"`
+}
+
+// SwapCycleLeg represents a step of a swap cycle, in which the giver gives an instrument satisfying a want of the receiver.
+// The giver of the leg accepts it on behalf of the participant.
+type SwapCycleLeg struct {
+	Position       int        `json:"position"`
+	GiverUserID    int64      `json:"giver_user_id"`
+	ReceiverUserID int64      `json:"receiver_user_id"`
+	InstrumentID   int64      `json:"instrument_id"`
+	WantID         *int64     `json:"want_id"`
+	IsAccepted     bool       `json:"is_accepted"`
+	AcceptedAt     *time.Time `json:"accepted_at"`
+}
+
+// HasParticipant checks whether the given user is a participant of the swap cycle.
+func (c *SwapCycle) HasParticipant(userID int64) bool {
+	return c.legOf(userID) != nil
+}
+
+// InstrumentIDs returns the ids of the instruments of the swap cycle.
+func (c *SwapCycle) InstrumentIDs() []int64 {
+	ids := make([]int64, 0, len(c.Legs))
+	for _, leg := range c.Legs {
+		ids = append(ids, leg.InstrumentID)
+	}
+	return ids
+}
+
+// ParticipantUserIDs returns the sorted ids of the participants of the swap cycle.
+func (c *SwapCycle) ParticipantUserIDs() []int64 {
+	ids := make([]int64, 0, len(c.Legs))
+	for _, leg := range c.Legs {
+		ids = append(ids, leg.GiverUserID)
+	}
+	slices.Sort(ids)
+	return ids
+}
+
+// legOf returns the leg given by the given user, or nil if the user is not a participant of the swap cycle.
+func (c *SwapCycle) legOf(userID int64) *SwapCycleLeg {
+	for _, leg := range c.Legs {
+		if leg.GiverUserID == userID {
+			return leg
+		}
+	}
+	return nil
+}
+
+// FindSwapCycle looks for the shortest swap cycle, in which the given user receives an instrument satisfying one of their wants,
+// and every other participant receives an instrument satisfying one of their wants in exchange for the one they give.
+// The candidate legs pair the wants of the receivers with the instruments of the givers satisfying them, they are tried in the given order.
+// The cycles of the excluded participant sets are skipped, the sets are expected to be sorted.
+// Returns the legs of the found cycle in order, or nil if there is no cycle with at most SwapCycleMaxParticipants participants.
+func FindSwapCycle(userID int64, candidates []*SwapCycleLeg, excludedParticipants [][]int64) []*SwapCycleLeg {
+	candidatesByReceiver := make(map[int64][]*SwapCycleLeg)
+	for _, candidate := range candidates {
+		candidatesByReceiver[candidate.ReceiverUserID] = append(candidatesByReceiver[candidate.ReceiverUserID], candidate)
+	}
+
+	search := swapCycleSearch{
+		userID:               userID,
+		candidatesByReceiver: candidatesByReceiver,
+		excludedParticipants: excludedParticipants,
+		visited:              map[int64]bool{userID: true},
+	}
+
+	for participants := SwapCycleMinParticipants; participants <= SwapCycleMaxParticipants; participants++ {
+		legs := search.extend(nil, participants)
+		if legs != nil {
+			return legs
+		}
+	}
+
+	return nil
+}
+
+// swapCycleSearch holds the state of a depth first search for a swap cycle of the given user.
+// The visited set contains the participants of the path currently being extended.
+type swapCycleSearch struct {
+	userID               int64
+	candidatesByReceiver map[int64][]*SwapCycleLeg
+	excludedParticipants [][]int64
+	visited              map[int64]bool
+}
+
+// extend extends the given path of legs depth first, until the cycle is closed with the given number of participants.
+// Returns the legs of the closed cycle, or nil if the path can not be closed.
+func (s *swapCycleSearch) extend(legs []*SwapCycleLeg, participants int) []*SwapCycleLeg {
+	receiverUserID := s.userID
+	if len(legs) > 0 {
+		receiverUserID = legs[len(legs)-1].GiverUserID
+	}
+
+	for _, candidate := range s.candidatesByReceiver[receiverUserID] {
+		leg := *candidate
+		leg.Position = len(legs) + 1
+		path := append(slices.Clone(legs), &leg)
+
+		if len(path) == participants {
+			if leg.GiverUserID == s.userID && !s.isExcluded(path) {
+				return path
+			}
+			continue
+		}
+
+		if s.visited[leg.GiverUserID] {
+			continue
+		}
+
+		s.visited[leg.GiverUserID] = true
+		found := s.extend(path, participants)
+		delete(s.visited, leg.GiverUserID)
+
+		if found != nil {
+			return found
+		}
+	}
+
+	return nil
+}
+
+// isExcluded checks whether the participants of the given legs form an excluded participant set.
+func (s *swapCycleSearch) isExcluded(legs []*SwapCycleLeg) bool {
+	participants := (&SwapCycle{Legs: legs}).ParticipantUserIDs()
+	return slices.ContainsFunc(s.excludedParticipants, func(excluded []int64) bool {
+		return slices.Equal(excluded, participants)
+	})
+}
+
+// TransitionSwapCycle validates the requested status transition of the given user and applies it on the given swap cycle at the given time.
+// Accepting records the acceptance of the user, the swap cycle becomes active when every participant accepted it.
+// A proposed swap cycle can be rejected and an active swap cycle can be ended by any of its participants.
+// A swap cycle can not be expired by its participants.
+// Returns an error wrapping ErrInvalidSwapCycleStatusTransition if the transition is not possible.
+// Due to pointer semantics, the function mutates the given swap cycle.
+func TransitionSwapCycle(cycle *SwapCycle, status string, userID int64, at time.Time) error {
+	leg := cycle.legOf(userID)
+	if leg == nil {
+		return ErrNotSwapCycleParticipant
+	}
+
+	switch status {
+	case SwapCycleStatusAccepted:
+		if cycle.Status != SwapCycleStatusProposed {
+			return ErrSwapCycleNotAcceptable
+		}
+		if leg.IsAccepted {
+			return ErrSwapCycleAlreadyAccepted
+		}
+		leg.IsAccepted = true
+		leg.AcceptedAt = &at
+
+		if !slices.ContainsFunc(cycle.Legs, func(leg *SwapCycleLeg) bool { return !leg.IsAccepted }) {
+			cycle.Status = SwapCycleStatusActive
+			cycle.ActivatedAt = &at
+		}
+	case SwapCycleStatusRejected:
+		if cycle.Status != SwapCycleStatusProposed {
+			return ErrSwapCycleNotRejectable
+		}
+		cycle.Status = SwapCycleStatusRejected
+		cycle.RejectedAt = &at
+	case SwapCycleStatusEnded:
+		if cycle.Status != SwapCycleStatusActive {
+			return ErrSwapCycleNotEndable
+		}
+		cycle.Status = SwapCycleStatusEnded
+		cycle.EndedAt = &at
+	default:
+		return fmt.Errorf("%w: unknown status %q", ErrInvalidSwapCycleStatusTransition, status)
+	}
+	return nil
+}
+
+// swapCycleColumns lists the columns of the swap_cycles table in the order expected by scanSwapCycle.
+const swapCycleColumns = `id, created_at, status, activated_at, rejected_at, expired_at, ended_at, version`
+
+// scanSwapCycle scans a row selected with swapCycleColumns into the given swap cycle.
+// The leading destinations are scanned from the columns preceding swapCycleColumns.
+func scanSwapCycle(row rowScanner, cycle *SwapCycle, leading ...any) error {
+	return row.Scan(append(leading,
+		&cycle.ID,
+		&cycle.CreatedAt,
+		&cycle.Status,
+		&cycle.ActivatedAt,
+		&cycle.RejectedAt,
+		&cycle.ExpiredAt,
+		&cycle.EndedAt,
+		&cycle.Version,
+	)...)
+}
+
+// loadSwapCycleLegs loads the legs of the given swap cycles in order.
+func loadSwapCycleLegs(ctx context.Context, q queryer, cycles ...*SwapCycle) (err error) {
+	if len(cycles) == 0 {
+		return nil
+	}
+
+	ids := make([]int64, 0, len(cycles))
+	byID := make(map[int64]*SwapCycle, len(cycles))
+	for _, cycle := range cycles {
+		cycle.Legs = []*SwapCycleLeg{}
+		ids = append(ids, cycle.ID)
+		byID[cycle.ID] = cycle
+	}
+
+	query := `
+		SELECT swap_cycle_id, position, giver_user_id, receiver_user_id, instrument_id, want_id, is_accepted, accepted_at
+		FROM swap_cycle_legs
+		WHERE swap_cycle_id = ANY($1)
+		ORDER BY swap_cycle_id, position`
+
+	rows, err := q.QueryContext(ctx, query, pq.Array(ids))
+	if err != nil {
+		return err
+	}
+	defer func() {
+		errClose := rows.Close()
+		if err == nil {
+			err = errClose
+		}
+	}()
+
+	for rows.Next() {
+		var cycleID int64
+		var leg SwapCycleLeg
+
+		err := rows.Scan(&cycleID, &leg.Position, &leg.GiverUserID, &leg.ReceiverUserID, &leg.InstrumentID, &leg.WantID, &leg.IsAccepted, &leg.AcceptedAt)
+		if err != nil {
+			return err
+		}
+
+		byID[cycleID].Legs = append(byID[cycleID].Legs, &leg)
+	}
+
+	return rows.Err()
+}
+
+// SwapCycleModel represents the swap cycle model, that stores the multi-party swaps in a database.
+type SwapCycleModel struct {
+	DB *sql.DB
+}
+
+// Create stores the given proposed swap cycle together with its legs and their acceptances within a transaction.
+// The involved instruments are locked, so concurrent requests can not put the same instrument into two active swaps.
+// Returns ErrRecordNotFound if any of the instruments does not exist,
+// ErrInstrumentAlreadySwapped if an instrument is already in an active swap or swap cycle,
+// ErrInstrumentDisputed if an instrument is under an open dispute.
+func (m *SwapCycleModel) Create(cycle *SwapCycle) (err error) {
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			err = errors.Join(err, tx.Rollback())
+		}
+	}()
+
+	err = lockInstruments(ctx, tx, cycle.InstrumentIDs()...)
+	if err != nil {
+		return err
+	}
+
+	for _, id := range cycle.InstrumentIDs() {
+		swapped, err := isInstrumentSwapped(ctx, tx, id)
+		if err != nil {
+			return err
+		}
+		if swapped {
+			return ErrInstrumentAlreadySwapped
+		}
+
+		disputed, err := isInstrumentDisputed(ctx, tx, id)
+		if err != nil {
+			return err
+		}
+		if disputed {
+			return ErrInstrumentDisputed
+		}
+	}
+
+	query := `
+		INSERT INTO swap_cycles (status)
+			VALUES ($1)
+		RETURNING id, created_at, version`
+
+	err = tx.QueryRowContext(ctx, query, cycle.Status).Scan(&cycle.ID, &cycle.CreatedAt, &cycle.Version)
+	if err != nil {
+		return err
+	}
+
+	for _, leg := range cycle.Legs {
+		query := `
+			INSERT INTO swap_cycle_legs (swap_cycle_id, position, giver_user_id, receiver_user_id, instrument_id, want_id, is_accepted, accepted_at)
+				VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`
+
+		_, err = tx.ExecContext(ctx, query, cycle.ID, leg.Position, leg.GiverUserID, leg.ReceiverUserID, leg.InstrumentID, leg.WantID, leg.IsAccepted, leg.AcceptedAt)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// GetCandidateLegs retrieves the possible legs of the swap cycles of the given user.
+// A candidate leg pairs a want of the receiver with an available instrument of another user satisfying it,
+// a want for an instrument type is satisfied by the instruments of its subtypes too,
+// only the receivers reachable from the user within SwapCycleMaxParticipants legs are considered.
+// The legs are ordered by the ids of their wants and instruments, their positions are not set.
+func (m *SwapCycleModel) GetCandidateLegs(userID int64) (legs []*SwapCycleLeg, err error) {
+	query := `
+		WITH RECURSIVE lineages (type, parent_id, name) AS (
+				SELECT name, parent_id, name
+				FROM categories
+			UNION
+				SELECT l.type, c.parent_id, c.name
+				FROM lineages l
+				JOIN categories c ON c.id = l.parent_id
+			), candidates AS (
+				SELECT w.user_id receiver_user_id, i.owner_user_id giver_user_id, i.id instrument_id, w.id want_id
+				FROM wants w
+				JOIN instruments i ON i.owner_user_id <> w.user_id
+				  AND (w.instrument_id IS NULL OR w.instrument_id = i.id)
+				  AND (w.instrument_type = '' OR EXISTS (
+						SELECT 1
+						FROM lineages l
+						WHERE l.type = i.type
+						  AND l.name = lower(w.instrument_type)
+					))
+				  AND (w.manufacturer = '' OR lower(w.manufacturer) = lower(i.manufacturer))
+				WHERE ` + availableInstrumentCondition + `
+			), reachable (user_id, legs) AS (
+				SELECT $1::bigint, 0
+				UNION
+				SELECT c.giver_user_id, r.legs + 1
+				FROM reachable r
+				JOIN candidates c ON c.receiver_user_id = r.user_id
+				WHERE r.legs < $2
+			)
+		SELECT receiver_user_id, giver_user_id, instrument_id, want_id
+		FROM candidates
+		WHERE receiver_user_id IN (SELECT user_id FROM reachable)
+		ORDER BY want_id, instrument_id`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, userID, SwapCycleMaxParticipants-1)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		errClose := rows.Close()
+		if err == nil {
+			err = errClose
+		}
+	}()
+
+	legs = []*SwapCycleLeg{}
+
+	for rows.Next() {
+		var leg SwapCycleLeg
+
+		err := rows.Scan(&leg.ReceiverUserID, &leg.GiverUserID, &leg.InstrumentID, &leg.WantID)
+		if err != nil {
+			return nil, err
+		}
+
+		legs = append(legs, &leg)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return legs, nil
+}
+
+// GetRecentParticipantSets retrieves the sorted participant ids of the swap cycles of the given user,
+// that are proposed or active, or were rejected or expired after the given time.
+func (m *SwapCycleModel) GetRecentParticipantSets(userID int64, since time.Time) (sets [][]int64, err error) {
+	query := `
+		SELECT array_agg(l.giver_user_id ORDER BY l.giver_user_id)
+		FROM swap_cycles c
+		JOIN swap_cycle_legs l ON l.swap_cycle_id = c.id
+		WHERE c.id IN (
+				SELECT swap_cycle_id
+				FROM swap_cycle_legs
+				WHERE giver_user_id = $1
+			)
+		  AND (c.status IN ($2, $3)
+		    OR (c.status = $4 AND c.rejected_at > $6)
+		    OR (c.status = $5 AND c.expired_at > $6))
+		GROUP BY c.id`
+
+	args := []any{userID, SwapCycleStatusProposed, SwapCycleStatusActive, SwapCycleStatusRejected, SwapCycleStatusExpired, since}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		errClose := rows.Close()
+		if err == nil {
+			err = errClose
+		}
+	}()
+
+	sets = [][]int64{}
+
+	for rows.Next() {
+		var set []int64
+
+		err := rows.Scan(pq.Array(&set))
+		if err != nil {
+			return nil, err
+		}
+
+		sets = append(sets, set)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return sets, nil
+}
+
+// Get retrieves the swap cycle with the given id together with its legs.
+// Returns ErrRecordNotFound if the swap cycle does not exist.
+func (m *SwapCycleModel) Get(id int64) (*SwapCycle, error) {
+	if id < 1 {
+		return nil, ErrRecordNotFound
+	}
+
+	query := `
+		SELECT ` + swapCycleColumns + `
+		FROM swap_cycles
+		WHERE id = $1`
+
+	var cycle SwapCycle
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := scanSwapCycle(m.DB.QueryRowContext(ctx, query, id), &cycle)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	err = loadSwapCycleLegs(ctx, m.DB, &cycle)
+	if err != nil {
+		return nil, err
+	}
+
+	return &cycle, nil
+}
+
+// GetAllForUser retrieves the swap cycles of the given participant together with their legs,
+// optionally filtered by status, paginated and sorted based on the given filters.
+func (m *SwapCycleModel) GetAllForUser(userID int64, status string, filters Filters) (cycles []*SwapCycle, metaData MetaData, err error) {
+
+	//nolint:gosec
+	query := fmt.Sprintf(`
+		SELECT count(*) over(), `+swapCycleColumns+`
+		FROM swap_cycles
+		WHERE id IN (
+				SELECT swap_cycle_id
+				FROM swap_cycle_legs
+				WHERE giver_user_id = $1
+			)
+		  AND (status = $2 OR $2 = '')
+		ORDER BY %s %s, id ASC
+		LIMIT $3 OFFSET $4`, filters.sortColumn(), filters.sortDirection())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, userID, status, filters.limit(), filters.offset())
+	if err != nil {
+		return nil, MetaData{}, err
+	}
+	defer func() {
+		errClose := rows.Close()
+		if err == nil {
+			err = errClose
+		}
+	}()
+
+	totalRecords := 0
+	cycles = []*SwapCycle{}
+
+	for rows.Next() {
+		var cycle SwapCycle
+
+		err := scanSwapCycle(rows, &cycle, &totalRecords)
+		if err != nil {
+			return nil, MetaData{}, err
+		}
+
+		cycles = append(cycles, &cycle)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, MetaData{}, err
+	}
+
+	err = loadSwapCycleLegs(ctx, m.DB, cycles...)
+	if err != nil {
+		return nil, MetaData{}, err
+	}
+
+	return cycles, calculateMetadata(totalRecords, filters.Page, filters.PageSize), nil
+}
+
+// Transition performs the requested status transition of the given user on the swap cycle with the given id within a transaction.
+// The swap cycle is locked, so the acceptances of the participants are validated against its latest state.
+// Returns ErrRecordNotFound if the swap cycle does not exist,
+// an error wrapping ErrInvalidSwapCycleStatusTransition if the transition is not possible.
+func (m *SwapCycleModel) Transition(id int64, status string, userID int64) (cycle *SwapCycle, err error) {
+	if id < 1 {
+		return nil, ErrRecordNotFound
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err != nil {
+			err = errors.Join(err, tx.Rollback())
+		}
+	}()
+
+	query := `
+		SELECT ` + swapCycleColumns + `
+		FROM swap_cycles
+		WHERE id = $1
+		FOR UPDATE`
+
+	cycle = &SwapCycle{}
+
+	err = scanSwapCycle(tx.QueryRowContext(ctx, query, id), cycle)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	err = loadSwapCycleLegs(ctx, tx, cycle)
+	if err != nil {
+		return nil, err
+	}
+
+	err = TransitionSwapCycle(cycle, status, userID, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	query = `
+		UPDATE swap_cycles
+			SET status = $1,
+					activated_at = $2,
+					rejected_at = $3,
+					ended_at = $4,
+					version = version + 1
+		WHERE id = $5
+		RETURNING version`
+
+	err = tx.QueryRowContext(ctx, query, cycle.Status, cycle.ActivatedAt, cycle.RejectedAt, cycle.EndedAt, cycle.ID).Scan(&cycle.Version)
+	if err != nil {
+		return nil, err
+	}
+
+	if status == SwapCycleStatusAccepted {
+		leg := cycle.legOf(userID)
+
+		query := `
+			UPDATE swap_cycle_legs
+				SET is_accepted = $1,
+						accepted_at = $2
+			WHERE swap_cycle_id = $3
+			  AND giver_user_id = $4`
+
+		_, err = tx.ExecContext(ctx, query, leg.IsAccepted, leg.AcceptedAt, cycle.ID, userID)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return nil, err
+	}
+
+	return cycle, nil
+}
+
+// ExpireProposed expires all proposed swap cycles that were created before the given time.
+// Returns the number of the expired swap cycles.
+func (m *SwapCycleModel) ExpireProposed(createdBefore time.Time) (int64, error) {
+	query := `
+		UPDATE swap_cycles
+			SET status = $1,
+					expired_at = NOW(),
+					version = version + 1
+		WHERE status = $2
+		  AND created_at < $3`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, query, SwapCycleStatusExpired, SwapCycleStatusProposed, createdBefore)
+	if err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected()
+}
@@ -0,0 +1,109 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/ttarnok/instrument-swap-api/internal/validator"
+)
+
+// Swap event types, besides the created event every swap status has its own event type.
+const (
+	SwapEventCreated    = "created"
+	SwapEventAccepted   = SwapStatusAccepted
+	SwapEventRejected   = SwapStatusRejected
+	SwapEventEnded      = SwapStatusEnded
+	SwapEventCancelled  = SwapStatusCancelled
+	SwapEventExpired    = SwapStatusExpired
+	SwapEventSuperseded = SwapStatusSuperseded
+	SwapEventOverdue    = SwapStatusOverdue
+)
+
+// SwapEvent represents a single entry in the history of a swap.
+type SwapEvent struct {
+	ID          int64      `json:"id"`
+	SwapID      int64      `json:"swap_id"`
+	CreatedAt   time.Time  `json:"created_at"`
+	Type        string     `json:"type"`
+	ActorUserID *int64     `json:"actor_user_id"` // nil for the events performed by the application
+	Note        string     `json:"note"`
+	TopUp       *SwapTopUp `json:"top_up,omitempty"` // the offered top-up, only set for the created events
+}
+
+// ValidateSwapEventNote checks the validity of a note attached to a swap event.
+func ValidateSwapEventNote(v *validator.Validator, note string) {
+	v.Check(len(note) <= 500, "note", "must not be more than 500 bytes long")
+}
+
+// insertSwapEvent stores the given swap event within the given transaction.
+func insertSwapEvent(ctx context.Context, tx *sql.Tx, event *SwapEvent) error {
+	query := `
+		INSERT INTO swap_events (swap_id, type, actor_user_id, note, top_up_amount, top_up_currency, top_up_payer)
+			VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING id, created_at`
+
+	args := append([]any{event.SwapID, event.Type, event.ActorUserID, event.Note}, topUpArgs(event.TopUp)...)
+
+	return tx.QueryRowContext(ctx, query, args...).Scan(&event.ID, &event.CreatedAt)
+}
+
+// SwapEventModel represents the swap event model, that stores the history of the swaps in a database.
+type SwapEventModel struct {
+	DB *sql.DB
+}
+
+// GetAllForSwap retrieves the events of the given swap in chronological order.
+func (m *SwapEventModel) GetAllForSwap(swapID int64) (events []*SwapEvent, err error) {
+	query := `
+		SELECT id, swap_id, created_at, type, actor_user_id, note, top_up_amount, top_up_currency, top_up_payer
+		FROM swap_events
+		WHERE swap_id = $1
+		ORDER BY created_at, id`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, swapID)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		errClose := rows.Close()
+		if err == nil {
+			err = errClose
+		}
+	}()
+
+	events = []*SwapEvent{}
+
+	for rows.Next() {
+		var event SwapEvent
+		var topUp nullSwapTopUp
+
+		err := rows.Scan(
+			&event.ID,
+			&event.SwapID,
+			&event.CreatedAt,
+			&event.Type,
+			&event.ActorUserID,
+			&event.Note,
+			&topUp.Amount,
+			&topUp.Currency,
+			&topUp.Payer,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		event.TopUp = topUp.topUp()
+
+		events = append(events, &event)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return events, nil
+}
@@ -0,0 +1,136 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/ttarnok/instrument-swap-api/internal/validator"
+)
+
+// SwapMessage represents a message sent by one party of a swap to the other party.
+type SwapMessage struct {
+	ID           int64      `json:"id"`
+	SwapID       int64      `json:"swap_id"`
+	SenderUserID int64      `json:"sender_user_id"`
+	CreatedAt    time.Time  `json:"created_at"`
+	Body         string     `json:"body"`
+	ReadAt       *time.Time `json:"read_at"` // nil until the other party reads the message
+}
+
+// ValidateSwapMessage checks the validity of a swap message,
+// adds all found validation errors into the validator.
+func ValidateSwapMessage(v *validator.Validator, message *SwapMessage) {
+	v.Check(message.Body != "", "body", "must be provided")
+	v.Check(len(message.Body) <= 2000, "body", "must not be more than 2000 bytes long")
+}
+
+// SwapMessageModel represents the swap message model, that stores the message threads of the swaps in a database.
+type SwapMessageModel struct {
+	DB *sql.DB
+}
+
+// Insert stores the given message.
+func (m *SwapMessageModel) Insert(message *SwapMessage) error {
+	query := `
+		INSERT INTO swap_messages (swap_id, sender_user_id, body)
+			VALUES ($1, $2, $3)
+		RETURNING id, created_at`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	return m.DB.
+		QueryRowContext(ctx, query, message.SwapID, message.SenderUserID, message.Body).
+		Scan(&message.ID, &message.CreatedAt)
+}
+
+// GetAllForSwap retrieves the messages of the given swap, paginated and sorted based on the given filters.
+func (m *SwapMessageModel) GetAllForSwap(swapID int64, filters Filters) (messages []*SwapMessage, metaData MetaData, err error) {
+
+	//nolint:gosec
+	query := fmt.Sprintf(`
+		SELECT count(*) over(), id, swap_id, sender_user_id, created_at, body, read_at
+		FROM swap_messages
+		WHERE swap_id = $1
+		ORDER BY %s %s, id ASC
+		LIMIT $2 OFFSET $3`, filters.sortColumn(), filters.sortDirection())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, swapID, filters.limit(), filters.offset())
+	if err != nil {
+		return nil, MetaData{}, err
+	}
+	defer func() {
+		errClose := rows.Close()
+		if err == nil {
+			err = errClose
+		}
+	}()
+
+	totalRecords := 0
+	messages = []*SwapMessage{}
+
+	for rows.Next() {
+		var message SwapMessage
+
+		err := rows.Scan(
+			&totalRecords,
+			&message.ID,
+			&message.SwapID,
+			&message.SenderUserID,
+			&message.CreatedAt,
+			&message.Body,
+			&message.ReadAt,
+		)
+		if err != nil {
+			return nil, MetaData{}, err
+		}
+
+		messages = append(messages, &message)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, MetaData{}, err
+	}
+
+	return messages, calculateMetadata(totalRecords, filters.Page, filters.PageSize), nil
+}
+
+// CountUnread returns the number of the messages of the given swap, that are sent to the given user and not read yet.
+func (m *SwapMessageModel) CountUnread(swapID int64, readerUserID int64) (int, error) {
+	query := `
+		SELECT count(*)
+		FROM swap_messages
+		WHERE swap_id = $1
+		  AND sender_user_id <> $2
+		  AND read_at IS NULL`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	var count int
+	err := m.DB.QueryRowContext(ctx, query, swapID, readerUserID).Scan(&count)
+	return count, err
+}
+
+// MarkAsRead marks the messages of the given swap with the given ids as read, if they are sent to the given user.
+func (m *SwapMessageModel) MarkAsRead(swapID int64, readerUserID int64, ids []int64) error {
+	query := `
+		UPDATE swap_messages
+			SET read_at = NOW()
+		WHERE swap_id = $1
+		  AND sender_user_id <> $2
+		  AND id = ANY($3)
+		  AND read_at IS NULL`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	_, err := m.DB.ExecContext(ctx, query, swapID, readerUserID, pq.Array(ids))
+	return err
+}
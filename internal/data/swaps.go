@@ -4,36 +4,493 @@ import (
 	"context"
 	"database/sql"
 	"errors"
+	"fmt"
+	"regexp"
+	"slices"
 	"time"
 
+	"github.com/lib/pq"
 	"github.com/ttarnok/instrument-swap-api/internal/validator"
 )
 
+// MaxSwapInstruments is the maximum number of instruments on one side of a swap.
+const MaxSwapInstruments = 10
+
+// Sides of a swap, an instrument of a swap item is either offered by the requester or asked from the recipient.
+// The payer of a top-up is also one of the sides.
+const (
+	SwapSideRequester = "requester"
+	SwapSideRecipient = "recipient"
+)
+
+// Regexp to validate the ISO 4217 currency codes of the top-ups.
+var (
+	CurrencyRX = regexp.MustCompile("^[A-Z]{3}$")
+)
+
+// Swap statuses that can be requested for an existing swap.
+const (
+	SwapStatusAccepted   = "accepted"
+	SwapStatusRejected   = "rejected"
+	SwapStatusEnded      = "ended"
+	SwapStatusCancelled  = "cancelled"
+	SwapStatusExpired    = "expired"
+	SwapStatusSuperseded = "superseded"
+	SwapStatusOverdue    = "overdue"
+)
+
+// SwapStatusPending is the status of the swaps, that are neither accepted nor ended yet.
+// A swap can not be transitioned into the pending status, it is only used to filter the swaps.
+const SwapStatusPending = "pending"
+
+// SwapStatuses lists the statuses the swaps can be filtered by.
+var SwapStatuses = []string{
+	SwapStatusPending,
+	SwapStatusAccepted,
+	SwapStatusRejected,
+	SwapStatusEnded,
+	SwapStatusCancelled,
+	SwapStatusExpired,
+	SwapStatusSuperseded,
+	SwapStatusOverdue,
+}
+
+// swapStatusConditions maps the statuses to the SQL conditions selecting the swaps with the given status.
+var swapStatusConditions = map[string]string{
+	SwapStatusPending:    "NOT s.is_accepted AND NOT s.is_ended",
+	SwapStatusAccepted:   "s.is_accepted AND NOT s.is_ended",
+	SwapStatusRejected:   "s.is_rejected",
+	SwapStatusEnded:      "s.is_accepted AND s.is_ended",
+	SwapStatusCancelled:  "s.is_cancelled",
+	SwapStatusExpired:    "s.is_expired",
+	SwapStatusSuperseded: "s.is_superseded",
+	SwapStatusOverdue:    "s.is_overdue AND NOT s.is_ended",
+}
+
+// Swap related errors.
+// These errors can be tested using errors.Is.
+var (
+	ErrInstrumentAlreadySwapped          = errors.New("instrument already in a swap")                                  // "instrument already in a swap"
+	ErrRequesterInstrumentAlreadySwapped = fmt.Errorf("requester %w", ErrInstrumentAlreadySwapped)                     // "requester instrument already in a swap"
+	ErrRecipientInstrumentAlreadySwapped = fmt.Errorf("recipient %w", ErrInstrumentAlreadySwapped)                     // "recipient instrument already in a swap"
+	ErrInvalidSwapStatusTransition       = errors.New("invalid swap status transition")                                // "invalid swap status transition"
+	ErrSwapNotAcceptable                 = fmt.Errorf("%w: swap is not acceptable", ErrInvalidSwapStatusTransition)    // "invalid swap status transition: swap is not acceptable"
+	ErrSwapNotRejectable                 = fmt.Errorf("%w: swap is not rejectable", ErrInvalidSwapStatusTransition)    // "invalid swap status transition: swap is not rejectable"
+	ErrSwapNotEndable                    = fmt.Errorf("%w: swap is not endable", ErrInvalidSwapStatusTransition)       // "invalid swap status transition: swap is not endable"
+	ErrSwapNotCancellable                = fmt.Errorf("%w: swap is not cancellable", ErrInvalidSwapStatusTransition)   // "invalid swap status transition: swap is not cancellable"
+	ErrSwapNotExpirable                  = fmt.Errorf("%w: swap is not expirable", ErrInvalidSwapStatusTransition)     // "invalid swap status transition: swap is not expirable"
+	ErrSwapNotCounterable                = fmt.Errorf("%w: swap can not be countered", ErrInvalidSwapStatusTransition) // "invalid swap status transition: swap can not be countered"
+	ErrSwapNotOverdue                    = fmt.Errorf("%w: swap can not be overdue", ErrInvalidSwapStatusTransition)   // "invalid swap status transition: swap can not be overdue"
+)
+
 // Swap represents an instrument swap record in the application.
+// Both sides of a swap can hold multiple instruments, RequesterInstrumentID and RecipientInstrumentID
+// hold the first instrument of the corresponding side.
+// A swap can be time-boxed with an agreed ReturnBy date, an accepted swap that is not ended until then is flagged overdue.
 type Swap struct {
-	ID                    int64      `json:"id"`
-	CreatedAt             time.Time  `json:"created_at"`
-	RequesterInstrumentID int64      `json:"requester_instrument_id"`
-	RecipientInstrumentID int64      `json:"recipient_instrument_id"`
-	IsAccepted            bool       `json:"is_accepted"`
-	AcceptedAt            *time.Time `json:"accepted_at"`
-	IsRejected            bool       `json:"is_rejected"`
-	RejectedAt            *time.Time `json:"rejected_at"`
-	IsEnded               bool       `json:"is_ended"`
-	EndedAt               *time.Time `json:"ended_at"`
-	Version               int32      `json:"version"`
+	ID                     int64      `json:"id"`
+	CreatedAt              time.Time  `json:"created_at"`
+	RequesterInstrumentID  int64      `json:"requester_instrument_id"`
+	RecipientInstrumentID  int64      `json:"recipient_instrument_id"`
+	RequesterInstrumentIDs []int64    `json:"requester_instrument_ids"`
+	RecipientInstrumentIDs []int64    `json:"recipient_instrument_ids"`
+	IsAccepted             bool       `json:"is_accepted"`
+	AcceptedAt             *time.Time `json:"accepted_at"`
+	IsRejected             bool       `json:"is_rejected"`
+	RejectedAt             *time.Time `json:"rejected_at"`
+	IsEnded                bool       `json:"is_ended"`
+	EndedAt                *time.Time `json:"ended_at"`
+	IsCancelled            bool       `json:"is_cancelled"`
+	CancelledAt            *time.Time `json:"cancelled_at"`
+	IsExpired              bool       `json:"is_expired"`
+	ExpiredAt              *time.Time `json:"expired_at"`
+	IsSuperseded           bool       `json:"is_superseded"`
+	SupersededAt           *time.Time `json:"superseded_at"`
+	ParentSwapID           *int64     `json:"parent_swap_id"`
+	TopUp                  *SwapTopUp `json:"top_up"`
+	StartsAt               *time.Time `json:"starts_at"`
+	ReturnBy               *time.Time `json:"return_by"`
+	IsOverdue              bool       `json:"is_overdue"`
+	OverdueAt              *time.Time `json:"overdue_at"`
+	Version                int32      `json:"version"`
+
+	RequesterInstruments []*SwapInstrument `json:"requester_instruments,omitempty"` // only set if the instruments are expanded
+	RecipientInstruments []*SwapInstrument `json:"recipient_instruments,omitempty"` // only set if the instruments are expanded
+}
+
+// SwapInstrument is a lightweight summary of an instrument of a swap.
+type SwapInstrument struct {
+	ID           int64  `json:"id"`
+	Name         string `json:"name"`
+	Manufacturer string `json:"manufacturer"`
+	OwnerUserID  int64  `json:"owner_user_id"`
+}
+
+// SwapTopUp represents a monetary top-up paid by one side of an uneven swap to the other side.
+type SwapTopUp struct {
+	Amount   int64  `json:"amount"`
+	Currency string `json:"currency"`
+	Payer    string `json:"payer"` // SwapSideRequester or SwapSideRecipient
+}
+
+// NewSwap returns a new swap with the given requester and recipient instruments.
+func NewSwap(requesterInstrumentIDs []int64, recipientInstrumentIDs []int64) *Swap {
+	swap := &Swap{
+		RequesterInstrumentIDs: requesterInstrumentIDs,
+		RecipientInstrumentIDs: recipientInstrumentIDs,
+	}
+	if len(requesterInstrumentIDs) > 0 {
+		swap.RequesterInstrumentID = requesterInstrumentIDs[0]
+	}
+	if len(recipientInstrumentIDs) > 0 {
+		swap.RecipientInstrumentID = recipientInstrumentIDs[0]
+	}
+	return swap
+}
+
+// Overdue reports whether the swap is flagged overdue and the instruments are not returned yet.
+func (s *Swap) Overdue() bool {
+	return s.IsOverdue && !s.IsEnded
+}
+
+// InstrumentIDs returns the ids of all instruments of the swap, the requester instruments come first.
+func (s *Swap) InstrumentIDs() []int64 {
+	return slices.Concat(s.RequesterInstrumentIDs, s.RecipientInstrumentIDs)
 }
 
 // ValidateSwap checks the validity of a swap,
 // adds all found validation errors into the validator.
 func ValidateSwap(v *validator.Validator, swap *Swap) {
-	v.Check(swap.RequesterInstrumentID != 0, "requester_instrument_id", "must not be empty")
-	v.Check(swap.RequesterInstrumentID >= 0, "requester_instrument_id", "must be greater than 0")
+	validateSwapSide(v, swap.RequesterInstrumentIDs, "requester_instrument_id")
+	validateSwapSide(v, swap.RecipientInstrumentIDs, "recipient_instrument_id")
+
+	for _, id := range swap.RequesterInstrumentIDs {
+		v.Check(!slices.Contains(swap.RecipientInstrumentIDs, id), "requester_instrument_id", "requester and recipient instruments must be different")
+	}
+
+	if swap.TopUp != nil {
+		v.Check(swap.TopUp.Amount > 0, "top_up.amount", "must be greater than 0")
+		v.Check(validator.Matches(swap.TopUp.Currency, CurrencyRX), "top_up.currency", "must be a 3 letter ISO 4217 currency code")
+		v.Check(validator.PermittedValue(swap.TopUp.Payer, SwapSideRequester, SwapSideRecipient), "top_up.payer", "must be requester or recipient")
+	}
+
+	if swap.ReturnBy != nil {
+		v.Check(swap.ReturnBy.After(time.Now()), "return_by", "must be in the future")
+		if swap.StartsAt != nil {
+			v.Check(swap.ReturnBy.After(*swap.StartsAt), "return_by", "must be after starts_at")
+		}
+	}
+}
+
+// ValidateSwapTopUp checks whether the top-up of the swap balances the estimated values of the two sides of the swap.
+// The values of the sides with the top-up added to the payer side may differ by the given tolerance,
+// that is a fraction of the value of the more valuable side. The top-up must be in the given currency of the estimated values.
+// A swap without a top-up is always valid.
+func ValidateSwapTopUp(v *validator.Validator, swap *Swap, requesterValue int64, recipientValue int64, currency string, tolerance float64) {
+	if swap.TopUp == nil {
+		return
+	}
+
+	// The estimated values are not converted, so only top-ups in their currency can balance them.
+	if swap.TopUp.Currency != currency {
+		v.Check(false, "top_up.currency", fmt.Sprintf("must be %s", currency))
+		return
+	}
+
+	switch swap.TopUp.Payer {
+	case SwapSideRequester:
+		requesterValue += swap.TopUp.Amount
+	case SwapSideRecipient:
+		recipientValue += swap.TopUp.Amount
+	}
+
+	difference := requesterValue - recipientValue
+	if difference < 0 {
+		difference = -difference
+	}
+
+	v.Check(
+		float64(difference) <= tolerance*float64(max(requesterValue, recipientValue)),
+		"top_up.amount",
+		"must balance the estimated value difference of the swapped instruments",
+	)
+}
+
+// validateSwapSide checks the validity of the instrument ids of one side of a swap.
+func validateSwapSide(v *validator.Validator, ids []int64, key string) {
+	v.Check(len(ids) != 0, key, "must not be empty")
+	v.Check(len(ids) <= MaxSwapInstruments, key+"s", fmt.Sprintf("must not contain more than %d instruments", MaxSwapInstruments))
+	v.Check(validator.Unique(ids), key+"s", "must not contain duplicate values")
+
+	for _, id := range ids {
+		v.Check(id > 0, key, "must be greater than 0")
+	}
+}
+
+// ValidateSwapStatusTransition checks whether the requested status transition is possible from the current state of the swap.
+// Returns an error wrapping ErrInvalidSwapStatusTransition if the transition is not possible, otherwise returns nil.
+func ValidateSwapStatusTransition(swap *Swap, status string) error {
+	switch status {
+	case SwapStatusAccepted:
+		if swap.IsAccepted || swap.IsRejected || swap.IsEnded {
+			return ErrSwapNotAcceptable
+		}
+	case SwapStatusRejected:
+		if swap.IsAccepted || swap.IsRejected || swap.IsEnded {
+			return ErrSwapNotRejectable
+		}
+	case SwapStatusEnded:
+		if !swap.IsAccepted || swap.IsEnded {
+			return ErrSwapNotEndable
+		}
+	case SwapStatusCancelled:
+		if swap.IsAccepted || swap.IsEnded {
+			return ErrSwapNotCancellable
+		}
+	case SwapStatusExpired:
+		if swap.IsAccepted || swap.IsEnded {
+			return ErrSwapNotExpirable
+		}
+	case SwapStatusSuperseded:
+		if swap.IsAccepted || swap.IsEnded {
+			return ErrSwapNotCounterable
+		}
+	case SwapStatusOverdue:
+		if !swap.IsAccepted || swap.IsEnded || swap.IsOverdue || swap.ReturnBy == nil {
+			return ErrSwapNotOverdue
+		}
+	default:
+		return fmt.Errorf("%w: unknown status %q", ErrInvalidSwapStatusTransition, status)
+	}
+	return nil
+}
+
+// TransitionSwapStatus validates the requested status transition and applies it on the given swap at the given time.
+// Due to pointer semantics, the function mutates the given swap.
+func TransitionSwapStatus(swap *Swap, status string, at time.Time) error {
+	err := ValidateSwapStatusTransition(swap, status)
+	if err != nil {
+		return err
+	}
+
+	switch status {
+	case SwapStatusAccepted:
+		swap.IsAccepted = true
+		swap.AcceptedAt = &at
+	case SwapStatusRejected:
+		swap.IsRejected = true
+		swap.IsEnded = true
+		swap.RejectedAt = &at
+		swap.EndedAt = &at
+	case SwapStatusEnded:
+		swap.IsEnded = true
+		swap.EndedAt = &at
+	case SwapStatusCancelled:
+		swap.IsCancelled = true
+		swap.IsEnded = true
+		swap.CancelledAt = &at
+		swap.EndedAt = &at
+	case SwapStatusExpired:
+		swap.IsExpired = true
+		swap.IsEnded = true
+		swap.ExpiredAt = &at
+		swap.EndedAt = &at
+	case SwapStatusSuperseded:
+		swap.IsSuperseded = true
+		swap.IsEnded = true
+		swap.SupersededAt = &at
+		swap.EndedAt = &at
+	case SwapStatusOverdue:
+		swap.IsOverdue = true
+		swap.OverdueAt = &at
+	}
+	return nil
+}
+
+// swapColumns lists the columns of the swaps table in the order expected by scanSwap.
+const swapColumns = `id, created_at, requester_instrument_id, recipient_instrument_id, is_accepted,
+		accepted_at, is_rejected, rejected_at, is_ended, ended_at, is_cancelled, cancelled_at,
+		is_expired, expired_at, is_superseded, superseded_at, parent_swap_id,
+		top_up_amount, top_up_currency, top_up_payer, starts_at, return_by, is_overdue, overdue_at, version`
+
+// rowScanner is implemented by both *sql.Row and *sql.Rows.
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+// scanSwap scans a row selected with swapColumns into the given swap.
+// The destinations of the columns selected before swapColumns can be given as leading.
+func scanSwap(row rowScanner, swap *Swap, leading ...any) error {
+	var topUp nullSwapTopUp
+
+	err := row.Scan(append(leading,
+		&swap.ID,
+		&swap.CreatedAt,
+		&swap.RequesterInstrumentID,
+		&swap.RecipientInstrumentID,
+		&swap.IsAccepted,
+		&swap.AcceptedAt,
+		&swap.IsRejected,
+		&swap.RejectedAt,
+		&swap.IsEnded,
+		&swap.EndedAt,
+		&swap.IsCancelled,
+		&swap.CancelledAt,
+		&swap.IsExpired,
+		&swap.ExpiredAt,
+		&swap.IsSuperseded,
+		&swap.SupersededAt,
+		&swap.ParentSwapID,
+		&topUp.Amount,
+		&topUp.Currency,
+		&topUp.Payer,
+		&swap.StartsAt,
+		&swap.ReturnBy,
+		&swap.IsOverdue,
+		&swap.OverdueAt,
+		&swap.Version,
+	)...)
+	if err != nil {
+		return err
+	}
+
+	swap.TopUp = topUp.topUp()
+	return nil
+}
+
+// nullSwapTopUp holds the nullable top-up columns of a row.
+type nullSwapTopUp struct {
+	Amount   sql.NullInt64
+	Currency sql.NullString
+	Payer    sql.NullString
+}
 
-	v.Check(swap.RecipientInstrumentID != 0, "recipient_instrument_id", "must not be empty")
-	v.Check(swap.RecipientInstrumentID >= 0, "recipient_instrument_id", "must be greater than 0")
+// topUp returns the scanned top-up, or nil if the row has no top-up.
+func (t nullSwapTopUp) topUp() *SwapTopUp {
+	if !t.Amount.Valid {
+		return nil
+	}
+	return &SwapTopUp{Amount: t.Amount.Int64, Currency: t.Currency.String, Payer: t.Payer.String}
+}
 
-	v.Check(swap.RecipientInstrumentID != swap.RequesterInstrumentID, "requester_instrument_id", "requester and recipient instruments must be different")
+// topUpArgs returns the column values of the given top-up, all of them are nil if there is no top-up.
+func topUpArgs(topUp *SwapTopUp) []any {
+	if topUp == nil {
+		return []any{nil, nil, nil}
+	}
+	return []any{topUp.Amount, topUp.Currency, topUp.Payer}
+}
+
+// queryer is implemented by both *sql.DB and *sql.Tx.
+type queryer interface {
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+}
+
+// loadSwapItems retrieves the instruments of the given swaps from the swap_items table.
+func loadSwapItems(ctx context.Context, q queryer, swaps ...*Swap) (err error) {
+	if len(swaps) == 0 {
+		return nil
+	}
+
+	byID := make(map[int64]*Swap, len(swaps))
+	ids := make([]int64, 0, len(swaps))
+	for _, swap := range swaps {
+		swap.RequesterInstrumentIDs = []int64{}
+		swap.RecipientInstrumentIDs = []int64{}
+		byID[swap.ID] = swap
+		ids = append(ids, swap.ID)
+	}
+
+	query := `
+		SELECT swap_id, instrument_id, side
+		FROM swap_items
+		WHERE swap_id = ANY($1)
+		ORDER BY swap_id, side, position`
+
+	rows, err := q.QueryContext(ctx, query, pq.Array(ids))
+	if err != nil {
+		return err
+	}
+	defer func() {
+		errClose := rows.Close()
+		if err == nil {
+			err = errClose
+		}
+	}()
+
+	for rows.Next() {
+		var swapID, instrumentID int64
+		var side string
+
+		err := rows.Scan(&swapID, &instrumentID, &side)
+		if err != nil {
+			return err
+		}
+
+		swap := byID[swapID]
+		switch side {
+		case SwapSideRequester:
+			swap.RequesterInstrumentIDs = append(swap.RequesterInstrumentIDs, instrumentID)
+		case SwapSideRecipient:
+			swap.RecipientInstrumentIDs = append(swap.RecipientInstrumentIDs, instrumentID)
+		}
+	}
+
+	return rows.Err()
+}
+
+// loadSwapInstruments retrieves the instrument summaries of both sides of the given swap.
+func loadSwapInstruments(ctx context.Context, q queryer, swap *Swap) (err error) {
+	query := `
+		SELECT si.side, i.id, i.name, i.manufacturer, i.owner_user_id
+		FROM swap_items si
+		JOIN instruments i ON i.id = si.instrument_id
+		WHERE si.swap_id = $1
+		ORDER BY si.side, si.position`
+
+	rows, err := q.QueryContext(ctx, query, swap.ID)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		errClose := rows.Close()
+		if err == nil {
+			err = errClose
+		}
+	}()
+
+	swap.RequesterInstruments = []*SwapInstrument{}
+	swap.RecipientInstruments = []*SwapInstrument{}
+
+	for rows.Next() {
+		var side string
+		var instrument SwapInstrument
+
+		err := rows.Scan(&side, &instrument.ID, &instrument.Name, &instrument.Manufacturer, &instrument.OwnerUserID)
+		if err != nil {
+			return err
+		}
+
+		switch side {
+		case SwapSideRequester:
+			swap.RequesterInstruments = append(swap.RequesterInstruments, &instrument)
+		case SwapSideRecipient:
+			swap.RecipientInstruments = append(swap.RecipientInstruments, &instrument)
+		}
+	}
+
+	return rows.Err()
+}
+
+// insertSwapItems stores the given instruments of one side of a swap within the given transaction.
+func insertSwapItems(ctx context.Context, tx *sql.Tx, swapID int64, side string, instrumentIDs []int64) error {
+	query := `
+		INSERT INTO swap_items (swap_id, instrument_id, side, position)
+			SELECT $1, item.instrument_id, $2, item.position - 1
+			FROM unnest($3::bigint[]) WITH ORDINALITY AS item(instrument_id, position)`
+
+	_, err := tx.ExecContext(ctx, query, swapID, side, pq.Array(instrumentIDs))
+	return err
 }
 
 // SwapModel represents the database layer and provides functionality to interact with the database.
@@ -41,29 +498,44 @@ type SwapModel struct {
 	DB *sql.DB
 }
 
-// GetAllForUser retrieves all swaps related to the given user.
-// Returns an error if the retrieval is not possible.
-func (s *SwapModel) GetAllForUser(userID int64) (swaps []*Swap, err error) {
+// GetAll retrieves the swaps of the given user, filtered, paginated and sorted based on the given parameters.
+// The swaps can be filtered by status, by the role of the user (SwapSideRequester or SwapSideRecipient),
+// by an instrument of the swaps and by the creation time, the empty and zero parameters are ignored.
+func (s *SwapModel) GetAll(userID int64, status string, role string, instrumentID int64, createdFrom time.Time, createdTo time.Time, filters Filters) (swaps []*Swap, metaData MetaData, err error) {
 
-	query := `
-	SELECT id, created_at, requester_instrument_id, recipient_instrument_id, is_accepted,
-		accepted_at, is_rejected, rejected_at, is_ended, ended_at, version
-	FROM (
-			SELECT s.*, irec.owner_user_id irec_owner_user_id, ireq.owner_user_id ireq_owner_user_id
-			FROM swaps s
-			JOIN instruments ireq ON s.requester_instrument_id = ireq.id
-			JOIN instruments irec ON s.recipient_instrument_id = irec.id
-			)
-	WHERE irec_owner_user_id = $1
-		 OR ireq_owner_user_id = $2
-	ORDER BY id`
+	statusCondition, ok := swapStatusConditions[status]
+	if !ok {
+		statusCondition = "TRUE"
+	}
+
+	//nolint:gosec
+	query := fmt.Sprintf(`
+		SELECT count(*) over(), `+swapColumns+`
+		FROM (
+				SELECT s.*, irec.owner_user_id irec_owner_user_id, ireq.owner_user_id ireq_owner_user_id
+				FROM swaps s
+				JOIN instruments ireq ON s.requester_instrument_id = ireq.id
+				JOIN instruments irec ON s.recipient_instrument_id = irec.id
+				WHERE %s
+				)
+		WHERE ((ireq_owner_user_id = $1 AND $2 IN ('', 'requester'))
+		    OR (irec_owner_user_id = $1 AND $2 IN ('', 'recipient')))
+		  AND (id IN (SELECT swap_id FROM swap_items WHERE instrument_id = $3) OR $3 = 0)
+		  AND (created_at >= $4 OR $4::timestamptz IS NULL)
+		  AND (created_at < $5 OR $5::timestamptz IS NULL)
+		ORDER BY %s %s, id ASC
+		LIMIT $6 OFFSET $7`, statusCondition, filters.sortColumn(), filters.sortDirection())
 
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
 
-	rows, err := s.DB.QueryContext(ctx, query, userID, userID)
+	args := []any{
+		userID, role, instrumentID, nullTime(createdFrom), nullTime(createdTo), filters.limit(), filters.offset(),
+	}
+
+	rows, err := s.DB.QueryContext(ctx, query, args...)
 	if err != nil {
-		return nil, err
+		return nil, MetaData{}, err
 	}
 
 	defer func() {
@@ -73,39 +545,39 @@ func (s *SwapModel) GetAllForUser(userID int64) (swaps []*Swap, err error) {
 		}
 	}()
 
+	totalRecords := 0
 	swaps = []*Swap{}
 
 	for rows.Next() {
 
 		var swap Swap
 
-		err := rows.Scan(
-			&swap.ID,
-			&swap.CreatedAt,
-			&swap.RequesterInstrumentID,
-			&swap.RecipientInstrumentID,
-			&swap.IsAccepted,
-			&swap.AcceptedAt,
-			&swap.IsRejected,
-			&swap.RejectedAt,
-			&swap.IsEnded,
-			&swap.EndedAt,
-			&swap.Version,
-		)
-
+		err := scanSwap(rows, &swap, &totalRecords)
 		if err != nil {
-			return nil, err
+			return nil, MetaData{}, err
 		}
 
 		swaps = append(swaps, &swap)
-
 	}
 
 	if err = rows.Err(); err != nil {
-		return nil, err
+		return nil, MetaData{}, err
 	}
 
-	return swaps, nil
+	err = loadSwapItems(ctx, s.DB, swaps...)
+	if err != nil {
+		return nil, MetaData{}, err
+	}
+
+	return swaps, calculateMetadata(totalRecords, filters.Page, filters.PageSize), nil
+}
+
+// nullTime returns nil for the zero time, otherwise returns the given time.
+func nullTime(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	return &t
 }
 
 // Get retrieves a swap based on the given swap id.
@@ -117,8 +589,7 @@ func (s *SwapModel) Get(id int64) (*Swap, error) {
 	}
 
 	query := `
-		SELECT id, created_at, requester_instrument_id, recipient_instrument_id, is_accepted,
-			accepted_at, is_rejected, rejected_at, is_ended, ended_at, version
+		SELECT ` + swapColumns + `
 		FROM swaps
 		WHERE id = $1
 		ORDER BY id`
@@ -128,19 +599,7 @@ func (s *SwapModel) Get(id int64) (*Swap, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
 
-	err := s.DB.QueryRowContext(ctx, query, id).Scan(
-		&swap.ID,
-		&swap.CreatedAt,
-		&swap.RequesterInstrumentID,
-		&swap.RecipientInstrumentID,
-		&swap.IsAccepted,
-		&swap.AcceptedAt,
-		&swap.IsRejected,
-		&swap.RejectedAt,
-		&swap.IsEnded,
-		&swap.EndedAt,
-		&swap.Version,
-	)
+	err := scanSwap(s.DB.QueryRowContext(ctx, query, id), &swap)
 	if err != nil {
 		switch {
 		case errors.Is(err, sql.ErrNoRows):
@@ -150,41 +609,41 @@ func (s *SwapModel) Get(id int64) (*Swap, error) {
 		}
 	}
 
+	err = loadSwapItems(ctx, s.DB, &swap)
+	if err != nil {
+		return nil, err
+	}
+
 	return &swap, nil
 }
 
-// GetByInstrumentID returns swaps based on an instrument id.
-// Returns ErrRecordNotFound if the given is is not found.
-func (s *SwapModel) GetByInstrumentID(id int64) (*Swap, error) {
+// GetForUser retrieves the swap with the given id, if the given user is its requester or recipient.
+// The instrument summaries of the swap are loaded too, if expandInstruments is true.
+// Returns ErrRecordNotFound if the swap does not exist or the user is not a party of the swap.
+func (s *SwapModel) GetForUser(id int64, userID int64, expandInstruments bool) (*Swap, error) {
+
 	if id < 1 {
 		return nil, ErrRecordNotFound
 	}
 
 	query := `
-		SELECT id, created_at, requester_instrument_id, recipient_instrument_id, is_accepted,
-			accepted_at, is_rejected, rejected_at, is_ended, ended_at, version
-		FROM swaps
-		WHERE (requester_instrument_id = $1 OR recipient_instrument_id = $2)
-		ORDER BY id`
+		SELECT ` + swapColumns + `
+		FROM (
+				SELECT s.*, irec.owner_user_id irec_owner_user_id, ireq.owner_user_id ireq_owner_user_id
+				FROM swaps s
+				JOIN instruments ireq ON s.requester_instrument_id = ireq.id
+				JOIN instruments irec ON s.recipient_instrument_id = irec.id
+				WHERE s.id = $1
+				)
+		WHERE irec_owner_user_id = $2
+		   OR ireq_owner_user_id = $2`
 
 	var swap Swap
 
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
 
-	err := s.DB.QueryRowContext(ctx, query, id, id).Scan(
-		&swap.ID,
-		&swap.CreatedAt,
-		&swap.RequesterInstrumentID,
-		&swap.RecipientInstrumentID,
-		&swap.IsAccepted,
-		&swap.AcceptedAt,
-		&swap.IsRejected,
-		&swap.RejectedAt,
-		&swap.IsEnded,
-		&swap.EndedAt,
-		&swap.Version,
-	)
+	err := scanSwap(s.DB.QueryRowContext(ctx, query, id, userID), &swap)
 	if err != nil {
 		switch {
 		case errors.Is(err, sql.ErrNoRows):
@@ -194,28 +653,244 @@ func (s *SwapModel) GetByInstrumentID(id int64) (*Swap, error) {
 		}
 	}
 
+	err = loadSwapItems(ctx, s.DB, &swap)
+	if err != nil {
+		return nil, err
+	}
+
+	if expandInstruments {
+		err = loadSwapInstruments(ctx, s.DB, &swap)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	return &swap, nil
 }
 
-// Insert stores the given swap into the database.
-func (s *SwapModel) Insert(swap *Swap) error {
+// GetAllForInstrument retrieves the whole swap history of the given instrument, paginated and sorted based on the given filters.
+func (s *SwapModel) GetAllForInstrument(instrumentID int64, filters Filters) (swaps []*Swap, metaData MetaData, err error) {
 
-	query := `
-		INSERT INTO swaps (requester_instrument_id, recipient_instrument_id)
-			VALUES($1, $2)
-		RETURNING id, created_at, version`
+	//nolint:gosec
+	query := fmt.Sprintf(`
+		SELECT count(*) over(), `+swapColumns+`
+		FROM swaps
+		WHERE id IN (SELECT swap_id FROM swap_items WHERE instrument_id = $1)
+		ORDER BY %s %s, id ASC
+		LIMIT $2 OFFSET $3`, filters.sortColumn(), filters.sortDirection())
 
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
 
-	return s.DB.
-		QueryRowContext(ctx, query, swap.RequesterInstrumentID, swap.RecipientInstrumentID).
+	rows, err := s.DB.QueryContext(ctx, query, instrumentID, filters.limit(), filters.offset())
+	if err != nil {
+		return nil, MetaData{}, err
+	}
+
+	defer func() {
+		errClose := rows.Close()
+		if err == nil {
+			err = errClose
+		}
+	}()
+
+	totalRecords := 0
+	swaps = []*Swap{}
+
+	for rows.Next() {
+
+		var swap Swap
+
+		err := scanSwap(rows, &swap, &totalRecords)
+		if err != nil {
+			return nil, MetaData{}, err
+		}
+
+		swaps = append(swaps, &swap)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, MetaData{}, err
+	}
+
+	err = loadSwapItems(ctx, s.DB, swaps...)
+	if err != nil {
+		return nil, MetaData{}, err
+	}
+
+	return swaps, calculateMetadata(totalRecords, filters.Page, filters.PageSize), nil
+}
+
+// lockInstruments locks the rows of the given instruments until the end of the given transaction.
+// The rows are locked in ascending id order to prevent deadlocks between concurrent transactions.
+// Returns ErrRecordNotFound if any of the instruments does not exist.
+func lockInstruments(ctx context.Context, tx *sql.Tx, ids ...int64) error {
+	ids = slices.Clone(ids)
+	slices.Sort(ids)
+
+	for _, id := range ids {
+		var lockedID int64
+		err := tx.QueryRowContext(ctx, `SELECT id FROM instruments WHERE id = $1 FOR UPDATE`, id).Scan(&lockedID)
+		if err != nil {
+			switch {
+			case errors.Is(err, sql.ErrNoRows):
+				return ErrRecordNotFound
+			default:
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// instrumentSwappedCondition is the SQL condition selecting the instruments aliased as i, that are part of an active swap,
+// that is not ended yet, or part of a proposed or an active swap cycle. The rejected, cancelled, expired and superseded swaps are ended too.
+const instrumentSwappedCondition = `(EXISTS (
+			SELECT 1
+			FROM swap_items si
+			JOIN swaps s ON s.id = si.swap_id
+			WHERE si.instrument_id = i.id
+			  AND NOT s.is_ended
+		) OR EXISTS (
+			SELECT 1
+			FROM swap_cycle_legs l
+			JOIN swap_cycles c ON c.id = l.swap_cycle_id
+			WHERE l.instrument_id = i.id
+			  AND c.status IN ('` + SwapCycleStatusProposed + `', '` + SwapCycleStatusActive + `')
+		))`
+
+// isInstrumentSwapped checks whether the given instrument is part of an active swap or swap cycle, see instrumentSwappedCondition.
+// The instrument is expected to exist.
+func isInstrumentSwapped(ctx context.Context, tx *sql.Tx, instrumentID int64) (bool, error) {
+	query := `
+		SELECT ` + instrumentSwappedCondition + `
+		FROM instruments i
+		WHERE i.id = $1`
+
+	var swapped bool
+	err := tx.QueryRowContext(ctx, query, instrumentID).Scan(&swapped)
+	return swapped, err
+}
+
+// insertSwap stores the given swap together with its created event within the given transaction.
+// The involved instruments are locked until the end of the transaction.
+// Returns ErrRecordNotFound if any of the instruments does not exist,
+// ErrRequesterInstrumentAlreadySwapped or ErrRecipientInstrumentAlreadySwapped if an instrument is already in an active swap,
+// ErrRequesterInstrumentDisputed or ErrRecipientInstrumentDisputed if an instrument is under an open dispute.
+func insertSwap(ctx context.Context, tx *sql.Tx, swap *Swap, actorUserID int64, note string) error {
+	err := lockInstruments(ctx, tx, swap.InstrumentIDs()...)
+	if err != nil {
+		return err
+	}
+
+	for _, id := range swap.RequesterInstrumentIDs {
+		swapped, err := isInstrumentSwapped(ctx, tx, id)
+		if err != nil {
+			return err
+		}
+		if swapped {
+			return ErrRequesterInstrumentAlreadySwapped
+		}
+	}
+
+	for _, id := range swap.RecipientInstrumentIDs {
+		swapped, err := isInstrumentSwapped(ctx, tx, id)
+		if err != nil {
+			return err
+		}
+		if swapped {
+			return ErrRecipientInstrumentAlreadySwapped
+		}
+	}
+
+	for _, id := range swap.RequesterInstrumentIDs {
+		disputed, err := isInstrumentDisputed(ctx, tx, id)
+		if err != nil {
+			return err
+		}
+		if disputed {
+			return ErrRequesterInstrumentDisputed
+		}
+	}
+
+	for _, id := range swap.RecipientInstrumentIDs {
+		disputed, err := isInstrumentDisputed(ctx, tx, id)
+		if err != nil {
+			return err
+		}
+		if disputed {
+			return ErrRecipientInstrumentDisputed
+		}
+	}
+
+	query := `
+		INSERT INTO swaps (requester_instrument_id, recipient_instrument_id, parent_swap_id, starts_at, return_by, top_up_amount, top_up_currency, top_up_payer)
+			VALUES($1, $2, $3, $4, $5, $6, $7, $8)
+		RETURNING id, created_at, version`
+
+	args := append([]any{swap.RequesterInstrumentID, swap.RecipientInstrumentID, swap.ParentSwapID, swap.StartsAt, swap.ReturnBy}, topUpArgs(swap.TopUp)...)
+
+	err = tx.
+		QueryRowContext(ctx, query, args...).
 		Scan(&swap.ID, &swap.CreatedAt, &swap.Version)
+	if err != nil {
+		return err
+	}
+
+	err = insertSwapItems(ctx, tx, swap.ID, SwapSideRequester, swap.RequesterInstrumentIDs)
+	if err != nil {
+		return err
+	}
+
+	err = insertSwapItems(ctx, tx, swap.ID, SwapSideRecipient, swap.RecipientInstrumentIDs)
+	if err != nil {
+		return err
+	}
+
+	return insertSwapEvent(ctx, tx, &SwapEvent{SwapID: swap.ID, Type: SwapEventCreated, ActorUserID: &actorUserID, Note: note, TopUp: swap.TopUp})
+}
+
+// getSwapForUpdate retrieves the swap with the given id and locks it until the end of the given transaction.
+// Returns ErrRecordNotFound if the swap does not exist.
+func getSwapForUpdate(ctx context.Context, tx *sql.Tx, id int64) (*Swap, error) {
+	if id < 1 {
+		return nil, ErrRecordNotFound
+	}
+
+	query := `
+		SELECT ` + swapColumns + `
+		FROM swaps
+		WHERE id = $1
+		FOR UPDATE`
+
+	var swap Swap
+
+	err := scanSwap(tx.QueryRowContext(ctx, query, id), &swap)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	err = loadSwapItems(ctx, tx, &swap)
+	if err != nil {
+		return nil, err
+	}
+
+	return &swap, nil
 }
 
-// Update updates the given swap in the database.
-// Returns ErrEditConflict in case of conflicting update.
-func (s *SwapModel) Update(swap *Swap) error {
+// transitionSwap performs the requested status transition on the given locked swap within the given transaction,
+// and records the corresponding event.
+// Returns an error wrapping ErrInvalidSwapStatusTransition if the transition is not possible.
+func transitionSwap(ctx context.Context, tx *sql.Tx, swap *Swap, status string, actorUserID int64, note string) error {
+	err := TransitionSwapStatus(swap, status, time.Now())
+	if err != nil {
+		return err
+	}
 
 	query := `
 		UPDATE swaps
@@ -225,9 +900,16 @@ func (s *SwapModel) Update(swap *Swap) error {
 					rejected_at = $4,
 					is_ended = $5,
 					ended_at = $6,
+					is_cancelled = $7,
+					cancelled_at = $8,
+					is_expired = $9,
+					expired_at = $10,
+					is_superseded = $11,
+					superseded_at = $12,
+					is_overdue = $13,
+					overdue_at = $14,
 					version = version + 1
-		WHERE id = $7
-		  AND version = $8
+		WHERE id = $15
 		RETURNING version`
 
 	args := []any{
@@ -237,21 +919,192 @@ func (s *SwapModel) Update(swap *Swap) error {
 		swap.RejectedAt,
 		swap.IsEnded,
 		swap.EndedAt,
+		swap.IsCancelled,
+		swap.CancelledAt,
+		swap.IsExpired,
+		swap.ExpiredAt,
+		swap.IsSuperseded,
+		swap.SupersededAt,
+		swap.IsOverdue,
+		swap.OverdueAt,
 		swap.ID,
-		swap.Version,
 	}
 
+	err = tx.QueryRowContext(ctx, query, args...).Scan(&swap.Version)
+	if err != nil {
+		return err
+	}
+
+	return insertSwapEvent(ctx, tx, &SwapEvent{SwapID: swap.ID, Type: status, ActorUserID: &actorUserID, Note: note})
+}
+
+// Create stores the given swap into the database within a transaction, together with its created event.
+// The involved instruments are locked, so concurrent requests can not put the same instrument into two active swaps.
+// Returns ErrRecordNotFound if any of the instruments does not exist,
+// ErrRequesterInstrumentAlreadySwapped or ErrRecipientInstrumentAlreadySwapped if an instrument is already in an active swap,
+// ErrRequesterInstrumentDisputed or ErrRecipientInstrumentDisputed if an instrument is under an open dispute.
+func (s *SwapModel) Create(swap *Swap, actorUserID int64, note string) (err error) {
+
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
 
-	err := s.DB.QueryRowContext(ctx, query, args...).Scan(&swap.Version)
+	tx, err := s.DB.BeginTx(ctx, nil)
 	if err != nil {
-		switch {
-		case errors.Is(err, sql.ErrNoRows):
-			return ErrEditConflict
-		default:
-			return err
+		return err
+	}
+	defer func() {
+		if err != nil {
+			err = errors.Join(err, tx.Rollback())
+		}
+	}()
+
+	err = insertSwap(ctx, tx, swap, actorUserID, note)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// Transition changes the status of the swap with the given id and version within a transaction and records the corresponding event.
+// The swap is locked, so the status rules are validated against its latest state.
+// Returns ErrRecordNotFound if the swap does not exist,
+// ErrEditConflict if the swap has been modified since the given version was retrieved,
+// an error wrapping ErrInvalidSwapStatusTransition if the transition is not possible.
+func (s *SwapModel) Transition(id int64, version int32, status string, actorUserID int64, note string) (swap *Swap, err error) {
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	tx, err := s.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err != nil {
+			err = errors.Join(err, tx.Rollback())
 		}
+	}()
+
+	swap, err = getSwapForUpdate(ctx, tx, id)
+	if err != nil {
+		return nil, err
 	}
-	return nil
+	if swap.Version != version {
+		return nil, ErrEditConflict
+	}
+
+	err = transitionSwap(ctx, tx, swap, status, actorUserID, note)
+	if err != nil {
+		return nil, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return nil, err
+	}
+
+	return swap, nil
+}
+
+// CounterOffer supersedes the swap with the given id with the given counter swap within a transaction.
+// The counter swap is linked to the superseded swap, both changes are recorded in the history of the swaps.
+// Returns ErrRecordNotFound if the swap or any of the counter swap instruments does not exist,
+// an error wrapping ErrInvalidSwapStatusTransition if the swap can not be superseded,
+// ErrRequesterInstrumentAlreadySwapped or ErrRecipientInstrumentAlreadySwapped if an instrument is already in another active swap,
+// ErrRequesterInstrumentDisputed or ErrRecipientInstrumentDisputed if an instrument is under an open dispute.
+func (s *SwapModel) CounterOffer(id int64, counter *Swap, actorUserID int64, note string) (err error) {
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	tx, err := s.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			err = errors.Join(err, tx.Rollback())
+		}
+	}()
+
+	original, err := getSwapForUpdate(ctx, tx, id)
+	if err != nil {
+		return err
+	}
+
+	err = transitionSwap(ctx, tx, original, SwapStatusSuperseded, actorUserID, "")
+	if err != nil {
+		return err
+	}
+
+	counter.ParentSwapID = &original.ID
+
+	err = insertSwap(ctx, tx, counter, actorUserID, note)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// ExpirePending expires all pending swaps that were created before the given time, and records their expired events.
+// Returns the number of the expired swaps.
+func (s *SwapModel) ExpirePending(createdBefore time.Time) (int64, error) {
+
+	query := `
+		WITH expired AS (
+			UPDATE swaps
+				SET is_expired = TRUE,
+						expired_at = NOW(),
+						is_ended = TRUE,
+						ended_at = NOW(),
+						version = version + 1
+			WHERE NOT is_accepted
+			  AND NOT is_ended
+			  AND created_at < $1
+			RETURNING id
+		)
+		INSERT INTO swap_events (swap_id, type)
+			SELECT id, $2 FROM expired`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	result, err := s.DB.ExecContext(ctx, query, createdBefore, SwapEventExpired)
+	if err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected()
+}
+
+// FlagOverdue flags all accepted swaps that are not ended before their return by date passed the given time,
+// and records their overdue events. Returns the number of the flagged swaps.
+func (s *SwapModel) FlagOverdue(at time.Time) (int64, error) {
+
+	query := `
+		WITH overdue AS (
+			UPDATE swaps
+				SET is_overdue = TRUE,
+						overdue_at = NOW(),
+						version = version + 1
+			WHERE is_accepted
+			  AND NOT is_ended
+			  AND NOT is_overdue
+			  AND return_by < $1
+			RETURNING id
+		)
+		INSERT INTO swap_events (swap_id, type)
+			SELECT id, $2 FROM overdue`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	result, err := s.DB.ExecContext(ctx, query, at, SwapEventOverdue)
+	if err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected()
 }
@@ -2,6 +2,7 @@ package data
 
 import (
 	"context"
+	"crypto/sha256"
 	"database/sql"
 	"errors"
 	"time"
@@ -21,13 +22,15 @@ var AnonymousUser = &User{}
 
 // User struct represents an user.
 type User struct {
-	ID        int64     `json:"id"`
-	CreatedAt time.Time `json:"created_at"`
-	Name      string    `json:"name"`
-	Email     string    `json:"email"`
-	Password  password  `json:"-"`
-	Activated bool      `json:"activated"`
-	Version   int       `json:"-"`
+	ID          int64       `json:"id"`
+	CreatedAt   time.Time   `json:"created_at"`
+	Name        string      `json:"name"`
+	Email       string      `json:"email"`
+	Password    password    `json:"-"`
+	Activated   bool        `json:"activated"`
+	Reputation  Reputation  `json:"reputation"`
+	Version     int         `json:"-"`
+	Permissions Permissions `json:"-"`
 }
 
 // IsAnonymous returns true if the given user is AnonymousUser.
@@ -98,6 +101,11 @@ func ValidateUser(v *validator.Validator, user *User) {
 	}
 }
 
+// userReputationColumns selects the aggregated reputation of the user, in the order expected by the user list scan.
+// The single user queries leave it out, the handlers returning a user load the reputation separately.
+const userReputationColumns = `(SELECT ROUND(COALESCE(avg(reviews.rating), 0), 2) FROM reviews WHERE reviews.reviewee_user_id = users.id),
+		(SELECT count(*) FROM reviews WHERE reviews.reviewee_user_id = users.id)`
+
 // UserModel represents the user model, that stores users in a database.
 type UserModel struct {
 	DB *sql.DB
@@ -132,7 +140,7 @@ func (m *UserModel) Insert(user *User) error {
 // GetAll retrieves all users from the database.
 func (m *UserModel) GetAll() (users []*User, err error) {
 	query := `
-	SELECT id, created_at, name, email, password_hash, activated, version
+	SELECT id, created_at, name, email, password_hash, activated, version, ` + userReputationColumns + `
 		FROM users
 	WHERE is_deleted = FALSE`
 
@@ -164,6 +172,8 @@ func (m *UserModel) GetAll() (users []*User, err error) {
 			&user.Password.hash,
 			&user.Activated,
 			&user.Version,
+			&user.Reputation.Average,
+			&user.Reputation.Count,
 		)
 
 		if err != nil {
@@ -254,6 +264,49 @@ func (m *UserModel) GetByID(id int64) (*User, error) {
 	return &user, nil
 }
 
+// GetForToken retrieves the user that owns the given, non expired token with the given scope.
+// Returns ErrRecordNotFound if no matching user is found.
+func (m *UserModel) GetForToken(tokenScope, tokenPlaintext string) (*User, error) {
+	tokenHash := sha256.Sum256([]byte(tokenPlaintext))
+
+	query := `
+		SELECT users.id, users.created_at, users.name, users.email, users.password_hash, users.activated, users.version
+			FROM users
+			INNER JOIN tokens ON users.id = tokens.user_id
+		WHERE tokens.hash = $1
+			AND tokens.scope = $2
+			AND tokens.expiry > $3
+			AND users.is_deleted = FALSE`
+
+	args := []any{tokenHash[:], tokenScope, time.Now()}
+
+	var user User
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, args...).Scan(
+		&user.ID,
+		&user.CreatedAt,
+		&user.Name,
+		&user.Email,
+		&user.Password.hash,
+		&user.Activated,
+		&user.Version,
+	)
+
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return &user, nil
+}
+
 // Update updates the given user in the databse.
 // Returns ErrDuplicateEmail if the given email is already stored in the database.
 // Returns ErrEditConflict in case of conflicting update.
@@ -293,9 +346,10 @@ func (m *UserModel) Update(user *User) error {
 	return nil
 }
 
-// Delete deletes the user from the database with the given user id.
-// Returns ErrRecordNotFound if the given user is not found.
-func (m *UserModel) Delete(id int64) error {
+// Delete deletes the user from the database with the given user id and version.
+// Returns ErrRecordNotFound if the given user id is not valid,
+// ErrEditConflict if the user has been modified or deleted since the given version was retrieved.
+func (m *UserModel) Delete(id int64, version int) error {
 
 	if id < 0 {
 		return ErrRecordNotFound
@@ -303,14 +357,15 @@ func (m *UserModel) Delete(id int64) error {
 
 	query := `
 		UPDATE users
-			SET is_deleted = true, deleted_at = NOW()
+			SET is_deleted = true, deleted_at = NOW(), version = version + 1
 		WHERE id = $1
+		  AND version = $2
 		  AND is_deleted = FALSE`
 
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
 
-	result, err := m.DB.ExecContext(ctx, query, id)
+	result, err := m.DB.ExecContext(ctx, query, id, version)
 	if err != nil {
 		return err
 	}
@@ -321,7 +376,7 @@ func (m *UserModel) Delete(id int64) error {
 	}
 
 	if rowsAffected == 0 {
-		return ErrRecordNotFound
+		return ErrEditConflict
 	}
 
 	return nil
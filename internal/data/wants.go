@@ -0,0 +1,132 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/ttarnok/instrument-swap-api/internal/validator"
+)
+
+// Want represents an instrument a user would like to get in a swap.
+// A want can describe a specific instrument, an instrument type, a manufacturer or any combination of these.
+type Want struct {
+	ID             int64     `json:"id"`
+	UserID         int64     `json:"user_id"`
+	CreatedAt      time.Time `json:"created_at"`
+	InstrumentType string    `json:"instrument_type"`
+	Manufacturer   string    `json:"manufacturer"`
+	InstrumentID   *int64    `json:"instrument_id"`
+}
+
+// ValidateWant checks the validity of a want,
+// adds all found validation errors into the validator.
+func ValidateWant(v *validator.Validator, want *Want) {
+	v.Check(want.InstrumentType != "" || want.Manufacturer != "" || want.InstrumentID != nil, "want", "must provide an instrument_type, a manufacturer or an instrument_id")
+
+	v.Check(len(want.InstrumentType) <= 100, "instrument_type", "must not be more than 100 bytes long")
+	v.Check(len(want.Manufacturer) <= 500, "manufacturer", "must not be more than 500 bytes long")
+
+	if want.InstrumentID != nil {
+		v.Check(*want.InstrumentID > 0, "instrument_id", "must be a positive integer")
+	}
+}
+
+// WantModel represents the want model, that stores the wants of the users in a database.
+type WantModel struct {
+	DB *sql.DB
+}
+
+// Insert stores the given want.
+func (m *WantModel) Insert(want *Want) error {
+	query := `
+		INSERT INTO wants (user_id, instrument_type, manufacturer, instrument_id)
+			VALUES ($1, $2, $3, $4)
+		RETURNING id, created_at`
+
+	args := []any{want.UserID, want.InstrumentType, want.Manufacturer, want.InstrumentID}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	return m.DB.QueryRowContext(ctx, query, args...).Scan(&want.ID, &want.CreatedAt)
+}
+
+// GetAllForUser retrieves the wants of the given user, ordered by their ids.
+func (m *WantModel) GetAllForUser(userID int64) ([]*Want, error) {
+	query := `
+		SELECT id, user_id, created_at, instrument_type, manufacturer, instrument_id
+		FROM wants
+		WHERE user_id = $1
+		ORDER BY id`
+
+	return m.getAll(query, userID)
+}
+
+// getAll retrieves the wants selected by the given query.
+func (m *WantModel) getAll(query string, args ...any) (wants []*Want, err error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		errClose := rows.Close()
+		if err == nil {
+			err = errClose
+		}
+	}()
+
+	wants = []*Want{}
+
+	for rows.Next() {
+		var want Want
+
+		err := rows.Scan(&want.ID, &want.UserID, &want.CreatedAt, &want.InstrumentType, &want.Manufacturer, &want.InstrumentID)
+		if err != nil {
+			return nil, err
+		}
+
+		wants = append(wants, &want)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return wants, nil
+}
+
+// Delete removes the want with the given id of the given user.
+// Returns ErrRecordNotFound if the user has no want with the given id.
+func (m *WantModel) Delete(id int64, userID int64) error {
+	if id < 1 {
+		return ErrRecordNotFound
+	}
+
+	query := `
+		DELETE FROM wants
+		WHERE id = $1
+		  AND user_id = $2`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, query, id, userID)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+
+	return nil
+}
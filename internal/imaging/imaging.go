@@ -0,0 +1,311 @@
+// Package imaging prepares the uploaded images for storage.
+// The images are re-encoded, so no metadata of the uploaded files (e.g. EXIF location data) is kept.
+package imaging
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"image"
+	"image/draw"
+	"image/jpeg"
+	"image/png"
+	"net/http"
+	"runtime"
+)
+
+// Image related errors.
+// These errors can be tested using errors.Is.
+var (
+	ErrUnsupportedFormat = errors.New("unsupported image format")       // "unsupported image format"
+	ErrInvalidImage      = errors.New("invalid image")                  // "invalid image"
+	ErrTooLarge          = errors.New("image dimensions are too large") // "image dimensions are too large"
+)
+
+// Supported content types.
+const (
+	ContentTypeJPEG = "image/jpeg"
+	ContentTypePNG  = "image/png"
+)
+
+// Image dimension limits.
+const (
+	MaxDimension  = 8000       // the maximum width and height of the processed images in pixels
+	MaxPixels     = 24_000_000 // the maximum number of pixels of the processed images
+	ThumbnailSize = 320        // the maximum width and height of the thumbnails in pixels
+)
+
+// jpegQuality is the quality of the encoded JPEG images.
+const jpegQuality = 90
+
+// slots limits the number of the images processed at the same time, so the memory used by the decoded images is bounded.
+var slots = make(chan struct{}, runtime.GOMAXPROCS(0))
+
+// Image is an encoded image.
+type Image struct {
+	ContentType string
+	Data        []byte
+	Width       int
+	Height      int
+}
+
+// Process prepares the given uploaded image for storage.
+// The content type is sniffed from the data, only JPEG and PNG images are accepted.
+// JPEG images are rotated according to their EXIF orientation, then every image is re-encoded in its original format
+// without its metadata. The returned thumbnail fits into a ThumbnailSize square, smaller images are not enlarged.
+// At most GOMAXPROCS images are decoded at the same time, the other calls wait for their turn.
+// Returns ErrUnsupportedFormat, ErrInvalidImage or ErrTooLarge if the image can not be processed.
+func Process(data []byte) (photo *Image, thumbnail *Image, err error) {
+	contentType := http.DetectContentType(data)
+	if contentType != ContentTypeJPEG && contentType != ContentTypePNG {
+		return nil, nil, ErrUnsupportedFormat
+	}
+
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, nil, ErrInvalidImage
+	}
+	if cfg.Width > MaxDimension || cfg.Height > MaxDimension || cfg.Width*cfg.Height > MaxPixels {
+		return nil, nil, ErrTooLarge
+	}
+
+	slots <- struct{}{}
+	defer func() { <-slots }()
+
+	var img image.Image
+	switch contentType {
+	case ContentTypeJPEG:
+		img, err = jpeg.Decode(bytes.NewReader(data))
+	default:
+		img, err = png.Decode(bytes.NewReader(data))
+	}
+	if err != nil {
+		return nil, nil, ErrInvalidImage
+	}
+
+	if contentType == ContentTypeJPEG {
+		if orientation := exifOrientation(data); orientation != 1 {
+			img = orient(toRGBA(img), orientation)
+		}
+	}
+
+	photo, err = encode(img, contentType)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// an image fitting into the thumbnail is its own thumbnail
+	if img.Bounds().Dx() <= ThumbnailSize && img.Bounds().Dy() <= ThumbnailSize {
+		return photo, photo, nil
+	}
+
+	thumbnail, err = encode(resize(toRGBA(img), ThumbnailSize), contentType)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return photo, thumbnail, nil
+}
+
+// encode encodes the given image in the format of the given content type.
+func encode(img image.Image, contentType string) (*Image, error) {
+	var buf bytes.Buffer
+
+	var err error
+	switch contentType {
+	case ContentTypeJPEG:
+		err = jpeg.Encode(&buf, img, &jpeg.Options{Quality: jpegQuality})
+	default:
+		err = png.Encode(&buf, img)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &Image{
+		ContentType: contentType,
+		Data:        buf.Bytes(),
+		Width:       img.Bounds().Dx(),
+		Height:      img.Bounds().Dy(),
+	}, nil
+}
+
+// toRGBA converts the given image into an RGBA image with its origin at 0, 0.
+// Returns the image itself if it is already such an image.
+func toRGBA(img image.Image) *image.RGBA {
+	if rgba, ok := img.(*image.RGBA); ok && rgba.Bounds().Min == (image.Point{}) {
+		return rgba
+	}
+
+	bounds := img.Bounds()
+	dst := image.NewRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	draw.Draw(dst, dst.Bounds(), img, bounds.Min, draw.Src)
+	return dst
+}
+
+// resize scales the given image down with area averaging, to fit into a size x size square.
+// Returns the image itself if it already fits.
+func resize(src *image.RGBA, size int) *image.RGBA {
+	sw, sh := src.Bounds().Dx(), src.Bounds().Dy()
+	if sw <= size && sh <= size {
+		return src
+	}
+
+	tw, th := size, max(1, sh*size/sw)
+	if sh > sw {
+		tw, th = max(1, sw*size/sh), size
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, tw, th))
+
+	for ty := range th {
+		y0 := ty * sh / th
+		y1 := max((ty+1)*sh/th, y0+1)
+
+		for tx := range tw {
+			x0 := tx * sw / tw
+			x1 := max((tx+1)*sw/tw, x0+1)
+
+			var sum [4]int
+			for y := y0; y < y1; y++ {
+				offset := src.PixOffset(x0, y)
+				for x := x0; x < x1; x++ {
+					for c := range 4 {
+						sum[c] += int(src.Pix[offset+c])
+					}
+					offset += 4
+				}
+			}
+
+			n := (y1 - y0) * (x1 - x0)
+			offset := dst.PixOffset(tx, ty)
+			for c := range 4 {
+				dst.Pix[offset+c] = uint8(sum[c] / n) //nolint:gosec // The average of uint8 values fits into an uint8.
+			}
+		}
+	}
+
+	return dst
+}
+
+// orient transforms the given image according to the given EXIF orientation, so it is displayed upright.
+func orient(src *image.RGBA, orientation int) *image.RGBA {
+	w, h := src.Bounds().Dx(), src.Bounds().Dy()
+
+	dw, dh := w, h
+	if orientation >= 5 {
+		dw, dh = h, w
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, dw, dh))
+
+	for y := range dh {
+		for x := range dw {
+			var sx, sy int
+			switch orientation {
+			case 2:
+				sx, sy = w-1-x, y
+			case 3:
+				sx, sy = w-1-x, h-1-y
+			case 4:
+				sx, sy = x, h-1-y
+			case 5:
+				sx, sy = y, x
+			case 6:
+				sx, sy = y, h-1-x
+			case 7:
+				sx, sy = w-1-y, h-1-x
+			case 8:
+				sx, sy = w-1-y, x
+			default:
+				sx, sy = x, y
+			}
+			copy(dst.Pix[dst.PixOffset(x, y):dst.PixOffset(x, y)+4], src.Pix[src.PixOffset(sx, sy):src.PixOffset(sx, sy)+4])
+		}
+	}
+
+	return dst
+}
+
+// exifOrientation returns the EXIF orientation of the given JPEG data, between 1 and 8.
+// Returns 1, the upright orientation, if the data has no valid orientation tag.
+func exifOrientation(data []byte) int {
+	if len(data) < 4 || data[0] != 0xFF || data[1] != 0xD8 {
+		return 1
+	}
+
+	for i := 2; i+4 <= len(data); {
+		if data[i] != 0xFF {
+			return 1
+		}
+		marker := data[i+1]
+		if marker == 0xFF {
+			// Fill byte.
+			i++
+			continue
+		}
+		if marker == 0xDA || marker == 0xD9 {
+			// The metadata segments precede the image data.
+			return 1
+		}
+
+		length := int(binary.BigEndian.Uint16(data[i+2:]))
+		if length < 2 || i+2+length > len(data) {
+			return 1
+		}
+
+		segment := data[i+4 : i+2+length]
+		if marker == 0xE1 && bytes.HasPrefix(segment, []byte("Exif\x00\x00")) {
+			return tiffOrientation(segment[6:])
+		}
+
+		i += 2 + length
+	}
+
+	return 1
+}
+
+// tiffOrientation returns the orientation tag of the first image file directory of the given TIFF data.
+// Returns 1 if the data has no valid orientation tag.
+func tiffOrientation(tiff []byte) int {
+	if len(tiff) < 8 {
+		return 1
+	}
+
+	var order binary.ByteOrder
+	switch string(tiff[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return 1
+	}
+	if order.Uint16(tiff[2:]) != 42 {
+		return 1
+	}
+
+	ifd := int(order.Uint32(tiff[4:]))
+	if ifd < 8 || ifd+2 > len(tiff) {
+		return 1
+	}
+
+	entries := int(order.Uint16(tiff[ifd:]))
+	for k := range entries {
+		entry := ifd + 2 + 12*k
+		if entry+12 > len(tiff) {
+			return 1
+		}
+
+		const orientationTag, shortType = 0x0112, 3
+		if order.Uint16(tiff[entry:]) == orientationTag && order.Uint16(tiff[entry+2:]) == shortType {
+			orientation := int(order.Uint16(tiff[entry+8:]))
+			if orientation < 1 || orientation > 8 {
+				return 1
+			}
+			return orientation
+		}
+	}
+
+	return 1
+}
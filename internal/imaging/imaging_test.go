@@ -0,0 +1,239 @@
+package imaging
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"sync"
+	"testing"
+)
+
+// halfRedImage returns an image of the given size with a red left half and a blue right half.
+func halfRedImage(width int, height int) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := range height {
+		for x := range width {
+			c := color.RGBA{B: 255, A: 255}
+			if x < width/2 {
+				c = color.RGBA{R: 255, A: 255}
+			}
+			img.Set(x, y, c)
+		}
+	}
+	return img
+}
+
+// encodeJPEG encodes the given image as JPEG, with an EXIF segment holding the given orientation if it is not 0.
+func encodeJPEG(t *testing.T, img image.Image, orientation byte) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: 100})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if orientation == 0 {
+		return buf.Bytes()
+	}
+
+	tiff := []byte{
+		'M', 'M', 0x00, 0x2A, 0x00, 0x00, 0x00, 0x08, // big endian header, first IFD at offset 8
+		0x00, 0x01, // one entry
+		0x01, 0x12, 0x00, 0x03, 0x00, 0x00, 0x00, 0x01, 0x00, orientation, 0x00, 0x00, // orientation, SHORT, count 1
+		0x00, 0x00, 0x00, 0x00, // no next IFD
+	}
+	payload := append([]byte("Exif\x00\x00"), tiff...)
+	length := len(payload) + 2
+	segment := append([]byte{0xFF, 0xE1, byte(length >> 8), byte(length)}, payload...)
+
+	data := buf.Bytes()
+	return append(append(append([]byte{}, data[:2]...), segment...), data[2:]...)
+}
+
+// encodePNG encodes the given image as PNG.
+func encodePNG(t *testing.T, img image.Image) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	err := png.Encode(&buf, img)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// TestProcess implements unit tests for Process.
+func TestProcess(t *testing.T) {
+
+	type testCase struct {
+		name                string
+		data                []byte
+		expectedErr         error
+		expectedContentType string
+		expectedSize        image.Point
+		expectedThumbSize   image.Point
+	}
+
+	testCases := []testCase{
+		{
+			name:                "jpeg",
+			data:                encodeJPEG(t, halfRedImage(640, 480), 0),
+			expectedContentType: ContentTypeJPEG,
+			expectedSize:        image.Pt(640, 480),
+			expectedThumbSize:   image.Pt(320, 240),
+		},
+		{
+			name:                "png",
+			data:                encodePNG(t, halfRedImage(200, 800)),
+			expectedContentType: ContentTypePNG,
+			expectedSize:        image.Pt(200, 800),
+			expectedThumbSize:   image.Pt(80, 320),
+		},
+		{
+			name:                "small image is not enlarged",
+			data:                encodePNG(t, halfRedImage(16, 8)),
+			expectedContentType: ContentTypePNG,
+			expectedSize:        image.Pt(16, 8),
+			expectedThumbSize:   image.Pt(16, 8),
+		},
+		{
+			name:                "rotated jpeg",
+			data:                encodeJPEG(t, halfRedImage(640, 480), 6),
+			expectedContentType: ContentTypeJPEG,
+			expectedSize:        image.Pt(480, 640),
+			expectedThumbSize:   image.Pt(240, 320),
+		},
+		{
+			name:        "text",
+			data:        []byte("definitely not an image"),
+			expectedErr: ErrUnsupportedFormat,
+		},
+		{
+			name:        "gif",
+			data:        []byte("GIF89a\x01\x00\x01\x00\x00\x00\x00;"),
+			expectedErr: ErrUnsupportedFormat,
+		},
+		{
+			name:        "truncated jpeg",
+			data:        encodeJPEG(t, halfRedImage(64, 64), 0)[:200],
+			expectedErr: ErrInvalidImage,
+		},
+		{
+			name:        "too wide",
+			data:        encodePNG(t, image.NewGray(image.Rect(0, 0, MaxDimension+1, 1))),
+			expectedErr: ErrTooLarge,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			photo, thumbnail, err := Process(tc.data)
+			if tc.expectedErr != nil {
+				if !errors.Is(err, tc.expectedErr) {
+					t.Fatalf(`expected error %v, got %v`, tc.expectedErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			for _, img := range []*Image{photo, thumbnail} {
+				if img.ContentType != tc.expectedContentType {
+					t.Errorf(`expected content type %q, got %q`, tc.expectedContentType, img.ContentType)
+				}
+
+				cfg, _, err := image.DecodeConfig(bytes.NewReader(img.Data))
+				if err != nil {
+					t.Fatal(err)
+				}
+				if cfg.Width != img.Width || cfg.Height != img.Height {
+					t.Errorf(`expected encoded size %dx%d, got %dx%d`, img.Width, img.Height, cfg.Width, cfg.Height)
+				}
+
+				if bytes.Contains(img.Data, []byte("Exif")) {
+					t.Error(`expected the EXIF metadata to be stripped`)
+				}
+			}
+
+			if photo.Width != tc.expectedSize.X || photo.Height != tc.expectedSize.Y {
+				t.Errorf(`expected size %v, got %dx%d`, tc.expectedSize, photo.Width, photo.Height)
+			}
+			if thumbnail.Width != tc.expectedThumbSize.X || thumbnail.Height != tc.expectedThumbSize.Y {
+				t.Errorf(`expected thumbnail size %v, got %dx%d`, tc.expectedThumbSize, thumbnail.Width, thumbnail.Height)
+			}
+		})
+	}
+}
+
+// TestProcessOrientation checks that the rotated JPEG images are turned upright.
+func TestProcessOrientation(t *testing.T) {
+	// Rotating clockwise turns the red left half into the top half.
+	photo, _, err := Process(encodeJPEG(t, halfRedImage(64, 32), 6))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	img, err := jpeg.Decode(bytes.NewReader(photo.Data))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	isRed := func(c color.Color) bool {
+		r, g, b, _ := c.RGBA()
+		return r > 0xC000 && g < 0x4000 && b < 0x4000
+	}
+
+	if !isRed(img.At(16, 8)) {
+		t.Errorf(`expected the top half to be red, got %v`, img.At(16, 8))
+	}
+	if isRed(img.At(16, 56)) {
+		t.Errorf(`expected the bottom half not to be red, got %v`, img.At(16, 56))
+	}
+}
+
+// TestToRGBA checks that the RGBA images are not copied, while the other images are converted.
+func TestToRGBA(t *testing.T) {
+	rgba := halfRedImage(16, 8)
+	if toRGBA(rgba) != rgba {
+		t.Error(`expected an RGBA image to be returned as it is`)
+	}
+
+	gray := image.NewGray(image.Rect(4, 4, 20, 12))
+	converted := toRGBA(gray)
+	if converted.Bounds() != image.Rect(0, 0, 16, 8) {
+		t.Errorf(`expected the converted image to have bounds %v, got %v`, image.Rect(0, 0, 16, 8), converted.Bounds())
+	}
+}
+
+// TestProcessConcurrently checks that the images processed at the same time wait for each other and all of them are processed.
+func TestProcessConcurrently(t *testing.T) {
+	data := encodeJPEG(t, halfRedImage(640, 480), 6)
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 4*cap(slots))
+
+	for range cap(errs) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, _, err := Process(data)
+			errs <- err
+		}()
+	}
+
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		if err != nil {
+			t.Error(err)
+		}
+	}
+	if len(slots) != 0 {
+		t.Errorf(`expected every processing slot to be released, got %d taken`, len(slots))
+	}
+}
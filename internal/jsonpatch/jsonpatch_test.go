@@ -0,0 +1,255 @@
+package jsonpatch
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+// assertJSONEqual reports an error if the given JSON documents are not equal.
+func assertJSONEqual(t *testing.T, expected string, got []byte) {
+	t.Helper()
+
+	var e, g any
+	err := json.Unmarshal([]byte(expected), &e)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = json.Unmarshal(got, &g)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	eb, _ := json.Marshal(e)
+	gb, _ := json.Marshal(g)
+	if !bytes.Equal(eb, gb) {
+		t.Errorf(`expected document %s, got %s`, eb, gb)
+	}
+}
+
+// TestMergePatch implements unit tests for MergePatch, the cases are based on the examples of RFC 7396.
+func TestMergePatch(t *testing.T) {
+
+	type testCase struct {
+		name     string
+		doc      string
+		patch    string
+		expected string
+	}
+
+	testCases := []testCase{
+		{name: "replace member", doc: `{"a":"b"}`, patch: `{"a":"c"}`, expected: `{"a":"c"}`},
+		{name: "add member", doc: `{"a":"b"}`, patch: `{"b":"c"}`, expected: `{"a":"b","b":"c"}`},
+		{name: "remove member", doc: `{"a":"b"}`, patch: `{"a":null}`, expected: `{}`},
+		{name: "remove one of the members", doc: `{"a":"b","b":"c"}`, patch: `{"a":null}`, expected: `{"b":"c"}`},
+		{name: "replace array", doc: `{"a":["b"]}`, patch: `{"a":"c"}`, expected: `{"a":"c"}`},
+		{name: "replace with array", doc: `{"a":"c"}`, patch: `{"a":["b"]}`, expected: `{"a":["b"]}`},
+		{name: "nested objects", doc: `{"a":{"b":"c"}}`, patch: `{"a":{"b":"d","c":null}}`, expected: `{"a":{"b":"d"}}`},
+		{name: "arrays are not merged", doc: `{"a":[{"b":"c"}]}`, patch: `{"a":[1]}`, expected: `{"a":[1]}`},
+		{name: "non object patch", doc: `{"a":"foo"}`, patch: `"bar"`, expected: `"bar"`},
+		{name: "null member of a new object", doc: `{"e":null}`, patch: `{"a":{"bb":{"ccc":null}}}`, expected: `{"e":null,"a":{"bb":{}}}`},
+		{name: "large numbers are kept", doc: `{"a":1}`, patch: `{"b":12345678901234567890}`, expected: `{"a":1,"b":12345678901234567890}`},
+		{name: "empty patch", doc: `{"a":"b"}`, patch: `{}`, expected: `{"a":"b"}`},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := MergePatch([]byte(tc.doc), []byte(tc.patch))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			assertJSONEqual(t, tc.expected, got)
+		})
+	}
+
+	t.Run("invalid patch", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := MergePatch([]byte(`{}`), []byte(`{"a":`))
+		if !errors.Is(err, ErrInvalidPatch) {
+			t.Errorf(`expected error %v, got %v`, ErrInvalidPatch, err)
+		}
+	})
+}
+
+// TestApply implements unit tests for Apply, the cases are based on the examples of RFC 6902.
+func TestApply(t *testing.T) {
+
+	type testCase struct {
+		name          string
+		doc           string
+		patch         string
+		expected      string
+		expectedError error
+	}
+
+	testCases := []testCase{
+		{
+			name:     "add object member",
+			doc:      `{"foo":"bar"}`,
+			patch:    `[{"op":"add","path":"/baz","value":"qux"}]`,
+			expected: `{"baz":"qux","foo":"bar"}`,
+		},
+		{
+			name:     "add array element",
+			doc:      `{"foo":["bar","baz"]}`,
+			patch:    `[{"op":"add","path":"/foo/1","value":"qux"}]`,
+			expected: `{"foo":["bar","qux","baz"]}`,
+		},
+		{
+			name:     "append array element",
+			doc:      `{"foo":["bar"]}`,
+			patch:    `[{"op":"add","path":"/foo/-","value":["abc","def"]}]`,
+			expected: `{"foo":["bar",["abc","def"]]}`,
+		},
+		{
+			name:     "add null value",
+			doc:      `{"foo":"bar"}`,
+			patch:    `[{"op":"add","path":"/foo","value":null}]`,
+			expected: `{"foo":null}`,
+		},
+		{
+			name:     "remove object member",
+			doc:      `{"baz":"qux","foo":"bar"}`,
+			patch:    `[{"op":"remove","path":"/baz"}]`,
+			expected: `{"foo":"bar"}`,
+		},
+		{
+			name:     "remove array element",
+			doc:      `{"foo":["bar","qux","baz"]}`,
+			patch:    `[{"op":"remove","path":"/foo/1"}]`,
+			expected: `{"foo":["bar","baz"]}`,
+		},
+		{
+			name:     "replace value",
+			doc:      `{"baz":"qux","foo":"bar"}`,
+			patch:    `[{"op":"replace","path":"/baz","value":"boo"}]`,
+			expected: `{"baz":"boo","foo":"bar"}`,
+		},
+		{
+			name:     "move value",
+			doc:      `{"foo":{"bar":"baz","waldo":"fred"},"qux":{"corge":"grault"}}`,
+			patch:    `[{"op":"move","from":"/foo/waldo","path":"/qux/thud"}]`,
+			expected: `{"foo":{"bar":"baz"},"qux":{"corge":"grault","thud":"fred"}}`,
+		},
+		{
+			name:     "move array element",
+			doc:      `{"foo":["all","grass","cows","eat"]}`,
+			patch:    `[{"op":"move","from":"/foo/1","path":"/foo/3"}]`,
+			expected: `{"foo":["all","cows","eat","grass"]}`,
+		},
+		{
+			name:     "copy value",
+			doc:      `{"foo":{"bar":1}}`,
+			patch:    `[{"op":"copy","from":"/foo","path":"/baz"},{"op":"replace","path":"/baz/bar","value":2}]`,
+			expected: `{"foo":{"bar":1},"baz":{"bar":2}}`,
+		},
+		{
+			name:     "successful test",
+			doc:      `{"baz":"qux","foo":["a",2,"c"]}`,
+			patch:    `[{"op":"test","path":"/baz","value":"qux"},{"op":"test","path":"/foo/1","value":2.0}]`,
+			expected: `{"baz":"qux","foo":["a",2,"c"]}`,
+		},
+		{
+			name:          "failed test",
+			doc:           `{"baz":"qux"}`,
+			patch:         `[{"op":"test","path":"/baz","value":"bar"}]`,
+			expectedError: ErrTestFailed,
+		},
+		{
+			name:     "escaped pointer",
+			doc:      `{"/":9,"~1":10}`,
+			patch:    `[{"op":"test","path":"/~01","value":10},{"op":"remove","path":"/~1"}]`,
+			expected: `{"~1":10}`,
+		},
+		{
+			name:     "replace whole document",
+			doc:      `{"foo":"bar"}`,
+			patch:    `[{"op":"replace","path":"","value":{"baz":"qux"}}]`,
+			expected: `{"baz":"qux"}`,
+		},
+		{
+			name:          "add to non existent parent",
+			doc:           `{"foo":"bar"}`,
+			patch:         `[{"op":"add","path":"/baz/bat","value":"qux"}]`,
+			expectedError: ErrPathNotFound,
+		},
+		{
+			name:          "remove non existent member",
+			doc:           `{"foo":"bar"}`,
+			patch:         `[{"op":"remove","path":"/baz"}]`,
+			expectedError: ErrPathNotFound,
+		},
+		{
+			name:          "replace non existent member",
+			doc:           `{"foo":"bar"}`,
+			patch:         `[{"op":"replace","path":"/baz","value":1}]`,
+			expectedError: ErrPathNotFound,
+		},
+		{
+			name:          "array index with leading zero",
+			doc:           `{"foo":["bar","baz"]}`,
+			patch:         `[{"op":"remove","path":"/foo/01"}]`,
+			expectedError: ErrPathNotFound,
+		},
+		{
+			name:          "array index out of range",
+			doc:           `{"foo":["bar"]}`,
+			patch:         `[{"op":"add","path":"/foo/2","value":"baz"}]`,
+			expectedError: ErrPathNotFound,
+		},
+		{
+			name:          "move into a child",
+			doc:           `{"foo":{"bar":1}}`,
+			patch:         `[{"op":"move","from":"/foo","path":"/foo/bar/baz"}]`,
+			expectedError: ErrInvalidPatch,
+		},
+		{
+			name:          "unknown operation",
+			doc:           `{"foo":"bar"}`,
+			patch:         `[{"op":"merge","path":"/foo","value":1}]`,
+			expectedError: ErrInvalidPatch,
+		},
+		{
+			name:          "missing value",
+			doc:           `{"foo":"bar"}`,
+			patch:         `[{"op":"add","path":"/baz"}]`,
+			expectedError: ErrInvalidPatch,
+		},
+		{
+			name:          "invalid pointer",
+			doc:           `{"foo":"bar"}`,
+			patch:         `[{"op":"remove","path":"foo"}]`,
+			expectedError: ErrInvalidPatch,
+		},
+		{
+			name:          "not an array of operations",
+			doc:           `{"foo":"bar"}`,
+			patch:         `{"op":"remove","path":"/foo"}`,
+			expectedError: ErrInvalidPatch,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := Apply([]byte(tc.doc), []byte(tc.patch))
+			if tc.expectedError != nil {
+				if !errors.Is(err, tc.expectedError) {
+					t.Errorf(`expected error %v, got %v`, tc.expectedError, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			assertJSONEqual(t, tc.expected, got)
+		})
+	}
+}
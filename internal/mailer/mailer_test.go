@@ -0,0 +1,256 @@
+package mailer
+
+import (
+	"bufio"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net"
+	"net/mail"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// fakeSMTPMessage represents a message received by fakeSMTPServer.
+type fakeSMTPMessage struct {
+	from string
+	to   []string
+	data string
+}
+
+// fakeSMTPServer is a minimal SMTP server for testing purposes, it records every received message.
+type fakeSMTPServer struct {
+	listener net.Listener
+	messages []fakeSMTPMessage
+	wg       sync.WaitGroup
+	sync.Mutex
+}
+
+// newFakeSMTPServer starts a new fakeSMTPServer on a random local port.
+func newFakeSMTPServer(t *testing.T) *fakeSMTPServer {
+	t.Helper()
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := &fakeSMTPServer{listener: l}
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			s.wg.Add(1)
+			go func() {
+				defer s.wg.Done()
+				s.handle(conn)
+			}()
+		}
+	}()
+
+	return s
+}
+
+// port returns the port the server is listening on.
+func (s *fakeSMTPServer) port(t *testing.T) int {
+	t.Helper()
+
+	_, port, err := net.SplitHostPort(s.listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, err := strconv.Atoi(port)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+// close stops the server and waits for all connections to finish.
+func (s *fakeSMTPServer) close() {
+	_ = s.listener.Close()
+	s.wg.Wait()
+}
+
+// received returns the messages received by the server.
+func (s *fakeSMTPServer) received() []fakeSMTPMessage {
+	s.Lock()
+	defer s.Unlock()
+	return s.messages
+}
+
+// handle serves a single SMTP session.
+func (s *fakeSMTPServer) handle(conn net.Conn) {
+	defer func() {
+		_ = conn.Close()
+	}()
+
+	r := bufio.NewReader(conn)
+	reply := func(line string) {
+		_, _ = io.WriteString(conn, line+"\r\n")
+	}
+
+	var msg fakeSMTPMessage
+
+	reply("220 localhost fake SMTP")
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		line = strings.TrimRight(line, "\r\n")
+		cmd := strings.ToUpper(line)
+
+		switch {
+		case strings.HasPrefix(cmd, "EHLO"), strings.HasPrefix(cmd, "HELO"):
+			reply("250 localhost")
+		case strings.HasPrefix(cmd, "MAIL FROM:"):
+			msg.from = strings.Trim(line[len("MAIL FROM:"):], "<> ")
+			reply("250 OK")
+		case strings.HasPrefix(cmd, "RCPT TO:"):
+			msg.to = append(msg.to, strings.Trim(line[len("RCPT TO:"):], "<> "))
+			reply("250 OK")
+		case cmd == "DATA":
+			reply("354 End data with <CR><LF>.<CR><LF>")
+			var sb strings.Builder
+			for {
+				dl, err := r.ReadString('\n')
+				if err != nil {
+					return
+				}
+				if dl == ".\r\n" {
+					break
+				}
+				sb.WriteString(strings.TrimPrefix(dl, "."))
+			}
+			msg.data = sb.String()
+			s.Lock()
+			s.messages = append(s.messages, msg)
+			s.Unlock()
+			msg = fakeSMTPMessage{}
+			reply("250 OK")
+		case cmd == "QUIT":
+			reply("221 Bye")
+			return
+		default:
+			reply("250 OK")
+		}
+	}
+}
+
+// TestSMTPMailerSend tests that SMTPMailer delivers the rendered template to the SMTP server.
+func TestSMTPMailerSend(t *testing.T) {
+	server := newFakeSMTPServer(t)
+	defer server.close()
+
+	m := NewSMTPMailer("127.0.0.1", server.port(t), "", "", "Instrument Swap <no-reply@example.com>")
+
+	data := map[string]any{
+		"activationToken": "ABCDEFGHIJKLMNOPQRSTUVWXYZ",
+		"userID":          42,
+	}
+
+	err := m.Send("johndoe@example.com", "user_welcome.tmpl", data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	messages := server.received()
+	if len(messages) != 1 {
+		t.Fatalf("expected 1 received message, got %d", len(messages))
+	}
+
+	if messages[0].from != "no-reply@example.com" {
+		t.Errorf(`expected envelope sender "no-reply@example.com", got %q`, messages[0].from)
+	}
+	if len(messages[0].to) != 1 || messages[0].to[0] != "johndoe@example.com" {
+		t.Errorf(`expected envelope recipient "johndoe@example.com", got %v`, messages[0].to)
+	}
+
+	parsed, err := mail.ReadMessage(strings.NewReader(messages[0].data))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if parsed.Header.Get("Subject") != "Welcome to Instrument Swap!" {
+		t.Errorf(`expected subject "Welcome to Instrument Swap!", got %q`, parsed.Header.Get("Subject"))
+	}
+
+	mediaType, params, err := mime.ParseMediaType(parsed.Header.Get("Content-Type"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mediaType != "multipart/alternative" {
+		t.Fatalf(`expected multipart/alternative content type, got %q`, mediaType)
+	}
+
+	mr := multipart.NewReader(parsed.Body, params["boundary"])
+	partCount := 0
+	for {
+		part, err := mr.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		partCount++
+
+		body, err := io.ReadAll(part)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(string(body), "ABCDEFGHIJKLMNOPQRSTUVWXYZ") {
+			t.Errorf("expected part %q to contain the activation token", part.Header.Get("Content-Type"))
+		}
+	}
+
+	if partCount != 2 {
+		t.Errorf("expected 2 message parts, got %d", partCount)
+	}
+}
+
+// TestSMTPMailerSendErrors tests the error cases of SMTPMailer.
+func TestSMTPMailerSendErrors(t *testing.T) {
+	server := newFakeSMTPServer(t)
+	defer server.close()
+
+	tests := []struct {
+		name         string
+		sender       string
+		templateFile string
+	}{
+		{
+			name:         "non existent template",
+			sender:       "no-reply@example.com",
+			templateFile: "non_existent.tmpl",
+		},
+		{
+			name:         "invalid sender",
+			sender:       "invalid sender",
+			templateFile: "user_welcome.tmpl",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			m := NewSMTPMailer("127.0.0.1", server.port(t), "", "", tc.sender)
+
+			err := m.Send("johndoe@example.com", tc.templateFile, nil)
+			if err == nil {
+				t.Error("expected an error, got nil")
+			}
+		})
+	}
+
+	if len(server.received()) != 0 {
+		t.Errorf("expected no received messages, got %d", len(server.received()))
+	}
+}
@@ -0,0 +1,214 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// S3Storage stores the blobs as objects in a bucket of an S3 compatible object storage, e.g. AWS S3 or MinIO.
+// The bucket is addressed path style, the requests are signed with AWS Signature Version 4.
+type S3Storage struct {
+	endpoint  *url.URL
+	bucket    string
+	region    string
+	accessKey string
+	secretKey string
+	client    *http.Client
+	now       func() time.Time
+}
+
+// bucketRX is the pattern of the valid bucket names.
+var bucketRX = regexp.MustCompile(`^[a-z0-9][a-z0-9.-]{1,61}[a-z0-9]$`)
+
+// NewS3Storage creates a new S3Storage for the given bucket, the endpoint is the base URL of the object storage,
+// e.g. https://s3.eu-central-1.amazonaws.com or http://localhost:9000.
+func NewS3Storage(endpoint string, bucket string, region string, accessKey string, secretKey string) (*S3Storage, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme == "" || u.Host == "" {
+		return nil, fmt.Errorf("storage: invalid endpoint %q", endpoint)
+	}
+	if !bucketRX.MatchString(bucket) {
+		return nil, fmt.Errorf("storage: invalid bucket name %q", bucket)
+	}
+
+	return &S3Storage{
+		endpoint:  u,
+		bucket:    bucket,
+		region:    region,
+		accessKey: accessKey,
+		secretKey: secretKey,
+		client:    &http.Client{Timeout: 30 * time.Second},
+		now:       time.Now,
+	}, nil
+}
+
+// Put uploads the given data as the object of the given key.
+func (s *S3Storage) Put(ctx context.Context, key string, data []byte, contentType string) (err error) {
+	resp, err := s.do(ctx, http.MethodPut, key, data, contentType)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		errClose := resp.Body.Close()
+		if err == nil {
+			err = errClose
+		}
+	}()
+
+	if resp.StatusCode != http.StatusOK {
+		return unexpectedResponse(resp)
+	}
+
+	return nil
+}
+
+// Get downloads the object of the given key.
+// Returns ErrNotFound if the object does not exist.
+func (s *S3Storage) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	resp, err := s.do(ctx, http.MethodGet, key, nil, "")
+	if err != nil {
+		return nil, err
+	}
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return resp.Body, nil
+	case http.StatusNotFound:
+		return nil, errors.Join(ErrNotFound, resp.Body.Close())
+	default:
+		return nil, errors.Join(unexpectedResponse(resp), resp.Body.Close())
+	}
+}
+
+// Delete removes the object of the given key.
+func (s *S3Storage) Delete(ctx context.Context, key string) (err error) {
+	resp, err := s.do(ctx, http.MethodDelete, key, nil, "")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		errClose := resp.Body.Close()
+		if err == nil {
+			err = errClose
+		}
+	}()
+
+	if resp.StatusCode != http.StatusNoContent && resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNotFound {
+		return unexpectedResponse(resp)
+	}
+
+	return nil
+}
+
+// do sends a signed request for the object of the given key.
+func (s *S3Storage) do(ctx context.Context, method string, key string, body []byte, contentType string) (*http.Response, error) {
+	err := validateKey(key)
+	if err != nil {
+		return nil, err
+	}
+
+	u := *s.endpoint
+	// The valid keys and bucket names contain no characters, that would need escaping.
+	u.Path = strings.TrimSuffix(u.Path, "/") + "/" + s.bucket + "/" + key
+
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.ContentLength = int64(len(body))
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+
+	s.sign(req, body)
+
+	return s.client.Do(req)
+}
+
+// sign adds the AWS Signature Version 4 authorization headers to the given request.
+// Only the host, the x-amz-content-sha256 and the x-amz-date headers are signed.
+func (s *S3Storage) sign(req *http.Request, body []byte) {
+	now := s.now().UTC()
+	amzDate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+
+	payloadHash := sha256Hex(body)
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+
+	scope := date + "/" + s.region + "/s3/aws4_request"
+	signature := signatureV4(req, s.secretKey, date, s.region, payloadHash)
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		s.accessKey, scope, signedHeaders, signature))
+}
+
+// signedHeaders lists the headers signed by S3Storage.sign.
+const signedHeaders = "host;x-amz-content-sha256;x-amz-date"
+
+// signatureV4 calculates the AWS Signature Version 4 of the given request for the s3 service.
+// The request needs to have the X-Amz-Date and the X-Amz-Content-Sha256 headers.
+func signatureV4(req *http.Request, secretKey string, date string, region string, payloadHash string) string {
+	amzDate := req.Header.Get("X-Amz-Date")
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		req.URL.Query().Encode(),
+		"host:" + req.URL.Host + "\n" +
+			"x-amz-content-sha256:" + payloadHash + "\n" +
+			"x-amz-date:" + amzDate + "\n",
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+
+	stringToSign := strings.Join([]string{
+		"AWS4-HMAC-SHA256",
+		amzDate,
+		date + "/" + region + "/s3/aws4_request",
+		sha256Hex([]byte(canonicalRequest)),
+	}, "\n")
+
+	return hex.EncodeToString(hmacSHA256(signingKey(secretKey, date, region, "s3"), stringToSign))
+}
+
+// signingKey derives the AWS Signature Version 4 signing key of the given day, region and service.
+func signingKey(secretKey string, date string, region string, service string) []byte {
+	key := hmacSHA256([]byte("AWS4"+secretKey), date)
+	key = hmacSHA256(key, region)
+	key = hmacSHA256(key, service)
+	return hmacSHA256(key, "aws4_request")
+}
+
+// hmacSHA256 returns the HMAC-SHA256 of the given data with the given key.
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
+
+// sha256Hex returns the hex encoded SHA256 hash of the given data.
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// unexpectedResponse returns an error describing the given unexpected response of the object storage.
+func unexpectedResponse(resp *http.Response) error {
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+	return fmt.Errorf("storage: unexpected response status %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+}
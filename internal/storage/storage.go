@@ -0,0 +1,44 @@
+// Package storage provides blob storage for the uploaded files of the application.
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"regexp"
+	"strings"
+)
+
+// Storage related errors.
+// These errors can be tested using errors.Is.
+var (
+	ErrNotFound   = errors.New("storage: object not found") // "storage: object not found"
+	ErrInvalidKey = errors.New("storage: invalid key")      // "storage: invalid key"
+)
+
+// Storage is an interface for storing blobs under slash separated keys.
+type Storage interface {
+	// Put stores the given data under the given key, overwriting the existing object.
+	Put(ctx context.Context, key string, data []byte, contentType string) error
+	// Get returns the content of the object stored under the given key, the caller has to close it.
+	// Returns ErrNotFound if the object does not exist.
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	// Delete removes the object stored under the given key, deleting a non existent object is not an error.
+	Delete(ctx context.Context, key string) error
+}
+
+// keyRX is the pattern of the valid keys, slash separated segments of letters, digits, dots, hyphens and underscores.
+var keyRX = regexp.MustCompile(`^[A-Za-z0-9._-]+(/[A-Za-z0-9._-]+)*$`)
+
+// validateKey returns ErrInvalidKey if the given key could escape the storage root or would need escaping.
+func validateKey(key string) error {
+	if !keyRX.MatchString(key) {
+		return ErrInvalidKey
+	}
+	for _, segment := range strings.Split(key, "/") {
+		if segment == "." || segment == ".." {
+			return ErrInvalidKey
+		}
+	}
+	return nil
+}
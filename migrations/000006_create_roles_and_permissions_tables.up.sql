@@ -0,0 +1,33 @@
+CREATE TABLE IF NOT EXISTS permissions (
+  id bigserial PRIMARY KEY,
+  code text UNIQUE NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS roles (
+  id bigserial PRIMARY KEY,
+  code text UNIQUE NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS roles_permissions (
+  role_id bigint NOT NULL REFERENCES roles(id) ON DELETE CASCADE,
+  permission_id bigint NOT NULL REFERENCES permissions(id) ON DELETE CASCADE,
+  PRIMARY KEY (role_id, permission_id)
+);
+
+CREATE TABLE IF NOT EXISTS users_roles (
+  user_id bigint NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  role_id bigint NOT NULL REFERENCES roles(id) ON DELETE CASCADE,
+  PRIMARY KEY (user_id, role_id)
+);
+
+INSERT INTO permissions (code)
+  VALUES ('users:read'), ('users:moderate');
+
+INSERT INTO roles (code)
+  VALUES ('admin');
+
+INSERT INTO roles_permissions (role_id, permission_id)
+  SELECT roles.id, permissions.id
+    FROM roles
+    CROSS JOIN permissions
+  WHERE roles.code = 'admin';
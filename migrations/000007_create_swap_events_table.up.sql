@@ -0,0 +1,23 @@
+CREATE TABLE IF NOT EXISTS swap_events (
+  id bigserial PRIMARY KEY,
+  swap_id bigint NOT NULL REFERENCES swaps(id) ON DELETE CASCADE,
+  created_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+  type text NOT NULL,
+  actor_user_id bigint REFERENCES users(id) ON DELETE SET NULL,
+  note text NOT NULL DEFAULT ''
+);
+
+CREATE INDEX IF NOT EXISTS swap_events_swap_id_idx ON swap_events(swap_id);
+
+-- The history of the already existing swaps is reconstructed from their timestamps, the actors are unknown.
+INSERT INTO swap_events (swap_id, created_at, type)
+  SELECT id, created_at, 'created' FROM swaps;
+
+INSERT INTO swap_events (swap_id, created_at, type)
+  SELECT id, accepted_at, 'accepted' FROM swaps WHERE is_accepted;
+
+INSERT INTO swap_events (swap_id, created_at, type)
+  SELECT id, rejected_at, 'rejected' FROM swaps WHERE is_rejected;
+
+INSERT INTO swap_events (swap_id, created_at, type)
+  SELECT id, ended_at, 'ended' FROM swaps WHERE is_ended AND NOT is_rejected;
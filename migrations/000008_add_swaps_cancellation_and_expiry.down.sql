@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS swaps_pending_created_at_idx;
+
+ALTER TABLE swaps DROP COLUMN IF EXISTS expired_at;
+ALTER TABLE swaps DROP COLUMN IF EXISTS is_expired;
+ALTER TABLE swaps DROP COLUMN IF EXISTS cancelled_at;
+ALTER TABLE swaps DROP COLUMN IF EXISTS is_cancelled;
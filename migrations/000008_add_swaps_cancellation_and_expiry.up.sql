@@ -0,0 +1,6 @@
+ALTER TABLE swaps ADD COLUMN IF NOT EXISTS is_cancelled boolean NOT NULL DEFAULT FALSE;
+ALTER TABLE swaps ADD COLUMN IF NOT EXISTS cancelled_at timestamp(0) with time zone;
+ALTER TABLE swaps ADD COLUMN IF NOT EXISTS is_expired boolean NOT NULL DEFAULT FALSE;
+ALTER TABLE swaps ADD COLUMN IF NOT EXISTS expired_at timestamp(0) with time zone;
+
+CREATE INDEX IF NOT EXISTS swaps_pending_created_at_idx ON swaps(created_at) WHERE NOT is_accepted AND NOT is_ended;
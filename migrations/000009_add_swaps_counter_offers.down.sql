@@ -0,0 +1,3 @@
+ALTER TABLE swaps DROP COLUMN IF EXISTS parent_swap_id;
+ALTER TABLE swaps DROP COLUMN IF EXISTS superseded_at;
+ALTER TABLE swaps DROP COLUMN IF EXISTS is_superseded;
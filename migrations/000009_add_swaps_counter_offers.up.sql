@@ -0,0 +1,3 @@
+ALTER TABLE swaps ADD COLUMN IF NOT EXISTS is_superseded boolean NOT NULL DEFAULT FALSE;
+ALTER TABLE swaps ADD COLUMN IF NOT EXISTS superseded_at timestamp(0) with time zone;
+ALTER TABLE swaps ADD COLUMN IF NOT EXISTS parent_swap_id bigint REFERENCES swaps(id) ON DELETE SET NULL;
@@ -0,0 +1,16 @@
+CREATE TABLE IF NOT EXISTS swap_items (
+  swap_id bigint NOT NULL REFERENCES swaps(id) ON DELETE CASCADE,
+  instrument_id bigint NOT NULL REFERENCES instruments(id) ON DELETE RESTRICT,
+  side text NOT NULL CHECK (side IN ('requester', 'recipient')),
+  position smallint NOT NULL,
+  PRIMARY KEY (swap_id, instrument_id)
+);
+
+CREATE INDEX IF NOT EXISTS swap_items_instrument_id_idx ON swap_items(instrument_id);
+
+-- Every already existing swap holds a single instrument on both sides.
+INSERT INTO swap_items (swap_id, instrument_id, side, position)
+  SELECT id, requester_instrument_id, 'requester', 0 FROM swaps;
+
+INSERT INTO swap_items (swap_id, instrument_id, side, position)
+  SELECT id, recipient_instrument_id, 'recipient', 0 FROM swaps;
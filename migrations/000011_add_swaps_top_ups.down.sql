@@ -0,0 +1,7 @@
+ALTER TABLE swap_events DROP COLUMN IF EXISTS top_up_payer;
+ALTER TABLE swap_events DROP COLUMN IF EXISTS top_up_currency;
+ALTER TABLE swap_events DROP COLUMN IF EXISTS top_up_amount;
+
+ALTER TABLE swaps DROP COLUMN IF EXISTS top_up_payer;
+ALTER TABLE swaps DROP COLUMN IF EXISTS top_up_currency;
+ALTER TABLE swaps DROP COLUMN IF EXISTS top_up_amount;
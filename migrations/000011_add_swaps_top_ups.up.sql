@@ -0,0 +1,7 @@
+ALTER TABLE swaps ADD COLUMN IF NOT EXISTS top_up_amount bigint CHECK (top_up_amount > 0);
+ALTER TABLE swaps ADD COLUMN IF NOT EXISTS top_up_currency text;
+ALTER TABLE swaps ADD COLUMN IF NOT EXISTS top_up_payer text CHECK (top_up_payer IN ('requester', 'recipient'));
+
+ALTER TABLE swap_events ADD COLUMN IF NOT EXISTS top_up_amount bigint;
+ALTER TABLE swap_events ADD COLUMN IF NOT EXISTS top_up_currency text;
+ALTER TABLE swap_events ADD COLUMN IF NOT EXISTS top_up_payer text;
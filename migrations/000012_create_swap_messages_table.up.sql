@@ -0,0 +1,10 @@
+CREATE TABLE IF NOT EXISTS swap_messages (
+  id bigserial PRIMARY KEY,
+  swap_id bigint NOT NULL REFERENCES swaps(id) ON DELETE CASCADE,
+  sender_user_id bigint NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  created_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+  body text NOT NULL,
+  read_at timestamp(0) with time zone
+);
+
+CREATE INDEX IF NOT EXISTS swap_messages_swap_id_idx ON swap_messages(swap_id);
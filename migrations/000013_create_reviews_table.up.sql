@@ -0,0 +1,12 @@
+CREATE TABLE IF NOT EXISTS reviews (
+  id bigserial PRIMARY KEY,
+  swap_id bigint NOT NULL REFERENCES swaps(id) ON DELETE CASCADE,
+  reviewer_user_id bigint NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  reviewee_user_id bigint NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  created_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+  rating smallint NOT NULL CHECK (rating BETWEEN 1 AND 5),
+  comment text NOT NULL DEFAULT '',
+  UNIQUE (swap_id, reviewer_user_id)
+);
+
+CREATE INDEX IF NOT EXISTS reviews_reviewee_user_id_idx ON reviews(reviewee_user_id);
@@ -0,0 +1,4 @@
+DELETE FROM permissions WHERE code = 'disputes:moderate';
+
+DROP TABLE IF EXISTS dispute_notes;
+DROP TABLE IF EXISTS disputes;
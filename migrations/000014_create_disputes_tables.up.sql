@@ -0,0 +1,35 @@
+CREATE TABLE IF NOT EXISTS disputes (
+  id bigserial PRIMARY KEY,
+  swap_id bigint NOT NULL REFERENCES swaps(id) ON DELETE CASCADE,
+  opened_by_user_id bigint NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  created_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+  status text NOT NULL CHECK (status IN ('open', 'under_review', 'resolved')),
+  reason text NOT NULL,
+  resolution text NOT NULL DEFAULT '',
+  resolved_at timestamp(0) with time zone,
+  resolved_by_user_id bigint REFERENCES users(id) ON DELETE SET NULL,
+  version integer NOT NULL DEFAULT 1
+);
+
+-- A swap can have only one unresolved dispute at a time.
+CREATE UNIQUE INDEX IF NOT EXISTS disputes_swap_id_unresolved_idx ON disputes(swap_id) WHERE status <> 'resolved';
+
+CREATE TABLE IF NOT EXISTS dispute_notes (
+  id bigserial PRIMARY KEY,
+  dispute_id bigint NOT NULL REFERENCES disputes(id) ON DELETE CASCADE,
+  author_user_id bigint NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  created_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+  body text NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS dispute_notes_dispute_id_idx ON dispute_notes(dispute_id);
+
+INSERT INTO permissions (code)
+  VALUES ('disputes:moderate');
+
+INSERT INTO roles_permissions (role_id, permission_id)
+  SELECT roles.id, permissions.id
+    FROM roles
+    CROSS JOIN permissions
+  WHERE roles.code = 'admin'
+    AND permissions.code = 'disputes:moderate';
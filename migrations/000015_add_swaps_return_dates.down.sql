@@ -0,0 +1,8 @@
+DROP INDEX IF EXISTS swaps_accepted_return_by_idx;
+
+ALTER TABLE swaps DROP CONSTRAINT IF EXISTS swaps_return_by_check;
+
+ALTER TABLE swaps DROP COLUMN IF EXISTS overdue_at;
+ALTER TABLE swaps DROP COLUMN IF EXISTS is_overdue;
+ALTER TABLE swaps DROP COLUMN IF EXISTS return_by;
+ALTER TABLE swaps DROP COLUMN IF EXISTS starts_at;
@@ -0,0 +1,8 @@
+ALTER TABLE swaps ADD COLUMN IF NOT EXISTS starts_at timestamp(0) with time zone;
+ALTER TABLE swaps ADD COLUMN IF NOT EXISTS return_by timestamp(0) with time zone;
+ALTER TABLE swaps ADD COLUMN IF NOT EXISTS is_overdue boolean NOT NULL DEFAULT FALSE;
+ALTER TABLE swaps ADD COLUMN IF NOT EXISTS overdue_at timestamp(0) with time zone;
+
+ALTER TABLE swaps ADD CONSTRAINT swaps_return_by_check CHECK (return_by > starts_at);
+
+CREATE INDEX IF NOT EXISTS swaps_accepted_return_by_idx ON swaps(return_by) WHERE is_accepted AND NOT is_ended AND NOT is_overdue;
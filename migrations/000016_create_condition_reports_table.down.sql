@@ -0,0 +1 @@
+DROP TABLE IF EXISTS condition_reports;
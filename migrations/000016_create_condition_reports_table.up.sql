@@ -0,0 +1,12 @@
+CREATE TABLE IF NOT EXISTS condition_reports (
+  id bigserial PRIMARY KEY,
+  swap_id bigint NOT NULL REFERENCES swaps(id) ON DELETE CASCADE,
+  instrument_id bigint NOT NULL REFERENCES instruments(id) ON DELETE CASCADE,
+  reporter_user_id bigint NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  created_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+  stage text NOT NULL CHECK (stage IN ('handover', 'return')),
+  grade text NOT NULL CHECK (grade IN ('mint', 'excellent', 'good', 'fair', 'poor')),
+  defects text[] NOT NULL DEFAULT '{}',
+  notes text NOT NULL DEFAULT '',
+  CONSTRAINT condition_reports_swap_instrument_reporter_stage_key UNIQUE (swap_id, instrument_id, reporter_user_id, stage)
+);
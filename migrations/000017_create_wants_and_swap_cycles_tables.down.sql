@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS swap_cycle_legs;
+DROP TABLE IF EXISTS swap_cycles;
+DROP TABLE IF EXISTS wants;
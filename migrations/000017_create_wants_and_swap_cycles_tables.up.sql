@@ -0,0 +1,38 @@
+CREATE TABLE IF NOT EXISTS wants (
+  id bigserial PRIMARY KEY,
+  user_id bigint NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  created_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+  instrument_type text NOT NULL DEFAULT '',
+  manufacturer text NOT NULL DEFAULT '',
+  instrument_id bigint REFERENCES instruments(id) ON DELETE CASCADE,
+  CHECK (instrument_type <> '' OR manufacturer <> '' OR instrument_id IS NOT NULL)
+);
+
+CREATE INDEX IF NOT EXISTS wants_user_id_idx ON wants(user_id);
+
+CREATE TABLE IF NOT EXISTS swap_cycles (
+  id bigserial PRIMARY KEY,
+  created_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+  status text NOT NULL CHECK (status IN ('proposed', 'active', 'rejected', 'ended')),
+  activated_at timestamp(0) with time zone,
+  rejected_at timestamp(0) with time zone,
+  ended_at timestamp(0) with time zone,
+  version integer NOT NULL DEFAULT 1
+);
+
+CREATE TABLE IF NOT EXISTS swap_cycle_legs (
+  id bigserial PRIMARY KEY,
+  swap_cycle_id bigint NOT NULL REFERENCES swap_cycles(id) ON DELETE CASCADE,
+  position integer NOT NULL,
+  giver_user_id bigint NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  receiver_user_id bigint NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  instrument_id bigint NOT NULL REFERENCES instruments(id) ON DELETE RESTRICT,
+  want_id bigint REFERENCES wants(id) ON DELETE SET NULL,
+  is_accepted boolean NOT NULL DEFAULT FALSE,
+  accepted_at timestamp(0) with time zone,
+  UNIQUE (swap_cycle_id, position),
+  UNIQUE (swap_cycle_id, giver_user_id)
+);
+
+CREATE INDEX IF NOT EXISTS swap_cycle_legs_giver_user_id_idx ON swap_cycle_legs(giver_user_id);
+CREATE INDEX IF NOT EXISTS swap_cycle_legs_instrument_id_idx ON swap_cycle_legs(instrument_id);
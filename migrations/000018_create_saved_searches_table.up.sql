@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS saved_searches (
+  id bigserial PRIMARY KEY,
+  user_id bigint NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  created_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+  name text NOT NULL DEFAULT '',
+  manufacturer text NOT NULL DEFAULT '',
+  type text NOT NULL DEFAULT '',
+  famous_owners text[] NOT NULL DEFAULT '{}'
+);
+
+CREATE INDEX IF NOT EXISTS saved_searches_user_id_idx ON saved_searches(user_id);
@@ -0,0 +1,5 @@
+DELETE FROM permissions WHERE code = 'categories:write';
+
+ALTER TABLE instruments DROP CONSTRAINT IF EXISTS instruments_type_fkey;
+
+DROP TABLE IF EXISTS categories;
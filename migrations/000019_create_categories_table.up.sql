@@ -0,0 +1,45 @@
+CREATE TABLE IF NOT EXISTS categories (
+  id bigserial PRIMARY KEY,
+  parent_id bigint REFERENCES categories(id) ON DELETE RESTRICT,
+  created_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+  name text NOT NULL,
+  version integer NOT NULL DEFAULT 1,
+  CONSTRAINT categories_name_key UNIQUE (name)
+);
+
+CREATE INDEX IF NOT EXISTS categories_parent_id_idx ON categories(parent_id);
+
+INSERT INTO categories (name)
+  VALUES ('keyboard'), ('guitar'), ('bass'), ('drums'), ('amplifier'), ('pedal');
+
+INSERT INTO categories (parent_id, name)
+  SELECT categories.id, subtypes.name
+    FROM categories
+    JOIN (VALUES
+      ('keyboard', 'synthesizer'),
+      ('keyboard', 'piano'),
+      ('keyboard', 'organ'),
+      ('guitar', 'electric-guitar'),
+      ('guitar', 'acoustic-guitar'),
+      ('bass', 'electric-bass'),
+      ('bass', 'double-bass'),
+      ('drums', 'drum-kit'),
+      ('drums', 'drum-machine')
+    ) AS subtypes (parent, name) ON subtypes.parent = categories.name;
+
+INSERT INTO categories (name)
+  SELECT DISTINCT type FROM instruments
+  ON CONFLICT (name) DO NOTHING;
+
+ALTER TABLE instruments
+  ADD CONSTRAINT instruments_type_fkey FOREIGN KEY (type) REFERENCES categories(name) ON UPDATE CASCADE ON DELETE RESTRICT;
+
+INSERT INTO permissions (code)
+  VALUES ('categories:write');
+
+INSERT INTO roles_permissions (role_id, permission_id)
+  SELECT roles.id, permissions.id
+    FROM roles
+    CROSS JOIN permissions
+  WHERE roles.code = 'admin'
+    AND permissions.code = 'categories:write';
@@ -0,0 +1,15 @@
+CREATE TABLE IF NOT EXISTS instrument_photos (
+  id bigserial PRIMARY KEY,
+  instrument_id bigint NOT NULL REFERENCES instruments(id) ON DELETE CASCADE,
+  created_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+  position integer NOT NULL CHECK (position > 0),
+  content_type text NOT NULL,
+  size bigint NOT NULL,
+  width integer NOT NULL,
+  height integer NOT NULL,
+  thumbnail_width integer NOT NULL,
+  thumbnail_height integer NOT NULL,
+  image_key text NOT NULL,
+  thumbnail_key text NOT NULL,
+  CONSTRAINT instrument_photos_instrument_id_position_key UNIQUE (instrument_id, position) DEFERRABLE INITIALLY DEFERRED
+);
@@ -0,0 +1,17 @@
+CREATE TABLE IF NOT EXISTS instrument_revisions (
+  id bigserial PRIMARY KEY,
+  instrument_id bigint NOT NULL REFERENCES instruments(id) ON DELETE CASCADE,
+  version integer NOT NULL,
+  changed_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+  changed_by_user_id bigint REFERENCES users(id) ON DELETE SET NULL,
+  name text NOT NULL,
+  manufacturer text NOT NULL,
+  manufacture_year integer NOT NULL,
+  type text NOT NULL,
+  estimated_value integer NOT NULL,
+  condition text NOT NULL,
+  description text,
+  famous_owners text[],
+  owner_user_id bigint NOT NULL,
+  CONSTRAINT instrument_revisions_instrument_id_version_key UNIQUE (instrument_id, version)
+);
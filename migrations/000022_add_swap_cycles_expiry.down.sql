@@ -0,0 +1,8 @@
+DROP INDEX IF EXISTS swap_cycles_proposed_created_at_idx;
+
+UPDATE swap_cycles SET status = 'rejected', rejected_at = expired_at WHERE status = 'expired';
+
+ALTER TABLE swap_cycles DROP CONSTRAINT IF EXISTS swap_cycles_status_check;
+ALTER TABLE swap_cycles ADD CONSTRAINT swap_cycles_status_check CHECK (status IN ('proposed', 'active', 'rejected', 'ended'));
+
+ALTER TABLE swap_cycles DROP COLUMN IF EXISTS expired_at;
@@ -0,0 +1,6 @@
+ALTER TABLE swap_cycles ADD COLUMN IF NOT EXISTS expired_at timestamp(0) with time zone;
+
+ALTER TABLE swap_cycles DROP CONSTRAINT IF EXISTS swap_cycles_status_check;
+ALTER TABLE swap_cycles ADD CONSTRAINT swap_cycles_status_check CHECK (status IN ('proposed', 'active', 'rejected', 'expired', 'ended'));
+
+CREATE INDEX IF NOT EXISTS swap_cycles_proposed_created_at_idx ON swap_cycles(created_at) WHERE status = 'proposed';